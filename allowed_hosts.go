@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// AllowedHosts creates a new middleware handler rejecting requests whose
+// Host header isn't listed with 400 Bad Request. An entry of the form
+// "*.example.com" allows every subdomain of example.com but not the domain
+// itself.
+func AllowedHosts(hosts ...string) http.HandlerFunc {
+	exact := make(map[string]struct{}, len(hosts))
+	var suffixes []string
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if strings.HasPrefix(host, "*.") {
+			suffixes = append(suffixes, host[1:])
+			continue
+		}
+		exact[host] = struct{}{}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		host := strings.ToLower(c.Origin().Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(host, ".")
+
+		if host != "" {
+			if _, ok := exact[host]; ok {
+				return c.Next()
+			}
+			for _, suffix := range suffixes {
+				if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+					return c.Next()
+				}
+			}
+		}
+		c.AbortWithStatus(utils.StatusBadRequest)
+		return fmt.Errorf("%w: %q", ErrHostDenied, host)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	handler := AllowedHosts("Example.com", " *.api.example.com ")
+	for _, tt := range []struct {
+		host string
+		want int
+	}{
+		{"example.com", utils.StatusOK},
+		{"EXAMPLE.COM:8443", utils.StatusOK},
+		{"example.com.", utils.StatusOK},
+		{"v1.api.example.com", utils.StatusOK},
+		{"a.b.api.example.com", utils.StatusOK},
+		// The wildcard doesn't cover the domain itself
+		{"api.example.com", utils.StatusBadRequest},
+		{"www.example.com", utils.StatusBadRequest},
+		{"evilapi.example.com", utils.StatusBadRequest},
+		{"example.com.evil.com", utils.StatusBadRequest},
+		{"", utils.StatusBadRequest},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = tt.host
+		c := run(t, req, handler, ok)
+		if c.Recorder.Code != tt.want {
+			t.Errorf("%q: status = %d, want %d", tt.host, c.Recorder.Code, tt.want)
+		}
+		if tt.want != utils.StatusOK && !errors.Is(c.Errors()[0], ErrHostDenied) {
+			t.Errorf("%q: err = %v", tt.host, c.Errors()[0])
+		}
+	}
+}
+
+func TestAllowedHostsIPv6(t *testing.T) {
+	handler := AllowedHosts("::1")
+	for host, want := range map[string]int{"[::1]:8080": utils.StatusOK, "[::2]:8080": utils.StatusBadRequest} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		if c := run(t, req, handler, ok); c.Recorder.Code != want {
+			t.Errorf("%q: status = %d, want %d", host, c.Recorder.Code, want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// AuditEvent records one state changing request
+type AuditEvent struct {
+	Time       time.Time     `json:"time"`
+	Principal  string        `json:"principal,omitempty"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	IP         string        `json:"ip"`
+	RequestID  string        `json:"request_id,omitempty"`
+	Status     int           `json:"status"`
+	Duration   time.Duration `json:"duration"`
+	BodyDigest string        `json:"body_digest,omitempty"`
+	// PrevHash is set by JSONAuditSink to the sha256 of the line before,
+	// so removed or edited lines break the chain
+	PrevHash string `json:"prev_hash,omitempty"`
+}
+
+// AuditSink stores audit events
+type AuditSink interface {
+	Write(event AuditEvent) error
+}
+
+// ConfigAudit defines the config for middleware.
+type ConfigAudit struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Methods that are audited
+	//
+	// Optional. Default: POST, PUT, PATCH, DELETE
+	Methods []string
+
+	// PrincipalKeys are the context keys the authenticated principal is
+	// read from when authentication runs before Audit, the first one set
+	// wins. JWTClaims contribute their sub. Authentication running after
+	// Audit reports the principal with SetPrincipal.
+	//
+	// Optional. Default: "username" (BasicAuth), "claims" (JWT)
+	PrincipalKeys []string
+
+	// RequestIDKey is the context key of the request id when RequestID
+	// runs before Audit. The id of a RequestID middleware after it is
+	// picked up as well, the X-Request-ID header is used without either.
+	//
+	// Optional. Default: "requestid"
+	RequestIDKey string
+
+	// DigestBody adds the sha256 of the request body to the event
+	//
+	// Optional. Default: false
+	DigestBody bool
+
+	// MaxDigestSize is the largest body digested in bytes, larger bodies
+	// are passed on without a digest
+	//
+	// Optional. Default: 1 MB
+	MaxDigestSize int64
+
+	// Redact is applied to the body before it is digested, e.g. to blank
+	// out passwords so equal requests hash the same
+	//
+	// Optional. Default: nil
+	Redact func(body []byte) []byte
+
+	// Sink receives the events once the response is complete, or the
+	// handler panicked
+	//
+	// Required
+	Sink AuditSink
+
+	// OnError is called when the sink fails, the request is not affected
+	//
+	// Optional. Default: nil
+	OnError func(event AuditEvent, err error)
+}
+
+// ConfigAuditDefault is the default config
+var ConfigAuditDefault = ConfigAudit{
+	Next: nil,
+	Methods: []string{
+		utils.MethodPost,
+		utils.MethodPut,
+		utils.MethodPatch,
+		utils.MethodDelete,
+	},
+	PrincipalKeys: []string{"username", "claims"},
+	RequestIDKey:  "requestid",
+	MaxDigestSize: defaultMaxBufferSize,
+}
+
+// Helper function to set default values
+func configAuditDefault(config ...ConfigAudit) ConfigAudit {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigAuditDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Methods == nil {
+		cfg.Methods = ConfigAuditDefault.Methods
+	}
+	if cfg.PrincipalKeys == nil {
+		cfg.PrincipalKeys = ConfigAuditDefault.PrincipalKeys
+	}
+	if cfg.RequestIDKey == "" {
+		cfg.RequestIDKey = ConfigAuditDefault.RequestIDKey
+	}
+	if cfg.MaxDigestSize <= 0 {
+		cfg.MaxDigestSize = ConfigAuditDefault.MaxDigestSize
+	}
+	return cfg
+}
+
+// Audit creates a new middleware handler
+func Audit(config ConfigAudit) http.HandlerFunc {
+	// Set default config
+	cfg := configAuditDefault(config)
+
+	if cfg.Sink == nil {
+		panic("audit: Sink is required")
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if !containsMethod(cfg.Methods, c.Method()) {
+			return c.Next()
+		}
+
+		event := AuditEvent{
+			Time:      time.Now(),
+			Method:    c.Method(),
+			Path:      c.Origin().URL.Path,
+			IP:        peerAddr(c),
+			Principal: auditPrincipal(c, cfg.PrincipalKeys),
+		}
+		if rid, ok := c.Value(cfg.RequestIDKey).(string); ok {
+			event.RequestID = rid
+		}
+		if cfg.DigestBody {
+			event.BodyDigest = auditBodyDigest(c, cfg.Redact, cfg.MaxDigestSize)
+		}
+		info := trackRequest(c)
+
+		// Write the event even when the handler panics
+		defer func() {
+			r := recover()
+
+			// What the middlewares after us stored is invisible, they report it
+			if principal := info.loadPrincipal(); principal != "" {
+				event.Principal = principal
+			}
+			if rid := info.loadRequestID(); rid != "" {
+				event.RequestID = rid
+			}
+			if event.RequestID == "" {
+				event.RequestID = c.Header(utils.HeaderXRequestID, "")
+			}
+			event.Status = c.StatusCode()
+			if r != nil {
+				event.Status = utils.StatusInternalServerError
+			}
+			event.Duration = time.Since(event.Time)
+
+			if sErr := cfg.Sink.Write(event); sErr != nil && cfg.OnError != nil {
+				cfg.OnError(event, sErr)
+			}
+			if r != nil {
+				panic(r)
+			}
+		}()
+		return c.Next()
+	}
+}
+
+// auditPrincipal returns the first principal stored under one of the keys
+func auditPrincipal(c http.Context, keys []string) string {
+	for _, key := range keys {
+		switch v := c.Value(key).(type) {
+		case nil:
+			continue
+		case string:
+			if v != "" {
+				return v
+			}
+		case JWTClaims:
+			if sub, ok := v["sub"].(string); ok && sub != "" {
+				return sub
+			}
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// auditBodyDigest hashes the request body and restores it for the handler,
+// bodies over max aren't hashed
+func auditBodyDigest(c http.Context, redact func([]byte) []byte, max int64) string {
+	req := c.Origin()
+	if req == nil || req.Body == nil || req.ContentLength > max {
+		return ""
+	}
+	body, _ := io.ReadAll(io.LimitReader(req.Body, max+1))
+	if int64(len(body)) > max {
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+		return ""
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if redact != nil {
+		body = redact(append([]byte(nil), body...))
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryAuditSink keeps the events in memory, e.g. for tests
+type MemoryAuditSink struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+// Write stores the event
+func (s *MemoryAuditSink) Write(event AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Events returns a copy of the stored events
+func (s *MemoryAuditSink) Events() []AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEvent(nil), s.events...)
+}
+
+// JSONAuditSink writes every event as a line of JSON. Each line carries
+// the hash of the one before in prev_hash, see VerifyJSONAuditLog.
+type JSONAuditSink struct {
+	mu       sync.Mutex
+	w        io.Writer
+	prev     string
+	failures atomic.Uint64
+}
+
+// NewJSONAuditSink creates a sink writing JSON lines to w
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w}
+}
+
+// Resume continues the chain of an existing log whose last line is last,
+// e.g. when the sink appends to the file of a previous run
+func (s *JSONAuditSink) Resume(last []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prev = auditLineHash(bytes.TrimRight(last, "\n"))
+}
+
+// Write appends the event to the writer
+func (s *JSONAuditSink) Write(event AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event.PrevHash = s.prev
+	line, err := json.Marshal(event)
+	if err == nil {
+		_, err = s.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		s.failures.Add(1)
+		return err
+	}
+	s.prev = auditLineHash(line)
+	return nil
+}
+
+// Failures returns the number of events the sink failed to write so far
+func (s *JSONAuditSink) Failures() uint64 {
+	return s.failures.Load()
+}
+
+// VerifyJSONAuditLog checks that every line of a log written by
+// JSONAuditSink carries the hash of the line before. The first line isn't
+// checked, it may continue a log rotated away.
+func VerifyJSONAuditLog(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, defaultMaxBufferSize)
+	var prev string
+	for n := 1; scanner.Scan(); n++ {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("audit: line %d: %w", n, err)
+		}
+		if n > 1 && event.PrevHash != prev {
+			return fmt.Errorf("audit: line %d doesn't follow line %d", n, n-1)
+		}
+		prev = auditLineHash(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+func auditLineHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
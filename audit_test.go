@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+type failingAuditSink struct{}
+
+func (failingAuditSink) Write(AuditEvent) error { return errors.New("disk full") }
+
+func TestAuditRecordsDownstreamPrincipalAndRequestID(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	req := basicAuthRequest("john", "doe")
+	req.Method = "POST"
+	req.RemoteAddr = "192.0.2.7:4000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	req.Header.Set(utils.HeaderXRequestID, "rid-1")
+	run(t, req,
+		Audit(ConfigAudit{Sink: sink}),
+		RequestID(),
+		BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}}),
+		func(c http.Context) error {
+			return c.Status(utils.StatusCreated).String("created")
+		},
+	)
+
+	events := sink.Events()
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	e := events[0]
+	if e.Principal != "john" {
+		t.Errorf("principal = %q", e.Principal)
+	}
+	if e.RequestID != "rid-1" {
+		t.Errorf("request id = %q", e.RequestID)
+	}
+	if e.IP != "192.0.2.7" {
+		t.Errorf("ip = %q, want the peer address", e.IP)
+	}
+	if e.Status != utils.StatusCreated || e.Method != "POST" || e.Path != "/" {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestAuditPrincipalFromEarlierMiddleware(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	req := basicAuthRequest("john", "doe")
+	req.Method = "DELETE"
+	run(t, req,
+		BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}}),
+		Audit(ConfigAudit{Sink: sink}),
+		ok,
+	)
+	if events := sink.Events(); len(events) != 1 || events[0].Principal != "john" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestAuditMethodFiltering(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	handler := Audit(ConfigAudit{Sink: sink, Methods: []string{"PUT"}})
+	for _, method := range []string{"GET", "POST", "PUT"} {
+		run(t, httptest.NewRequest(method, "/", nil), handler, ok)
+	}
+	if events := sink.Events(); len(events) != 1 || events[0].Method != "PUT" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestAuditRedactedDigest(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	redact := func(body []byte) []byte {
+		return bytes.ReplaceAll(body, []byte("hunter2"), []byte("***"))
+	}
+	var seen string
+	handler := func(c http.Context) error {
+		body, _ := io.ReadAll(c.Origin().Body)
+		seen = string(body)
+		return nil
+	}
+	run(t, httptest.NewRequest("POST", "/", strings.NewReader(`{"password":"hunter2"}`)),
+		Audit(ConfigAudit{Sink: sink, DigestBody: true, Redact: redact}), handler)
+
+	sum := sha256.Sum256([]byte(`{"password":"***"}`))
+	if events := sink.Events(); len(events) != 1 || events[0].BodyDigest != hex.EncodeToString(sum[:]) {
+		t.Errorf("events = %+v", events)
+	}
+	if seen != `{"password":"hunter2"}` {
+		t.Errorf("handler read %q, want the unredacted body", seen)
+	}
+}
+
+func TestAuditDigestCap(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	body := strings.Repeat("a", 100)
+	var seen int
+	handler := func(c http.Context) error {
+		b, _ := io.ReadAll(c.Origin().Body)
+		seen = len(b)
+		return nil
+	}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.ContentLength = -1
+	run(t, req, Audit(ConfigAudit{Sink: sink, DigestBody: true, MaxDigestSize: 10}), handler)
+	if events := sink.Events(); len(events) != 1 || events[0].BodyDigest != "" {
+		t.Errorf("events = %+v, want no digest", events)
+	}
+	if seen != len(body) {
+		t.Errorf("handler read %d bytes, want %d", seen, len(body))
+	}
+}
+
+func TestAuditSinkFailure(t *testing.T) {
+	failures := 0
+	c := run(t, httptest.NewRequest("POST", "/", nil), Audit(ConfigAudit{
+		Sink:    failingAuditSink{},
+		OnError: func(AuditEvent, error) { failures++ },
+	}), ok)
+	if failures != 1 {
+		t.Errorf("failures = %d", failures)
+	}
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "ok" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+}
+
+func TestJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONAuditSink(&buf)
+	run(t, httptest.NewRequest("PATCH", "/a", nil), Audit(ConfigAudit{Sink: sink}), ok)
+	run(t, httptest.NewRequest("PATCH", "/b", nil), Audit(ConfigAudit{Sink: sink}), ok)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	var e AuditEvent
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil || e.Path != "/b" {
+		t.Errorf("event = %+v, %v", e, err)
+	}
+}
+
+func TestJSONAuditSinkChain(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONAuditSink(&buf)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		run(t, httptest.NewRequest("DELETE", path, nil), Audit(ConfigAudit{Sink: sink}), ok)
+	}
+	log := buf.String()
+	if err := VerifyJSONAuditLog(strings.NewReader(log)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(log, "\n")
+	for name, tampered := range map[string]string{
+		"edited":  lines[0] + strings.Replace(lines[1], "/b", "/x", 1) + lines[2],
+		"removed": lines[0] + lines[2],
+	} {
+		if err := VerifyJSONAuditLog(strings.NewReader(tampered)); err == nil {
+			t.Errorf("%s line not detected", name)
+		}
+	}
+
+	// A new sink appending to the log continues the chain
+	resumed := NewJSONAuditSink(&buf)
+	resumed.Resume([]byte(lines[2]))
+	run(t, httptest.NewRequest("DELETE", "/d", nil), Audit(ConfigAudit{Sink: resumed}), ok)
+	if err := VerifyJSONAuditLog(&buf); err != nil {
+		t.Error(err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestJSONAuditSinkFailures(t *testing.T) {
+	sink := NewJSONAuditSink(failingWriter{})
+	handler := Audit(ConfigAudit{Sink: sink})
+	run(t, httptest.NewRequest("POST", "/", nil), handler, ok)
+	run(t, httptest.NewRequest("POST", "/", nil), handler, ok)
+	if sink.Failures() != 2 {
+		t.Errorf("failures = %d", sink.Failures())
+	}
+}
+
+func TestAuditPanic(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v", r)
+		}
+		if events := sink.Events(); len(events) != 1 || events[0].Status != utils.StatusInternalServerError {
+			t.Errorf("events = %+v", events)
+		}
+	}()
+	run(t, httptest.NewRequest("POST", "/", nil), Audit(ConfigAudit{Sink: sink}), func(c http.Context) error {
+		panic("boom")
+	})
+}
@@ -3,11 +3,17 @@ package middleware
 import (
 	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
+	"math"
 	http2 "net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sujit-baniya/framework/contracts/http"
 	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/internal/defaults"
 )
 
 // ConfigBasicAuth defines the config for middleware.
@@ -29,6 +35,17 @@ type ConfigBasicAuth struct {
 	// Optional. Default: "Restricted".
 	Realm string
 
+	// Scheme is the auth scheme of the WWW-Authenticate challenge, API
+	// clients can be given a custom one to keep browsers from prompting
+	//
+	// Optional. Default: "basic"
+	Scheme string
+
+	// OmitChallenge leaves the WWW-Authenticate header out of 401 responses
+	//
+	// Optional. Default: false
+	OmitChallenge bool
+
 	// Authorizer defines a function you can pass
 	// to check the credentials however you want.
 	// It will be called with a username and password
@@ -53,6 +70,24 @@ type ConfigBasicAuth struct {
 	//
 	// Optional. Default: "password"
 	ContextPassword string
+
+	// LenientBase64 also accepts credentials encoded with the URL-safe or
+	// unpadded base64 alphabets, which some non-browser clients send
+	//
+	// Optional. Default: false
+	LenientBase64 bool
+
+	// MaxFailures is the number of failed attempts after which a username
+	// is locked out, 0 disables the lockout
+	//
+	// Optional. Default: 0
+	MaxFailures int
+
+	// LockoutDuration is how long a username stays locked out, locked out
+	// requests get a 429 with a Retry-After header
+	//
+	// Optional. Default: 15 * time.Minute
+	LockoutDuration time.Duration
 }
 
 // ConfigBasicAuthDefault is the default config
@@ -60,57 +95,50 @@ var ConfigBasicAuthDefault = ConfigBasicAuth{
 	Next:            nil,
 	Users:           map[string]string{},
 	Realm:           "Restricted",
+	Scheme:          "basic",
 	Authorizer:      nil,
 	Unauthorized:    nil,
 	ContextUsername: "username",
 	ContextPassword: "password",
+	LockoutDuration: 15 * time.Minute,
 }
 
 // Helper function to set default values
 func configBasicAuthDefault(config ...ConfigBasicAuth) ConfigBasicAuth {
-	// Return default config if nothing provided
-	if len(config) < 1 {
-		return ConfigBasicAuthDefault
-	}
-
-	// Override default config
-	cfg := config[0]
-
-	// Set default values
-	if cfg.Next == nil {
-		cfg.Next = ConfigBasicAuthDefault.Next
-	}
-	if cfg.Users == nil {
-		cfg.Users = ConfigBasicAuthDefault.Users
-	}
-	if cfg.Realm == "" {
-		cfg.Realm = ConfigBasicAuthDefault.Realm
-	}
-	if cfg.Authorizer == nil {
-		cfg.Authorizer = func(user, pass string) bool {
-			userPwd, exist := cfg.Users[user]
-			return exist && subtle.ConstantTimeCompare(utils.UnsafeBytes(userPwd), utils.UnsafeBytes(pass)) == 1
+	return defaults.Config(config, ConfigBasicAuthDefault, func(cfg *ConfigBasicAuth) {
+		if cfg.Next == nil {
+			cfg.Next = ConfigBasicAuthDefault.Next
 		}
-	}
-	if cfg.Unauthorized == nil {
-		cfg.Unauthorized = func(c http.Context) error {
-			c.SetHeader("WWW-Authenticate", "basic realm="+cfg.Realm)
-			c.AbortWithStatus(http2.StatusUnauthorized)
-			return utils.ErrUnauthorized
+		if cfg.Users == nil {
+			cfg.Users = ConfigBasicAuthDefault.Users
 		}
-	}
-	if cfg.ContextUsername == "" {
-		cfg.ContextUsername = ConfigBasicAuthDefault.ContextUsername
-	}
-	if cfg.ContextPassword == "" {
-		cfg.ContextPassword = ConfigBasicAuthDefault.ContextPassword
-	}
-	return cfg
+		defaults.Value(&cfg.Realm, ConfigBasicAuthDefault.Realm)
+		defaults.Value(&cfg.Scheme, ConfigBasicAuthDefault.Scheme)
+		if cfg.Authorizer == nil {
+			cfg.Authorizer = func(user, pass string) bool {
+				userPwd, exist := cfg.Users[user]
+				return exist && subtle.ConstantTimeCompare(utils.UnsafeBytes(userPwd), utils.UnsafeBytes(pass)) == 1
+			}
+		}
+		if cfg.Unauthorized == nil {
+			cfg.Unauthorized = func(c http.Context) error {
+				if !cfg.OmitChallenge {
+					c.SetHeader("WWW-Authenticate", cfg.Scheme+" realm="+cfg.Realm)
+				}
+				c.AbortWithStatus(http2.StatusUnauthorized)
+				return ErrUnauthorizedBasicAuth
+			}
+		}
+		defaults.Value(&cfg.ContextUsername, ConfigBasicAuthDefault.ContextUsername)
+		defaults.Value(&cfg.ContextPassword, ConfigBasicAuthDefault.ContextPassword)
+		defaults.Positive(&cfg.LockoutDuration, ConfigBasicAuthDefault.LockoutDuration)
+	})
 }
 
 func BasicAuth(config ConfigBasicAuth) http.HandlerFunc {
 	// Set default config
 	cfg := configBasicAuthDefault(config)
+	lockout := &authLockout{failures: make(map[string]*authFailures), pruneAt: authLockoutPrune}
 	return func(c http.Context) error {
 		// Don't execute middleware if Next returns true
 		if cfg.Next != nil && cfg.Next(c) {
@@ -127,6 +155,9 @@ func BasicAuth(config ConfigBasicAuth) http.HandlerFunc {
 
 		// Decode the header contents
 		raw, err := base64.StdEncoding.DecodeString(auth[6:])
+		if err != nil && cfg.LenientBase64 {
+			raw, err = decodeLenientBase64(auth[6:])
+		}
 		if err != nil {
 			return cfg.Unauthorized(c)
 		}
@@ -145,13 +176,110 @@ func BasicAuth(config ConfigBasicAuth) http.HandlerFunc {
 		username := creds[:index]
 		password := creds[index+1:]
 
+		if cfg.MaxFailures > 0 {
+			if wait := lockout.remaining(username); wait > 0 {
+				retryAfter := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+				c.SetHeader(utils.HeaderRetryAfter, retryAfter)
+				c.AbortWithStatus(utils.StatusTooManyRequests)
+				return fmt.Errorf("%w: retry after %ss", ErrBasicAuthLockedOut, retryAfter)
+			}
+		}
+
 		if cfg.Authorizer(username, password) {
+			if cfg.MaxFailures > 0 {
+				lockout.reset(username)
+			}
 			c.WithValue(cfg.ContextUsername, username)
 			c.WithValue(cfg.ContextPassword, password)
+			SetPrincipal(c, username)
 			return c.Next()
 		}
 
 		// Authentication failed
+		if cfg.MaxFailures > 0 {
+			lockout.fail(username, cfg.MaxFailures, cfg.LockoutDuration)
+		}
 		return cfg.Unauthorized(c)
 	}
 }
+
+// decodeLenientBase64 tries the base64 variants clients get wrong
+func decodeLenientBase64(s string) (raw []byte, err error) {
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if raw, err = enc.DecodeString(s); err == nil {
+			return raw, nil
+		}
+	}
+	return nil, err
+}
+
+// authLockout counts failed attempts per username
+type authLockout struct {
+	mu       sync.Mutex
+	failures map[string]*authFailures
+	pruneAt  int
+}
+
+type authFailures struct {
+	count int
+	last  time.Time
+	until time.Time
+}
+
+// authLockoutPrune is the least number of tracked usernames at which stale
+// entries are dropped. Usernames that failed within the lockout duration are
+// kept, so the map holds one entry per username tried in that window.
+const authLockoutPrune = 10000
+
+// remaining returns how long the username is still locked out
+func (l *authLockout) remaining(username string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[username]
+	if !ok || f.until.IsZero() {
+		return 0
+	}
+	wait := time.Until(f.until)
+	if wait <= 0 {
+		delete(l.failures, username)
+		return 0
+	}
+	return wait
+}
+
+// fail records a failed attempt and locks the username out once max is reached
+func (l *authLockout) fail(username string, max int, duration time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	f, ok := l.failures[username]
+	if !ok || now.Sub(f.last) > duration {
+		f = &authFailures{}
+		l.failures[username] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= max {
+		f.until = now.Add(duration)
+	}
+	if len(l.failures) < l.pruneAt {
+		return
+	}
+	for name, f := range l.failures {
+		if now.Sub(f.last) > duration && now.After(f.until) {
+			delete(l.failures, name)
+		}
+	}
+	// Wait for the map to double before the next pass, keeping fail
+	// amortized O(1) when most usernames are still tracked
+	l.pruneAt = 2 * len(l.failures)
+	if l.pruneAt < authLockoutPrune {
+		l.pruneAt = authLockoutPrune
+	}
+}
+
+func (l *authLockout) reset(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, username)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// BasicAuthOption sets a field of ConfigBasicAuth, see BasicAuthWith
+type BasicAuthOption func(cfg *ConfigBasicAuth)
+
+// BasicAuthWith creates a BasicAuth middleware from options applied to
+// ConfigBasicAuthDefault in order, a later option overrides an earlier one
+//
+//	middleware.BasicAuthWith(
+//		middleware.BasicAuthUsers(map[string]string{"admin": secret}),
+//		middleware.BasicAuthLockout(5, 15*time.Minute),
+//	)
+func BasicAuthWith(opts ...BasicAuthOption) http.HandlerFunc {
+	cfg := ConfigBasicAuthDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return BasicAuth(cfg)
+}
+
+// BasicAuthOptions bundles options into one, e.g. to share a preset
+// between services
+func BasicAuthOptions(opts ...BasicAuthOption) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// BasicAuthNext sets ConfigBasicAuth.Next
+func BasicAuthNext(next func(c http.Context) bool) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.Next = next }
+}
+
+// BasicAuthUsers sets ConfigBasicAuth.Users to a copy of users
+func BasicAuthUsers(users map[string]string) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) {
+		cfg.Users = make(map[string]string, len(users))
+		for user, pass := range users {
+			cfg.Users[user] = pass
+		}
+	}
+}
+
+// BasicAuthRealm sets ConfigBasicAuth.Realm
+func BasicAuthRealm(realm string) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.Realm = realm }
+}
+
+// BasicAuthScheme sets ConfigBasicAuth.Scheme
+func BasicAuthScheme(scheme string) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.Scheme = scheme }
+}
+
+// BasicAuthOmitChallenge sets ConfigBasicAuth.OmitChallenge
+func BasicAuthOmitChallenge() BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.OmitChallenge = true }
+}
+
+// BasicAuthAuthorizer sets ConfigBasicAuth.Authorizer
+func BasicAuthAuthorizer(authorizer func(user, pass string) bool) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.Authorizer = authorizer }
+}
+
+// BasicAuthUnauthorized sets ConfigBasicAuth.Unauthorized
+func BasicAuthUnauthorized(handler http.HandlerFunc) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.Unauthorized = handler }
+}
+
+// BasicAuthContextKeys sets ConfigBasicAuth.ContextUsername and
+// ContextPassword
+func BasicAuthContextKeys(username, password string) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) {
+		cfg.ContextUsername = username
+		cfg.ContextPassword = password
+	}
+}
+
+// BasicAuthLenientBase64 sets ConfigBasicAuth.LenientBase64
+func BasicAuthLenientBase64() BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) { cfg.LenientBase64 = true }
+}
+
+// BasicAuthLockout sets ConfigBasicAuth.MaxFailures and LockoutDuration
+func BasicAuthLockout(maxFailures int, d time.Duration) BasicAuthOption {
+	return func(cfg *ConfigBasicAuth) {
+		cfg.MaxFailures = maxFailures
+		cfg.LockoutDuration = d
+	}
+}
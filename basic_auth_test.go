@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func basicAuthRequest(user, pass string) *stdHttp.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAuthorization, "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+	return req
+}
+
+func TestBasicAuthValidCredentials(t *testing.T) {
+	var username any
+	c := run(t, basicAuthRequest("john", "doe"),
+		BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}}),
+		func(c http.Context) error {
+			username = c.Value("username")
+			return c.String("ok")
+		},
+	)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "ok" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+	if username != "john" {
+		t.Errorf("username = %v", username)
+	}
+}
+
+func TestBasicAuthInvalidCredentials(t *testing.T) {
+	for name, req := range map[string]*stdHttp.Request{
+		"wrong password": basicAuthRequest("john", "wrong"),
+		"unknown user":   basicAuthRequest("jane", "doe"),
+		"missing":        httptest.NewRequest("GET", "/", nil),
+	} {
+		c := run(t, req, BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}}), ok)
+		if c.Recorder.Code != utils.StatusUnauthorized {
+			t.Errorf("%s: status = %d", name, c.Recorder.Code)
+		}
+		if got := c.Recorder.Header().Get("WWW-Authenticate"); got != "basic realm=Restricted" {
+			t.Errorf("%s: challenge = %q", name, got)
+		}
+		if c.Body() != "" {
+			t.Errorf("%s: handler ran", name)
+		}
+		if err := c.Errors()[0]; !errors.Is(err, ErrUnauthorizedBasicAuth) || !errors.Is(err, utils.ErrUnauthorized) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestBasicAuthChallenge(t *testing.T) {
+	users := map[string]string{"john": "doe"}
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"default", BasicAuth(ConfigBasicAuth{Users: users}), "basic realm=Restricted"},
+		{"custom", BasicAuth(ConfigBasicAuth{Users: users, Scheme: "X-Basic", Realm: "api"}), "X-Basic realm=api"},
+		{"omitted", BasicAuth(ConfigBasicAuth{Users: users, OmitChallenge: true}), ""},
+		{"custom option", BasicAuthWith(BasicAuthUsers(users), BasicAuthScheme("Bearer"), BasicAuthRealm("api")), "Bearer realm=api"},
+		{"omitted option", BasicAuthWith(BasicAuthUsers(users), BasicAuthOmitChallenge()), ""},
+	} {
+		c := run(t, basicAuthRequest("john", "wrong"), tt.handler, ok)
+		if c.Recorder.Code != utils.StatusUnauthorized {
+			t.Errorf("%s: status = %d", tt.name, c.Recorder.Code)
+		}
+		if got := c.Recorder.Header().Values("WWW-Authenticate"); strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: challenge = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBasicAuthLenientBase64(t *testing.T) {
+	// "d?e>!" encodes to characters that differ between the alphabets, and
+	// "john:d?e>!" needs padding
+	for _, tt := range []struct {
+		name            string
+		encoded         string
+		strict, lenient int
+	}{
+		{"std", base64.StdEncoding.EncodeToString([]byte("john:d?e>!")), utils.StatusOK, utils.StatusOK},
+		{"raw std", base64.RawStdEncoding.EncodeToString([]byte("john:d?e>!")), utils.StatusUnauthorized, utils.StatusOK},
+		{"url", base64.URLEncoding.EncodeToString([]byte("john:d?e>!")), utils.StatusUnauthorized, utils.StatusOK},
+		{"raw url", base64.RawURLEncoding.EncodeToString([]byte("john:d?e>!")), utils.StatusUnauthorized, utils.StatusOK},
+		{"garbage", "!!not base64!!", utils.StatusUnauthorized, utils.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderAuthorization, "Basic "+tt.encoded)
+		cfg := ConfigBasicAuth{Users: map[string]string{"john": "d?e>!"}}
+		if c := run(t, req, BasicAuth(cfg), ok); c.Recorder.Code != tt.strict {
+			t.Errorf("%s: strict status = %d, want %d", tt.name, c.Recorder.Code, tt.strict)
+		}
+		cfg.LenientBase64 = true
+		if c := run(t, req, BasicAuth(cfg), ok); c.Recorder.Code != tt.lenient {
+			t.Errorf("%s: lenient status = %d, want %d", tt.name, c.Recorder.Code, tt.lenient)
+		}
+	}
+}
+
+func TestBasicAuthLockout(t *testing.T) {
+	handler := BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}, MaxFailures: 2})
+	for i := 0; i < 2; i++ {
+		run(t, basicAuthRequest("john", "wrong"), handler, ok)
+	}
+	c := run(t, basicAuthRequest("john", "doe"), handler, ok)
+	if c.Recorder.Code != utils.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", c.Recorder.Code)
+	}
+	if c.Recorder.Header().Get(utils.HeaderRetryAfter) == "" {
+		t.Error("missing Retry-After")
+	}
+	if err := c.Errors()[0]; !errors.Is(err, ErrBasicAuthLockedOut) {
+		t.Errorf("err = %v", err)
+	}
+	if c := run(t, basicAuthRequest("jane", "x"), handler, ok); c.Recorder.Code != utils.StatusUnauthorized {
+		t.Errorf("other user status = %d", c.Recorder.Code)
+	}
+}
+
+func TestBasicAuthLockoutRetryAfter(t *testing.T) {
+	handler := BasicAuth(ConfigBasicAuth{
+		Users:           map[string]string{"john": "doe"},
+		MaxFailures:     3,
+		LockoutDuration: 90 * time.Second,
+	})
+	for i := 0; i < 2; i++ {
+		if c := run(t, basicAuthRequest("john", "wrong"), handler, ok); c.Recorder.Code != utils.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d", i+1, c.Recorder.Code)
+		}
+	}
+	// The third failure locks the username out, the response still is a 401
+	c := run(t, basicAuthRequest("john", "wrong"), handler, ok)
+	if c.Recorder.Code != utils.StatusUnauthorized || c.Recorder.Header().Get(utils.HeaderRetryAfter) != "" {
+		t.Fatalf("locking failure: status = %d, Retry-After = %q", c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderRetryAfter))
+	}
+
+	// The remaining lockout is rounded up to whole seconds
+	c = run(t, basicAuthRequest("john", "doe"), handler, ok)
+	if got := c.Recorder.Header().Get(utils.HeaderRetryAfter); got != "90" {
+		t.Errorf("Retry-After = %q, want 90", got)
+	}
+	if c.Body() == "ok" {
+		t.Error("locked out request reached the handler")
+	}
+}
+
+func TestBasicAuthLockoutExpires(t *testing.T) {
+	handler := BasicAuth(ConfigBasicAuth{
+		Users:           map[string]string{"john": "doe"},
+		MaxFailures:     1,
+		LockoutDuration: 50 * time.Millisecond,
+	})
+	run(t, basicAuthRequest("john", "wrong"), handler, ok)
+	c := run(t, basicAuthRequest("john", "doe"), handler, ok)
+	if c.Recorder.Code != utils.StatusTooManyRequests || c.Recorder.Header().Get(utils.HeaderRetryAfter) != "1" {
+		t.Fatalf("status = %d, Retry-After = %q", c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderRetryAfter))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if c := run(t, basicAuthRequest("john", "doe"), handler, ok); c.Body() != "ok" {
+		t.Errorf("after the lockout: status = %d", c.Recorder.Code)
+	}
+}
+
+func TestBasicAuthLockoutResetOnSuccess(t *testing.T) {
+	handler := BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}, MaxFailures: 2})
+	for i := 0; i < 3; i++ {
+		// A successful login in between clears the failures
+		run(t, basicAuthRequest("john", "wrong"), handler, ok)
+		if c := run(t, basicAuthRequest("john", "doe"), handler, ok); c.Body() != "ok" {
+			t.Fatalf("round %d: status = %d", i, c.Recorder.Code)
+		}
+	}
+}
+
+func TestBasicAuthLockoutPrune(t *testing.T) {
+	l := &authLockout{failures: make(map[string]*authFailures), pruneAt: authLockoutPrune}
+	for i := 0; i < authLockoutPrune; i++ {
+		l.fail(strconv.Itoa(i), 5, time.Hour)
+	}
+	// Nothing is stale yet, the next pass waits for the map to double
+	if len(l.failures) != authLockoutPrune || l.pruneAt != 2*authLockoutPrune {
+		t.Fatalf("tracked = %d, pruneAt = %d", len(l.failures), l.pruneAt)
+	}
+
+	for i := authLockoutPrune; i < 2*authLockoutPrune; i++ {
+		l.fail(strconv.Itoa(i), 5, time.Nanosecond)
+	}
+	if len(l.failures) > 1 || l.pruneAt != authLockoutPrune {
+		t.Errorf("tracked = %d, pruneAt = %d", len(l.failures), l.pruneAt)
+	}
+}
@@ -0,0 +1,325 @@
+package middleware
+
+import (
+	"encoding/json"
+	stdHttp "net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+const (
+	cacheHit  = "HIT"
+	cacheMiss = "MISS"
+
+	// cacheVarySeparator separates the base key from the request header
+	// values a response varies on
+	cacheVarySeparator = "|vary|"
+)
+
+// ConfigCache defines the config for middleware.
+type ConfigCache struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Expiration is the time that a cached response will live
+	//
+	// Optional. Default: 1 * time.Minute
+	Expiration time.Duration
+
+	// ExpirationGenerator allows you to generate a custom expiration per
+	// request, returning 0 falls back to Expiration
+	//
+	// Optional. Default: nil
+	ExpirationGenerator func(c http.Context) time.Duration
+
+	// KeyGenerator allows you to generate custom keys
+	//
+	// Optional. Default: method + path + sorted query
+	KeyGenerator func(c http.Context) string
+
+	// CacheHeader is the header reporting whether the response was a cache
+	// HIT or MISS
+	//
+	// Optional. Default: "X-Cache"
+	CacheHeader string
+
+	// MaxBufferSize is the largest response body that is cached, larger
+	// responses are streamed through and not cached.
+	//
+	// Optional. Default: 1 MB
+	MaxBufferSize int
+
+	// Storage is used to store the cached responses
+	//
+	// Optional. Default: an in memory store for this process only
+	Storage storage.Storage
+}
+
+// ConfigCacheDefault is the default config
+var ConfigCacheDefault = ConfigCache{
+	Next:          nil,
+	Expiration:    1 * time.Minute,
+	KeyGenerator:  defaultCacheKeyGenerator,
+	CacheHeader:   "X-Cache",
+	MaxBufferSize: defaultMaxBufferSize,
+}
+
+// Helper function to set default values
+func configCacheDefault(config ...ConfigCache) ConfigCache {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCacheDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Expiration <= 0 {
+		cfg.Expiration = ConfigCacheDefault.Expiration
+	}
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigCacheDefault.KeyGenerator
+	}
+	if cfg.CacheHeader == "" {
+		cfg.CacheHeader = ConfigCacheDefault.CacheHeader
+	}
+	if cfg.MaxBufferSize <= 0 {
+		cfg.MaxBufferSize = ConfigCacheDefault.MaxBufferSize
+	}
+	return cfg
+}
+
+// cachePruneKeys is the number of tracked keys before track first drops
+// the expired ones
+const cachePruneKeys = 1024
+
+// CachePurger invalidates responses stored by a Cache middleware
+type CachePurger struct {
+	mu      sync.Mutex
+	storage storage.Storage
+	keys    map[string]time.Time
+	pruneAt int
+}
+
+// Purge removes the cached response for the given key, including all
+// variants stored for its Vary header
+func (p *CachePurger) Purge(key string) error {
+	if err := p.PurgePrefix(key + cacheVarySeparator); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	delete(p.keys, key)
+	p.mu.Unlock()
+	return p.storage.Delete(key)
+}
+
+// PurgePrefix removes every cached response whose key starts with prefix
+func (p *CachePurger) PurgePrefix(prefix string) error {
+	p.mu.Lock()
+	var keys []string
+	for key := range p.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			delete(p.keys, key)
+		}
+	}
+	p.mu.Unlock()
+	for _, key := range keys {
+		if err := p.storage.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// track remembers a stored key, dropping the expired keys once there are
+// pruneAt of them so the map doesn't grow with every key ever stored
+func (p *CachePurger) track(key string, exp time.Duration) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys[key] = now.Add(exp)
+	if len(p.keys) < p.pruneAt {
+		return
+	}
+	for k, e := range p.keys {
+		if now.After(e) {
+			delete(p.keys, k)
+		}
+	}
+	// Wait for the map to double before the next pass, keeping track
+	// amortized O(1) when most keys are still live
+	p.pruneAt = 2 * len(p.keys)
+	if p.pruneAt < cachePruneKeys {
+		p.pruneAt = cachePruneKeys
+	}
+}
+
+// Cache creates a new middleware handler and the purger for its entries
+func Cache(config ConfigCache) (http.HandlerFunc, *CachePurger) {
+	// Set default config
+	cfg := configCacheDefault(config)
+
+	purger := &CachePurger{
+		storage: cfg.Storage,
+		keys:    make(map[string]time.Time),
+		pruneAt: cachePruneKeys,
+	}
+	if purger.storage == nil {
+		purger.storage = newMemoryStorage()
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only cache GET and HEAD methods
+		if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+			return c.Next()
+		}
+
+		key := cfg.KeyGenerator(c)
+		// The key doesn't tell users apart, their responses are only shared
+		// when marked public
+		credentials := c.Header(utils.HeaderAuthorization, "") != "" || c.Header(utils.HeaderCookie, "") != ""
+
+		// Serve the cached response if there is one
+		res := loadCachedResponse(purger.storage, key)
+		if res != nil && len(res.Vary) > 0 {
+			// The base key only lists the headers the response varies on
+			res = loadCachedResponse(purger.storage, cacheVaryKey(c, key, res.Vary))
+		}
+		if res != nil && res.Status != 0 &&
+			(!credentials || cacheControlHas(res.Header.Get(utils.HeaderCacheControl), "public")) {
+			res.Header.Set(cfg.CacheHeader, cacheHit)
+			return writeResponse(c, res.Status, res.Header, res.Body)
+		}
+
+		c.SetHeader(cfg.CacheHeader, cacheMiss)
+		rec, ok := recordResponse(c, cfg.MaxBufferSize)
+		if !ok {
+			return c.Next()
+		}
+		err := rec.next(c)
+
+		// Only successful responses the handler allows to be shared are
+		// cached, never ones setting a cookie for this client
+		cacheControl := rec.Header().Get(utils.HeaderCacheControl)
+		if err != nil || rec.Status() != utils.StatusOK || rec.overflow ||
+			cacheControlHas(cacheControl, "no-store") || cacheControlHas(cacheControl, "private") ||
+			(credentials && !cacheControlHas(cacheControl, "public")) ||
+			rec.Header().Get(utils.HeaderSetCookie) != "" {
+			return err
+		}
+
+		// Vary: * can never be served from a cache
+		vary := parseVary(rec.Header().Get(utils.HeaderVary))
+		if len(vary) == 1 && vary[0] == "*" {
+			return err
+		}
+
+		exp := cfg.Expiration
+		if cfg.ExpirationGenerator != nil {
+			if e := cfg.ExpirationGenerator(c); e > 0 {
+				exp = e
+			}
+		}
+		if len(vary) > 0 {
+			if raw, mErr := json.Marshal(storedResponse{Vary: vary}); mErr == nil && purger.storage.Set(key, raw, exp) == nil {
+				purger.track(key, exp)
+			}
+			key = cacheVaryKey(c, key, vary)
+		}
+		raw, mErr := json.Marshal(storedResponse{
+			Status: rec.Status(),
+			Header: rec.Header().Clone(),
+			Body:   rec.Body(),
+		})
+		if mErr == nil && purger.storage.Set(key, raw, exp) == nil {
+			purger.track(key, exp)
+		}
+		return err
+	}, purger
+}
+
+func loadCachedResponse(store storage.Storage, key string) *storedResponse {
+	raw, _ := store.Get(key)
+	if raw == nil {
+		return nil
+	}
+	var res storedResponse
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil
+	}
+	if res.Header == nil {
+		res.Header = stdHttp.Header{}
+	}
+	return &res
+}
+
+// cacheVaryKey extends the key with the request values of the Vary headers
+func cacheVaryKey(c http.Context, key string, vary []string) string {
+	var b strings.Builder
+	b.WriteString(key)
+	b.WriteString(cacheVarySeparator)
+	for i, name := range vary {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(c.Header(name, "")))
+	}
+	return b.String()
+}
+
+// cacheControlHas reports whether a Cache-Control header has the directive
+func cacheControlHas(header, directive string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVary returns the sorted, canonical header names of a Vary header
+func parseVary(header string) []string {
+	var vary []string
+	for _, name := range strings.Split(header, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			return []string{"*"}
+		}
+		vary = append(vary, stdHttp.CanonicalHeaderKey(name))
+	}
+	sort.Strings(vary)
+	return vary
+}
+
+func defaultCacheKeyGenerator(c http.Context) string {
+	req := c.Origin()
+	key := c.Method() + ":" + req.URL.Path
+	if query := req.URL.Query(); len(query) > 0 {
+		// Encode sorts the values by key
+		key += "?" + query.Encode()
+	}
+	return key
+}
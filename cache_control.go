@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// CacheRule is the Cache-Control policy of the requests it matches
+type CacheRule struct {
+	// Path is a prefix like "/assets/", or a glob like "/static/*.css" when
+	// it contains *, ? or [. An empty Path matches every request.
+	Path string
+
+	// Methods the rule applies to
+	//
+	// Optional. Default: GET, HEAD
+	Methods []string
+
+	// MaxAge in seconds, -1 sends max-age=0
+	MaxAge int
+
+	// SMaxAge in seconds for shared caches, -1 sends s-maxage=0
+	SMaxAge int
+
+	// StaleWhileRevalidate in seconds
+	StaleWhileRevalidate int
+
+	NoStore   bool
+	NoCache   bool
+	Private   bool
+	Public    bool
+	Immutable bool
+}
+
+// ConfigCacheControl defines the config for middleware.
+type ConfigCacheControl struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Rules are tried in order, the first matching one wins. They apply
+	// to responses with a status below 400.
+	//
+	// Required
+	Rules []CacheRule
+
+	// Override replaces a Cache-Control header set by the handler
+	//
+	// Optional. Default: false
+	Override bool
+
+	// Expires adds an Expires header for HTTP/1.0 caches
+	//
+	// Optional. Default: false
+	Expires bool
+}
+
+// ConfigCacheControlDefault is the default config
+var ConfigCacheControlDefault = ConfigCacheControl{
+	Next: nil,
+}
+
+// Helper function to set default values
+func configCacheControlDefault(config ...ConfigCacheControl) ConfigCacheControl {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCacheControlDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	for i := range cfg.Rules {
+		if cfg.Rules[i].Methods == nil {
+			cfg.Rules[i].Methods = []string{utils.MethodGet, utils.MethodHead}
+		}
+	}
+	return cfg
+}
+
+// cacheControlRule is a rule with its serialized header
+type cacheControlRule struct {
+	CacheRule
+	glob  bool
+	value string
+}
+
+// CacheControl creates a new middleware handler
+func CacheControl(config ConfigCacheControl) http.HandlerFunc {
+	// Set default config
+	cfg := configCacheControlDefault(config)
+
+	if len(cfg.Rules) == 0 {
+		panic("cache control: at least one rule is required")
+	}
+	rules := make([]cacheControlRule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Public && rule.Private {
+			panic("cache control: a rule can't be both Public and Private")
+		}
+		glob := strings.ContainsAny(rule.Path, "*?[")
+		if glob {
+			if _, err := path.Match(rule.Path, "/"); err != nil {
+				panic("cache control: invalid pattern " + rule.Path)
+			}
+		}
+		rules[i] = cacheControlRule{CacheRule: rule, glob: glob, value: rule.String()}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		rule := matchCacheRule(rules, c.Method(), c.Origin().URL.Path)
+		if rule == nil {
+			return c.Next()
+		}
+
+		rec, ok := onHeaders(c, func(status int, header stdHttp.Header) {
+			if status >= utils.StatusBadRequest || rule.value == "" {
+				return
+			}
+			if !cfg.Override && header.Get(utils.HeaderCacheControl) != "" {
+				return
+			}
+			header.Set(utils.HeaderCacheControl, rule.value)
+			if cfg.Expires && (cfg.Override || header.Get(utils.HeaderExpires) == "") {
+				header.Set(utils.HeaderExpires, rule.expires(time.Now()))
+			}
+		})
+		if !ok {
+			return c.Next()
+		}
+		return rec.next(c)
+	}
+}
+
+// matchCacheRule returns the first rule matching the request
+func matchCacheRule(rules []cacheControlRule, method, reqPath string) *cacheControlRule {
+	for i := range rules {
+		rule := &rules[i]
+		if !containsMethod(rule.Methods, method) {
+			continue
+		}
+		if rule.glob {
+			if ok, _ := path.Match(rule.Path, reqPath); ok {
+				return rule
+			}
+		} else if strings.HasPrefix(reqPath, rule.Path) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// String serializes the directives of the rule into a Cache-Control value
+func (r CacheRule) String() string {
+	var directives []string
+	if r.Public {
+		directives = append(directives, "public")
+	}
+	if r.Private {
+		directives = append(directives, "private")
+	}
+	if r.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if r.NoStore {
+		directives = append(directives, "no-store")
+	}
+	if r.MaxAge != 0 {
+		directives = append(directives, "max-age="+cacheSeconds(r.MaxAge))
+	}
+	if r.SMaxAge != 0 {
+		directives = append(directives, "s-maxage="+cacheSeconds(r.SMaxAge))
+	}
+	if r.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+strconv.Itoa(r.StaleWhileRevalidate))
+	}
+	if r.Immutable {
+		directives = append(directives, "immutable")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// expires returns the Expires value matching the rule, a date in the past
+// for responses HTTP/1.0 caches must not reuse, which know no private
+func (r CacheRule) expires(now time.Time) string {
+	if r.NoStore || r.NoCache || r.Private || r.MaxAge <= 0 {
+		return time.Unix(0, 0).UTC().Format(stdHttp.TimeFormat)
+	}
+	return now.Add(time.Duration(r.MaxAge) * time.Second).UTC().Format(stdHttp.TimeFormat)
+}
+
+// cacheSeconds formats an age, -1 meaning 0
+func cacheSeconds(seconds int) string {
+	if seconds < 0 {
+		return "0"
+	}
+	return strconv.Itoa(seconds)
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// cacheControlResult runs the request and returns the headers as sent
+func cacheControlResult(t *testing.T, handler http.HandlerFunc, method, target string, final http.HandlerFunc) stdHttp.Header {
+	t.Helper()
+	return run(t, httptest.NewRequest(method, target, nil), handler, final).Recorder.Result().Header
+}
+
+func TestCacheRuleString(t *testing.T) {
+	for _, tt := range []struct {
+		rule CacheRule
+		want string
+	}{
+		{CacheRule{}, ""},
+		{CacheRule{MaxAge: 31536000, Public: true, Immutable: true}, "public, max-age=31536000, immutable"},
+		{CacheRule{Private: true, NoCache: true, MaxAge: -1}, "private, no-cache, max-age=0"},
+		{CacheRule{NoStore: true}, "no-store"},
+		{CacheRule{Public: true, MaxAge: 60, SMaxAge: 600, StaleWhileRevalidate: 30}, "public, max-age=60, s-maxage=600, stale-while-revalidate=30"},
+		{CacheRule{SMaxAge: -1}, "s-maxage=0"},
+	} {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("%+v: %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestCacheControlRules(t *testing.T) {
+	handler := CacheControl(ConfigCacheControl{Rules: []CacheRule{
+		{Path: "/assets/*.css", MaxAge: 31536000, Public: true, Immutable: true},
+		{Path: "/assets/", MaxAge: 3600, Public: true},
+		{Path: "/api/", Methods: []string{"GET"}, NoStore: true},
+		{Path: "/api/", Methods: []string{"POST"}, Private: true, NoCache: true},
+	}})
+	for _, tt := range []struct {
+		method, target, want string
+	}{
+		{"GET", "/assets/site.css", "public, max-age=31536000, immutable"},
+		// * doesn't cross a /, the prefix rule is next
+		{"GET", "/assets/css/site.css", "public, max-age=3600"},
+		{"HEAD", "/assets/app.js", "public, max-age=3600"},
+		{"GET", "/api/users", "no-store"},
+		{"POST", "/api/users", "private, no-cache"},
+		{"DELETE", "/api/users", ""},
+		{"POST", "/assets/app.js", ""},
+		{"GET", "/other", ""},
+	} {
+		h := cacheControlResult(t, handler, tt.method, tt.target, ok)
+		if got := h.Get(utils.HeaderCacheControl); got != tt.want {
+			t.Errorf("%s %s: Cache-Control = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCacheControlOverride(t *testing.T) {
+	rules := []CacheRule{{MaxAge: 60, Public: true}}
+	own := func(c http.Context) error {
+		c.SetHeader(utils.HeaderCacheControl, "no-store")
+		return c.String("ok")
+	}
+	failed := func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusNotFound)
+		return nil
+	}
+	for _, tt := range []struct {
+		override bool
+		final    http.HandlerFunc
+		want     string
+	}{
+		{false, own, "no-store"},
+		{true, own, "public, max-age=60"},
+		{false, ok, "public, max-age=60"},
+		// Errors aren't cached
+		{true, failed, ""},
+	} {
+		handler := CacheControl(ConfigCacheControl{Rules: rules, Override: tt.override})
+		h := cacheControlResult(t, handler, "GET", "/", tt.final)
+		if got := h.Get(utils.HeaderCacheControl); got != tt.want {
+			t.Errorf("Override %v: Cache-Control = %q, want %q", tt.override, got, tt.want)
+		}
+	}
+}
+
+func TestCacheControlExpires(t *testing.T) {
+	epoch := time.Unix(0, 0).UTC().Format(stdHttp.TimeFormat)
+	for _, tt := range []struct {
+		rule   CacheRule
+		maxAge time.Duration
+	}{
+		{CacheRule{MaxAge: 3600, Public: true}, time.Hour},
+		{CacheRule{MaxAge: 3600, Private: true}, 0},
+		{CacheRule{NoStore: true}, 0},
+	} {
+		before := time.Now().Truncate(time.Second)
+		h := cacheControlResult(t, CacheControl(ConfigCacheControl{Rules: []CacheRule{tt.rule}, Expires: true}), "GET", "/", ok)
+		expires, err := stdHttp.ParseTime(h.Get(utils.HeaderExpires))
+		if err != nil {
+			t.Errorf("%+v: Expires = %q", tt.rule, h.Get(utils.HeaderExpires))
+			continue
+		}
+		if tt.maxAge == 0 {
+			if h.Get(utils.HeaderExpires) != epoch {
+				t.Errorf("%+v: Expires = %q, want the past", tt.rule, h.Get(utils.HeaderExpires))
+			}
+			continue
+		}
+		if d := expires.Sub(before); d < tt.maxAge || d > tt.maxAge+2*time.Second {
+			t.Errorf("%+v: Expires in %v", tt.rule, d)
+		}
+	}
+
+	// Without the option there is no Expires
+	h := cacheControlResult(t, CacheControl(ConfigCacheControl{Rules: []CacheRule{{MaxAge: 60}}}), "GET", "/", ok)
+	if h.Get(utils.HeaderExpires) != "" {
+		t.Errorf("Expires = %q", h.Get(utils.HeaderExpires))
+	}
+}
+
+func TestCacheControlInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]ConfigCacheControl{
+		"no rules":           {},
+		"public and private": {Rules: []CacheRule{{Public: true, Private: true}}},
+		"bad pattern":        {Rules: []CacheRule{{Path: "/[a"}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: CacheControl didn't panic", name)
+				}
+			}()
+			CacheControl(cfg)
+		}()
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// CacheOption sets a field of ConfigCache, see CacheWith
+type CacheOption func(cfg *ConfigCache)
+
+// CacheWith creates a Cache middleware and its purger from options applied
+// to ConfigCacheDefault in order, a later option overrides an earlier one
+//
+//	handler, purger := middleware.CacheWith(
+//		middleware.CacheExpiration(5*time.Minute),
+//		middleware.CacheStorage(redisStore),
+//	)
+func CacheWith(opts ...CacheOption) (http.HandlerFunc, *CachePurger) {
+	cfg := ConfigCacheDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Cache(cfg)
+}
+
+// CacheOptions bundles options into one, e.g. to share a preset between
+// services
+func CacheOptions(opts ...CacheOption) CacheOption {
+	return func(cfg *ConfigCache) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// CacheNext sets ConfigCache.Next
+func CacheNext(next func(c http.Context) bool) CacheOption {
+	return func(cfg *ConfigCache) { cfg.Next = next }
+}
+
+// CacheExpiration sets ConfigCache.Expiration
+func CacheExpiration(d time.Duration) CacheOption {
+	return func(cfg *ConfigCache) { cfg.Expiration = d }
+}
+
+// CacheExpirationGenerator sets ConfigCache.ExpirationGenerator
+func CacheExpirationGenerator(generator func(c http.Context) time.Duration) CacheOption {
+	return func(cfg *ConfigCache) { cfg.ExpirationGenerator = generator }
+}
+
+// CacheKeyGenerator sets ConfigCache.KeyGenerator
+func CacheKeyGenerator(generator func(c http.Context) string) CacheOption {
+	return func(cfg *ConfigCache) { cfg.KeyGenerator = generator }
+}
+
+// CacheStatusHeader sets ConfigCache.CacheHeader
+func CacheStatusHeader(header string) CacheOption {
+	return func(cfg *ConfigCache) { cfg.CacheHeader = header }
+}
+
+// CacheMaxBufferSize sets ConfigCache.MaxBufferSize
+func CacheMaxBufferSize(n int) CacheOption {
+	return func(cfg *ConfigCache) { cfg.MaxBufferSize = n }
+}
+
+// CacheStorage sets ConfigCache.Storage
+func CacheStorage(store storage.Storage) CacheOption {
+	return func(cfg *ConfigCache) { cfg.Storage = store }
+}
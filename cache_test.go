@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"fmt"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// countingHandler responds with the number of times it ran
+func countingHandler(calls *int, prepare func(c http.Context)) http.HandlerFunc {
+	return func(c http.Context) error {
+		*calls++
+		if prepare != nil {
+			prepare(c)
+		}
+		return c.String("call %d", *calls)
+	}
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	calls := 0
+	handler, _ := Cache(ConfigCache{Storage: newTestStorage()})
+	final := countingHandler(&calls, nil)
+
+	c := run(t, httptest.NewRequest("GET", "/items?b=2&a=1", nil), handler, final)
+	if got := c.Recorder.Header().Get("X-Cache"); got != cacheMiss || c.Body() != "call 1" {
+		t.Fatalf("first = %s %q", got, c.Body())
+	}
+	// Same query in another order hits the same key
+	c = run(t, httptest.NewRequest("GET", "/items?a=1&b=2", nil), handler, final)
+	if got := c.Recorder.Header().Get("X-Cache"); got != cacheHit || c.Body() != "call 1" {
+		t.Errorf("second = %s %q", got, c.Body())
+	}
+	c = run(t, httptest.NewRequest("POST", "/items?a=1&b=2", nil), handler, final)
+	if c.Body() != "call 2" {
+		t.Errorf("POST served from the cache: %q", c.Body())
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	calls := 0
+	store := newTestStorage()
+	handler, _ := Cache(ConfigCache{
+		Storage:             store,
+		ExpirationGenerator: func(c http.Context) time.Duration { return 10 * time.Second },
+	})
+	final := countingHandler(&calls, nil)
+
+	run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+	store.advance(9 * time.Second)
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, final); c.Body() != "call 1" {
+		t.Errorf("before expiry = %q", c.Body())
+	}
+	store.advance(time.Second)
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, final); c.Body() != "call 2" {
+		t.Errorf("after expiry = %q", c.Body())
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	calls := 0
+	handler, purger := Cache(ConfigCache{
+		Storage:      newTestStorage(),
+		KeyGenerator: func(c http.Context) string { return c.Origin().URL.Path },
+	})
+	final := countingHandler(&calls, nil)
+
+	run(t, httptest.NewRequest("GET", "/users/1", nil), handler, final)
+	run(t, httptest.NewRequest("GET", "/users/2", nil), handler, final)
+	run(t, httptest.NewRequest("GET", "/posts/1", nil), handler, final)
+
+	if err := purger.Purge("/users/1"); err != nil {
+		t.Fatal(err)
+	}
+	if c := run(t, httptest.NewRequest("GET", "/users/1", nil), handler, final); c.Body() != "call 4" {
+		t.Errorf("purged key = %q", c.Body())
+	}
+	if err := purger.PurgePrefix("/users/"); err != nil {
+		t.Fatal(err)
+	}
+	if c := run(t, httptest.NewRequest("GET", "/users/2", nil), handler, final); c.Body() != "call 5" {
+		t.Errorf("purged prefix = %q", c.Body())
+	}
+	if c := run(t, httptest.NewRequest("GET", "/posts/1", nil), handler, final); c.Body() != "call 3" {
+		t.Errorf("other key = %q", c.Body())
+	}
+}
+
+func TestCachePurgerPrunesExpiredKeys(t *testing.T) {
+	_, purger := Cache(ConfigCache{})
+	expired := time.Now().Add(-time.Second)
+	for i := 0; i < cachePruneKeys-1; i++ {
+		purger.keys[fmt.Sprintf("key-%d", i)] = expired
+	}
+	purger.track("live", time.Minute)
+	if len(purger.keys) != 1 || purger.pruneAt != cachePruneKeys {
+		t.Fatalf("tracked keys = %d, pruneAt = %d", len(purger.keys), purger.pruneAt)
+	}
+
+	// Live keys are kept and the next pass waits for the map to double
+	for i := 1; i < cachePruneKeys; i++ {
+		purger.track(fmt.Sprintf("live-%d", i), time.Minute)
+	}
+	if len(purger.keys) != cachePruneKeys || purger.pruneAt != 2*cachePruneKeys {
+		t.Errorf("tracked keys = %d, pruneAt = %d", len(purger.keys), purger.pruneAt)
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	for name, prepare := range map[string]func(c http.Context){
+		"no-store": func(c http.Context) { c.SetHeader(utils.HeaderCacheControl, "no-store") },
+		"private":  func(c http.Context) { c.SetHeader(utils.HeaderCacheControl, "private, max-age=60") },
+		"cookie": func(c http.Context) {
+			c.Cookie(&http.Cookie{Name: "session", Value: "secret"})
+		},
+		"error": func(c http.Context) { c.Status(stdHttp.StatusInternalServerError) },
+	} {
+		calls := 0
+		handler, _ := Cache(ConfigCache{Storage: newTestStorage()})
+		final := countingHandler(&calls, prepare)
+		run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+		c := run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+		if c.Body() != "call 2" {
+			t.Errorf("%s: response was cached: %q", name, c.Body())
+		}
+		if strings.Contains(c.Recorder.Header().Get(utils.HeaderSetCookie), "secret") && calls != 2 {
+			t.Errorf("%s: cookie replayed from the cache", name)
+		}
+	}
+}
+
+func TestCacheCredentials(t *testing.T) {
+	withCredentials := func(header, value string) *stdHttp.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(header, value)
+		return req
+	}
+	for name, tc := range map[string]struct {
+		cacheControl string
+		shared       bool
+	}{
+		"unmarked": {"max-age=60", false},
+		"public":   {"public, max-age=60", true},
+	} {
+		calls := 0
+		handler, _ := Cache(ConfigCache{Storage: newTestStorage()})
+		final := countingHandler(&calls, func(c http.Context) { c.SetHeader(utils.HeaderCacheControl, tc.cacheControl) })
+
+		run(t, withCredentials(utils.HeaderAuthorization, "Bearer alice"), handler, final)
+		c := run(t, withCredentials(utils.HeaderCookie, "session=bob"), handler, final)
+		if got := c.Body() == "call 1"; got != tc.shared {
+			t.Errorf("%s: credentialed response shared = %v, want %v", name, got, tc.shared)
+		}
+
+		// A response cached for anonymous clients isn't served to an
+		// authenticated one unless it's public
+		calls = 0
+		handler, _ = Cache(ConfigCache{Storage: newTestStorage()})
+		run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+		c = run(t, withCredentials(utils.HeaderAuthorization, "Bearer alice"), handler, final)
+		if got := c.Body() == "call 1"; got != tc.shared {
+			t.Errorf("%s: cached response served to a credentialed request = %v, want %v", name, got, tc.shared)
+		}
+	}
+}
+
+func TestCacheMaxBufferSize(t *testing.T) {
+	calls := 0
+	handler, _ := Cache(ConfigCache{Storage: newTestStorage(), MaxBufferSize: 4})
+	final := countingHandler(&calls, nil)
+	run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, final); c.Body() != "call 2" {
+		t.Errorf("body over MaxBufferSize was cached: %q", c.Body())
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	calls := 0
+	handler, purger := Cache(ConfigCache{
+		Storage:      newTestStorage(),
+		KeyGenerator: func(c http.Context) string { return c.Origin().URL.Path },
+	})
+	final := func(c http.Context) error {
+		calls++
+		c.SetHeader(utils.HeaderVary, "accept-language, Accept-Encoding")
+		return c.String("%s %d", c.Header(utils.HeaderAcceptLanguage, ""), calls)
+	}
+	request := func(language, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/greeting", nil)
+		req.Header.Set(utils.HeaderAcceptLanguage, language)
+		req.Header.Set(utils.HeaderAcceptEncoding, encoding)
+		return run(t, req, handler, final).Recorder
+	}
+
+	for _, tt := range []struct {
+		language, encoding, want, cache string
+	}{
+		{"en", "gzip", "en 1", cacheMiss},
+		{"fr", "gzip", "fr 2", cacheMiss},
+		{"en", "gzip", "en 1", cacheHit},
+		{"fr", "gzip", "fr 2", cacheHit},
+		{"en", "br", "en 3", cacheMiss},
+	} {
+		res := request(tt.language, tt.encoding)
+		if res.Body.String() != tt.want || res.Header().Get("X-Cache") != tt.cache {
+			t.Errorf("%s/%s: %s %q, want %s %q", tt.language, tt.encoding,
+				res.Header().Get("X-Cache"), res.Body.String(), tt.cache, tt.want)
+		}
+	}
+
+	// Purging the key drops every variant
+	if err := purger.Purge("/greeting"); err != nil {
+		t.Fatal(err)
+	}
+	if res := request("fr", "gzip"); res.Body.String() != "fr 4" {
+		t.Errorf("variant kept after purge: %q", res.Body.String())
+	}
+}
+
+func TestCacheVaryStar(t *testing.T) {
+	calls := 0
+	handler, _ := Cache(ConfigCache{Storage: newTestStorage()})
+	final := countingHandler(&calls, func(c http.Context) { c.SetHeader(utils.HeaderVary, "*") })
+	run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, final); c.Body() != "call 2" {
+		t.Errorf("Vary: * response was cached: %q", c.Body())
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"crypto/x509"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigClientCert defines the config for middleware.
+type ConfigClientCert struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// AllowedNames limits access to certificates whose common name or one
+	// of whose DNS, email or URI subject alternative names is listed
+	//
+	// Optional. Default: nil, any verified certificate
+	AllowedNames []string
+
+	// ContextKey stores the verified *x509.Certificate for the handlers
+	//
+	// Optional. Default: "client_cert"
+	ContextKey string
+
+	// Unauthorized is called for requests without an accepted certificate
+	//
+	// Optional. Default: responds with 403 Forbidden
+	Unauthorized http.HandlerFunc
+}
+
+// ConfigClientCertDefault is the default config
+var ConfigClientCertDefault = ConfigClientCert{
+	Next:       nil,
+	ContextKey: "client_cert",
+	Unauthorized: func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusForbidden)
+		return ErrClientCertDenied
+	},
+}
+
+// Helper function to set default values
+func configClientCertDefault(config ...ConfigClientCert) ConfigClientCert {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigClientCertDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigClientCertDefault.ContextKey
+	}
+	if cfg.Unauthorized == nil {
+		cfg.Unauthorized = ConfigClientCertDefault.Unauthorized
+	}
+	return cfg
+}
+
+// RequireClientCert creates a new middleware handler rejecting requests
+// without a client certificate verified during the TLS handshake. The
+// server's tls.Config must request and verify client certificates, e.g.
+// with ClientAuth set to tls.VerifyClientCertIfGiven and ClientCAs.
+func RequireClientCert(config ...ConfigClientCert) http.HandlerFunc {
+	// Set default config
+	cfg := configClientCertDefault(config...)
+
+	allowed := make(map[string]struct{}, len(cfg.AllowedNames))
+	for _, name := range cfg.AllowedNames {
+		allowed[name] = struct{}{}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only verified chains count, PeerCertificates may be unverified
+		state := c.Origin().TLS
+		if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+			return cfg.Unauthorized(c)
+		}
+		cert := state.VerifiedChains[0][0]
+		if len(allowed) > 0 && !clientCertAllowed(cert, allowed) {
+			return cfg.Unauthorized(c)
+		}
+
+		c.WithValue(cfg.ContextKey, cert)
+		return c.Next()
+	}
+}
+
+// clientCertAllowed reports whether a name of cert is allowed
+func clientCertAllowed(cert *x509.Certificate, allowed map[string]struct{}) bool {
+	names := []string{cert.Subject.CommonName}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if _, ok := allowed[name]; ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// certRequest is a request over a connection whose handshake verified cert,
+// or only received it when verified is false
+func certRequest(cert *x509.Certificate, verified bool) *stdHttp.Request {
+	req := httptest.NewRequest("GET", "https://internal.example.com/", nil)
+	if cert == nil {
+		req.TLS = &tls.ConnectionState{}
+		return req
+	}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if verified {
+		req.TLS.VerifiedChains = [][]*x509.Certificate{{cert, {Subject: pkix.Name{CommonName: "Internal CA"}}}}
+	}
+	return req
+}
+
+// certHandler responds with the common name of the certificate in the context
+func certHandler(key string) http.HandlerFunc {
+	return func(c http.Context) error {
+		cert, _ := c.Value(key).(*x509.Certificate)
+		if cert == nil {
+			return c.String("no certificate")
+		}
+		return c.String(cert.Subject.CommonName)
+	}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	billing := &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}}
+	handler := RequireClientCert()
+
+	c := run(t, certRequest(billing, true), handler, certHandler("client_cert"))
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "billing" {
+		t.Errorf("verified: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+
+	plain := httptest.NewRequest("GET", "/", nil)
+	for name, req := range map[string]*stdHttp.Request{
+		"plain http":     plain,
+		"no certificate": certRequest(nil, false),
+		"not verified":   certRequest(billing, false),
+	} {
+		c := run(t, req, handler, certHandler("client_cert"))
+		if c.Recorder.Code != utils.StatusForbidden || !errors.Is(c.Errors()[0], ErrClientCertDenied) {
+			t.Errorf("%s: status = %d, err = %v", name, c.Recorder.Code, c.Errors()[0])
+		}
+	}
+}
+
+func TestRequireClientCertAllowedNames(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/ns/prod/sa/orders")
+	handler := RequireClientCert(ConfigClientCert{
+		AllowedNames: []string{"billing", "orders.internal", "ops@example.com", "spiffe://example.com/ns/prod/sa/orders"},
+		ContextKey:   "peer",
+	})
+	for _, tt := range []struct {
+		name string
+		cert *x509.Certificate
+		want int
+	}{
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}}, utils.StatusOK},
+		{"dns name", &x509.Certificate{Subject: pkix.Name{CommonName: "x"}, DNSNames: []string{"other", "orders.internal"}}, utils.StatusOK},
+		{"email", &x509.Certificate{EmailAddresses: []string{"ops@example.com"}}, utils.StatusOK},
+		{"uri", &x509.Certificate{URIs: []*url.URL{spiffe}}, utils.StatusOK},
+		{"not listed", &x509.Certificate{Subject: pkix.Name{CommonName: "reports"}, DNSNames: []string{"reports.internal"}}, utils.StatusForbidden},
+		{"no names", &x509.Certificate{}, utils.StatusForbidden},
+	} {
+		if c := run(t, certRequest(tt.cert, true), handler, certHandler("peer")); c.Recorder.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.want)
+		}
+	}
+
+	custom := RequireClientCert(ConfigClientCert{Unauthorized: func(c http.Context) error {
+		return c.Status(utils.StatusUnauthorized).String("certificate required")
+	}})
+	if c := run(t, certRequest(nil, false), custom, ok); c.Recorder.Code != utils.StatusUnauthorized || c.Body() != "certificate required" {
+		t.Errorf("custom: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
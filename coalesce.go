@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"strings"
+	"sync"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigCoalesce defines the config for middleware.
+type ConfigCoalesce struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// KeyGenerator groups the requests sharing one execution, it must
+	// cover everything the response depends on
+	//
+	// Optional. Default: method, path and query, plus the Authorization,
+	// Cookie, Accept and Accept-Encoding headers
+	KeyGenerator func(c http.Context) string
+
+	// Methods that are coalesced, only safe methods are accepted
+	//
+	// Optional. Default: GET, HEAD
+	Methods []string
+
+	// MaxBodySize is the largest response replayed to the waiting
+	// requests, they run the handler themselves for larger ones
+	//
+	// Optional. Default: 1 MB
+	MaxBodySize int
+}
+
+// ConfigCoalesceDefault is the default config
+var ConfigCoalesceDefault = ConfigCoalesce{
+	Next: nil,
+	KeyGenerator: func(c http.Context) string {
+		return strings.Join([]string{
+			c.Method(),
+			c.Origin().URL.RequestURI(),
+			c.Header(utils.HeaderAuthorization, ""),
+			c.Header(utils.HeaderCookie, ""),
+			c.Header(utils.HeaderAccept, ""),
+			c.Header(utils.HeaderAcceptEncoding, ""),
+		}, "\x00")
+	},
+	Methods:     []string{utils.MethodGet, utils.MethodHead},
+	MaxBodySize: defaultMaxBufferSize,
+}
+
+// Helper function to set default values
+func configCoalesceDefault(config ...ConfigCoalesce) ConfigCoalesce {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCoalesceDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigCoalesceDefault.KeyGenerator
+	}
+	if cfg.Methods == nil {
+		cfg.Methods = ConfigCoalesceDefault.Methods
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = ConfigCoalesceDefault.MaxBodySize
+	}
+	return cfg
+}
+
+// coalesceCall is the execution the requests of a group wait for
+type coalesceCall struct {
+	done   chan struct{}
+	shared bool
+	status int
+	header stdHttp.Header
+	body   []byte
+}
+
+// Coalesce creates a new middleware handler running the handler once for
+// concurrent identical requests and replaying its response to all of them.
+// Requests arriving after the execution finished run the handler again.
+func Coalesce(config ConfigCoalesce) http.HandlerFunc {
+	// Set default config
+	cfg := configCoalesceDefault(config)
+
+	for _, method := range cfg.Methods {
+		switch method {
+		case utils.MethodGet, utils.MethodHead, utils.MethodOptions, utils.MethodTrace:
+		default:
+			panic("coalesce: unsafe method " + method)
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]*coalesceCall)
+	)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if !containsMethod(cfg.Methods, c.Method()) {
+			return c.Next()
+		}
+
+		key := cfg.KeyGenerator(c)
+		mu.Lock()
+		if call, ok := calls[key]; ok {
+			mu.Unlock()
+
+			// Wait for the leader, giving up only affects this request
+			select {
+			case <-call.done:
+			case <-c.Origin().Context().Done():
+				return c.Origin().Context().Err()
+			}
+			if !call.shared {
+				return c.Next()
+			}
+			return writeResponse(c, call.status, call.header, call.body)
+		}
+		call := &coalesceCall{done: make(chan struct{})}
+		calls[key] = call
+		mu.Unlock()
+
+		// Release the waiters even when the handler panics
+		defer func() {
+			mu.Lock()
+			delete(calls, key)
+			mu.Unlock()
+			close(call.done)
+		}()
+
+		rec, ok := recordResponse(c, cfg.MaxBodySize)
+		if !ok {
+			return c.Next()
+		}
+		err := rec.next(c)
+
+		// Failures are retried and cookies are personal, waiters run the
+		// handler themselves
+		if err == nil && rec.Status() < utils.StatusInternalServerError && !rec.overflow && rec.Header().Get(utils.HeaderSetCookie) == "" {
+			call.shared = true
+			call.status = rec.Status()
+			call.header = rec.Header().Clone()
+			call.body = append([]byte(nil), rec.Body()...)
+		}
+		return err
+	}
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// runCoalesced sends the first request, then the others while the handler
+// is still busy with it, and returns the contexts and how often respond ran
+func runCoalesced(t *testing.T, cfg ConfigCoalesce, respond func(c http.Context, n int32) error, reqs ...*stdHttp.Request) ([]*middlewaretest.MockContext, int32) {
+	t.Helper()
+	var (
+		calls   int32
+		entered = make(chan struct{}, len(reqs))
+		release = make(chan struct{})
+		keyed   sync.WaitGroup
+	)
+	keyGenerator := cfg.KeyGenerator
+	if keyGenerator == nil {
+		keyGenerator = ConfigCoalesceDefault.KeyGenerator
+	}
+	cfg.KeyGenerator = func(c http.Context) string {
+		defer keyed.Done()
+		return keyGenerator(c)
+	}
+	handler := Coalesce(cfg)
+	final := func(c http.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		entered <- struct{}{}
+		<-release
+		return respond(c, n)
+	}
+
+	contexts := make([]*middlewaretest.MockContext, len(reqs))
+	var done sync.WaitGroup
+	keyed.Add(len(reqs))
+	for i, req := range reqs {
+		done.Add(1)
+		go func(i int, req *stdHttp.Request) {
+			defer done.Done()
+			contexts[i] = run(t, req, handler, final)
+		}(i, req)
+		if i == 0 {
+			// The leader is busy before the others arrive
+			<-entered
+		}
+	}
+	keyed.Wait()
+	// Let the others reach the wait for the leader
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+	return contexts, calls
+}
+
+func newRequests(n int, method, target string) []*stdHttp.Request {
+	reqs := make([]*stdHttp.Request, n)
+	for i := range reqs {
+		reqs[i] = httptest.NewRequest(method, target, nil)
+	}
+	return reqs
+}
+
+func TestCoalesce(t *testing.T) {
+	respond := func(c http.Context, n int32) error {
+		c.SetHeader("X-Execution", strconv.Itoa(int(n)))
+		return c.Status(utils.StatusCreated).String("result %d", n)
+	}
+	contexts, calls := runCoalesced(t, ConfigCoalesce{}, respond, newRequests(20, "GET", "/report?year=2024")...)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times", calls)
+	}
+	for i, c := range contexts {
+		if c.Recorder.Code != utils.StatusCreated || c.Body() != "result 1" || c.Recorder.Header().Get("X-Execution") != "1" {
+			t.Errorf("request %d: status = %d, body = %q, headers = %v", i, c.Recorder.Code, c.Body(), c.Recorder.Header())
+		}
+	}
+
+	// Once the execution finished, requests run the handler again
+	handler := Coalesce(ConfigCoalesce{})
+	var n int32
+	counting := func(c http.Context) error {
+		return c.String("result %d", atomic.AddInt32(&n, 1))
+	}
+	run(t, httptest.NewRequest("GET", "/", nil), handler, counting)
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, counting); c.Body() != "result 2" {
+		t.Errorf("later request: body = %q", c.Body())
+	}
+}
+
+func TestCoalesceDefaultKey(t *testing.T) {
+	respond := func(c http.Context, n int32) error {
+		return c.String("result %d", n)
+	}
+	for _, tt := range []struct {
+		name  string
+		other func(req *stdHttp.Request)
+	}{
+		{"query", func(req *stdHttp.Request) { req.URL.RawQuery = "year=2023" }},
+		{"method", func(req *stdHttp.Request) { req.Method = "HEAD" }},
+		{"authorization", func(req *stdHttp.Request) { req.Header.Set(utils.HeaderAuthorization, "Bearer other") }},
+		{"encoding", func(req *stdHttp.Request) { req.Header.Set(utils.HeaderAcceptEncoding, "gzip") }},
+		{"cookie", func(req *stdHttp.Request) { req.Header.Set(utils.HeaderCookie, "session=other") }},
+	} {
+		reqs := newRequests(3, "GET", "/report?year=2024")
+		for _, req := range reqs {
+			req.Header.Set(utils.HeaderAuthorization, "Bearer token")
+		}
+		tt.other(reqs[2])
+		contexts, calls := runCoalesced(t, ConfigCoalesce{}, respond, reqs...)
+		if calls != 2 || contexts[1].Body() != contexts[0].Body() || contexts[2].Body() == contexts[0].Body() {
+			t.Errorf("%s: handler ran %d times, bodies %q, %q, %q", tt.name, calls, contexts[0].Body(), contexts[1].Body(), contexts[2].Body())
+		}
+	}
+}
+
+func TestCoalesceNotShared(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		cfg     ConfigCoalesce
+		respond func(c http.Context, n int32) error
+	}{
+		{"server error", ConfigCoalesce{}, func(c http.Context, n int32) error {
+			c.AbortWithStatus(utils.StatusServiceUnavailable)
+			return errors.New("unavailable")
+		}},
+		{"cookie", ConfigCoalesce{}, func(c http.Context, n int32) error {
+			c.SetHeader(utils.HeaderSetCookie, "session="+strconv.Itoa(int(n)))
+			return c.String("ok")
+		}},
+		{"large body", ConfigCoalesce{MaxBodySize: 4}, func(c http.Context, n int32) error {
+			return c.String("too large")
+		}},
+	} {
+		// The waiters run the handler themselves
+		if _, calls := runCoalesced(t, tt.cfg, tt.respond, newRequests(5, "GET", "/")...); calls != 5 {
+			t.Errorf("%s: handler ran %d times", tt.name, calls)
+		}
+	}
+}
+
+func TestCoalesceUnsafeMethods(t *testing.T) {
+	handler := Coalesce(ConfigCoalesce{})
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(2)
+	var done sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			run(t, httptest.NewRequest("POST", "/", strings.NewReader("body")), handler, blockingHandler(&started, release))
+		}()
+	}
+	// Both requests are in the handler at once
+	started.Wait()
+	close(release)
+	done.Wait()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Coalesce didn't panic for POST")
+		}
+	}()
+	Coalesce(ConfigCoalesce{Methods: []string{"GET", "POST"}})
+}
+
+func TestCoalesceWaiterCancelled(t *testing.T) {
+	handler := Coalesce(ConfigCoalesce{})
+	var started sync.WaitGroup
+	started.Add(1)
+	release := make(chan struct{})
+	leader := make(chan *middlewaretest.MockContext)
+	go func() {
+		leader <- run(t, httptest.NewRequest("GET", "/", nil), handler, blockingHandler(&started, release))
+	}()
+	started.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	c := run(t, req, handler, ok)
+	if !errors.Is(c.Errors()[0], context.Canceled) {
+		t.Errorf("waiter: err = %v", c.Errors()[0])
+	}
+
+	// The leader isn't affected
+	close(release)
+	if c := <-leader; c.Recorder.Code != utils.StatusOK || c.Body() != "ok" {
+		t.Errorf("leader: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
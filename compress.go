@@ -0,0 +1,418 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	stdHttp "net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// CompressLevel determines the compression algorithm's trade-off
+type CompressLevel int
+
+// Represents compression level that will be used in the middleware
+const (
+	CompressLevelDefault         CompressLevel = 0
+	CompressLevelBestSpeed       CompressLevel = 1
+	CompressLevelBestCompression CompressLevel = 2
+)
+
+// ConfigCompress defines the config for middleware.
+type ConfigCompress struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Level determines the compression algorithm
+	//
+	// Optional. Default: CompressLevelDefault
+	Level CompressLevel
+
+	// MinLength is the smallest body that is compressed
+	//
+	// Optional. Default: 1024
+	MinLength int
+
+	// MaxBufferSize is the largest body that is compressed, larger
+	// responses are streamed through uncompressed.
+	//
+	// Optional. Default: 1 MB
+	MaxBufferSize int
+
+	// OnCompress is called after a body was compressed with its content
+	// type and its size before and after compression
+	//
+	// Optional. Default: nil
+	OnCompress func(contentType string, in, out int)
+
+	// Encoders adds encodings to the built-in br, zstd, gzip and deflate or
+	// replaces them, e.g. with an encoder of another library:
+	//
+	//	Encoders: map[string]middleware.CompressEncoder{
+	//		"br": func(w io.Writer, _ middleware.CompressLevel) (io.WriteCloser, error) {
+	//			return cbrotli.NewWriter(w, cbrotli.WriterOptions{Quality: 5}), nil
+	//		},
+	//	}
+	//
+	// Encodings registered here but missing from Order are preferred over
+	// the ones in it. An encoder registered as "zstd" must not use a
+	// dictionary, clients decode zstd without one, see Dictionary.
+	//
+	// Optional. Default: nil
+	Encoders map[string]CompressEncoder
+
+	// Dictionary is a zstd dictionary shared with the clients, e.g. a
+	// typical JSON response, which shrinks small bodies a lot. Clients that
+	// accept dcz and name the dictionary's hash in the Available-Dictionary
+	// header get the body compressed against it, the others get the
+	// encodings of Order. Serve the dictionary itself with a
+	// Use-As-Dictionary header, so browsers keep it and announce it.
+	//
+	// Optional. Default: nil
+	Dictionary []byte
+
+	// Order is the server's preference among the encodings the client
+	// accepts equally, encodings without an encoder are skipped
+	//
+	// Optional. Default: br, zstd, gzip, deflate
+	Order []string
+}
+
+// ConfigCompressDefault is the default config
+var ConfigCompressDefault = ConfigCompress{
+	Next:          nil,
+	Level:         CompressLevelDefault,
+	MinLength:     1024,
+	MaxBufferSize: defaultMaxBufferSize,
+	Order:         []string{"br", "zstd", "gzip", "deflate"},
+}
+
+// Helper function to set default values
+func configCompressDefault(config ...ConfigCompress) ConfigCompress {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCompressDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = ConfigCompressDefault.MinLength
+	}
+	if cfg.MaxBufferSize <= 0 {
+		cfg.MaxBufferSize = ConfigCompressDefault.MaxBufferSize
+	}
+	if cfg.Order == nil {
+		cfg.Order = ConfigCompressDefault.Order
+	}
+	return cfg
+}
+
+// CompressEncoder creates a writer compressing into w
+type CompressEncoder func(w io.Writer, level CompressLevel) (io.WriteCloser, error)
+
+// compressEncoders are the encodings supported out of the box
+var compressEncoders = map[string]CompressEncoder{
+	"br": func(w io.Writer, level CompressLevel) (io.WriteCloser, error) {
+		return brotli.NewWriterLevel(w, brotliLevel(level)), nil
+	},
+	"zstd": func(w io.Writer, level CompressLevel) (io.WriteCloser, error) {
+		enc, err := zstdEncoder(level)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdWriter{w: w, enc: enc}, nil
+	},
+	"gzip": func(w io.Writer, level CompressLevel) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, flateLevel(level))
+	},
+	"deflate": func(w io.Writer, level CompressLevel) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flateLevel(level))
+	},
+}
+
+func flateLevel(level CompressLevel) int {
+	switch level {
+	case CompressLevelBestSpeed:
+		return flate.BestSpeed
+	case CompressLevelBestCompression:
+		return flate.BestCompression
+	}
+	return flate.DefaultCompression
+}
+
+func brotliLevel(level CompressLevel) int {
+	switch level {
+	case CompressLevelBestSpeed:
+		return brotli.BestSpeed
+	case CompressLevelBestCompression:
+		return brotli.BestCompression
+	}
+	return brotli.DefaultCompression
+}
+
+// zstdEncoders holds a *zstd.Encoder per CompressLevel, encoders are safe
+// for concurrent use with EncodeAll
+var zstdEncoders sync.Map
+
+func zstdEncoder(level CompressLevel) (*zstd.Encoder, error) {
+	if enc, ok := zstdEncoders.Load(level); ok {
+		return enc.(*zstd.Encoder), nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel(level)), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := zstdEncoders.LoadOrStore(level, enc)
+	return actual.(*zstd.Encoder), nil
+}
+
+func zstdLevel(level CompressLevel) zstd.EncoderLevel {
+	switch level {
+	case CompressLevelBestSpeed:
+		return zstd.SpeedFastest
+	case CompressLevelBestCompression:
+		return zstd.SpeedBestCompression
+	}
+	return zstd.SpeedDefault
+}
+
+// zstdWriter compresses the body in one frame on Close with a shared
+// encoder, Compress buffers the body anyway
+type zstdWriter struct {
+	w      io.Writer
+	enc    *zstd.Encoder
+	prefix []byte
+	body   []byte
+}
+
+func (z *zstdWriter) Write(b []byte) (int, error) {
+	z.body = append(z.body, b...)
+	return len(b), nil
+}
+
+func (z *zstdWriter) Close() error {
+	_, err := z.w.Write(z.enc.EncodeAll(z.body, z.prefix))
+	return err
+}
+
+const headerAvailableDictionary = "Available-Dictionary"
+
+// dczMagic starts every dcz body, followed by the sha256 of the dictionary
+var dczMagic = []byte{0x5e, 0x2a, 0x4d, 0x18, 0x20, 0x00, 0x00, 0x00}
+
+// dczEncoder returns the encoder of the dcz coding for dict and the value
+// of the Available-Dictionary header of clients that have it
+func dczEncoder(dict []byte, level CompressLevel) (CompressEncoder, string) {
+	enc, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstdLevel(level)),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithEncoderDictRaw(0, dict))
+	if err != nil {
+		panic(fmt.Errorf("compress: %w", err))
+	}
+	sum := sha256.Sum256(dict)
+	prefix := append(append([]byte(nil), dczMagic...), sum[:]...)
+	return func(w io.Writer, _ CompressLevel) (io.WriteCloser, error) {
+		return &zstdWriter{w: w, enc: enc, prefix: append([]byte(nil), prefix...)}, nil
+	}, ":" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// Compress creates a new middleware handler
+func Compress(config ...ConfigCompress) http.HandlerFunc {
+	// Set default config
+	cfg := configCompressDefault(config...)
+
+	encoders := make(map[string]CompressEncoder, len(compressEncoders)+len(cfg.Encoders))
+	for name, enc := range compressEncoders {
+		encoders[name] = enc
+	}
+	listed := make(map[string]bool, len(cfg.Order))
+	for _, name := range cfg.Order {
+		listed[strings.ToLower(name)] = true
+	}
+	// Registered encodings missing from Order come first
+	order := make([]string, 0, len(cfg.Order)+len(cfg.Encoders))
+	for name, enc := range cfg.Encoders {
+		name = strings.ToLower(name)
+		encoders[name] = enc
+		if !listed[name] {
+			order = append(order, name)
+		}
+	}
+	sort.Strings(order)
+	for _, name := range cfg.Order {
+		if name = strings.ToLower(name); encoders[name] != nil {
+			order = append(order, name)
+		}
+	}
+	var dictionary string
+	if len(cfg.Dictionary) > 0 {
+		encoders["dcz"], dictionary = dczEncoder(cfg.Dictionary, cfg.Level)
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		accept := c.Header(utils.HeaderAcceptEncoding, "")
+		encoding := negotiateEncoding(accept, order)
+		if dictionary != "" && c.Header(headerAvailableDictionary, "") == dictionary &&
+			negotiateEncoding(accept, []string{"dcz"}) != "" {
+			encoding = "dcz"
+		}
+		if encoding == "" || c.Method() == utils.MethodHead {
+			return c.Next()
+		}
+
+		rec, ok := bufferResponse(c, cfg.MaxBufferSize)
+		if !ok {
+			return c.Next()
+		}
+		if err := rec.next(c); err != nil {
+			_ = rec.flush()
+			return err
+		}
+
+		body := rec.Body()
+		header := rec.Header()
+		contentType := header.Get(utils.HeaderContentType)
+		if contentType == "" && !rec.overflow {
+			// Sniff the type like net/http would, it can't once the body is
+			// compressed
+			contentType = stdHttp.DetectContentType(body)
+		}
+		if rec.overflow || len(body) < cfg.MinLength ||
+			rec.Status() == utils.StatusNoContent || rec.Status() == utils.StatusNotModified ||
+			// A range of the identity body can't be compressed on its own
+			rec.Status() == utils.StatusPartialContent || header.Get(utils.HeaderContentRange) != "" ||
+			header.Get(utils.HeaderContentEncoding) != "" ||
+			strings.Contains(header.Get(utils.HeaderCacheControl), "no-transform") ||
+			!compressibleType(contentType) {
+			return rec.flush()
+		}
+
+		var out bytes.Buffer
+		w, err := encoders[encoding](&out, cfg.Level)
+		if err != nil {
+			return rec.flush()
+		}
+		if _, err = w.Write(body); err == nil {
+			err = w.Close()
+		}
+		if err != nil || out.Len() >= len(body) {
+			return rec.flush()
+		}
+
+		if cfg.OnCompress != nil {
+			cfg.OnCompress(contentType, len(body), out.Len())
+		}
+		header.Set(utils.HeaderContentType, contentType)
+		header.Set(utils.HeaderContentEncoding, encoding)
+		addVary(header, utils.HeaderAcceptEncoding)
+		if dictionary != "" {
+			addVary(header, headerAvailableDictionary)
+		}
+		header.Set(utils.HeaderContentLength, strconv.Itoa(out.Len()))
+		// The compressed bytes differ from the ones the strong validator
+		// was computed over
+		if etag := header.Get(utils.HeaderETag); strings.HasPrefix(etag, `"`) {
+			header.Set(utils.HeaderETag, "W/"+etag)
+		}
+		rec.body.Reset()
+		rec.body.Write(out.Bytes())
+		return rec.flush()
+	}
+}
+
+// addVary adds name to the Vary header unless it is already listed or the
+// response varies on everything
+func addVary(header stdHttp.Header, name string) {
+	for _, value := range header.Values(utils.HeaderVary) {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "*" || strings.EqualFold(v, name) {
+				return
+			}
+		}
+	}
+	header.Add(utils.HeaderVary, name)
+}
+
+// negotiateEncoding returns the encoding of order with the highest quality
+// in the Accept-Encoding header, "" when the client accepts none of them
+func negotiateEncoding(accept string, order []string) string {
+	if accept == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, q := parseQuality(part)
+		qualities[name] = q
+	}
+	best, bestQ := "", 0.0
+	for _, name := range order {
+		q, ok := qualities[name]
+		if !ok {
+			// An encoding that isn't listed falls back to the wildcard
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// parseQuality splits a header list element into its lowercased value and
+// its q parameter
+func parseQuality(part string) (string, float64) {
+	value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(k, "q") {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(value)), q
+}
+
+// compressibleType reports whether a content type benefits from compression
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml",
+		"application/wasm", "image/svg+xml", "application/manifest+json":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// CompressOption sets a field of ConfigCompress, see CompressWith
+type CompressOption func(cfg *ConfigCompress)
+
+// CompressWith creates a Compress middleware from options applied to
+// ConfigCompressDefault in order, a later option overrides an earlier one
+//
+//	middleware.CompressWith(
+//		middleware.CompressAtLevel(middleware.CompressLevelBestSpeed),
+//		middleware.CompressMinLength(256),
+//	)
+func CompressWith(opts ...CompressOption) http.HandlerFunc {
+	cfg := ConfigCompressDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Compress(cfg)
+}
+
+// CompressOptions bundles options into one, e.g. to share a preset
+// between services
+func CompressOptions(opts ...CompressOption) CompressOption {
+	return func(cfg *ConfigCompress) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// CompressNext sets ConfigCompress.Next
+func CompressNext(next func(c http.Context) bool) CompressOption {
+	return func(cfg *ConfigCompress) { cfg.Next = next }
+}
+
+// CompressAtLevel sets ConfigCompress.Level
+func CompressAtLevel(level CompressLevel) CompressOption {
+	return func(cfg *ConfigCompress) { cfg.Level = level }
+}
+
+// CompressMinLength sets ConfigCompress.MinLength. Unlike the field, 0
+// compresses bodies of any length.
+func CompressMinLength(n int) CompressOption {
+	return func(cfg *ConfigCompress) {
+		cfg.MinLength = n
+		if cfg.MinLength <= 0 {
+			cfg.MinLength = 1
+		}
+	}
+}
+
+// CompressMaxBufferSize sets ConfigCompress.MaxBufferSize
+func CompressMaxBufferSize(n int) CompressOption {
+	return func(cfg *ConfigCompress) { cfg.MaxBufferSize = n }
+}
+
+// CompressOnCompress sets ConfigCompress.OnCompress
+func CompressOnCompress(fn func(contentType string, in, out int)) CompressOption {
+	return func(cfg *ConfigCompress) { cfg.OnCompress = fn }
+}
+
+// CompressEncoding adds an encoder to ConfigCompress.Encoders
+func CompressEncoding(name string, encoder CompressEncoder) CompressOption {
+	return func(cfg *ConfigCompress) {
+		encoders := make(map[string]CompressEncoder, len(cfg.Encoders)+1)
+		for k, v := range cfg.Encoders {
+			encoders[k] = v
+		}
+		encoders[strings.ToLower(name)] = encoder
+		cfg.Encoders = encoders
+	}
+}
+
+// CompressOrder sets ConfigCompress.Order
+func CompressOrder(encodings ...string) CompressOption {
+	return func(cfg *ConfigCompress) { cfg.Order = encodings }
+}
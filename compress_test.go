@@ -0,0 +1,332 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+var compressBody = strings.Repeat("compressible text ", 200)
+
+func textHandler(c http.Context) error {
+	c.SetHeader(utils.HeaderContentType, "text/plain; charset=utf-8")
+	return c.String(compressBody)
+}
+
+// fakeBrotli replaces the built-in Brotli encoder, it deflates with a
+// marker so the tests can tell its output from the built-in one
+func fakeBrotli(w io.Writer, _ CompressLevel) (io.WriteCloser, error) {
+	if _, err := w.Write([]byte("BR")); err != nil {
+		return nil, err
+	}
+	return flate.NewWriter(w, flate.BestCompression)
+}
+
+func TestCompressNegotiation(t *testing.T) {
+	replaced := Compress(ConfigCompress{Encoders: map[string]CompressEncoder{"br": fakeBrotli}})
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		accept  string
+		want    string
+		fake    bool
+	}{
+		{"br client", Compress(), "gzip, deflate, br", "br", false},
+		{"gzip only client", Compress(), "gzip", "gzip", false},
+		{"br refused", Compress(), "br;q=0, gzip", "gzip", false},
+		{"gzip preferred by quality", Compress(), "br;q=0.5, gzip", "gzip", false},
+		{"br only", Compress(), "br", "br", false},
+		{"replaced br encoder", replaced, "br, gzip", "br", true},
+		{"identity", Compress(), "", "", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set(utils.HeaderAcceptEncoding, tt.accept)
+		}
+		c := run(t, req, tt.handler, textHandler)
+		h := c.Recorder.Header()
+		if got := h.Get(utils.HeaderContentEncoding); got != tt.want {
+			t.Errorf("%s: Content-Encoding = %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		body := c.Recorder.Body.Bytes()
+		var decoded []byte
+		switch {
+		case tt.fake:
+			if !bytes.HasPrefix(body, []byte("BR")) {
+				t.Errorf("%s: body wasn't written by the registered encoder", tt.name)
+				continue
+			}
+			decoded, _ = io.ReadAll(flate.NewReader(bytes.NewReader(body[2:])))
+		case tt.want == "br":
+			var err error
+			if decoded, err = io.ReadAll(brotli.NewReader(bytes.NewReader(body))); err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+		case tt.want == "gzip":
+			r, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			decoded, _ = io.ReadAll(r)
+		default:
+			decoded = body
+		}
+		if string(decoded) != compressBody {
+			t.Errorf("%s: decoded body differs", tt.name)
+		}
+		if tt.want != "" && h.Get(utils.HeaderContentLength) != strconv.Itoa(len(body)) {
+			t.Errorf("%s: Content-Length = %q for %d bytes", tt.name, h.Get(utils.HeaderContentLength), len(body))
+		}
+		if tt.want != "" && !strings.Contains(h.Get(utils.HeaderVary), utils.HeaderAcceptEncoding) {
+			t.Errorf("%s: Vary = %q", tt.name, h.Get(utils.HeaderVary))
+		}
+	}
+}
+
+func TestCompressOrder(t *testing.T) {
+	handler := Compress(ConfigCompress{Order: []string{"gzip", "br"}})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAcceptEncoding, "br, gzip")
+	c := run(t, req, handler, textHandler)
+	if got := c.Recorder.Header().Get(utils.HeaderContentEncoding); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want the first of Order", got)
+	}
+}
+
+func TestCompressSkipsSmallBodies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAcceptEncoding, "gzip")
+	c := run(t, req, Compress(), func(c http.Context) error {
+		c.SetHeader(utils.HeaderContentType, "text/plain")
+		return c.String("small")
+	})
+	if c.Recorder.Header().Get(utils.HeaderContentEncoding) != "" || c.Body() != "small" {
+		t.Errorf("small body compressed")
+	}
+}
+
+func TestCompressZstd(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAcceptEncoding, "gzip, zstd")
+	c := run(t, req, Compress(), textHandler)
+	if got := c.Recorder.Header().Get(utils.HeaderContentEncoding); got != "zstd" {
+		t.Fatalf("Content-Encoding = %q, want zstd", got)
+	}
+	dec, _ := zstd.NewReader(nil)
+	defer dec.Close()
+	decoded, err := dec.DecodeAll(c.Recorder.Body.Bytes(), nil)
+	if err != nil || string(decoded) != compressBody {
+		t.Errorf("decoded body differs: %v", err)
+	}
+}
+
+func TestCompressRegisteredZstd(t *testing.T) {
+	// fakeZstd replaces the built-in zstd encoder
+	fakeZstd := func(w io.Writer, level CompressLevel) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flateLevel(level))
+	}
+	handler := Compress(ConfigCompress{Encoders: map[string]CompressEncoder{"ZSTD": fakeZstd}})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAcceptEncoding, "gzip, zstd")
+	c := run(t, req, handler, textHandler)
+	if got := c.Recorder.Header().Get(utils.HeaderContentEncoding); got != "zstd" {
+		t.Fatalf("Content-Encoding = %q, want zstd", got)
+	}
+	decoded, _ := io.ReadAll(flate.NewReader(c.Recorder.Body))
+	if string(decoded) != compressBody {
+		t.Errorf("decoded body differs")
+	}
+}
+
+func TestCompressDictionary(t *testing.T) {
+	dict := []byte(`{"id":1,"name":"John Doe","email":"john@example.com","created_at":"2024-01-01T00:00:00Z","roles":["admin","user"],"active":true}`)
+	body := `{"id":42,"name":"Jane Roe","email":"jane@example.com","created_at":"2024-03-01T09:30:00Z","roles":["user"],"active":true}`
+	sum := sha256.Sum256(dict)
+	available := ":" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+
+	sizes := make(map[string]int)
+	handler := Compress(ConfigCompress{
+		MinLength:  1,
+		Dictionary: dict,
+		OnCompress: func(_ string, in, out int) { sizes["last"] = out },
+	})
+	for _, tt := range []struct {
+		name       string
+		accept     string
+		dictionary string
+		dcz        bool
+	}{
+		{"dictionary", "gzip, br, zstd, dcz", available, true},
+		{"other dictionary", "zstd, dcz", ":" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + ":", false},
+		{"dcz refused", "zstd, dcz;q=0", available, false},
+		{"no dictionary", "zstd, dcz", "", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderAcceptEncoding, tt.accept)
+		if tt.dictionary != "" {
+			req.Header.Set(headerAvailableDictionary, tt.dictionary)
+		}
+		sizes["last"] = len(body)
+		c := run(t, req, handler, func(c http.Context) error {
+			c.SetHeader(utils.HeaderContentType, "application/json")
+			return c.String(body)
+		})
+		h := c.Recorder.Header()
+		if got := h.Get(utils.HeaderContentEncoding); (got == "dcz") != tt.dcz {
+			t.Errorf("%s: Content-Encoding = %q", tt.name, got)
+			continue
+		}
+		sizes[tt.name] = sizes["last"]
+		if vary := h.Values(utils.HeaderVary); h.Get(utils.HeaderContentEncoding) != "" && !strings.Contains(strings.Join(vary, ","), headerAvailableDictionary) {
+			t.Errorf("%s: Vary = %q", tt.name, vary)
+		}
+		if !tt.dcz {
+			continue
+		}
+
+		// A dcz body is the magic number and the dictionary's hash before
+		// the zstd frame
+		raw := c.Recorder.Body.Bytes()
+		if len(raw) < 40 || !bytes.Equal(raw[:8], dczMagic) || !bytes.Equal(raw[8:40], sum[:]) {
+			t.Fatalf("%s: dcz header = %x", tt.name, raw)
+		}
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderDictRaw(0, dict))
+		decoded, err := dec.DecodeAll(raw[40:], nil)
+		dec.Close()
+		if err != nil || string(decoded) != body {
+			t.Errorf("%s: decoded %q, %v", tt.name, decoded, err)
+		}
+	}
+	if sizes["dictionary"] >= sizes["no dictionary"] {
+		t.Errorf("sizes = %v, the dictionary should shrink the body", sizes)
+	}
+}
+
+func TestCompressSniffsMissingContentType(t *testing.T) {
+	var compressed []string
+	handler := Compress(ConfigCompress{OnCompress: func(contentType string, in, out int) {
+		compressed = append(compressed, contentType)
+		if in != len(compressBody) || out <= 0 || out >= in {
+			t.Errorf("sizes = %d -> %d", in, out)
+		}
+	}})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAcceptEncoding, "gzip")
+	c := run(t, req, handler, func(c http.Context) error {
+		return c.String(compressBody)
+	})
+	h := c.Recorder.Header()
+	if h.Get(utils.HeaderContentEncoding) != "gzip" || h.Get(utils.HeaderContentType) != "text/plain; charset=utf-8" {
+		t.Errorf("headers = %v", h)
+	}
+	if len(compressed) != 1 || compressed[0] != "text/plain; charset=utf-8" {
+		t.Errorf("OnCompress got %q", compressed)
+	}
+
+	// Binary content isn't compressed
+	c = run(t, req, handler, func(c http.Context) error {
+		return c.String("\x89PNG\r\n\x1a\n" + compressBody)
+	})
+	if c.Recorder.Header().Get(utils.HeaderContentEncoding) != "" {
+		t.Errorf("sniffed png compressed")
+	}
+}
+
+func TestCompressVary(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		vary []string
+		want []string
+	}{
+		{"none", nil, []string{"Accept-Encoding"}},
+		{"other", []string{"Origin"}, []string{"Origin", "Accept-Encoding"}},
+		{"listed", []string{"Origin, accept-encoding"}, []string{"Origin, accept-encoding"}},
+		{"separate value", []string{"Origin", "Accept-Encoding"}, []string{"Origin", "Accept-Encoding"}},
+		{"star", []string{"*"}, []string{"*"}},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderAcceptEncoding, "gzip")
+		c := run(t, req, Compress(), func(c http.Context) error {
+			for _, v := range tt.vary {
+				c.(*middlewaretest.MockContext).Res.Header().Add(utils.HeaderVary, v)
+			}
+			return textHandler(c)
+		})
+		h := c.Recorder.Header()
+		if h.Get(utils.HeaderContentEncoding) != "gzip" {
+			t.Fatalf("%s: response not compressed", tt.name)
+		}
+		if got := h.Values(utils.HeaderVary); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: Vary = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Uncompressed responses are left alone
+	req := httptest.NewRequest("GET", "/", nil)
+	c := run(t, req, Compress(), textHandler)
+	if got := c.Recorder.Header().Values(utils.HeaderVary); len(got) != 0 {
+		t.Errorf("identity: Vary = %q", got)
+	}
+}
+
+func TestCompressSkipsRanges(t *testing.T) {
+	for name, prepare := range map[string]func(c http.Context){
+		"206": func(c http.Context) { c.Status(utils.StatusPartialContent) },
+		"content range": func(c http.Context) {
+			c.SetHeader(utils.HeaderContentRange, "bytes 0-3599/7200")
+		},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderAcceptEncoding, "gzip")
+		c := run(t, req, Compress(), func(c http.Context) error {
+			prepare(c)
+			return textHandler(c)
+		})
+		if c.Recorder.Header().Get(utils.HeaderContentEncoding) != "" || c.Body() != compressBody {
+			t.Errorf("%s: range compressed", name)
+		}
+	}
+}
+
+func TestCompressETag(t *testing.T) {
+	for _, tt := range []struct{ etag, want string }{
+		{`"v1"`, `W/"v1"`},
+		{`W/"v1"`, `W/"v1"`},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderAcceptEncoding, "gzip")
+		c := run(t, req, Compress(), func(c http.Context) error {
+			c.SetHeader(utils.HeaderETag, tt.etag)
+			return textHandler(c)
+		})
+		if got := c.Recorder.Header().Get(utils.HeaderETag); got != tt.want {
+			t.Errorf("ETag %s = %s, want %s", tt.etag, got, tt.want)
+		}
+	}
+
+	// Identity responses keep their strong validator
+	c := run(t, httptest.NewRequest("GET", "/", nil), Compress(), func(c http.Context) error {
+		c.SetHeader(utils.HeaderETag, `"v1"`)
+		return textHandler(c)
+	})
+	if got := c.Recorder.Header().Get(utils.HeaderETag); got != `"v1"` {
+		t.Errorf("identity ETag = %s", got)
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigConditional defines the config for middleware.
+type ConfigConditional struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// MaxBufferSize is the largest response body held back for a
+	// conversion, larger responses are streamed through unchanged
+	//
+	// Optional. Default: 1 MB
+	MaxBufferSize int
+}
+
+// ConfigConditionalDefault is the default config
+var ConfigConditionalDefault = ConfigConditional{
+	Next:          nil,
+	MaxBufferSize: defaultMaxBufferSize,
+}
+
+// Helper function to set default values
+func configConditionalDefault(config ...ConfigConditional) ConfigConditional {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigConditionalDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.MaxBufferSize <= 0 {
+		cfg.MaxBufferSize = ConfigConditionalDefault.MaxBufferSize
+	}
+	return cfg
+}
+
+// SetLastModified sets the Last-Modified header for ConditionalGet. It
+// returns false when If-Unmodified-Since fails, handlers of state-changing
+// requests must then return without applying the change, the response is
+// turned into 412 Precondition Failed.
+func SetLastModified(c http.Context, t time.Time) bool {
+	t = t.UTC().Truncate(time.Second)
+	c.SetHeader(utils.HeaderLastModified, t.Format(stdHttp.TimeFormat))
+	return evalLastModified(c, t) != utils.StatusPreconditionFailed
+}
+
+// ConditionalGet creates a new middleware handler answering If-Modified-Since
+// and If-Unmodified-Since for responses with a Last-Modified header, as set
+// by SetLastModified. Unmodified
+// responses become 304 Not Modified, failed If-Unmodified-Since conditions
+// 412 Precondition Failed, both without a body. As RFC 9110 requires, the
+// dates are ignored when If-None-Match or If-Match are sent, so the ETag
+// middleware decides those requests.
+func ConditionalGet(config ...ConfigConditional) http.HandlerFunc {
+	// Set default config
+	cfg := configConditionalDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		rec, ok := bufferResponse(c, cfg.MaxBufferSize)
+		if !ok {
+			return c.Next()
+		}
+		if err := rec.next(c); err != nil {
+			_ = rec.flush()
+			return err
+		}
+
+		// Values stored by the handler don't reach us, the header does
+		lastModified, err := stdHttp.ParseTime(rec.Header().Get(utils.HeaderLastModified))
+		if err != nil || rec.overflow {
+			return rec.flush()
+		}
+		// Conditions only apply to responses that would have succeeded
+		status := rec.Status()
+		switch evalLastModified(c, lastModified) {
+		case utils.StatusPreconditionFailed:
+			if status >= 200 && status < 300 {
+				notModified(rec)
+				rec.status = utils.StatusPreconditionFailed
+			}
+		case utils.StatusNotModified:
+			if status == utils.StatusOK {
+				notModified(rec)
+			}
+		}
+		return rec.flush()
+	}
+}
+
+// evalLastModified evaluates the date preconditions of RFC 9110 section
+// 13.2.2 and returns 412, 304 or 0 when the request proceeds
+func evalLastModified(c http.Context, lastModified time.Time) int {
+	if c.Header(utils.HeaderIfMatch, "") == "" {
+		// Malformed dates are ignored
+		if since, err := stdHttp.ParseTime(c.Header(utils.HeaderIfUnmodifiedSince, "")); err == nil && lastModified.After(since) {
+			return utils.StatusPreconditionFailed
+		}
+	}
+	if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+		return 0
+	}
+	if c.Header(utils.HeaderIfNoneMatch, "") == "" {
+		if since, err := stdHttp.ParseTime(c.Header(utils.HeaderIfModifiedSince, "")); err == nil && !lastModified.After(since) {
+			return utils.StatusNotModified
+		}
+	}
+	return 0
+}
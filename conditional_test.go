@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var conditionalModified = time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+
+// conditionalHandler sets the Last-Modified time and records whether the
+// change of a state-changing request was applied
+func conditionalHandler(applied *bool) http.HandlerFunc {
+	return func(c http.Context) error {
+		if !SetLastModified(c, conditionalModified) {
+			return nil
+		}
+		if applied != nil {
+			*applied = true
+		}
+		c.SetHeader(utils.HeaderContentType, "text/plain")
+		return c.String("content")
+	}
+}
+
+func conditionalRequest(method string, headers map[string]string) *stdHttp.Request {
+	req := httptest.NewRequest(method, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func TestConditionalGetNotModified(t *testing.T) {
+	req := conditionalRequest("GET", map[string]string{
+		utils.HeaderIfModifiedSince: conditionalModified.Truncate(time.Second).Format(stdHttp.TimeFormat),
+	})
+	c := run(t, req, ConditionalGet(), conditionalHandler(nil))
+	if c.Recorder.Code != utils.StatusNotModified {
+		t.Fatalf("status = %d, want 304", c.Recorder.Code)
+	}
+	if c.Body() != "" {
+		t.Errorf("body = %q, want none", c.Body())
+	}
+	if got := c.Recorder.Header().Get(utils.HeaderLastModified); got != "Fri, 01 Mar 2024 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if got := c.Recorder.Header().Get(utils.HeaderContentType); got != "" {
+		t.Errorf("Content-Type = %q, want none", got)
+	}
+}
+
+func TestConditionalGetModified(t *testing.T) {
+	req := conditionalRequest("GET", map[string]string{
+		utils.HeaderIfModifiedSince: conditionalModified.Add(-time.Hour).Format(stdHttp.TimeFormat),
+	})
+	c := run(t, req, ConditionalGet(), conditionalHandler(nil))
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "content" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+}
+
+func TestConditionalGetPreconditionFailed(t *testing.T) {
+	applied := false
+	req := conditionalRequest("PUT", map[string]string{
+		utils.HeaderIfUnmodifiedSince: conditionalModified.Add(-time.Hour).Format(stdHttp.TimeFormat),
+	})
+	c := run(t, req, ConditionalGet(), conditionalHandler(&applied))
+	if c.Recorder.Code != utils.StatusPreconditionFailed {
+		t.Errorf("status = %d, want 412", c.Recorder.Code)
+	}
+	if applied {
+		t.Error("change applied despite the failed precondition")
+	}
+}
+
+func TestConditionalGetEntityTagPrecedence(t *testing.T) {
+	req := conditionalRequest("GET", map[string]string{
+		utils.HeaderIfNoneMatch:     `"other"`,
+		utils.HeaderIfModifiedSince: conditionalModified.Add(time.Hour).Format(stdHttp.TimeFormat),
+	})
+	c := run(t, req, ConditionalGet(), conditionalHandler(nil))
+	if c.Recorder.Code != utils.StatusOK {
+		t.Errorf("status = %d, If-None-Match must take precedence", c.Recorder.Code)
+	}
+
+	applied := false
+	req = conditionalRequest("PUT", map[string]string{
+		utils.HeaderIfMatch:           `"x"`,
+		utils.HeaderIfUnmodifiedSince: conditionalModified.Add(-time.Hour).Format(stdHttp.TimeFormat),
+	})
+	c = run(t, req, ConditionalGet(), conditionalHandler(&applied))
+	if c.Recorder.Code != utils.StatusOK || !applied {
+		t.Errorf("status = %d, If-Match must take precedence", c.Recorder.Code)
+	}
+}
+
+func TestConditionalGetMalformedDate(t *testing.T) {
+	req := conditionalRequest("GET", map[string]string{utils.HeaderIfModifiedSince: "yesterday"})
+	c := run(t, req, ConditionalGet(), conditionalHandler(nil))
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "content" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+}
+
+func TestConditionalGetWithoutLastModified(t *testing.T) {
+	req := conditionalRequest("GET", map[string]string{
+		utils.HeaderIfModifiedSince: time.Now().Format(stdHttp.TimeFormat),
+	})
+	c := run(t, req, ConditionalGet(), ok)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "ok" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+}
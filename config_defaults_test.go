@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestConfigCorsDefault(t *testing.T) {
+	preflight := func(handler http.HandlerFunc, origin string) stdHttp.Header {
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set(utils.HeaderOrigin, origin)
+		req.Header.Set(utils.HeaderAccessControlRequestMethod, "GET")
+		return run(t, req, handler, ok).Recorder.Header()
+	}
+	stored := func(handler http.HandlerFunc, key string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderOrigin, "https://a.com")
+		var origin string
+		run(t, req, handler, func(c http.Context) error {
+			origin, _ = c.Value(key).(string)
+			return c.String("ok")
+		})
+		return origin
+	}
+
+	for _, handler := range []http.HandlerFunc{Cors(), Cors(ConfigCors{}), Cors(ConfigCors{MaxOrigins: -1})} {
+		h := preflight(handler, "https://a.com")
+		if h.Get(utils.HeaderAccessControlAllowOrigin) != "*" || h.Get(utils.HeaderAccessControlAllowMethods) != ConfigCorsDefault.AllowMethods {
+			t.Errorf("zero config: headers = %v", h)
+		}
+		if h.Get(utils.HeaderAccessControlMaxAge) != "" || h.Get(utils.HeaderAccessControlAllowCredentials) != "" {
+			t.Errorf("zero config: headers = %v", h)
+		}
+		if origin := stored(handler, "cors_origin"); origin != "*" {
+			t.Errorf("zero config: stored origin = %q", origin)
+		}
+	}
+
+	// MaxOrigins falls back to 100
+	origins := strings.TrimSuffix(strings.Repeat("https://a.com,", 100), ",")
+	Cors(ConfigCors{AllowOrigins: origins})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("101 origins accepted with the default MaxOrigins")
+			}
+		}()
+		Cors(ConfigCors{AllowOrigins: origins + ",https://b.com"})
+	}()
+
+	explicit := Cors(ConfigCors{AllowOrigins: "https://a.com", AllowMethods: "GET", MaxAge: 60, MaxOrigins: 2, ContextKey: "k"})
+	h := preflight(explicit, "https://a.com")
+	if h.Get(utils.HeaderAccessControlAllowOrigin) != "https://a.com" || h.Get(utils.HeaderAccessControlAllowMethods) != "GET" ||
+		h.Get(utils.HeaderAccessControlMaxAge) != "60" {
+		t.Errorf("explicit config: headers = %v", h)
+	}
+	if origin := stored(explicit, "k"); origin != "https://a.com" {
+		t.Errorf("explicit config: stored origin = %q", origin)
+	}
+}
+
+func TestConfigSecureDefault(t *testing.T) {
+	for _, handler := range []http.HandlerFunc{Secure(), Secure(ConfigSecure{})} {
+		h := run(t, httptest.NewRequest("GET", "/", nil), handler, ok).Recorder.Header()
+		if h.Get(utils.HeaderXXSSProtection) != "1; mode=block" || h.Get(utils.HeaderXContentTypeOptions) != "nosniff" ||
+			h.Get(utils.HeaderXFrameOptions) != "SAMEORIGIN" {
+			t.Errorf("zero config: headers = %v", h)
+		}
+		if h.Get(utils.HeaderStrictTransportSecurity) != "" {
+			t.Errorf("zero config: headers = %v", h)
+		}
+	}
+
+	explicit := Secure(ConfigSecure{XSSProtection: "0", ContentTypeNosniff: "x", XFrameOptions: "DENY", HSTSMaxAge: 10})
+	h := run(t, httptest.NewRequest("GET", "https://example.com/", nil), explicit, ok).Recorder.Header()
+	if h.Get(utils.HeaderXXSSProtection) != "0" || h.Get(utils.HeaderXContentTypeOptions) != "x" ||
+		h.Get(utils.HeaderXFrameOptions) != "DENY" || h.Get(utils.HeaderStrictTransportSecurity) != "max-age=10; includeSubdomains" {
+		t.Errorf("explicit config: headers = %v", h)
+	}
+}
+
+func TestConfigBasicAuthDefault(t *testing.T) {
+	cfg := configBasicAuthDefault(ConfigBasicAuth{})
+	if cfg.Realm != "Restricted" || cfg.Scheme != "basic" || cfg.ContextUsername != "username" ||
+		cfg.ContextPassword != "password" || cfg.LockoutDuration != 15*time.Minute || cfg.Users == nil {
+		t.Errorf("zero config = %+v", cfg)
+	}
+	if cfg.Authorizer == nil || cfg.Unauthorized == nil || cfg.Next != nil {
+		t.Errorf("functions = %+v", cfg)
+	}
+	if cfg.Authorizer("john", "doe") {
+		t.Error("default authorizer accepted unknown user")
+	}
+
+	cfg = configBasicAuthDefault(ConfigBasicAuth{Users: map[string]string{"john": "doe"}, Realm: "R", LockoutDuration: time.Second})
+	if !cfg.Authorizer("john", "doe") || cfg.Authorizer("john", "x") {
+		t.Error("default authorizer doesn't check Users")
+	}
+	if cfg.Realm != "R" || cfg.LockoutDuration != time.Second {
+		t.Errorf("explicit config = %+v", cfg)
+	}
+
+	c := run(t, httptest.NewRequest("GET", "/", nil), BasicAuth(ConfigBasicAuth{}), ok)
+	if c.Recorder.Code != utils.StatusUnauthorized || c.Recorder.Header().Get("WWW-Authenticate") != "basic realm=Restricted" {
+		t.Errorf("response = %d %v", c.Recorder.Code, c.Recorder.Header())
+	}
+}
+
+func TestConfigRecoverDefault(t *testing.T) {
+	cfg := configRecoverDefault(ConfigRecover{})
+	if cfg.Output != os.Stderr || cfg.ErrorHandler == nil || cfg.RequestIDKey != "requestid" ||
+		!reflect.DeepEqual(cfg.PrincipalKeys, []string{"username", "claims"}) {
+		t.Errorf("zero config = %+v", cfg)
+	}
+	if cfg.StackTraceHandler != nil {
+		t.Error("stack trace handler set without EnableStackTrace")
+	}
+	if cfg = configRecoverDefault(ConfigRecover{EnableStackTrace: true}); cfg.StackTraceHandler == nil {
+		t.Error("no stack trace handler with EnableStackTrace")
+	}
+	cfg = configRecoverDefault(ConfigRecover{PrincipalKeys: []string{}, RequestIDKey: "rid"})
+	if len(cfg.PrincipalKeys) != 0 || cfg.RequestIDKey != "rid" {
+		t.Errorf("explicit config = %+v", cfg)
+	}
+}
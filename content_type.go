@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigRequireContentType defines the config for middleware.
+type ConfigRequireContentType struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Types are the accepted media types, a subtype of * accepts every
+	// subtype, e.g. "application/*"
+	//
+	// Required
+	Types []string
+
+	// ErrorHandler is called with the media type of a rejected request,
+	// "" when it sent none
+	//
+	// Optional. Default: responds with 415 Unsupported Media Type
+	ErrorHandler func(c http.Context, mediaType string) error
+}
+
+// ConfigRequireContentTypeDefault is the default config
+var ConfigRequireContentTypeDefault = ConfigRequireContentType{
+	Next: nil,
+	ErrorHandler: func(c http.Context, mediaType string) error {
+		c.AbortWithStatus(utils.StatusUnsupportedMediaType)
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, mediaType)
+	},
+}
+
+// Helper function to set default values
+func configRequireContentTypeDefault(config ...ConfigRequireContentType) ConfigRequireContentType {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigRequireContentTypeDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigRequireContentTypeDefault.ErrorHandler
+	}
+	return cfg
+}
+
+// RequireContentType creates a new middleware handler accepting request
+// bodies of the given media types only
+func RequireContentType(types ...string) http.HandlerFunc {
+	return RequireContentTypeConfig(ConfigRequireContentType{Types: types})
+}
+
+// RequireContentTypeConfig creates a new middleware handler with a config
+func RequireContentTypeConfig(config ConfigRequireContentType) http.HandlerFunc {
+	// Set default config
+	cfg := configRequireContentTypeDefault(config)
+
+	if len(cfg.Types) == 0 {
+		panic("content type: at least one type is required")
+	}
+	types := make([]string, len(cfg.Types))
+	for i, t := range cfg.Types {
+		types[i] = strings.ToLower(strings.TrimSpace(t))
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only requests carrying a body are checked
+		switch c.Method() {
+		case utils.MethodPost, utils.MethodPut, utils.MethodPatch:
+		default:
+			return c.Next()
+		}
+		r := c.Origin()
+		if r.ContentLength == 0 && len(r.TransferEncoding) == 0 {
+			return c.Next()
+		}
+
+		header := c.Header(utils.HeaderContentType, "")
+		if header == "" {
+			return cfg.ErrorHandler(c, "")
+		}
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return cfg.ErrorHandler(c, header)
+		}
+		for _, t := range types {
+			if matchMediaType(mediaType, t) {
+				return c.Next()
+			}
+		}
+		return cfg.ErrorHandler(c, mediaType)
+	}
+}
+
+// matchMediaType matches a media type against a pattern allowing a *
+// type or subtype
+func matchMediaType(mediaType, pattern string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, pattern[:len(pattern)-1])
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestRequireContentType(t *testing.T) {
+	handler := RequireContentType("application/json", "text/*")
+	for _, tt := range []struct {
+		contentType string
+		want        int
+	}{
+		{"application/json", utils.StatusOK},
+		{"Application/JSON; charset=utf-8", utils.StatusOK},
+		{"text/plain", utils.StatusOK},
+		{"text/csv; header=present", utils.StatusOK},
+		{"application/xml", utils.StatusUnsupportedMediaType},
+		{"application/json-seq", utils.StatusUnsupportedMediaType},
+		{"textual/plain", utils.StatusUnsupportedMediaType},
+		{"application/json; charset", utils.StatusUnsupportedMediaType},
+		{"", utils.StatusUnsupportedMediaType},
+	} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("body"))
+		if tt.contentType != "" {
+			req.Header.Set(utils.HeaderContentType, tt.contentType)
+		}
+		c := run(t, req, handler, ok)
+		if c.Recorder.Code != tt.want {
+			t.Errorf("%q: status = %d, want %d", tt.contentType, c.Recorder.Code, tt.want)
+		}
+		if tt.want != utils.StatusOK && !errors.Is(c.Errors()[0], ErrUnsupportedMediaType) {
+			t.Errorf("%q: err = %v", tt.contentType, c.Errors()[0])
+		}
+	}
+}
+
+func TestRequireContentTypePassthrough(t *testing.T) {
+	handler := RequireContentType("application/json")
+
+	// Methods without a body and empty bodies aren't checked
+	for _, method := range []string{"GET", "HEAD", "DELETE", "OPTIONS"} {
+		req := httptest.NewRequest(method, "/", strings.NewReader("body"))
+		req.Header.Set(utils.HeaderContentType, "application/xml")
+		if c := run(t, req, handler, ok); c.Body() != "ok" {
+			t.Errorf("%s: status = %d", method, c.Recorder.Code)
+		}
+	}
+	req := httptest.NewRequest("POST", "/", nil)
+	if c := run(t, req, handler, ok); c.Body() != "ok" {
+		t.Errorf("empty POST: status = %d", c.Recorder.Code)
+	}
+
+	// A chunked body has no length but is checked
+	req = httptest.NewRequest("PUT", "/", strings.NewReader("body"))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	req.Header.Set(utils.HeaderContentType, "application/xml")
+	if c := run(t, req, handler, ok); c.Recorder.Code != utils.StatusUnsupportedMediaType {
+		t.Errorf("chunked PUT: status = %d", c.Recorder.Code)
+	}
+}
+
+func TestRequireContentTypeConfig(t *testing.T) {
+	var rejected []string
+	handler := RequireContentTypeConfig(ConfigRequireContentType{
+		Types: []string{"*/*"},
+		ErrorHandler: func(c http.Context, mediaType string) error {
+			rejected = append(rejected, mediaType)
+			return c.String("rejected")
+		},
+	})
+	req := httptest.NewRequest("PATCH", "/", strings.NewReader("body"))
+	req.Header.Set(utils.HeaderContentType, "image/png")
+	if c := run(t, req, handler, ok); c.Body() != "ok" {
+		t.Errorf("*/*: body = %q", c.Body())
+	}
+
+	req = httptest.NewRequest("PATCH", "/", strings.NewReader("body"))
+	if c := run(t, req, handler, ok); c.Body() != "rejected" || len(rejected) != 1 || rejected[0] != "" {
+		t.Errorf("missing header: body = %q, rejected %q", c.Body(), rejected)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RequireContentType didn't panic without types")
+		}
+	}()
+	RequireContentType()
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// ConfigCookieKey defines the config of CookieKeyGenerator
+type ConfigCookieKey struct {
+	// CookieName of the client id cookie
+	//
+	// Optional. Default: "client_id"
+	CookieName string
+
+	// CookieDomain of the client id cookie
+	//
+	// Optional. Default: ""
+	CookieDomain string
+
+	// CookiePath of the client id cookie
+	//
+	// Optional. Default: "/"
+	CookiePath string
+
+	// Expiration of the client id cookie
+	//
+	// Optional. Default: 365 * 24 * time.Hour
+	Expiration time.Duration
+
+	// ContextKey stores the client id for the handlers
+	//
+	// Optional. Default: "client_id"
+	ContextKey string
+}
+
+// ConfigCookieKeyDefault is the default config
+var ConfigCookieKeyDefault = ConfigCookieKey{
+	CookieName: "client_id",
+	CookiePath: "/",
+	Expiration: 365 * 24 * time.Hour,
+	ContextKey: "client_id",
+}
+
+// Helper function to set default values
+func configCookieKeyDefault(config ...ConfigCookieKey) ConfigCookieKey {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCookieKeyDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.CookieName == "" {
+		cfg.CookieName = ConfigCookieKeyDefault.CookieName
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = ConfigCookieKeyDefault.CookiePath
+	}
+	if cfg.Expiration <= 0 {
+		cfg.Expiration = ConfigCookieKeyDefault.Expiration
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigCookieKeyDefault.ContextKey
+	}
+	return cfg
+}
+
+// CookieKeyGenerator returns a limiter KeyGenerator counting requests per
+// anonymous client, identified by a signed cookie issued on first contact.
+// Cookies with a wrong signature are replaced by a new one. Clients can
+// always drop the cookie to start over, combine it with a limiter per IP.
+//
+//	limiter.New(limiter.Config{KeyGenerator: middleware.CookieKeyGenerator(secret)})
+func CookieKeyGenerator(secret []byte, config ...ConfigCookieKey) func(c http.Context) string {
+	// Set default config
+	cfg := configCookieKeyDefault(config...)
+
+	if len(secret) == 0 {
+		panic("cookie key: secret is required")
+	}
+	sign := func(id string) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(id))
+		return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	return func(c http.Context) string {
+		// Reuse the id of this request, e.g. one issued a moment ago
+		if id, ok := c.Value(cfg.ContextKey).(string); ok && id != "" {
+			return "client:" + id
+		}
+
+		id, signature, _ := strings.Cut(c.Cookies(cfg.CookieName), ".")
+		if id == "" || !hmac.Equal([]byte(signature), []byte(sign(id))) {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err != nil {
+				panic(err)
+			}
+			id = base64.RawURLEncoding.EncodeToString(buf)
+			c.Cookie(&http.Cookie{
+				Name:     cfg.CookieName,
+				Value:    id + "." + sign(id),
+				Domain:   cfg.CookieDomain,
+				Path:     cfg.CookiePath,
+				Expires:  time.Now().Add(cfg.Expiration),
+				Secure:   isHTTPS(c),
+				HTTPOnly: true,
+				SameSite: "Lax",
+			})
+		}
+		c.WithValue(cfg.ContextKey, id)
+		return "client:" + id
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/limiter"
+)
+
+func TestCookieKeyGenerator(t *testing.T) {
+	handler := limiter.New(limiter.Config{Max: 1, KeyGenerator: CookieKeyGenerator([]byte("secret"))})
+	clientID := func(c http.Context) error {
+		id, _ := c.Value("client_id").(string)
+		return c.String(id)
+	}
+	send := func(cookie string) (*stdHttp.Response, string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if cookie != "" {
+			req.Header.Set(utils.HeaderCookie, cookie)
+		}
+		c := run(t, req, handler, clientID)
+		return c.Recorder.Result(), c.Body()
+	}
+	issued := func(res *stdHttp.Response) string {
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == "client_id" {
+				return cookie.Name + "=" + cookie.Value
+			}
+		}
+		return ""
+	}
+
+	res, id := send("")
+	cookie := issued(res)
+	if res.StatusCode != utils.StatusOK || cookie == "" || !strings.HasPrefix(cookie, "client_id="+id+".") {
+		t.Fatalf("first contact: status = %d, id = %q, Set-Cookie = %q", res.StatusCode, id, res.Header.Values(utils.HeaderSetCookie))
+	}
+	if setCookie := res.Header.Get(utils.HeaderSetCookie); !strings.Contains(setCookie, "HttpOnly") || !strings.Contains(setCookie, "SameSite=Lax") {
+		t.Errorf("Set-Cookie = %q", setCookie)
+	}
+
+	// The same client shares the bucket, the cookie isn't issued again
+	if res, _ := send(cookie); res.StatusCode != utils.StatusTooManyRequests || issued(res) != "" {
+		t.Errorf("same client: status = %d, Set-Cookie = %q", res.StatusCode, res.Header.Values(utils.HeaderSetCookie))
+	}
+
+	// Tampered cookies are replaced by a new client id
+	_, signature, _ := strings.Cut(cookie, ".")
+	for _, tampered := range []string{
+		"client_id=forged." + signature,
+		cookie + "x",
+		"client_id=" + id,
+		"client_id=." + signature,
+	} {
+		res, newID := send(tampered)
+		if res.StatusCode != utils.StatusOK || newID == id || issued(res) == "" || !strings.HasPrefix(issued(res), "client_id="+newID+".") {
+			t.Errorf("%q: status = %d, id = %q, Set-Cookie = %q", tampered, res.StatusCode, newID, res.Header.Values(utils.HeaderSetCookie))
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("CookieKeyGenerator didn't panic without a secret")
+		}
+	}()
+	CookieKeyGenerator(nil)
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigCookiePolicy defines the config for middleware.
+type ConfigCookiePolicy struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// AllowScriptAccess are the cookies JavaScript may read, they don't get
+	// HttpOnly
+	//
+	// Optional. Default: nil
+	AllowScriptAccess []string
+
+	// SameSite is added to cookies without the attribute: "Lax", "Strict"
+	// or "None"
+	//
+	// Optional. Default: "Lax"
+	SameSite string
+
+	// ValidatePrefixes enforces the rules of the __Secure- and __Host-
+	// cookie name prefixes. Secure is added to such cookies, __Host-
+	// cookies with a Domain or a Path other than / are removed, browsers
+	// would reject them anyway.
+	//
+	// Optional. Default: false
+	ValidatePrefixes bool
+
+	// OnInvalid is called with the Set-Cookie values that were removed
+	//
+	// Optional. Default: nil
+	OnInvalid func(c http.Context, setCookie string)
+}
+
+// ConfigCookiePolicyDefault is the default config
+var ConfigCookiePolicyDefault = ConfigCookiePolicy{
+	Next:     nil,
+	SameSite: "Lax",
+}
+
+// Helper function to set default values
+func configCookiePolicyDefault(config ...ConfigCookiePolicy) ConfigCookiePolicy {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCookiePolicyDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.SameSite == "" {
+		cfg.SameSite = ConfigCookiePolicyDefault.SameSite
+	}
+	return cfg
+}
+
+// CookiePolicy creates a new middleware handler adding the security
+// attributes missing from the cookies set by the handlers: Secure on HTTPS
+// requests, HttpOnly and SameSite. Attributes that are present are left
+// as they are, including a weaker SameSite.
+func CookiePolicy(config ConfigCookiePolicy) http.HandlerFunc {
+	// Set default config
+	cfg := configCookiePolicyDefault(config)
+
+	switch strings.ToLower(cfg.SameSite) {
+	case "lax", "strict", "none":
+	default:
+		panic("cookie policy: invalid SameSite " + cfg.SameSite)
+	}
+	scriptAccess := make(map[string]struct{}, len(cfg.AllowScriptAccess))
+	for _, name := range cfg.AllowScriptAccess {
+		scriptAccess[name] = struct{}{}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		https := isHTTPS(c)
+		rec, ok := onHeaders(c, func(status int, header stdHttp.Header) {
+			cookies := header.Values(utils.HeaderSetCookie)
+			if len(cookies) == 0 {
+				return
+			}
+			kept := make([]string, 0, len(cookies))
+			for _, cookie := range cookies {
+				if cookie, ok := enforceCookiePolicy(cookie, cfg, scriptAccess, https); ok {
+					kept = append(kept, cookie)
+				} else if cfg.OnInvalid != nil {
+					cfg.OnInvalid(c, cookie)
+				}
+			}
+			header.Del(utils.HeaderSetCookie)
+			for _, cookie := range kept {
+				header.Add(utils.HeaderSetCookie, cookie)
+			}
+		})
+		if !ok {
+			return c.Next()
+		}
+		return rec.next(c)
+	}
+}
+
+// enforceCookiePolicy appends the missing attributes to a Set-Cookie value
+// and returns false for cookies breaking the rules of their prefix
+func enforceCookiePolicy(cookie string, cfg ConfigCookiePolicy, scriptAccess map[string]struct{}, https bool) (string, bool) {
+	parts := strings.Split(cookie, ";")
+	name, _, _ := strings.Cut(parts[0], "=")
+	name = strings.TrimSpace(name)
+
+	var secure, httpOnly, sameSite, domain bool
+	path := ""
+	for _, attr := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(attr), "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "secure":
+			secure = true
+		case "httponly":
+			httpOnly = true
+		case "samesite":
+			sameSite = true
+		case "domain":
+			domain = true
+		case "path":
+			path = strings.TrimSpace(value)
+		}
+	}
+
+	prefixed := false
+	if cfg.ValidatePrefixes {
+		switch {
+		case strings.HasPrefix(name, "__Host-"):
+			if domain || path != "/" {
+				return cookie, false
+			}
+			prefixed = true
+		case strings.HasPrefix(name, "__Secure-"):
+			prefixed = true
+		}
+	}
+
+	if !secure && (https || prefixed) {
+		cookie += "; Secure"
+	}
+	if _, ok := scriptAccess[name]; !ok && !httpOnly {
+		cookie += "; HttpOnly"
+	}
+	if !sameSite {
+		cookie += "; SameSite=" + cfg.SameSite
+	}
+	return cookie, true
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// respondWithCookies responds with a Set-Cookie header per cookie
+func respondWithCookies(cookies ...string) http.HandlerFunc {
+	return func(c http.Context) error {
+		header := c.(*middlewaretest.MockContext).Res.Header()
+		for _, cookie := range cookies {
+			header.Add(utils.HeaderSetCookie, cookie)
+		}
+		return c.String("ok")
+	}
+}
+
+func TestCookiePolicy(t *testing.T) {
+	handler := CookiePolicy(ConfigCookiePolicy{AllowScriptAccess: []string{"csrf_token"}})
+	final := respondWithCookies(
+		"session=abc; Path=/; Domain=example.com; Expires=Wed, 21 Oct 2037 07:28:00 GMT; Max-Age=3600",
+		"csrf_token=xyz; Path=/",
+		"prefs=dark; Secure; HttpOnly; SameSite=None",
+		"theme=light; httponly; samesite=strict",
+	)
+	for _, tt := range []struct {
+		name  string
+		https bool
+		want  []string
+	}{
+		{"http", false, []string{
+			// The attributes are kept as they are
+			"session=abc; Path=/; Domain=example.com; Expires=Wed, 21 Oct 2037 07:28:00 GMT; Max-Age=3600; HttpOnly; SameSite=Lax",
+			"csrf_token=xyz; Path=/; SameSite=Lax",
+			"prefs=dark; Secure; HttpOnly; SameSite=None",
+			"theme=light; httponly; samesite=strict",
+		}},
+		{"https", true, []string{
+			"session=abc; Path=/; Domain=example.com; Expires=Wed, 21 Oct 2037 07:28:00 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=Lax",
+			"csrf_token=xyz; Path=/; Secure; SameSite=Lax",
+			"prefs=dark; Secure; HttpOnly; SameSite=None",
+			"theme=light; httponly; samesite=strict; Secure",
+		}},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.https {
+			req.Header.Set(utils.HeaderXForwardedProto, "https")
+		}
+		c := run(t, req, handler, final)
+		got := c.Recorder.Result().Header.Values(utils.HeaderSetCookie)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: Set-Cookie = %q", tt.name, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: Set-Cookie = %q, want %q", tt.name, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestCookiePolicyPrefixes(t *testing.T) {
+	var removed []string
+	handler := CookiePolicy(ConfigCookiePolicy{
+		SameSite:         "Strict",
+		ValidatePrefixes: true,
+		OnInvalid: func(c http.Context, setCookie string) {
+			removed = append(removed, setCookie)
+		},
+	})
+	final := respondWithCookies(
+		"__Host-id=1; Path=/",
+		"__Host-domain=2; Path=/; Domain=example.com",
+		"__Host-path=3; Path=/app",
+		"__Host-nopath=4",
+		"__Secure-token=5; Path=/app; Domain=example.com",
+		"plain=6",
+	)
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, final)
+	want := []string{
+		// Prefixed cookies are Secure even over plain HTTP
+		"__Host-id=1; Path=/; Secure; HttpOnly; SameSite=Strict",
+		"__Secure-token=5; Path=/app; Domain=example.com; Secure; HttpOnly; SameSite=Strict",
+		"plain=6; HttpOnly; SameSite=Strict",
+	}
+	got := c.Recorder.Result().Header.Values(utils.HeaderSetCookie)
+	if len(got) != len(want) {
+		t.Fatalf("Set-Cookie = %q", got)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Set-Cookie = %q, want %q", got[i], want[i])
+		}
+	}
+	if len(removed) != 3 || removed[0] != "__Host-domain=2; Path=/; Domain=example.com" {
+		t.Errorf("removed = %q", removed)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("CookiePolicy didn't panic for an invalid SameSite")
+		}
+	}()
+	CookiePolicy(ConfigCookiePolicy{SameSite: "Loose"})
+}
@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"fmt"
 	"github.com/sujit-baniya/framework/utils"
 	stdHttp "net/http"
 	"strconv"
 	"strings"
 
 	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/internal/defaults"
 )
 
 // ConfigCors defines the config for middleware.
@@ -48,10 +50,48 @@ type ConfigCors struct {
 	ExposeHeaders string
 
 	// MaxAge indicates how long (in seconds) the results of a preflight request
-	// can be cached.
+	// can be cached. Set it to -1 to send a max-age of 0, which stops
+	// browsers from caching preflights, 0 leaves the header out.
 	//
 	// Optional. Default value 0.
 	MaxAge int
+
+	// EnforceMethods rejects cross-origin requests whose method is not in
+	// AllowMethods with 403 Forbidden, instead of only announcing the
+	// allowed methods in preflight responses. Requests whose Origin is the
+	// scheme and host of the request itself aren't cross-origin.
+	//
+	// Optional. Default value false.
+	EnforceMethods bool
+
+	// MaxOrigins caps the number of entries in AllowOrigins so a huge list
+	// can't slow down every request. Exceeding it panics at construction.
+	//
+	// Optional. Default value 100.
+	MaxOrigins int
+
+	// ReflectAllOrigins echoes any Origin in Access-Control-Allow-Origin
+	// instead of sending "*", which some caches handle better. It is
+	// ignored with AllowCredentials, credentialed requests need the
+	// explicit AllowOrigins list.
+	//
+	// Optional. Default value false.
+	ReflectAllOrigins bool
+
+	// SkipWithoutOrigin passes requests without an Origin header, sent by
+	// non-browser clients and same-origin requests, on without any CORS
+	// headers. OPTIONS requests without Origin reach the handlers as they
+	// are no preflights. Only Vary: Origin is added, so caches don't serve
+	// these responses to cross-origin requests.
+	//
+	// Optional. Default value false.
+	SkipWithoutOrigin bool
+
+	// ContextKey stores the resolved Access-Control-Allow-Origin for the
+	// handlers, empty when the origin is not allowed.
+	//
+	// Optional. Default value "cors_origin".
+	ContextKey string
 }
 
 // ConfigCorsDefault is the default config
@@ -70,36 +110,53 @@ var ConfigCorsDefault = ConfigCors{
 	AllowCredentials: false,
 	ExposeHeaders:    "",
 	MaxAge:           0,
+	MaxOrigins:       100,
+	ContextKey:       "cors_origin",
 }
 
+// Helper function to set default values
+func configCorsDefault(config ...ConfigCors) ConfigCors {
+	return defaults.Config(config, ConfigCorsDefault, func(cfg *ConfigCors) {
+		defaults.Value(&cfg.AllowMethods, ConfigCorsDefault.AllowMethods)
+		defaults.Value(&cfg.AllowOrigins, ConfigCorsDefault.AllowOrigins)
+		defaults.Positive(&cfg.MaxOrigins, ConfigCorsDefault.MaxOrigins)
+		defaults.Value(&cfg.ContextKey, ConfigCorsDefault.ContextKey)
+	})
+}
+
+// maxOriginLength is the longest origin accepted, a scheme and port on top
+// of the 253 characters of a domain name
+const maxOriginLength = 253 + len("https://") + len(":65535")
+
 // Cors creates a new middleware handler
 func Cors(config ...ConfigCors) http.HandlerFunc {
 	// Set default config
-	cfg := ConfigCorsDefault
+	cfg := configCorsDefault(config...)
 
-	// Override config if provided
-	if len(config) > 0 {
-		cfg = config[0]
+	// Convert string to slice
+	allowOrigins := strings.Split(strings.ReplaceAll(cfg.AllowOrigins, " ", ""), ",")
 
-		// Set default values
-		if cfg.AllowMethods == "" {
-			cfg.AllowMethods = ConfigCorsDefault.AllowMethods
-		}
-		if cfg.AllowOrigins == "" {
-			cfg.AllowOrigins = ConfigCorsDefault.AllowOrigins
+	// Refuse configurations that would make matching expensive
+	if len(allowOrigins) > cfg.MaxOrigins {
+		panic(fmt.Sprintf("cors: %d origins exceed MaxOrigins of %d", len(allowOrigins), cfg.MaxOrigins))
+	}
+	for _, o := range allowOrigins {
+		if len(o) > maxOriginLength {
+			panic(fmt.Sprintf("cors: origin %.32q... is longer than %d characters", o, maxOriginLength))
 		}
 	}
 
-	// Convert string to slice
-	allowOrigins := strings.Split(strings.ReplaceAll(cfg.AllowOrigins, " ", ""), ",")
-
 	// Strip white spaces
 	allowMethods := strings.ReplaceAll(cfg.AllowMethods, " ", "")
-	allowHeaders := strings.ReplaceAll(cfg.AllowHeaders, " ", "")
+	allowMethodList := strings.Split(strings.ToUpper(allowMethods), ",")
+	allowHeaders := normalizeHeaderList(cfg.AllowHeaders)
 	exposeHeaders := strings.ReplaceAll(cfg.ExposeHeaders, " ", "")
 
 	// Convert int to string
 	maxAge := strconv.Itoa(cfg.MaxAge)
+	if cfg.MaxAge < 0 {
+		maxAge = "0"
+	}
 
 	// Return new handler
 	return func(c http.Context) error {
@@ -110,27 +167,39 @@ func Cors(config ...ConfigCors) http.HandlerFunc {
 
 		// Get origin header
 		origin := c.Header(utils.HeaderOrigin, "")
+		if origin == "" && cfg.SkipWithoutOrigin {
+			vary(c, utils.HeaderOrigin)
+			return c.Next()
+		}
 		allowOrigin := ""
 
 		// Check allowed origins
-		for _, o := range allowOrigins {
-			if o == "*" && cfg.AllowCredentials {
-				allowOrigin = origin
-				break
-			}
-			if o == "*" || o == origin {
-				allowOrigin = o
-				break
-			}
-			if matchSubdomain(origin, o) {
-				allowOrigin = origin
-				break
+		if cfg.ReflectAllOrigins && !cfg.AllowCredentials {
+			allowOrigin = origin
+		} else {
+			for _, o := range allowOrigins {
+				if o == "*" && cfg.AllowCredentials {
+					allowOrigin = origin
+					break
+				}
+				if o == "*" || o == origin {
+					allowOrigin = o
+					break
+				}
+				if matchSubdomain(origin, o) {
+					allowOrigin = origin
+					break
+				}
 			}
 		}
 
 		// Simple request
 		if c.Method() != stdHttp.MethodOptions {
-			c.Vary(utils.HeaderOrigin)
+			if cfg.EnforceMethods && origin != "" && !containsMethod(allowMethodList, c.Method()) && !sameOrigin(c, origin) {
+				c.AbortWithStatus(utils.StatusForbidden)
+				return fmt.Errorf("%w: %s", ErrCORSMethodDenied, c.Method())
+			}
+			vary(c, utils.HeaderOrigin)
 			c.SetHeader(utils.HeaderAccessControlAllowOrigin, allowOrigin)
 
 			if cfg.AllowCredentials {
@@ -139,13 +208,12 @@ func Cors(config ...ConfigCors) http.HandlerFunc {
 			if exposeHeaders != "" {
 				c.SetHeader(utils.HeaderAccessControlExposeHeaders, exposeHeaders)
 			}
+			c.WithValue(cfg.ContextKey, allowOrigin)
 			return c.Next()
 		}
 
 		// Preflight request
-		c.Vary(utils.HeaderOrigin)
-		c.Vary(utils.HeaderAccessControlRequestMethod)
-		c.Vary(utils.HeaderAccessControlRequestHeaders)
+		vary(c, utils.HeaderOrigin, utils.HeaderAccessControlRequestMethod, utils.HeaderAccessControlRequestHeaders)
 		c.SetHeader(utils.HeaderAccessControlAllowOrigin, allowOrigin)
 		c.SetHeader(utils.HeaderAccessControlAllowMethods, allowMethods)
 
@@ -158,18 +226,37 @@ func Cors(config ...ConfigCors) http.HandlerFunc {
 		if allowHeaders != "" {
 			c.SetHeader(utils.HeaderAccessControlAllowHeaders, allowHeaders)
 		} else {
-			h := c.Header(utils.HeaderAccessControlRequestHeaders, "")
+			h := normalizeHeaderList(c.Header(utils.HeaderAccessControlRequestHeaders, ""))
 			if h != "" {
 				c.SetHeader(utils.HeaderAccessControlAllowHeaders, h)
 			}
 		}
-		if cfg.MaxAge > 0 {
+		if cfg.MaxAge != 0 {
 			c.SetHeader(utils.HeaderAccessControlMaxAge, maxAge)
 		}
 		return c.String("")
 	}
 }
 
+// normalizeHeaderList lowercases a comma separated list of header names and
+// drops duplicates, browsers send Access-Control-Request-Headers lowercased
+func normalizeHeaderList(list string) string {
+	seen := make(map[string]struct{})
+	names := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
 func matchScheme(domain, pattern string) bool {
 	didx := strings.Index(domain, ":")
 	pidx := strings.Index(pattern, ":")
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// CorsOption sets a field of ConfigCors, see CorsWith
+type CorsOption func(cfg *ConfigCors)
+
+// CorsWith creates a Cors middleware from options applied to
+// ConfigCorsDefault in order, a later option overrides an earlier one
+//
+//	middleware.CorsWith(
+//		middleware.CorsAllowOrigins("https://example.com"),
+//		middleware.CorsCredentials(),
+//	)
+func CorsWith(opts ...CorsOption) http.HandlerFunc {
+	cfg := ConfigCorsDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Cors(cfg)
+}
+
+// CorsOptions bundles options into one, e.g. to share a preset between
+// services
+func CorsOptions(opts ...CorsOption) CorsOption {
+	return func(cfg *ConfigCors) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// CorsNext sets ConfigCors.Next
+func CorsNext(next func(c http.Context) bool) CorsOption {
+	return func(cfg *ConfigCors) { cfg.Next = next }
+}
+
+// CorsAllowOrigins sets ConfigCors.AllowOrigins
+func CorsAllowOrigins(origins ...string) CorsOption {
+	return func(cfg *ConfigCors) { cfg.AllowOrigins = strings.Join(origins, ",") }
+}
+
+// CorsAllowMethods sets ConfigCors.AllowMethods
+func CorsAllowMethods(methods ...string) CorsOption {
+	return func(cfg *ConfigCors) { cfg.AllowMethods = strings.Join(methods, ",") }
+}
+
+// CorsAllowHeaders sets ConfigCors.AllowHeaders
+func CorsAllowHeaders(headers ...string) CorsOption {
+	return func(cfg *ConfigCors) { cfg.AllowHeaders = strings.Join(headers, ",") }
+}
+
+// CorsExposeHeaders sets ConfigCors.ExposeHeaders
+func CorsExposeHeaders(headers ...string) CorsOption {
+	return func(cfg *ConfigCors) { cfg.ExposeHeaders = strings.Join(headers, ",") }
+}
+
+// CorsCredentials sets ConfigCors.AllowCredentials
+func CorsCredentials() CorsOption {
+	return func(cfg *ConfigCors) { cfg.AllowCredentials = true }
+}
+
+// CorsMaxAge sets ConfigCors.MaxAge in whole seconds. Unlike the field, 0
+// sends a max-age of 0 to stop browsers from caching preflights.
+func CorsMaxAge(d time.Duration) CorsOption {
+	return func(cfg *ConfigCors) {
+		cfg.MaxAge = int(d / time.Second)
+		if cfg.MaxAge <= 0 {
+			cfg.MaxAge = -1
+		}
+	}
+}
+
+// CorsEnforceMethods sets ConfigCors.EnforceMethods
+func CorsEnforceMethods() CorsOption {
+	return func(cfg *ConfigCors) { cfg.EnforceMethods = true }
+}
+
+// CorsMaxOrigins sets ConfigCors.MaxOrigins
+func CorsMaxOrigins(n int) CorsOption {
+	return func(cfg *ConfigCors) { cfg.MaxOrigins = n }
+}
+
+// CorsReflectAllOrigins sets ConfigCors.ReflectAllOrigins
+func CorsReflectAllOrigins() CorsOption {
+	return func(cfg *ConfigCors) { cfg.ReflectAllOrigins = true }
+}
+
+// CorsSkipWithoutOrigin sets ConfigCors.SkipWithoutOrigin
+func CorsSkipWithoutOrigin() CorsOption {
+	return func(cfg *ConfigCors) { cfg.SkipWithoutOrigin = true }
+}
+
+// CorsContextKey sets ConfigCors.ContextKey
+func CorsContextKey(key string) CorsOption {
+	return func(cfg *ConfigCors) { cfg.ContextKey = key }
+}
@@ -0,0 +1,289 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestCorsSimpleRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://example.com")
+	c := run(t, req, Cors(ConfigCors{AllowOrigins: "https://example.com", AllowCredentials: true, ExposeHeaders: "X-Total"}), ok)
+
+	h := c.Recorder.Header()
+	if got := h.Get(utils.HeaderAccessControlAllowOrigin); got != "https://example.com" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := h.Get(utils.HeaderAccessControlAllowCredentials); got != "true" {
+		t.Errorf("Allow-Credentials = %q", got)
+	}
+	if got := h.Get(utils.HeaderAccessControlExposeHeaders); got != "X-Total" {
+		t.Errorf("Expose-Headers = %q", got)
+	}
+	if got := h.Get(utils.HeaderVary); got != utils.HeaderOrigin {
+		t.Errorf("Vary = %q", got)
+	}
+	if c.Body() != "ok" {
+		t.Errorf("body = %q", c.Body())
+	}
+	if !c.CalledInOrder("SetHeader", "Next", "String") {
+		t.Errorf("calls = %v", c.Calls())
+	}
+}
+
+func TestCorsDisallowedOrigin(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://evil.example")
+	c := run(t, req, Cors(ConfigCors{AllowOrigins: "https://example.com"}), ok)
+	if got := c.Recorder.Header().Get(utils.HeaderAccessControlAllowOrigin); got != "" {
+		t.Errorf("Allow-Origin = %q, want empty", got)
+	}
+}
+
+func TestCorsWildcardSubdomain(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://api.example.com")
+	c := run(t, req, Cors(ConfigCors{AllowOrigins: "https://*.example.com"}), ok)
+	if got := c.Recorder.Header().Get(utils.HeaderAccessControlAllowOrigin); got != "https://api.example.com" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://example.com")
+	req.Header.Set(utils.HeaderAccessControlRequestMethod, "PUT")
+	req.Header.Set(utils.HeaderAccessControlRequestHeaders, "x-custom")
+	reached := false
+	c := run(t, req, Cors(ConfigCors{MaxAge: 600}), func(c http.Context) error {
+		reached = true
+		return nil
+	})
+
+	h := c.Recorder.Header()
+	if reached {
+		t.Error("preflight reached the handler")
+	}
+	if got := h.Get(utils.HeaderAccessControlAllowOrigin); got != "*" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := h.Get(utils.HeaderAccessControlAllowMethods); got != ConfigCorsDefault.AllowMethods {
+		t.Errorf("Allow-Methods = %q", got)
+	}
+	if got := h.Get(utils.HeaderAccessControlAllowHeaders); got != "x-custom" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+	if got := h.Get(utils.HeaderAccessControlMaxAge); got != "600" {
+		t.Errorf("Max-Age = %q", got)
+	}
+	if got := h.Values(utils.HeaderVary); len(got) != 3 {
+		t.Errorf("Vary = %v", got)
+	}
+}
+
+func TestCorsEnforceMethods(t *testing.T) {
+	req := httptest.NewRequest("DELETE", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://example.com")
+	c := run(t, req, Cors(ConfigCors{AllowMethods: "GET,POST", EnforceMethods: true}), ok)
+	if c.Recorder.Code != utils.StatusForbidden {
+		t.Errorf("status = %d", c.Recorder.Code)
+	}
+	if err := c.Errors()[0]; !errors.Is(err, ErrCORSMethodDenied) {
+		t.Errorf("err = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name, method, origin string
+		cfg                  ConfigCors
+		want                 int
+	}{
+		{"allowed method", "DELETE", "https://example.com", ConfigCors{AllowMethods: "GET, delete", EnforceMethods: true}, utils.StatusOK},
+		{"without origin", "DELETE", "", ConfigCors{AllowMethods: "GET,POST", EnforceMethods: true}, utils.StatusOK},
+		// Browsers send Origin with same-origin DELETE requests too
+		{"same origin", "DELETE", "http://example.com", ConfigCors{AllowMethods: "GET,POST", EnforceMethods: true}, utils.StatusOK},
+		{"other port", "DELETE", "http://example.com:8080", ConfigCors{AllowMethods: "GET,POST", EnforceMethods: true}, utils.StatusForbidden},
+		{"other scheme", "DELETE", "https://example.com", ConfigCors{AllowMethods: "GET,POST", EnforceMethods: true}, utils.StatusForbidden},
+		{"not enforced", "DELETE", "https://example.com", ConfigCors{AllowMethods: "GET,POST"}, utils.StatusOK},
+		{"preflight", "OPTIONS", "https://example.com", ConfigCors{AllowMethods: "GET,POST", EnforceMethods: true}, utils.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.origin != "" {
+			req.Header.Set(utils.HeaderOrigin, tt.origin)
+		}
+		if tt.method == "OPTIONS" {
+			req.Header.Set(utils.HeaderAccessControlRequestMethod, "DELETE")
+		}
+		c := run(t, req, Cors(tt.cfg), ok)
+		if c.Recorder.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.want)
+		}
+	}
+}
+
+func TestCorsMaxOrigins(t *testing.T) {
+	origins := make([]string, 4)
+	for i := range origins {
+		origins[i] = "https://" + strings.Repeat("a", i+1) + ".example.com"
+	}
+	list := strings.Join(origins, ",")
+
+	// At the cap is fine
+	Cors(ConfigCors{AllowOrigins: list, MaxOrigins: 4})
+	for name, cfg := range map[string]ConfigCors{
+		"too many origins": {AllowOrigins: list, MaxOrigins: 3},
+		"too long origin":  {AllowOrigins: "https://" + strings.Repeat("a", 300) + ".com"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Cors didn't panic", name)
+				}
+			}()
+			Cors(cfg)
+		}()
+	}
+}
+
+func TestCorsLongOriginRejected(t *testing.T) {
+	// A pathological origin fails the 253 character guard of the wildcard
+	// match instead of being split and compared
+	origin := "https://" + strings.Repeat("a.", 200) + "example.com"
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, origin)
+	c := run(t, req, Cors(ConfigCors{AllowOrigins: "https://*.example.com"}), ok)
+	if got := c.Recorder.Header().Get(utils.HeaderAccessControlAllowOrigin); got != "" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if matchSubdomain(origin, "https://*.example.com") {
+		t.Error("matchSubdomain accepted a domain over 253 characters")
+	}
+}
+
+func TestCorsAllowHeadersNormalized(t *testing.T) {
+	for _, tt := range []struct {
+		allowHeaders, requested, want string
+	}{
+		{"Content-Type, X-Custom,content-type , X-CUSTOM,Authorization", "x-custom", "content-type,x-custom,authorization"},
+		{"", "X-Custom, x-custom,Content-Type", "x-custom,content-type"},
+	} {
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set(utils.HeaderOrigin, "https://example.com")
+		req.Header.Set(utils.HeaderAccessControlRequestMethod, "POST")
+		req.Header.Set(utils.HeaderAccessControlRequestHeaders, tt.requested)
+		c := run(t, req, Cors(ConfigCors{AllowHeaders: tt.allowHeaders}), ok)
+		if got := c.Recorder.Header().Get(utils.HeaderAccessControlAllowHeaders); got != tt.want {
+			t.Errorf("%q: Allow-Headers = %q, want %q", tt.allowHeaders, got, tt.want)
+		}
+	}
+}
+
+func TestCorsMaxAge(t *testing.T) {
+	for _, tt := range []struct {
+		maxAge int
+		want   []string
+	}{
+		{600, []string{"600"}},
+		{0, nil},
+		{-1, []string{"0"}},
+	} {
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set(utils.HeaderOrigin, "https://example.com")
+		req.Header.Set(utils.HeaderAccessControlRequestMethod, "PUT")
+		c := run(t, req, Cors(ConfigCors{MaxAge: tt.maxAge}), ok)
+		if got := c.Recorder.Header().Values(utils.HeaderAccessControlMaxAge); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("MaxAge %d: Max-Age = %q, want %q", tt.maxAge, got, tt.want)
+		}
+	}
+}
+
+func TestCorsContextKey(t *testing.T) {
+	originHandler := func(key string) http.HandlerFunc {
+		return func(c http.Context) error {
+			origin, _ := c.Value(key).(string)
+			return c.String(origin)
+		}
+	}
+	for _, tt := range []struct {
+		name, origin string
+		cfg          ConfigCors
+		key, want    string
+	}{
+		{"allowed", "https://example.com", ConfigCors{AllowOrigins: "https://example.com"}, "cors_origin", "https://example.com"},
+		{"subdomain", "https://api.example.com", ConfigCors{AllowOrigins: "https://*.example.com"}, "cors_origin", "https://api.example.com"},
+		{"wildcard", "https://other.example", ConfigCors{AllowOrigins: "*"}, "cors_origin", "*"},
+		{"rejected", "https://evil.example", ConfigCors{AllowOrigins: "https://example.com"}, "cors_origin", ""},
+		{"custom key", "https://example.com", ConfigCors{AllowOrigins: "https://example.com", ContextKey: "origin"}, "origin", "https://example.com"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderOrigin, tt.origin)
+		if c := run(t, req, Cors(tt.cfg), originHandler(tt.key)); c.Body() != tt.want {
+			t.Errorf("%s: origin in context = %q, want %q", tt.name, c.Body(), tt.want)
+		}
+	}
+}
+
+func TestCorsReflectAllOrigins(t *testing.T) {
+	for _, tt := range []struct {
+		name, method, origin string
+		cfg                  ConfigCors
+		want                 string
+	}{
+		{"reflected", "GET", "https://any.example", ConfigCors{ReflectAllOrigins: true}, "https://any.example"},
+		{"preflight", "OPTIONS", "https://any.example", ConfigCors{ReflectAllOrigins: true}, "https://any.example"},
+		{"beyond the list", "GET", "https://any.example", ConfigCors{ReflectAllOrigins: true, AllowOrigins: "https://example.com"}, "https://any.example"},
+		{"without the option", "GET", "https://any.example", ConfigCors{}, "*"},
+		// Credentials need the explicit list
+		{"credentials listed", "GET", "https://example.com", ConfigCors{ReflectAllOrigins: true, AllowCredentials: true, AllowOrigins: "https://example.com"}, "https://example.com"},
+		{"credentials not listed", "GET", "https://any.example", ConfigCors{ReflectAllOrigins: true, AllowCredentials: true, AllowOrigins: "https://example.com"}, ""},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		req.Header.Set(utils.HeaderOrigin, tt.origin)
+		if tt.method == "OPTIONS" {
+			req.Header.Set(utils.HeaderAccessControlRequestMethod, "GET")
+		}
+		h := run(t, req, Cors(tt.cfg), ok).Recorder.Header()
+		if got := h.Get(utils.HeaderAccessControlAllowOrigin); got != tt.want {
+			t.Errorf("%s: Allow-Origin = %q, want %q", tt.name, got, tt.want)
+		}
+		if !strings.Contains(h.Get(utils.HeaderVary), utils.HeaderOrigin) {
+			t.Errorf("%s: Vary = %q", tt.name, h.Get(utils.HeaderVary))
+		}
+	}
+}
+
+func TestCorsSkipWithoutOrigin(t *testing.T) {
+	handler := Cors(ConfigCors{AllowOrigins: "https://example.com", AllowCredentials: true, ExposeHeaders: "X-Total", SkipWithoutOrigin: true})
+	for _, method := range []string{"GET", "OPTIONS"} {
+		c := run(t, httptest.NewRequest(method, "/", nil), handler, ok)
+		// OPTIONS without Origin isn't a preflight, the handler answers it
+		if c.Body() != "ok" {
+			t.Errorf("%s without Origin: body = %q", method, c.Body())
+		}
+		for name := range c.Recorder.Header() {
+			if strings.HasPrefix(name, "Access-Control-") {
+				t.Errorf("%s without Origin: %s = %q", method, name, c.Recorder.Header().Get(name))
+			}
+		}
+		if got := c.Recorder.Header().Get(utils.HeaderVary); got != utils.HeaderOrigin {
+			t.Errorf("%s without Origin: Vary = %q", method, got)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://example.com")
+	h := run(t, req, handler, ok).Recorder.Header()
+	if h.Get(utils.HeaderAccessControlAllowOrigin) != "https://example.com" || h.Get(utils.HeaderAccessControlExposeHeaders) != "X-Total" {
+		t.Errorf("with Origin: headers = %v", h)
+	}
+
+	// Without the option the empty headers are still sent
+	c := run(t, httptest.NewRequest("GET", "/", nil), Cors(ConfigCors{AllowOrigins: "https://example.com", AllowCredentials: true}), ok)
+	if _, ok := c.Recorder.Header()[utils.HeaderAccessControlAllowOrigin]; !ok {
+		t.Errorf("default: headers = %v", c.Recorder.Header())
+	}
+}
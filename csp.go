@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+)
+
+// CSPBuilder builds a Content-Security-Policy for ConfigSecure, e.g.
+//
+//	new(CSPBuilder).Default("self").Script("self", "https://cdn.example.com").String()
+//
+// Sources of repeated directives are merged and keywords like self, none
+// or nonce-... are quoted. The zero value is ready to use.
+type CSPBuilder struct {
+	directives map[string][]string
+}
+
+// NewCSPBuilder returns an empty builder
+func NewCSPBuilder() *CSPBuilder {
+	return &CSPBuilder{}
+}
+
+// Default adds sources to default-src
+func (b *CSPBuilder) Default(sources ...string) *CSPBuilder {
+	return b.Directive("default-src", sources...)
+}
+
+// Script adds sources to script-src
+func (b *CSPBuilder) Script(sources ...string) *CSPBuilder {
+	return b.Directive("script-src", sources...)
+}
+
+// Style adds sources to style-src
+func (b *CSPBuilder) Style(sources ...string) *CSPBuilder {
+	return b.Directive("style-src", sources...)
+}
+
+// Img adds sources to img-src
+func (b *CSPBuilder) Img(sources ...string) *CSPBuilder {
+	return b.Directive("img-src", sources...)
+}
+
+// Connect adds sources to connect-src
+func (b *CSPBuilder) Connect(sources ...string) *CSPBuilder {
+	return b.Directive("connect-src", sources...)
+}
+
+// Font adds sources to font-src
+func (b *CSPBuilder) Font(sources ...string) *CSPBuilder {
+	return b.Directive("font-src", sources...)
+}
+
+// Media adds sources to media-src
+func (b *CSPBuilder) Media(sources ...string) *CSPBuilder {
+	return b.Directive("media-src", sources...)
+}
+
+// Object adds sources to object-src
+func (b *CSPBuilder) Object(sources ...string) *CSPBuilder {
+	return b.Directive("object-src", sources...)
+}
+
+// Frame adds sources to frame-src
+func (b *CSPBuilder) Frame(sources ...string) *CSPBuilder {
+	return b.Directive("frame-src", sources...)
+}
+
+// Worker adds sources to worker-src
+func (b *CSPBuilder) Worker(sources ...string) *CSPBuilder {
+	return b.Directive("worker-src", sources...)
+}
+
+// FrameAncestors adds sources to frame-ancestors
+func (b *CSPBuilder) FrameAncestors(sources ...string) *CSPBuilder {
+	return b.Directive("frame-ancestors", sources...)
+}
+
+// FormAction adds sources to form-action
+func (b *CSPBuilder) FormAction(sources ...string) *CSPBuilder {
+	return b.Directive("form-action", sources...)
+}
+
+// BaseURI adds sources to base-uri
+func (b *CSPBuilder) BaseURI(sources ...string) *CSPBuilder {
+	return b.Directive("base-uri", sources...)
+}
+
+// ReportURI sets the report-uri the browser posts violations to
+func (b *CSPBuilder) ReportURI(uri string) *CSPBuilder {
+	b.init()
+	b.directives["report-uri"] = []string{uri}
+	return b
+}
+
+// UpgradeInsecureRequests adds the upgrade-insecure-requests directive
+func (b *CSPBuilder) UpgradeInsecureRequests() *CSPBuilder {
+	return b.Directive("upgrade-insecure-requests")
+}
+
+// Directive adds sources to any directive, one without sources is sent
+// as a flag
+func (b *CSPBuilder) Directive(name string, sources ...string) *CSPBuilder {
+	b.init()
+	name = strings.ToLower(strings.TrimSpace(name))
+	merged := b.directives[name]
+	for _, source := range sources {
+		source = quoteCSPSource(strings.TrimSpace(source))
+		if source != "" && !containsString(merged, source) {
+			merged = append(merged, source)
+		}
+	}
+	b.directives[name] = merged
+	return b
+}
+
+func (b *CSPBuilder) init() {
+	if b.directives == nil {
+		b.directives = make(map[string][]string)
+	}
+}
+
+// String returns the policy with default-src first and the other
+// directives sorted by name
+func (b *CSPBuilder) String() string {
+	names := make([]string, 0, len(b.directives))
+	for name := range b.directives {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == "default-src" || names[j] == "default-src" {
+			return names[i] == "default-src"
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		sources := b.directives[name]
+		// 'none' is ignored by browsers once other sources are listed
+		if len(sources) > 1 {
+			kept := make([]string, 0, len(sources))
+			for _, source := range sources {
+				if source != "'none'" {
+					kept = append(kept, source)
+				}
+			}
+			sources = kept
+		}
+		if len(sources) == 0 {
+			parts = append(parts, name)
+			continue
+		}
+		parts = append(parts, name+" "+strings.Join(sources, " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// cspKeywords are the source expressions that must be single quoted
+var cspKeywords = map[string]struct{}{
+	"self":             {},
+	"none":             {},
+	"unsafe-inline":    {},
+	"unsafe-eval":      {},
+	"unsafe-hashes":    {},
+	"strict-dynamic":   {},
+	"report-sample":    {},
+	"wasm-unsafe-eval": {},
+}
+
+// quoteCSPSource single quotes keywords, nonces and hashes
+func quoteCSPSource(source string) string {
+	if strings.HasPrefix(source, "'") {
+		return source
+	}
+	lower := strings.ToLower(source)
+	if _, ok := cspKeywords[lower]; ok {
+		return "'" + lower + "'"
+	}
+	for _, prefix := range []string{"nonce-", "sha256-", "sha384-", "sha512-"} {
+		if strings.HasPrefix(lower, prefix) {
+			return "'" + source + "'"
+		}
+	}
+	return source
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
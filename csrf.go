@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var (
+	// ErrCSRFTokenMissing is returned when the request carries no token
+	ErrCSRFTokenMissing = fmt.Errorf("csrf: token missing: %w", utils.ErrForbidden)
+	// ErrCSRFTokenInvalid is returned when the token is unknown or doesn't match the cookie
+	ErrCSRFTokenInvalid = fmt.Errorf("csrf: token invalid: %w", utils.ErrForbidden)
+	// ErrCSRFTokenExpired is returned when the token outlived its Expiration
+	ErrCSRFTokenExpired = fmt.Errorf("csrf: token expired: %w", utils.ErrForbidden)
+)
+
+// ConfigCSRF defines the config for middleware.
+type ConfigCSRF struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// KeyLookup is a string in the form of "<source>:<key>" that is used
+	// to extract the token from the request.
+	// Possible values:
+	// - "header:<name>"
+	// - "query:<name>"
+	// - "form:<name>"
+	//
+	// Optional. Default: "header:X-CSRF-Token"
+	KeyLookup string
+
+	// CookieName is the name of the cookie holding the token
+	//
+	// Optional. Default: "csrf_"
+	CookieName string
+
+	// CookieDomain of the token cookie
+	//
+	// Optional. Default: ""
+	CookieDomain string
+
+	// CookiePath of the token cookie
+	//
+	// Optional. Default: "/"
+	CookiePath string
+
+	// CookieSecure indicates if the token cookie is only sent over HTTPS
+	//
+	// Optional. Default: false
+	CookieSecure bool
+
+	// CookieHTTPOnly indicates if the token cookie is hidden from scripts
+	//
+	// Optional. Default: false
+	CookieHTTPOnly bool
+
+	// CookieSameSite of the token cookie
+	//
+	// Optional. Default: "Lax"
+	CookieSameSite string
+
+	// Expiration is the duration before a token is rejected as expired
+	//
+	// Optional. Default: 1 * time.Hour
+	Expiration time.Duration
+
+	// Rotate issues a new token after every successfully validated request
+	//
+	// Optional. Default: false
+	Rotate bool
+
+	// ContextKey is the key to store the current token in the context
+	//
+	// Optional. Default: "csrf"
+	ContextKey string
+
+	// KeyGenerator creates a new token
+	//
+	// Optional. Default: 32 random bytes, base64url encoded
+	KeyGenerator func() string
+
+	// ErrorHandler is called when a token is missing, invalid or expired
+	//
+	// Optional. Default: responds with 403 Forbidden
+	ErrorHandler func(c http.Context, err error) error
+
+	// Storage is used to store the issued tokens
+	//
+	// Optional. Default: an in memory store for this process only
+	Storage storage.Storage
+}
+
+// ConfigCSRFDefault is the default config
+var ConfigCSRFDefault = ConfigCSRF{
+	Next:           nil,
+	KeyLookup:      "header:X-CSRF-Token",
+	CookieName:     "csrf_",
+	CookiePath:     "/",
+	CookieSameSite: "Lax",
+	Expiration:     1 * time.Hour,
+	ContextKey:     "csrf",
+	KeyGenerator:   defaultCSRFKeyGenerator,
+	ErrorHandler: func(c http.Context, err error) error {
+		c.AbortWithStatus(utils.StatusForbidden)
+		return err
+	},
+}
+
+// Helper function to set default values
+func configCSRFDefault(config ...ConfigCSRF) ConfigCSRF {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigCSRFDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.KeyLookup == "" {
+		cfg.KeyLookup = ConfigCSRFDefault.KeyLookup
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = ConfigCSRFDefault.CookieName
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = ConfigCSRFDefault.CookiePath
+	}
+	if cfg.CookieSameSite == "" {
+		cfg.CookieSameSite = ConfigCSRFDefault.CookieSameSite
+	}
+	if int(cfg.Expiration.Seconds()) <= 0 {
+		cfg.Expiration = ConfigCSRFDefault.Expiration
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigCSRFDefault.ContextKey
+	}
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigCSRFDefault.KeyGenerator
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigCSRFDefault.ErrorHandler
+	}
+	return cfg
+}
+
+// CSRF creates a new middleware handler
+func CSRF(config ...ConfigCSRF) http.HandlerFunc {
+	// Set default config
+	cfg := configCSRFDefault(config...)
+
+	store := cfg.Storage
+	if store == nil {
+		store = newMemoryStorage()
+	}
+	extractor := csrfExtractor(cfg.KeyLookup)
+
+	// issue stores a new token and hands it to the client
+	issue := func(c http.Context) {
+		token := cfg.KeyGenerator()
+		exp := time.Now().Add(cfg.Expiration)
+		// Keep expired tokens around for another window so they can be
+		// reported as expired instead of unknown
+		_ = store.Set(token, []byte(strconv.FormatInt(exp.Unix(), 10)), 2*cfg.Expiration)
+		c.Cookie(&http.Cookie{
+			Name:     cfg.CookieName,
+			Value:    token,
+			Domain:   cfg.CookieDomain,
+			Path:     cfg.CookiePath,
+			Expires:  exp,
+			Secure:   cfg.CookieSecure,
+			HTTPOnly: cfg.CookieHTTPOnly,
+			SameSite: cfg.CookieSameSite,
+		})
+		c.WithValue(cfg.ContextKey, token)
+	}
+
+	// check reports why a token can't be used, nil when it can
+	check := func(token string) error {
+		raw, _ := store.Get(token)
+		if raw == nil {
+			return ErrCSRFTokenInvalid
+		}
+		exp, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return ErrCSRFTokenInvalid
+		}
+		if time.Now().Unix() >= exp {
+			_ = store.Delete(token)
+			return ErrCSRFTokenExpired
+		}
+		return nil
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		cookie := c.Cookies(cfg.CookieName)
+
+		switch c.Method() {
+		case utils.MethodGet, utils.MethodHead, utils.MethodOptions, utils.MethodTrace:
+			// Safe methods only make sure the client holds a usable token
+			if cookie != "" && check(cookie) == nil {
+				c.WithValue(cfg.ContextKey, cookie)
+			} else {
+				issue(c)
+			}
+			return c.Next()
+		}
+
+		token := extractor(c)
+		if token == "" || cookie == "" {
+			return cfg.ErrorHandler(c, ErrCSRFTokenMissing)
+		}
+		if subtle.ConstantTimeCompare(utils.UnsafeBytes(token), utils.UnsafeBytes(cookie)) != 1 {
+			return cfg.ErrorHandler(c, ErrCSRFTokenInvalid)
+		}
+		if err := check(token); err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+
+		if cfg.Rotate {
+			_ = store.Delete(token)
+			issue(c)
+		} else {
+			c.WithValue(cfg.ContextKey, token)
+		}
+		return c.Next()
+	}
+}
+
+// csrfExtractor returns a function reading the token from the configured source
+func csrfExtractor(lookup string) func(c http.Context) string {
+	parts := strings.SplitN(lookup, ":", 2)
+	if len(parts) != 2 {
+		panic("csrf: KeyLookup must be in the form of <source>:<key>")
+	}
+	key := parts[1]
+	switch parts[0] {
+	case "header":
+		return func(c http.Context) string {
+			return c.Header(key, "")
+		}
+	case "query":
+		return func(c http.Context) string {
+			return c.Query(key, "")
+		}
+	case "form":
+		return func(c http.Context) string {
+			return c.Origin().FormValue(key)
+		}
+	}
+	panic("csrf: unsupported KeyLookup source " + parts[0])
+}
+
+func defaultCSRFKeyGenerator() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
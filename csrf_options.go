@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// CSRFOption sets a field of ConfigCSRF, see CSRFWith
+type CSRFOption func(cfg *ConfigCSRF)
+
+// CSRFWith creates a CSRF middleware from options applied to
+// ConfigCSRFDefault in order, a later option overrides an earlier one
+//
+//	middleware.CSRFWith(
+//		middleware.CSRFKeyLookup("form:_csrf"),
+//		middleware.CSRFSecureCookie(),
+//	)
+func CSRFWith(opts ...CSRFOption) http.HandlerFunc {
+	cfg := ConfigCSRFDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return CSRF(cfg)
+}
+
+// CSRFOptions bundles options into one, e.g. to share a preset between
+// services
+func CSRFOptions(opts ...CSRFOption) CSRFOption {
+	return func(cfg *ConfigCSRF) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// CSRFNext sets ConfigCSRF.Next
+func CSRFNext(next func(c http.Context) bool) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.Next = next }
+}
+
+// CSRFKeyLookup sets ConfigCSRF.KeyLookup
+func CSRFKeyLookup(lookup string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.KeyLookup = lookup }
+}
+
+// CSRFCookieName sets ConfigCSRF.CookieName
+func CSRFCookieName(name string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.CookieName = name }
+}
+
+// CSRFCookieDomain sets ConfigCSRF.CookieDomain
+func CSRFCookieDomain(domain string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.CookieDomain = domain }
+}
+
+// CSRFCookiePath sets ConfigCSRF.CookiePath
+func CSRFCookiePath(path string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.CookiePath = path }
+}
+
+// CSRFSecureCookie sets ConfigCSRF.CookieSecure and CookieHTTPOnly
+func CSRFSecureCookie() CSRFOption {
+	return func(cfg *ConfigCSRF) {
+		cfg.CookieSecure = true
+		cfg.CookieHTTPOnly = true
+	}
+}
+
+// CSRFCookieSameSite sets ConfigCSRF.CookieSameSite
+func CSRFCookieSameSite(sameSite string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.CookieSameSite = sameSite }
+}
+
+// CSRFExpiration sets ConfigCSRF.Expiration
+func CSRFExpiration(d time.Duration) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.Expiration = d }
+}
+
+// CSRFRotate sets ConfigCSRF.Rotate
+func CSRFRotate() CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.Rotate = true }
+}
+
+// CSRFContextKey sets ConfigCSRF.ContextKey
+func CSRFContextKey(key string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.ContextKey = key }
+}
+
+// CSRFKeyGenerator sets ConfigCSRF.KeyGenerator
+func CSRFKeyGenerator(keyGenerator func() string) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.KeyGenerator = keyGenerator }
+}
+
+// CSRFErrorHandler sets ConfigCSRF.ErrorHandler
+func CSRFErrorHandler(handler func(c http.Context, err error) error) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.ErrorHandler = handler }
+}
+
+// CSRFStorage sets ConfigCSRF.Storage
+func CSRFStorage(store storage.Storage) CSRFOption {
+	return func(cfg *ConfigCSRF) { cfg.Storage = store }
+}
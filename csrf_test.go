@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// csrfRequest sends the token both as the cookie and in the header
+func csrfRequest(method, token string) *stdHttp.Request {
+	req := httptest.NewRequest(method, "/", nil)
+	if token != "" {
+		req.AddCookie(&stdHttp.Cookie{Name: "csrf_", Value: token})
+		req.Header.Set("X-CSRF-Token", token)
+	}
+	return req
+}
+
+// csrfCookie returns the token cookie set by the response
+func csrfCookie(res *httptest.ResponseRecorder) string {
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == "csrf_" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func TestCSRFFreshToken(t *testing.T) {
+	handler := CSRF(ConfigCSRF{Storage: newTestStorage()})
+	token := csrfCookie(run(t, csrfRequest("GET", ""), handler, ok).Recorder)
+	if token == "" {
+		t.Fatal("no token issued")
+	}
+
+	c := run(t, csrfRequest("POST", token), handler, ok)
+	if c.Body() != "ok" {
+		t.Errorf("fresh token rejected: %v", c.Errors())
+	}
+	// Without rotation the token is used again
+	if csrfCookie(c.Recorder) != "" {
+		t.Errorf("token rotated")
+	}
+	if c := run(t, csrfRequest("POST", token), handler, ok); c.Body() != "ok" {
+		t.Errorf("second use rejected: %v", c.Errors())
+	}
+}
+
+func TestCSRFExpiredToken(t *testing.T) {
+	store := newTestStorage()
+	handler := CSRF(ConfigCSRF{Storage: store, Expiration: time.Minute})
+	token := csrfCookie(run(t, csrfRequest("GET", ""), handler, ok).Recorder)
+
+	// The token outlived its Expiration
+	_ = store.Set(token, []byte(strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)), time.Minute)
+	c := run(t, csrfRequest("POST", token), handler, ok)
+	if c.Recorder.Code != utils.StatusForbidden || !errors.Is(c.Errors()[0], ErrCSRFTokenExpired) {
+		t.Errorf("status = %d, errors = %v", c.Recorder.Code, c.Errors())
+	}
+
+	// A safe request replaces it
+	if renewed := csrfCookie(run(t, csrfRequest("GET", token), handler, ok).Recorder); renewed == "" || renewed == token {
+		t.Errorf("renewed token = %q", renewed)
+	}
+}
+
+func TestCSRFRotate(t *testing.T) {
+	handler := CSRF(ConfigCSRF{Storage: newTestStorage(), Rotate: true})
+	token := csrfCookie(run(t, csrfRequest("GET", ""), handler, ok).Recorder)
+
+	c := run(t, csrfRequest("POST", token), handler, ok)
+	rotated := csrfCookie(c.Recorder)
+	if c.Body() != "ok" || rotated == "" || rotated == token {
+		t.Fatalf("body = %q, rotated token = %q", c.Body(), rotated)
+	}
+
+	// The old token is spent, the new one works
+	if c := run(t, csrfRequest("POST", token), handler, ok); !errors.Is(c.Errors()[0], ErrCSRFTokenInvalid) {
+		t.Errorf("old token: %v", c.Errors())
+	}
+	if c := run(t, csrfRequest("POST", rotated), handler, ok); c.Body() != "ok" {
+		t.Errorf("rotated token rejected: %v", c.Errors())
+	}
+}
+
+func TestCSRFMismatch(t *testing.T) {
+	handler := CSRF(ConfigCSRF{Storage: newTestStorage()})
+	token := csrfCookie(run(t, csrfRequest("GET", ""), handler, ok).Recorder)
+	req := csrfRequest("POST", token)
+	req.Header.Set("X-CSRF-Token", "forged")
+	if c := run(t, req, handler, ok); !errors.Is(c.Errors()[0], ErrCSRFTokenInvalid) {
+		t.Errorf("errors = %v", c.Errors())
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	stdHttp "net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigDeadline defines the config for middleware.
+type ConfigDeadline struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Timeout of requests without a valid Header
+	//
+	// Optional. Default: 30 * time.Second
+	Timeout time.Duration
+
+	// Header the client sends its own timeout in, e.g. "X-Request-Timeout"
+	// with a Go duration like "1.5s" or seconds, or "Grpc-Timeout" with
+	// its own format like "100m" for 100 milliseconds
+	//
+	// Optional. Default: "", only Timeout is used
+	Header string
+
+	// MaxTimeout is the ceiling of the timeouts sent in Header
+	//
+	// Optional. Default: Timeout
+	MaxTimeout time.Duration
+
+	// GatewayTimeout responds with 504 Gateway Timeout when the deadline
+	// passed and the handler returned without writing anything
+	//
+	// Optional. Default: false
+	GatewayTimeout bool
+}
+
+// ConfigDeadlineDefault is the default config
+var ConfigDeadlineDefault = ConfigDeadline{
+	Next:    nil,
+	Timeout: 30 * time.Second,
+}
+
+// Helper function to set default values
+func configDeadlineDefault(config ...ConfigDeadline) ConfigDeadline {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDeadlineDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = ConfigDeadlineDefault.Timeout
+	}
+	if cfg.MaxTimeout <= 0 {
+		cfg.MaxTimeout = cfg.Timeout
+	}
+	return cfg
+}
+
+// Deadline creates a new middleware handler giving the request context a
+// deadline, so context aware calls of the handler are cancelled once it
+// passes. Unlike a timeout racing the handler it only propagates the
+// cancellation, the handler decides how to stop.
+func Deadline(config ConfigDeadline) http.HandlerFunc {
+	// Set default config
+	cfg := configDeadlineDefault(config)
+
+	grpc := strings.EqualFold(cfg.Header, "Grpc-Timeout")
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		timeout := cfg.Timeout
+		if cfg.Header != "" {
+			if d, ok := parseRequestTimeout(c.Header(cfg.Header, ""), grpc, cfg.MaxTimeout); ok {
+				timeout = d
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Origin().Context(), timeout)
+		defer cancel()
+		if !withRequestContext(c, ctx) {
+			return c.Next()
+		}
+
+		if !cfg.GatewayTimeout {
+			return c.Next()
+		}
+		rec, ok := captureResponse(c)
+		if !ok {
+			return c.Next()
+		}
+		// The engine drops the errors of the handlers, check the deadline
+		err := rec.next(c)
+		if ctx.Err() == context.DeadlineExceeded && !rec.wroteHeader {
+			c.AbortWithStatus(utils.StatusGatewayTimeout)
+			return ErrTimeout
+		}
+		return err
+	}
+}
+
+var requestType = reflect.TypeOf((*stdHttp.Request)(nil))
+
+// withRequestContext replaces the context of the engine's request, the
+// chi engine keeps the request in the exported Req field of the context
+// returned by EngineContext
+func withRequestContext(c http.Context, ctx context.Context) bool {
+	v := reflect.ValueOf(c.EngineContext())
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	f := v.Elem().FieldByName("Req")
+	if !f.IsValid() || !f.CanSet() || f.Type() != requestType || f.IsNil() {
+		return false
+	}
+	req := f.Interface().(*stdHttp.Request)
+	f.Set(reflect.ValueOf(req.WithContext(ctx)))
+	return true
+}
+
+// parseRequestTimeout parses a grpc-timeout value, or a Go duration or
+// seconds otherwise, capped at max
+func parseRequestTimeout(value string, grpc bool, max time.Duration) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if !grpc {
+		if d, err := time.ParseDuration(value); err == nil {
+			return min(d, max), d > 0
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		return scaleDuration(n, time.Second, max), true
+	}
+
+	// grpc-timeout is at most 8 digits followed by a unit
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok || len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return scaleDuration(n, unit, max), true
+}
+
+// scaleDuration returns n units capped at max, comparing before the
+// multiplication so 99999999H doesn't overflow
+func scaleDuration(n int64, unit, max time.Duration) time.Duration {
+	if n > int64(max/unit) {
+		return max
+	}
+	return min(time.Duration(n)*unit, max)
+}
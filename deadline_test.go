@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// deadlineOf returns how far the deadline of the handler's request is
+func deadlineOf(remaining *time.Duration) http.HandlerFunc {
+	return func(c http.Context) error {
+		if deadline, ok := c.Origin().Context().Deadline(); ok {
+			*remaining = time.Until(deadline)
+		}
+		return nil
+	}
+}
+
+func TestDeadlineStaticTimeout(t *testing.T) {
+	var remaining time.Duration
+	run(t, httptest.NewRequest("GET", "/", nil), Deadline(ConfigDeadline{Timeout: 5 * time.Second}), deadlineOf(&remaining))
+	if remaining <= 4*time.Second || remaining > 5*time.Second {
+		t.Errorf("remaining = %v, want about 5s", remaining)
+	}
+}
+
+func TestDeadlineHeaderCeiling(t *testing.T) {
+	handler := Deadline(ConfigDeadline{Timeout: 10 * time.Second, Header: "X-Request-Timeout", MaxTimeout: 20 * time.Second})
+	for value, want := range map[string]time.Duration{
+		"2s":      2 * time.Second,
+		"3":       3 * time.Second,
+		"1h":      20 * time.Second,
+		"invalid": 10 * time.Second,
+		// Would overflow time.Duration in nanoseconds
+		"9999999999999": 20 * time.Second,
+	} {
+		var remaining time.Duration
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-Timeout", value)
+		run(t, req, handler, deadlineOf(&remaining))
+		if remaining <= want-time.Second || remaining > want {
+			t.Errorf("%s: remaining = %v, want about %v", value, remaining, want)
+		}
+	}
+}
+
+func TestDeadlineGrpcTimeout(t *testing.T) {
+	handler := Deadline(ConfigDeadline{Header: "Grpc-Timeout", MaxTimeout: 20 * time.Second})
+	for value, want := range map[string]time.Duration{
+		"1500m": 1500 * time.Millisecond,
+		// 99999999 hours overflow time.Duration
+		"99999999H": 20 * time.Second,
+	} {
+		var remaining time.Duration
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Grpc-Timeout", value)
+		run(t, req, handler, deadlineOf(&remaining))
+		if remaining <= want-time.Second || remaining > want {
+			t.Errorf("%s: remaining = %v, want about %v", value, remaining, want)
+		}
+	}
+}
+
+func TestDeadlineGatewayTimeout(t *testing.T) {
+	slow := func(c http.Context) error {
+		<-c.Origin().Context().Done()
+		return c.Origin().Context().Err()
+	}
+	c := run(t, httptest.NewRequest("GET", "/", nil), Deadline(ConfigDeadline{Timeout: 10 * time.Millisecond, GatewayTimeout: true}), slow)
+	if c.Recorder.Code != utils.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", c.Recorder.Code)
+	}
+	if err := c.Errors()[0]; !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v", err)
+	}
+	if err := c.Errors()[1]; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handler err = %v", err)
+	}
+}
+
+func TestDeadlineGatewayTimeoutAfterWrite(t *testing.T) {
+	slow := func(c http.Context) error {
+		if err := c.String("partial"); err != nil {
+			return err
+		}
+		<-c.Origin().Context().Done()
+		return nil
+	}
+	c := run(t, httptest.NewRequest("GET", "/", nil), Deadline(ConfigDeadline{Timeout: 10 * time.Millisecond, GatewayTimeout: true}), slow)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "partial" {
+		t.Errorf("response = %d %q, written responses are kept", c.Recorder.Code, c.Body())
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// Headers announcing deprecated endpoints
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+)
+
+// DeprecationRule marks the endpoints it matches as deprecated
+type DeprecationRule struct {
+	// Path is a prefix like "/v1/", or a glob like "/v1/*/orders" when it
+	// contains *, ? or [
+	Path string
+
+	// Methods the rule applies to
+	//
+	// Optional. Default: nil, every method
+	Methods []string
+
+	// Since is when the endpoint was deprecated
+	//
+	// Optional. Default: zero, sends "Deprecation: true"
+	Since time.Time
+
+	// Sunset is when the endpoint stops working (RFC 8594)
+	//
+	// Optional. Default: zero, no Sunset header
+	Sunset time.Time
+
+	// Link to the documentation of the deprecation or its replacement
+	//
+	// Optional. Default: ""
+	Link string
+}
+
+// ConfigDeprecation defines the config for middleware.
+type ConfigDeprecation struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Rules are checked in order, the first matching one applies
+	//
+	// Required
+	Rules []DeprecationRule
+}
+
+// ConfigDeprecationDefault is the default config
+var ConfigDeprecationDefault = ConfigDeprecation{
+	Next: nil,
+}
+
+// Helper function to set default values
+func configDeprecationDefault(config ...ConfigDeprecation) ConfigDeprecation {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDeprecationDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+	return cfg
+}
+
+// deprecationRule is a rule with its headers serialized
+type deprecationRule struct {
+	DeprecationRule
+	glob        bool
+	deprecation string
+	sunset      string
+	link        string
+}
+
+// Deprecation creates a new middleware handler announcing deprecated
+// endpoints with the Deprecation, Sunset and Link headers
+func Deprecation(config ConfigDeprecation) http.HandlerFunc {
+	// Set default config
+	cfg := configDeprecationDefault(config)
+
+	if len(cfg.Rules) == 0 {
+		panic("deprecation: at least one rule is required")
+	}
+	rules := make([]deprecationRule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		glob := strings.ContainsAny(rule.Path, "*?[")
+		if glob {
+			if _, err := path.Match(rule.Path, "/"); err != nil {
+				panic("deprecation: invalid pattern " + rule.Path)
+			}
+		}
+		r := deprecationRule{DeprecationRule: rule, glob: glob, deprecation: "true"}
+		if !rule.Since.IsZero() {
+			r.deprecation = rule.Since.UTC().Format(stdHttp.TimeFormat)
+		}
+		if !rule.Sunset.IsZero() {
+			r.sunset = rule.Sunset.UTC().Format(stdHttp.TimeFormat)
+		}
+		if rule.Link != "" {
+			r.link = "<" + rule.Link + `>; rel="deprecation"`
+		}
+		rules[i] = r
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		reqPath := c.Origin().URL.Path
+		for i := range rules {
+			rule := &rules[i]
+			if rule.Methods != nil && !containsMethod(rule.Methods, c.Method()) {
+				continue
+			}
+			if rule.glob {
+				if ok, _ := path.Match(rule.Path, reqPath); !ok {
+					continue
+				}
+			} else if !strings.HasPrefix(reqPath, rule.Path) {
+				continue
+			}
+
+			c.SetHeader(HeaderDeprecation, rule.deprecation)
+			if rule.sunset != "" {
+				c.SetHeader(HeaderSunset, rule.sunset)
+			}
+			if rule.link != "" {
+				// Keep the Link values set by other middlewares
+				if w, ok := responseWriter(c); ok {
+					w.Header().Add(utils.HeaderLink, rule.link)
+				} else {
+					c.SetHeader(utils.HeaderLink, rule.link)
+				}
+			}
+			break
+		}
+		return c.Next()
+	}
+}
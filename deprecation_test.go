@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestDeprecation(t *testing.T) {
+	// Dates are sent in GMT whatever the zone they were given in
+	berlin := time.FixedZone("CEST", 2*3600)
+	handler := Deprecation(ConfigDeprecation{Rules: []DeprecationRule{
+		{Path: "/v1/*/orders", Since: time.Date(2024, 3, 1, 2, 0, 0, 0, berlin), Sunset: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Link: "https://docs.example.com/v2"},
+		{Path: "/v1/", Methods: []string{"POST"}},
+	}})
+	for _, tt := range []struct {
+		method, target            string
+		deprecation, sunset, link string
+	}{
+		{"GET", "/v1/acme/orders", "Fri, 01 Mar 2024 00:00:00 GMT", "Wed, 01 Jan 2025 00:00:00 GMT", `<https://docs.example.com/v2>; rel="deprecation"`},
+		{"POST", "/v1/acme/orders", "Fri, 01 Mar 2024 00:00:00 GMT", "Wed, 01 Jan 2025 00:00:00 GMT", `<https://docs.example.com/v2>; rel="deprecation"`},
+		// The first matching rule applies
+		{"POST", "/v1/users", "true", "", ""},
+		{"GET", "/v1/users", "", "", ""},
+		{"GET", "/v1/acme/orders/1", "", "", ""},
+		{"POST", "/v2/users", "", "", ""},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, tt.target, nil), handler, ok)
+		h := c.Recorder.Header()
+		if h.Get(HeaderDeprecation) != tt.deprecation || h.Get(HeaderSunset) != tt.sunset || h.Get(utils.HeaderLink) != tt.link {
+			t.Errorf("%s %s: Deprecation = %q, Sunset = %q, Link = %q", tt.method, tt.target, h.Get(HeaderDeprecation), h.Get(HeaderSunset), h.Get(utils.HeaderLink))
+		}
+	}
+}
+
+func TestDeprecationKeepsLinks(t *testing.T) {
+	preload := func(c http.Context) error {
+		c.SetHeader(utils.HeaderLink, "</app.css>; rel=preload")
+		return c.Next()
+	}
+	handler := Deprecation(ConfigDeprecation{Rules: []DeprecationRule{{Path: "/", Link: "/docs"}}})
+	c := run(t, httptest.NewRequest("GET", "/", nil), preload, handler, ok)
+	if links := c.Recorder.Header().Values(utils.HeaderLink); len(links) != 2 || links[1] != `</docs>; rel="deprecation"` {
+		t.Errorf("Link = %q", links)
+	}
+}
+
+func TestDeprecationInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]ConfigDeprecation{
+		"no rules":    {},
+		"bad pattern": {Rules: []DeprecationRule{{Path: "/v1/[a"}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Deprecation didn't panic", name)
+				}
+			}()
+			Deprecation(cfg)
+		}()
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ErrDrainTimeout is returned by Wait when requests are still in flight
+// once the timeout passed to Start elapsed
+var ErrDrainTimeout = errors.New("drain: timeout with requests in flight")
+
+// ConfigDrain defines the config for middleware.
+type ConfigDrain struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// HealthPaths report unhealthy as soon as draining starts so the load
+	// balancer stops routing, their connections are kept open
+	//
+	// Optional. Default: "/healthz", "/readyz"
+	HealthPaths []string
+
+	// RetryAfter in seconds sent with the 503 responses
+	//
+	// Optional. Default: 5
+	RetryAfter int
+}
+
+// ConfigDrainDefault is the default config
+var ConfigDrainDefault = ConfigDrain{
+	Next:        nil,
+	HealthPaths: []string{"/healthz", "/readyz"},
+	RetryAfter:  5,
+}
+
+// Helper function to set default values
+func configDrainDefault(config ...ConfigDrain) ConfigDrain {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDrainDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.HealthPaths == nil {
+		cfg.HealthPaths = ConfigDrainDefault.HealthPaths
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = ConfigDrainDefault.RetryAfter
+	}
+	return cfg
+}
+
+// Drainer stops a Drain middleware from accepting requests and waits for
+// the ones in flight
+type Drainer struct {
+	inFlight atomic.Int64
+	draining atomic.Bool
+	idleOnce sync.Once
+	idle     chan struct{}
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// Start rejects new requests from now on, Wait gives up after timeout
+func (d *Drainer) Start(timeout time.Duration) {
+	d.mu.Lock()
+	d.deadline = time.Now().Add(timeout)
+	d.mu.Unlock()
+
+	d.draining.Store(true)
+	if d.inFlight.Load() == 0 {
+		d.idleOnce.Do(func() { close(d.idle) })
+	}
+}
+
+// Draining reports whether Start was called
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// InFlight returns the number of requests being handled
+func (d *Drainer) InFlight() int64 {
+	return d.inFlight.Load()
+}
+
+// Wait returns once draining started and no request is in flight anymore,
+// ErrDrainTimeout when the timeout of Start elapses first, or the error of
+// ctx when it is done first
+func (d *Drainer) Wait(ctx context.Context) error {
+	d.mu.Lock()
+	deadline := d.deadline
+	d.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-d.idle:
+		return nil
+	case <-timeout:
+		return ErrDrainTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// done marks a request as finished
+func (d *Drainer) done() {
+	if d.inFlight.Add(-1) == 0 && d.draining.Load() {
+		d.idleOnce.Do(func() { close(d.idle) })
+	}
+}
+
+// Drain creates a new middleware handler tracking the requests in flight,
+// once the returned Drainer started draining new requests are answered
+// with 503 Service Unavailable and the connection is closed
+func Drain(config ...ConfigDrain) (http.HandlerFunc, *Drainer) {
+	// Set default config
+	cfg := configDrainDefault(config...)
+
+	d := &Drainer{idle: make(chan struct{})}
+	retryAfter := strconv.Itoa(cfg.RetryAfter)
+	health := make(map[string]struct{}, len(cfg.HealthPaths))
+	for _, p := range cfg.HealthPaths {
+		health[p] = struct{}{}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if _, ok := health[c.Origin().URL.Path]; ok {
+			if d.draining.Load() {
+				c.AbortWithStatus(utils.StatusServiceUnavailable)
+				return ErrDraining
+			}
+			return c.Next()
+		}
+
+		// Count the request before checking, so Start can't miss it
+		d.inFlight.Add(1)
+		defer d.done()
+		if d.draining.Load() {
+			c.SetHeader(utils.HeaderConnection, "close")
+			c.SetHeader(utils.HeaderRetryAfter, retryAfter)
+			c.AbortWithStatus(utils.StatusServiceUnavailable)
+			return ErrDraining
+		}
+		return c.Next()
+	}, d
+}
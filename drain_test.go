@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestDrain(t *testing.T) {
+	handler, drainer := Drain(ConfigDrain{HealthPaths: []string{"/ready"}, RetryAfter: 30})
+	if c := run(t, httptest.NewRequest("GET", "/ready", nil), handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("health before draining: status = %d", c.Recorder.Code)
+	}
+
+	var started, done sync.WaitGroup
+	release := make(chan struct{})
+	started.Add(3)
+	for i := 0; i < 3; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			if c := run(t, httptest.NewRequest("GET", "/slow", nil), handler, blockingHandler(&started, release)); c.Body() != "ok" {
+				t.Errorf("in flight: status = %d, body = %q", c.Recorder.Code, c.Body())
+			}
+		}()
+	}
+	started.Wait()
+	drainer.Start(time.Minute)
+	if !drainer.Draining() || drainer.InFlight() != 3 {
+		t.Errorf("draining = %v, in flight = %d", drainer.Draining(), drainer.InFlight())
+	}
+
+	// New requests are turned away while the others finish
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	h := c.Recorder.Header()
+	if c.Recorder.Code != utils.StatusServiceUnavailable || h.Get(utils.HeaderConnection) != "close" || h.Get(utils.HeaderRetryAfter) != "30" {
+		t.Errorf("new request: status = %d, headers = %v", c.Recorder.Code, h)
+	}
+	if !errors.Is(c.Errors()[0], ErrDraining) {
+		t.Errorf("new request: err = %v", c.Errors()[0])
+	}
+	c = run(t, httptest.NewRequest("GET", "/ready", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusServiceUnavailable || c.Recorder.Header().Get(utils.HeaderConnection) != "" {
+		t.Errorf("health while draining: status = %d, headers = %v", c.Recorder.Code, c.Recorder.Header())
+	}
+
+	waited := make(chan error, 1)
+	go func() { waited <- drainer.Wait(context.Background()) }()
+	select {
+	case err := <-waited:
+		t.Fatalf("Wait returned %v with requests in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	done.Wait()
+	if err := <-waited; err != nil || drainer.InFlight() != 0 {
+		t.Errorf("Wait = %v, in flight = %d", err, drainer.InFlight())
+	}
+}
+
+func TestDrainWait(t *testing.T) {
+	// Nothing in flight, Wait returns right away
+	_, drainer := Drain()
+	drainer.Start(time.Minute)
+	if err := drainer.Wait(context.Background()); err != nil {
+		t.Errorf("idle: Wait = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		timeout time.Duration
+		cancel  bool
+		want    error
+	}{
+		{"timeout", 20 * time.Millisecond, false, ErrDrainTimeout},
+		{"cancelled", time.Minute, true, context.Canceled},
+	} {
+		handler, drainer := Drain()
+		var started sync.WaitGroup
+		started.Add(1)
+		release := make(chan struct{})
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			run(t, httptest.NewRequest("GET", "/", nil), handler, blockingHandler(&started, release))
+		}()
+		started.Wait()
+
+		drainer.Start(tt.timeout)
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.cancel {
+			cancel()
+		}
+		if err := drainer.Wait(ctx); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Wait = %v", tt.name, err)
+		}
+		cancel()
+		close(release)
+		<-finished
+	}
+}
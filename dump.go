@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	stdHttp "net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// DumpFormat is the layout dumps are written in
+type DumpFormat int
+
+const (
+	// DumpText writes a readable block resembling the HTTP messages
+	DumpText DumpFormat = iota
+	// DumpJSON writes a JSON object per line
+	DumpJSON
+)
+
+// DumpRecord is a request and its response
+type DumpRecord struct {
+	Time           time.Time      `json:"time"`
+	Duration       time.Duration  `json:"duration"`
+	Method         string         `json:"method"`
+	URL            string         `json:"url"`
+	Proto          string         `json:"proto"`
+	RequestHeader  stdHttp.Header `json:"request_header"`
+	RequestBody    string         `json:"request_body,omitempty"`
+	Status         int            `json:"status"`
+	ResponseHeader stdHttp.Header `json:"response_header"`
+	ResponseBody   string         `json:"response_body,omitempty"`
+}
+
+// ConfigDump defines the config for middleware.
+type ConfigDump struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Filter selects the requests that are dumped, e.g. a debug header
+	// sent from an allowlisted IP
+	//
+	// Required
+	Filter func(c http.Context) bool
+
+	// Redact are the headers whose values are replaced in the dump
+	//
+	// Optional. Default: Authorization, Proxy-Authorization, Cookie, Set-Cookie
+	Redact []string
+
+	// MaxBodySize is the largest part of a body that is dumped
+	//
+	// Optional. Default: 64 KB
+	MaxBodySize int
+
+	// Format of the dumps written to Output
+	//
+	// Optional. Default: DumpText
+	Format DumpFormat
+
+	// Output the dumps are written to
+	//
+	// Optional. Default: os.Stderr
+	Output io.Writer
+
+	// OnDump receives the dumps instead of Output
+	//
+	// Optional. Default: nil
+	OnDump func(record DumpRecord)
+}
+
+// ConfigDumpDefault is the default config
+var ConfigDumpDefault = ConfigDump{
+	Next: nil,
+	Redact: []string{
+		utils.HeaderAuthorization,
+		utils.HeaderProxyAuthorization,
+		utils.HeaderCookie,
+		utils.HeaderSetCookie,
+	},
+	MaxBodySize: 64 * 1024,
+	Format:      DumpText,
+	Output:      os.Stderr,
+}
+
+// Helper function to set default values
+func configDumpDefault(config ...ConfigDump) ConfigDump {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDumpDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Redact == nil {
+		cfg.Redact = ConfigDumpDefault.Redact
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = ConfigDumpDefault.MaxBodySize
+	}
+	if cfg.Output == nil {
+		cfg.Output = ConfigDumpDefault.Output
+	}
+	return cfg
+}
+
+// redactedValue replaces the values of redacted headers
+const redactedValue = "[REDACTED]"
+
+// Dump creates a new middleware handler writing the requests matching the
+// filter and their responses, for reproducing issues outside production
+func Dump(config ConfigDump) http.HandlerFunc {
+	// Set default config
+	cfg := configDumpDefault(config)
+
+	if cfg.Filter == nil {
+		panic("dump: Filter is required")
+	}
+	var mu sync.Mutex
+	output := cfg.OnDump
+	if output == nil {
+		output = func(record DumpRecord) {
+			var out []byte
+			if cfg.Format == DumpJSON {
+				out, _ = json.Marshal(record)
+				out = append(out, '\n')
+			} else {
+				out = []byte(record.String())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = cfg.Output.Write(out)
+		}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if !cfg.Filter(c) {
+			return c.Next()
+		}
+
+		req := c.Origin()
+		record := DumpRecord{
+			Time:          time.Now(),
+			Method:        req.Method,
+			URL:           req.URL.RequestURI(),
+			Proto:         req.Proto,
+			RequestHeader: redactHeader(req.Header, cfg.Redact),
+		}
+		if req.Body != nil {
+			// Keep the part we read in front of the rest for the handler
+			head, _ := io.ReadAll(io.LimitReader(req.Body, int64(cfg.MaxBodySize)+1))
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+			truncated := len(head) > cfg.MaxBodySize
+			if truncated {
+				head = head[:cfg.MaxBodySize]
+			}
+			record.RequestBody = dumpBody(head, req.Header.Get(utils.HeaderContentType), truncated)
+		}
+
+		rec, ok := recordResponse(c, cfg.MaxBodySize)
+		if !ok {
+			return c.Next()
+		}
+		err := rec.next(c)
+
+		record.Duration = time.Since(record.Time)
+		record.Status = rec.Status()
+		record.ResponseHeader = redactHeader(rec.Header(), cfg.Redact)
+		contentType := rec.Header().Get(utils.HeaderContentType)
+		if rec.overflow {
+			record.ResponseBody = fmt.Sprintf("[%d bytes, %s, larger than %d]", rec.Size(), dumpContentType(contentType), cfg.MaxBodySize)
+		} else {
+			record.ResponseBody = dumpBody(rec.Body(), contentType, false)
+		}
+		output(record)
+		return err
+	}
+}
+
+// String formats the record as a readable block
+func (r DumpRecord) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- request %s\n%s %s %s\n", r.Time.Format(time.RFC3339Nano), r.Method, r.URL, r.Proto)
+	writeDumpHeader(&b, r.RequestHeader)
+	if r.RequestBody != "" {
+		b.WriteString("\n" + r.RequestBody + "\n")
+	}
+	fmt.Fprintf(&b, "--- response %d in %s\n", r.Status, r.Duration)
+	writeDumpHeader(&b, r.ResponseHeader)
+	if r.ResponseBody != "" {
+		b.WriteString("\n" + r.ResponseBody + "\n")
+	}
+	return b.String()
+}
+
+// writeDumpHeader writes the header lines sorted by name
+func writeDumpHeader(b *strings.Builder, header stdHttp.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			b.WriteString(name + ": " + value + "\n")
+		}
+	}
+}
+
+// redactHeader returns a copy of header with the values of redact replaced
+func redactHeader(header stdHttp.Header, redact []string) stdHttp.Header {
+	out := header.Clone()
+	if out == nil {
+		out = stdHttp.Header{}
+	}
+	for _, name := range redact {
+		if values := out.Values(name); len(values) > 0 {
+			redacted := make([]string, len(values))
+			for i := range redacted {
+				redacted[i] = redactedValue
+			}
+			out[stdHttp.CanonicalHeaderKey(name)] = redacted
+		}
+	}
+	return out
+}
+
+// dumpBody returns text bodies as they are and a summary of binary ones
+func dumpBody(body []byte, contentType string, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !isTextBody(body, contentType) {
+		return fmt.Sprintf("[binary body, %d bytes, %s]", len(body), dumpContentType(contentType))
+	}
+	if truncated {
+		return string(body) + fmt.Sprintf("\n[truncated after %d bytes]", len(body))
+	}
+	return string(body)
+}
+
+// isTextBody reports whether a body is readable, judged by its content type
+// or, without one, by its bytes
+func isTextBody(body []byte, contentType string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return compressibleType(contentType) || mediaType == "application/x-www-form-urlencoded"
+	}
+	return utf8.Valid(body) && bytes.IndexByte(body, 0) < 0
+}
+
+func dumpContentType(contentType string) string {
+	if contentType == "" {
+		return "no content type"
+	}
+	return contentType
+}
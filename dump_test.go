@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// debugRequests dumps the requests carrying X-Debug
+func debugRequests(c http.Context) bool {
+	return c.Header("X-Debug", "") != ""
+}
+
+func TestDumpText(t *testing.T) {
+	var out bytes.Buffer
+	handler := Dump(ConfigDump{Filter: debugRequests, Output: &out})
+	final := func(c http.Context) error {
+		c.SetHeader(utils.HeaderSetCookie, "session=secret-session")
+		c.SetHeader("X-Request-Id", "r1")
+		return c.Status(utils.StatusCreated).String("created")
+	}
+
+	req := httptest.NewRequest("POST", "/orders?draft=1", strings.NewReader(`{"item":1}`))
+	req.Header.Set("X-Debug", "1")
+	req.Header.Set(utils.HeaderContentType, "application/json")
+	req.Header.Set(utils.HeaderAuthorization, "Bearer secret-token")
+	req.Header.Set(utils.HeaderCookie, "session=secret-cookie")
+	c := run(t, req, handler, final)
+	if c.Recorder.Code != utils.StatusCreated || c.Body() != "created" {
+		t.Fatalf("status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+
+	dump := out.String()
+	for _, want := range []string{
+		"POST /orders?draft=1 HTTP/1.1\n",
+		"Authorization: [REDACTED]\n",
+		"Cookie: [REDACTED]\n",
+		"X-Debug: 1\n",
+		"\n{\"item\":1}\n",
+		"--- response 201 in ",
+		"Set-Cookie: [REDACTED]\n",
+		"X-Request-Id: r1\n",
+		"\ncreated\n",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump misses %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "secret") {
+		t.Errorf("dump leaks a secret:\n%s", dump)
+	}
+
+	// Requests the filter doesn't select aren't dumped
+	out.Reset()
+	run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if out.Len() != 0 {
+		t.Errorf("dumped %q", out.String())
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	var out bytes.Buffer
+	handler := Dump(ConfigDump{Filter: debugRequests, Output: &out, Format: DumpJSON, Redact: []string{"X-Api-Key"}})
+	req := httptest.NewRequest("GET", "/items", nil)
+	req.Header.Set("X-Debug", "1")
+	req.Header.Set("X-Api-Key", "k1")
+	req.Header.Set(utils.HeaderAuthorization, "Basic eDp5")
+	run(t, req, handler, ok)
+
+	var record DumpRecord
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil || !bytes.HasSuffix(out.Bytes(), []byte("}\n")) {
+		t.Fatalf("%q: %v", out.String(), err)
+	}
+	if record.Method != "GET" || record.URL != "/items" || record.Status != utils.StatusOK || record.ResponseBody != "ok" || record.RequestBody != "" {
+		t.Errorf("record = %+v", record)
+	}
+	// Redact replaces the default list
+	if record.RequestHeader.Get("X-Api-Key") != "[REDACTED]" || record.RequestHeader.Get(utils.HeaderAuthorization) != "Basic eDp5" {
+		t.Errorf("request header = %v", record.RequestHeader)
+	}
+}
+
+func TestDumpBodies(t *testing.T) {
+	var records []DumpRecord
+	handler := Dump(ConfigDump{Filter: debugRequests, MaxBodySize: 8, OnDump: func(record DumpRecord) {
+		records = append(records, record)
+	}})
+	for _, tt := range []struct {
+		name, contentType, body string
+		final                   http.HandlerFunc
+		request, response       string
+	}{
+		// The handler still reads the whole body, responses without a
+		// content type are judged by their bytes
+		{"cap", "text/plain", "0123456789abcdef", echoBody,
+			"01234567\n[truncated after 8 bytes]", "[16 bytes, no content type, larger than 8]"},
+		{"binary request", "image/png", "\x89PNG", echoBody,
+			"[binary body, 4 bytes, image/png]", "[binary body, 4 bytes, no content type]"},
+		{"binary response", "", "", func(c http.Context) error {
+			c.SetHeader(utils.HeaderContentType, "application/octet-stream")
+			return c.String("\x00\x01\x02")
+		}, "", "[binary body, 3 bytes, application/octet-stream]"},
+		{"no content type", "", "a=1&b=2", echoBody, "a=1&b=2", "a=1&b=2"},
+	} {
+		records = nil
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		req.Header.Set("X-Debug", "1")
+		if tt.contentType != "" {
+			req.Header.Set(utils.HeaderContentType, tt.contentType)
+		}
+		c := run(t, req, handler, tt.final)
+		if len(records) != 1 {
+			t.Fatalf("%s: %d dumps", tt.name, len(records))
+		}
+		if c.Recorder.Code == utils.StatusOK && tt.body != "" && c.Body() != tt.body {
+			t.Errorf("%s: handler read %q", tt.name, c.Body())
+		}
+		if records[0].RequestBody != tt.request || records[0].ResponseBody != tt.response {
+			t.Errorf("%s: request body %q, response body %q", tt.name, records[0].RequestBody, records[0].ResponseBody)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Dump didn't panic without a filter")
+		}
+	}()
+	Dump(ConfigDump{})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigEarlyData defines the config for middleware.
+type ConfigEarlyData struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// SafeMethods are allowed to be sent as TLS 1.3 early data
+	//
+	// Optional. Default: GET, HEAD, OPTIONS, TRACE
+	SafeMethods []string
+
+	// AllowEarlyData accepts early data for other requests, e.g. a POST
+	// carrying an idempotency key
+	//
+	// Optional. Default: nil
+	AllowEarlyData func(c http.Context) bool
+}
+
+// ConfigEarlyDataDefault is the default config
+var ConfigEarlyDataDefault = ConfigEarlyData{
+	Next: nil,
+	SafeMethods: []string{
+		utils.MethodGet,
+		utils.MethodHead,
+		utils.MethodOptions,
+		utils.MethodTrace,
+	},
+}
+
+// Helper function to set default values
+func configEarlyDataDefault(config ...ConfigEarlyData) ConfigEarlyData {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigEarlyDataDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.SafeMethods == nil {
+		cfg.SafeMethods = ConfigEarlyDataDefault.SafeMethods
+	}
+	return cfg
+}
+
+// EarlyData creates a new middleware handler rejecting replayable requests
+// sent as TLS 1.3 early data with 425 Too Early, see RFC 8470
+func EarlyData(config ...ConfigEarlyData) http.HandlerFunc {
+	// Set default config
+	cfg := configEarlyDataDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Requests after the handshake can't be replayed
+		if c.Header(utils.HeaderEarlyData, "") != "1" {
+			return c.Next()
+		}
+		if containsMethod(cfg.SafeMethods, c.Method()) {
+			return c.Next()
+		}
+		if cfg.AllowEarlyData != nil && cfg.AllowEarlyData(c) {
+			return c.Next()
+		}
+
+		// The client retries once the handshake completed
+		c.AbortWithStatus(utils.StatusTooEarly)
+		return fmt.Errorf("%w: %s", ErrTooEarly, c.Method())
+	}
+}
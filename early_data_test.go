@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func earlyDataRequest(method string) *stdHttp.Request {
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set(utils.HeaderEarlyData, "1")
+	return req
+}
+
+func TestEarlyData(t *testing.T) {
+	handler := EarlyData()
+	for _, method := range []string{"GET", "HEAD", "OPTIONS", "TRACE"} {
+		if c := run(t, earlyDataRequest(method), handler, ok); c.Recorder.Code != utils.StatusOK {
+			t.Errorf("%s: status = %d", method, c.Recorder.Code)
+		}
+	}
+
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		c := run(t, earlyDataRequest(method), handler, ok)
+		if c.Recorder.Code != utils.StatusTooEarly || c.Body() != "" {
+			t.Errorf("%s: status = %d, body = %q", method, c.Recorder.Code, c.Body())
+		}
+		if err := c.Errors()[0]; !errors.Is(err, ErrTooEarly) {
+			t.Errorf("%s: err = %v", method, err)
+		}
+	}
+
+	// Requests after the handshake pass whatever their method
+	if c := run(t, httptest.NewRequest("POST", "/", nil), handler, ok); c.Body() != "ok" {
+		t.Errorf("POST without Early-Data: status = %d", c.Recorder.Code)
+	}
+}
+
+func TestEarlyDataAllow(t *testing.T) {
+	handler := EarlyData(ConfigEarlyData{
+		AllowEarlyData: func(c http.Context) bool { return c.Header("Idempotency-Key", "") != "" },
+	})
+	req := earlyDataRequest("POST")
+	req.Header.Set("Idempotency-Key", "k")
+	if c := run(t, req, handler, ok); c.Body() != "ok" {
+		t.Errorf("idempotent POST: status = %d", c.Recorder.Code)
+	}
+	if c := run(t, earlyDataRequest("POST"), handler, ok); c.Recorder.Code != utils.StatusTooEarly {
+		t.Errorf("POST without key: status = %d", c.Recorder.Code)
+	}
+}
+
+func TestEarlyDataSafeMethods(t *testing.T) {
+	handler := EarlyData(ConfigEarlyData{SafeMethods: []string{"GET"}})
+	if c := run(t, earlyDataRequest("GET"), handler, ok); c.Body() != "ok" {
+		t.Errorf("GET: status = %d", c.Recorder.Code)
+	}
+	if c := run(t, earlyDataRequest("OPTIONS"), handler, ok); c.Recorder.Code != utils.StatusTooEarly {
+		t.Errorf("OPTIONS: status = %d", c.Recorder.Code)
+	}
+
+	// An empty list rejects every early request
+	handler = EarlyData(ConfigEarlyData{SafeMethods: []string{}})
+	if c := run(t, earlyDataRequest("GET"), handler, ok); c.Recorder.Code != utils.StatusTooEarly {
+		t.Errorf("GET with no safe methods: status = %d", c.Recorder.Code)
+	}
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	stdHttp "net/http"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigEncryptCookie defines the config for middleware.
+type ConfigEncryptCookie struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Keys are the 32 byte AES-256 keys. The first key encrypts, all keys
+	// are tried for decryption so old keys can be rotated out.
+	//
+	// Required.
+	Keys [][]byte
+
+	// Except lists the cookie names that stay plaintext
+	//
+	// Optional. Default: []string{}
+	Except []string
+}
+
+// ConfigEncryptCookieDefault is the default config
+var ConfigEncryptCookieDefault = ConfigEncryptCookie{
+	Next:   nil,
+	Except: []string{},
+}
+
+// Helper function to set default values
+func configEncryptCookieDefault(config ...ConfigEncryptCookie) ConfigEncryptCookie {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigEncryptCookieDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Except == nil {
+		cfg.Except = ConfigEncryptCookieDefault.Except
+	}
+	return cfg
+}
+
+// GenerateCookieKey returns a random key usable in ConfigEncryptCookie.Keys
+func GenerateCookieKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// EncryptCookie creates a new middleware handler. Requests fail with 500
+// Internal Server Error when the engine doesn't expose its response writer,
+// the cookies set by the handler can't be encrypted then.
+func EncryptCookie(config ConfigEncryptCookie) http.HandlerFunc {
+	// Set default config
+	cfg := configEncryptCookieDefault(config)
+
+	if len(cfg.Keys) == 0 {
+		panic("encrypt cookie: at least one key is required")
+	}
+	aeads := make([]cipher.AEAD, len(cfg.Keys))
+	for i, key := range cfg.Keys {
+		if len(key) != 32 {
+			panic(fmt.Sprintf("encrypt cookie: key %d must be 32 bytes", i))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			panic(err)
+		}
+		if aeads[i], err = cipher.NewGCM(block); err != nil {
+			panic(err)
+		}
+	}
+	except := make(map[string]struct{}, len(cfg.Except))
+	for _, name := range cfg.Except {
+		except[name] = struct{}{}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Decrypt the incoming cookies, dropping the ones that don't decrypt
+		req := c.Origin()
+		if cookies := req.Cookies(); len(cookies) > 0 {
+			req.Header.Del(utils.HeaderCookie)
+			for _, cookie := range cookies {
+				if _, ok := except[cookie.Name]; !ok {
+					value, err := decryptCookie(aeads, cookie.Name, cookie.Value)
+					if err != nil {
+						continue
+					}
+					cookie.Value = value
+				}
+				req.AddCookie(cookie)
+			}
+		}
+
+		// Encrypt the cookies set by the handler before they are sent
+		rec, ok := onHeaders(c, func(_ int, header stdHttp.Header) {
+			lines := header.Values(utils.HeaderSetCookie)
+			for i, line := range lines {
+				name, value, attrs := splitSetCookie(line)
+				if _, ok := except[name]; ok || name == "" {
+					continue
+				}
+				lines[i] = name + "=" + encryptCookie(aeads[0], name, value) + attrs
+			}
+		})
+		if !ok {
+			// The handler's cookies would be sent in the clear
+			c.AbortWithStatus(utils.StatusInternalServerError)
+			return utils.ErrInternalServerError
+		}
+		return rec.next(c)
+	}
+}
+
+// splitSetCookie splits a Set-Cookie line into its name, unquoted value and
+// the untouched attributes including the leading semicolon
+func splitSetCookie(line string) (name, value, attrs string) {
+	pair := line
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		pair, attrs = line[:i], line[i:]
+	}
+	i := strings.IndexByte(pair, '=')
+	if i < 0 {
+		return "", "", line
+	}
+	value = strings.TrimSpace(pair[i+1:])
+	// net/http quotes values with spaces or commas
+	if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return strings.TrimSpace(pair[:i]), value, attrs
+}
+
+// encryptCookie seals value with the cookie name as additional data, so
+// the value doesn't decrypt when it's moved to another cookie
+func encryptCookie(aead cipher.AEAD, name, value string) string {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+func decryptCookie(aeads []cipher.AEAD, name, value string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	for _, aead := range aeads {
+		size := aead.NonceSize()
+		if len(raw) < size {
+			continue
+		}
+		plain, err := aead.Open(nil, raw[:size], raw[size:], []byte(name))
+		if err == nil {
+			return string(plain), nil
+		}
+	}
+	return "", errors.New("encrypt cookie: cookie could not be decrypted")
+}
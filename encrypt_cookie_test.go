@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// setCookies returns the cookies of a response by name
+func setCookies(res *httptest.ResponseRecorder) map[string]*stdHttp.Cookie {
+	cookies := make(map[string]*stdHttp.Cookie)
+	for _, cookie := range res.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+func TestEncryptCookieRoundTrip(t *testing.T) {
+	handler := EncryptCookie(ConfigEncryptCookie{Keys: [][]byte{GenerateCookieKey()}, Except: []string{"csrf_"}})
+
+	// The handler sets plaintext cookies, the client receives them encrypted
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		// net/http quotes the value for its comma and space
+		c.Cookie(&http.Cookie{Name: "cart", Value: "item-1, item-2", Path: "/shop", HTTPOnly: true})
+		c.Cookie(&http.Cookie{Name: "csrf_", Value: "token"})
+		return nil
+	})
+	cookies := setCookies(c.Recorder)
+	cart := cookies["cart"]
+	if cart == nil || cart.Value == "item-1, item-2" || cart.Path != "/shop" || !cart.HttpOnly {
+		t.Fatalf("cart = %+v", cart)
+	}
+	if cookies["csrf_"] == nil || cookies["csrf_"].Value != "token" {
+		t.Errorf("excepted cookie = %+v", cookies["csrf_"])
+	}
+
+	// Sending them back, the handler reads plaintext
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cart)
+	req.AddCookie(cookies["csrf_"])
+	run(t, req, handler, func(c http.Context) error {
+		if got := c.Cookies("cart"); got != "item-1, item-2" {
+			t.Errorf("cart = %q", got)
+		}
+		if got := c.Cookies("csrf_"); got != "token" {
+			t.Errorf("csrf_ = %q", got)
+		}
+		return nil
+	})
+}
+
+func TestEncryptCookieTampered(t *testing.T) {
+	handler := EncryptCookie(ConfigEncryptCookie{Keys: [][]byte{GenerateCookieKey()}})
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		c.Cookie(&http.Cookie{Name: "role", Value: "user"})
+		return nil
+	})
+	sealed := setCookies(c.Recorder)["role"].Value
+	// Flip a character in the middle, the last one may only carry padding
+	// bits
+	flipped := []byte(sealed)
+	if flipped[20] == 'A' {
+		flipped[20] = 'B'
+	} else {
+		flipped[20] = 'A'
+	}
+
+	for _, value := range []string{string(flipped), "user", "admin", "!!!"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&stdHttp.Cookie{Name: "role", Value: value})
+		req.AddCookie(&stdHttp.Cookie{Name: "other", Value: sealed})
+		run(t, req, handler, func(c http.Context) error {
+			if got := c.Cookies("role"); got != "" {
+				t.Errorf("%q passed through as %q", value, got)
+			}
+			// The name is authenticated, a value moved to another cookie
+			// doesn't decrypt
+			if got := c.Cookies("other"); got != "" {
+				t.Errorf("other = %q", got)
+			}
+			return nil
+		})
+	}
+}
+
+func TestEncryptCookieKeyRotation(t *testing.T) {
+	oldKey, newKey := GenerateCookieKey(), GenerateCookieKey()
+	set := func(c http.Context) error {
+		c.Cookie(&http.Cookie{Name: "session", Value: "abc"})
+		return nil
+	}
+	before := EncryptCookie(ConfigEncryptCookie{Keys: [][]byte{oldKey}})
+	oldCookie := setCookies(run(t, httptest.NewRequest("GET", "/", nil), before, set).Recorder)["session"]
+
+	after := EncryptCookie(ConfigEncryptCookie{Keys: [][]byte{newKey, oldKey}})
+	newCookie := setCookies(run(t, httptest.NewRequest("GET", "/", nil), after, set).Recorder)["session"]
+
+	read := func(handler http.HandlerFunc, cookie *stdHttp.Cookie) string {
+		var got string
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(cookie)
+		run(t, req, handler, func(c http.Context) error {
+			got = c.Cookies("session")
+			return nil
+		})
+		return got
+	}
+	if read(after, oldCookie) != "abc" || read(after, newCookie) != "abc" {
+		t.Error("rotated keys don't decrypt both cookies")
+	}
+	// New cookies are encrypted with the first key only
+	if read(before, newCookie) != "" {
+		t.Error("new cookie decrypted with the old key")
+	}
+}
+
+func TestEncryptCookieInvalidKeys(t *testing.T) {
+	for _, keys := range [][][]byte{nil, {[]byte("short")}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("keys %q accepted", keys)
+				}
+			}()
+			EncryptCookie(ConfigEncryptCookie{Keys: keys})
+		}()
+	}
+}
+
+func TestSplitSetCookie(t *testing.T) {
+	for _, tt := range []struct {
+		line, name, value, attrs string
+	}{
+		{"a=1", "a", "1", ""},
+		{"a=1; Path=/; HttpOnly", "a", "1", "; Path=/; HttpOnly"},
+		{`cart="item-1, item-2"; Path=/shop`, "cart", "item-1, item-2", "; Path=/shop"},
+		{"invalid; Path=/", "", "", "invalid; Path=/"},
+	} {
+		name, value, attrs := splitSetCookie(tt.line)
+		if name != tt.name || value != tt.value || attrs != tt.attrs {
+			t.Errorf("%q: %q %q %q", tt.line, name, value, attrs)
+		}
+	}
+}
+
+// noEngineContext hides the engine's response writer
+type noEngineContext struct {
+	*middlewaretest.MockContext
+}
+
+func (noEngineContext) EngineContext() any {
+	return nil
+}
+
+func TestEncryptCookieWithoutWriter(t *testing.T) {
+	c := middlewaretest.NewMockContext(httptest.NewRequest("GET", "/", nil), ok)
+	err := EncryptCookie(ConfigEncryptCookie{Keys: [][]byte{GenerateCookieKey()}})(noEngineContext{c})
+	if !errors.Is(err, utils.ErrInternalServerError) || c.Recorder.Code != utils.StatusInternalServerError || c.Body() == "ok" {
+		t.Errorf("err = %v, status = %d, body = %q", err, c.Recorder.Code, c.Body())
+	}
+}
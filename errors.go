@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/limiter"
+)
+
+// Errors returned by the default failure paths of the middlewares, some
+// wrapped with details of the request. Each one wraps the utils error of
+// its status, so both match with errors.Is.
+//
+// The engine drops the error a middleware returns and Next always returns
+// nil, so the middlewares and error handlers before a failing middleware
+// never see it. Only direct callers of the handler do, like OnError, the
+// members of a Stack before it and tests:
+//
+//	app.Use(middleware.OnError(limiter.New(), func(c http.Context, err error) {
+//		switch {
+//		case errors.Is(err, middleware.ErrRateLimited):
+//			// rate limited
+//		case errors.Is(err, utils.ErrTooManyRequests):
+//			// any 429 Too Many Requests
+//		}
+//	}))
+//
+// Custom failure handlers return what they return. The CSRF, JWT and HMAC
+// middlewares return their own ErrCSRF*, ErrJWT* and ErrHMAC* errors, which
+// wrap the utils error of their status too.
+var (
+	// ErrRateLimited is returned by the limiter, see limiter.ErrRateLimited
+	ErrRateLimited = limiter.ErrRateLimited
+	// ErrTooManyConnections is returned by MaxConnPerIP
+	ErrTooManyConnections = fmt.Errorf("%w: too many connections", utils.ErrTooManyRequests)
+	// ErrUnauthorizedBasicAuth is returned by BasicAuth for missing or
+	// wrong credentials
+	ErrUnauthorizedBasicAuth = fmt.Errorf("%w: basic auth", utils.ErrUnauthorized)
+	// ErrBasicAuthLockedOut is returned by BasicAuth while a username is
+	// locked out
+	ErrBasicAuthLockedOut = fmt.Errorf("%w: basic auth locked out", utils.ErrTooManyRequests)
+	// ErrClientCertDenied is returned by RequireClientCert
+	ErrClientCertDenied = fmt.Errorf("%w: client certificate denied", utils.ErrForbidden)
+	// ErrOriginDenied is returned by OriginCheck
+	ErrOriginDenied = fmt.Errorf("%w: origin denied", utils.ErrForbidden)
+	// ErrCORSMethodDenied is returned by Cors with EnforceMethods
+	ErrCORSMethodDenied = fmt.Errorf("%w: cors method denied", utils.ErrForbidden)
+	// ErrIPDenied is returned by IPFilter
+	ErrIPDenied = fmt.Errorf("%w: ip denied", utils.ErrForbidden)
+	// ErrHostDenied is returned by AllowedHosts and HTTPSRedirect for
+	// hosts they don't serve
+	ErrHostDenied = fmt.Errorf("%w: host denied", utils.ErrBadRequest)
+	// ErrUserAgentDenied is returned by UserAgentFilter, wrapping
+	// utils.ErrTooManyRequests instead with UAActionTooManyRequests
+	ErrUserAgentDenied = fmt.Errorf("%w: user agent denied", utils.ErrForbidden)
+	// ErrUserAgentThrottled is returned by UserAgentFilter with
+	// UAActionTooManyRequests
+	ErrUserAgentThrottled = fmt.Errorf("%w: user agent throttled", utils.ErrTooManyRequests)
+	// ErrTenantDenied is returned by Tenant for ErrTenantForbidden
+	ErrTenantDenied = fmt.Errorf("%w: tenant denied", utils.ErrForbidden)
+	// ErrTenantNotFound is returned by Tenant for missing or unknown tenants
+	ErrTenantNotFound = fmt.Errorf("%w: tenant not found", utils.ErrNotFound)
+	// ErrUnsupportedMediaType is returned by RequireContentType
+	ErrUnsupportedMediaType = fmt.Errorf("%w: unsupported media type", utils.ErrUnsupportedMediaType)
+	// ErrTooEarly is returned by EarlyData for replayable requests
+	ErrTooEarly = fmt.Errorf("%w: early data", utils.ErrTooEarly)
+	// ErrHeadersTooLarge is returned by LimitHeaders
+	ErrHeadersTooLarge = fmt.Errorf("%w: headers too large", utils.ErrRequestHeaderFieldsTooLarge)
+	// ErrExpectationFailed is returned by ExpectContinue when Accept
+	// refuses the body
+	ErrExpectationFailed = fmt.Errorf("%w: expectation failed", utils.ErrExpectationFailed)
+	// ErrBodyTooLarge is returned by JSONGuard for too many tokens or a
+	// body over MaxBodySize, by ExpectContinue for a Content-Length over
+	// MaxBodySize and by Idempotency for a keyed body over MaxBufferSize
+	ErrBodyTooLarge = fmt.Errorf("%w: body too large", utils.ErrRequestEntityTooLarge)
+	// ErrBodyTooComplex is returned by JSONGuard for too deep nesting
+	ErrBodyTooComplex = fmt.Errorf("%w: body too complex", utils.ErrUnprocessableEntity)
+	// ErrMalformedBody is returned by JSONGuard and Sanitize for bodies
+	// that can't be parsed
+	ErrMalformedBody = fmt.Errorf("%w: malformed body", utils.ErrBadRequest)
+	// ErrInvalidParam is returned by Sanitize for parameters over
+	// MaxParamLength
+	ErrInvalidParam = fmt.Errorf("%w: invalid parameter", utils.ErrBadRequest)
+	// ErrIdempotencyKeyReused is returned by Idempotency for a key sent
+	// with a different request
+	ErrIdempotencyKeyReused = fmt.Errorf("%w: idempotency key reused", utils.ErrUnprocessableEntity)
+	// ErrDraining is returned by Drain once draining started
+	ErrDraining = fmt.Errorf("%w: draining", utils.ErrServiceUnavailable)
+	// ErrTimeout is returned by Deadline with GatewayTimeout
+	ErrTimeout = fmt.Errorf("%w: deadline exceeded", utils.ErrGatewayTimeout)
+)
+
+// OnError wraps h so fn is called with the error h returns, which the
+// engine would drop, e.g. to log or count the rejections of a middleware
+func OnError(h http.HandlerFunc, fn func(c http.Context, err error)) http.HandlerFunc {
+	return func(c http.Context) error {
+		err := h(c)
+		if err != nil {
+			fn(c, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/limiter"
+)
+
+func TestDefaultFailureErrors(t *testing.T) {
+	drain, drainer := Drain()
+	drainer.Start(time.Minute)
+	idempotency := Idempotency(ConfigIdempotency{Storage: newTestStorage()})
+	limited := limiter.New(limiter.Config{Max: 1})
+	userAgent := UserAgentFilter(ConfigUAFilter{BlockPatterns: []string{"badbot"}})
+	tenantLookup := func(err error) func(http.Context, string) (any, error) {
+		return func(http.Context, string) (any, error) { return nil, err }
+	}
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		// prepare runs before the request, e.g. to use up a limit
+		prepare func(t *testing.T)
+		request func() *stdHttp.Request
+		want    []error
+	}{
+		{
+			name:    "limiter",
+			handler: limited,
+			prepare: func(t *testing.T) { run(t, httptest.NewRequest("GET", "/", nil), limited, ok) },
+			request: func() *stdHttp.Request { return httptest.NewRequest("GET", "/", nil) },
+			want:    []error{ErrRateLimited, limiter.ErrRateLimited, utils.ErrTooManyRequests},
+		},
+		{
+			name:    "client cert",
+			handler: RequireClientCert(),
+			request: func() *stdHttp.Request { return httptest.NewRequest("GET", "/", nil) },
+			want:    []error{ErrClientCertDenied, utils.ErrForbidden},
+		},
+		{
+			name:    "origin check",
+			handler: OriginCheck(ConfigOriginCheck{TrustedOrigins: []string{"https://example.com"}}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/", nil)
+				req.Header.Set(utils.HeaderOrigin, "https://evil.com")
+				return req
+			},
+			want: []error{ErrOriginDenied, utils.ErrForbidden},
+		},
+		{
+			name:    "ip filter",
+			handler: IPFilter(ConfigIPFilter{Deny: []string{"192.0.2.0/24"}}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.0.2.1:1000"
+				return req
+			},
+			want: []error{ErrIPDenied, utils.ErrForbidden},
+		},
+		{
+			name:    "allowed hosts",
+			handler: AllowedHosts("example.com"),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Host = "evil.com"
+				return req
+			},
+			want: []error{ErrHostDenied, utils.ErrBadRequest},
+		},
+		{
+			name:    "user agent",
+			handler: userAgent,
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set(utils.HeaderUserAgent, "BadBot/1.0")
+				return req
+			},
+			want: []error{ErrUserAgentDenied, utils.ErrForbidden},
+		},
+		{
+			name: "user agent throttled",
+			handler: UserAgentFilter(ConfigUAFilter{
+				BlockPatterns: []string{"badbot"},
+				Action:        UAActionTooManyRequests,
+			}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set(utils.HeaderUserAgent, "BadBot/1.0")
+				return req
+			},
+			want: []error{ErrUserAgentThrottled, utils.ErrTooManyRequests},
+		},
+		{
+			name:    "tenant forbidden",
+			handler: Tenant(ConfigTenant{Sources: []TenantSource{TenantHeader}, Lookup: tenantLookup(ErrTenantForbidden)}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set("X-Tenant-ID", "acme")
+				return req
+			},
+			want: []error{ErrTenantDenied, utils.ErrForbidden},
+		},
+		{
+			name:    "tenant missing",
+			handler: Tenant(ConfigTenant{Sources: []TenantSource{TenantHeader}}),
+			request: func() *stdHttp.Request { return httptest.NewRequest("GET", "/", nil) },
+			want:    []error{ErrTenantNotFound, utils.ErrNotFound},
+		},
+		{
+			name:    "content type",
+			handler: RequireContentType("application/json"),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/", strings.NewReader("a=1"))
+				req.Header.Set(utils.HeaderContentType, "application/x-www-form-urlencoded")
+				return req
+			},
+			want: []error{ErrUnsupportedMediaType, utils.ErrUnsupportedMediaType},
+		},
+		{
+			name:    "early data",
+			handler: EarlyData(),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/", nil)
+				req.Header.Set(utils.HeaderEarlyData, "1")
+				return req
+			},
+			want: []error{ErrTooEarly, utils.ErrTooEarly},
+		},
+		{
+			name:    "limit headers",
+			handler: LimitHeaders(2, 0),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set("X-A", "1")
+				req.Header.Set("X-B", "2")
+				req.Header.Set("X-C", "3")
+				return req
+			},
+			want: []error{ErrHeadersTooLarge, utils.ErrRequestHeaderFieldsTooLarge},
+		},
+		{
+			name:    "expect continue",
+			handler: ExpectContinue(ConfigExpectContinue{Accept: func(http.Context) bool { return false }}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("PUT", "/", strings.NewReader("body"))
+				req.Header.Set(utils.HeaderExpect, "100-continue")
+				return req
+			},
+			want: []error{ErrExpectationFailed, utils.ErrExpectationFailed},
+		},
+		{
+			name:    "json guard depth",
+			handler: JSONGuard(ConfigJSONGuard{MaxDepth: 1}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":{"b":1}}`))
+				req.Header.Set(utils.HeaderContentType, "application/json")
+				return req
+			},
+			want: []error{ErrBodyTooComplex, utils.ErrUnprocessableEntity},
+		},
+		{
+			name:    "json guard tokens",
+			handler: JSONGuard(ConfigJSONGuard{MaxTokens: 2}),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/", strings.NewReader(`[1,2,3]`))
+				req.Header.Set(utils.HeaderContentType, "application/json")
+				return req
+			},
+			want: []error{ErrBodyTooLarge, utils.ErrRequestEntityTooLarge},
+		},
+		{
+			name:    "json guard malformed",
+			handler: JSONGuard(),
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":`))
+				req.Header.Set(utils.HeaderContentType, "application/json")
+				return req
+			},
+			want: []error{ErrMalformedBody, utils.ErrBadRequest},
+		},
+		{
+			name:    "sanitize",
+			handler: Sanitize(ConfigSanitize{MaxParamLength: 3}),
+			request: func() *stdHttp.Request { return httptest.NewRequest("GET", "/?q=toolong", nil) },
+			want:    []error{ErrInvalidParam, utils.ErrBadRequest},
+		},
+		{
+			name:    "idempotency",
+			handler: idempotency,
+			prepare: func(t *testing.T) {
+				req := httptest.NewRequest("POST", "/a", nil)
+				req.Header.Set("Idempotency-Key", "k")
+				run(t, req, idempotency, ok)
+			},
+			request: func() *stdHttp.Request {
+				req := httptest.NewRequest("POST", "/b", nil)
+				req.Header.Set("Idempotency-Key", "k")
+				return req
+			},
+			want: []error{ErrIdempotencyKeyReused, utils.ErrUnprocessableEntity},
+		},
+		{
+			name:    "drain",
+			handler: drain,
+			request: func() *stdHttp.Request { return httptest.NewRequest("GET", "/", nil) },
+			want:    []error{ErrDraining, utils.ErrServiceUnavailable},
+		},
+		{
+			name:    "csrf",
+			handler: CSRF(),
+			request: func() *stdHttp.Request { return httptest.NewRequest("POST", "/", nil) },
+			want:    []error{ErrCSRFTokenMissing, utils.ErrForbidden},
+		},
+		{
+			name:    "jwt",
+			handler: JWT(ConfigJWT{SigningKey: []byte("secret")}),
+			request: func() *stdHttp.Request { return httptest.NewRequest("GET", "/", nil) },
+			want:    []error{ErrJWTMissing, utils.ErrUnauthorized},
+		},
+		{
+			name:    "hmac",
+			handler: HMACAuth(ConfigHMAC{Secrets: [][]byte{[]byte("secret")}}),
+			request: func() *stdHttp.Request { return httptest.NewRequest("POST", "/", nil) },
+			want:    []error{ErrHMACMissing, utils.ErrUnauthorized},
+		},
+	} {
+		if tt.prepare != nil {
+			tt.prepare(t)
+		}
+		c := run(t, tt.request(), tt.handler, ok)
+		err := c.Errors()[0]
+		for _, want := range tt.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: err = %v, want errors.Is %v", tt.name, err, want)
+			}
+		}
+		if c.Body() == "ok" {
+			t.Errorf("%s: request wasn't rejected", tt.name)
+		}
+	}
+}
+
+func TestCustomFailureHandlersUnaffected(t *testing.T) {
+	errCustom := errors.New("custom")
+	c := run(t, httptest.NewRequest("GET", "/", nil), RequireClientCert(ConfigClientCert{
+		Unauthorized: func(c http.Context) error { return errCustom },
+	}), ok)
+	if err := c.Errors()[0]; err != errCustom {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	var got error
+	handler := OnError(RequireClientCert(), func(c http.Context, err error) { got = err })
+	run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if !errors.Is(got, ErrClientCertDenied) {
+		t.Errorf("OnError got %v", got)
+	}
+
+	got = nil
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "example.com"
+	run(t, req, OnError(AllowedHosts("example.com"), func(c http.Context, err error) { got = err }), ok)
+	if got != nil {
+		t.Errorf("OnError called for a passing request with %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigETag defines the config for middleware.
+type ConfigETag struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Weak indicates that a weak validator is used. Weak etags are easy
+	// to generate, but are far less useful for comparisons. Strong
+	// validators are ideal for comparisons but can be very difficult
+	// to generate efficiently.
+	//
+	// Optional. Default: false
+	Weak bool
+
+	// MaxBufferSize is the largest response body an ETag is generated for,
+	// larger responses are streamed through without one.
+	//
+	// Optional. Default: 1 MB
+	MaxBufferSize int
+}
+
+// ConfigETagDefault is the default config
+var ConfigETagDefault = ConfigETag{
+	Next:          nil,
+	Weak:          false,
+	MaxBufferSize: defaultMaxBufferSize,
+}
+
+// Helper function to set default values
+func configETagDefault(config ...ConfigETag) ConfigETag {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigETagDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.MaxBufferSize <= 0 {
+		cfg.MaxBufferSize = ConfigETagDefault.MaxBufferSize
+	}
+	return cfg
+}
+
+// ETag creates a new middleware handler
+func ETag(config ...ConfigETag) http.HandlerFunc {
+	// Set default config
+	cfg := configETagDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		rec, ok := bufferResponse(c, cfg.MaxBufferSize)
+		if !ok {
+			return c.Next()
+		}
+		if err := rec.next(c); err != nil {
+			_ = rec.flush()
+			return err
+		}
+
+		// Only successful GET and HEAD responses with a body get an ETag
+		body := rec.Body()
+		if rec.overflow || len(body) == 0 || rec.Status() != utils.StatusOK ||
+			(c.Method() != utils.MethodGet && c.Method() != utils.MethodHead) {
+			return rec.flush()
+		}
+
+		// Don't override an ETag set by the handler
+		etag := rec.Header().Get(utils.HeaderETag)
+		if etag == "" {
+			etag = generateETag(body, cfg.Weak)
+			rec.Header().Set(utils.HeaderETag, etag)
+		}
+
+		if etagMatches(c.Header(utils.HeaderIfNoneMatch, ""), etag) {
+			notModified(rec)
+		}
+		return rec.flush()
+	}
+}
+
+// generateETag hashes the body into a validator that is identical for
+// identical bodies in every process
+func generateETag(body []byte, weak bool) string {
+	sum := sha1.Sum(body)
+	etag := `"` + strconv.FormatInt(int64(len(body)), 16) + "-" + hex.EncodeToString(sum[:]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// etagMatches reports whether an If-None-Match header matches the etag
+// using the weak comparison of RFC 7232
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified turns a held back response into an empty 304
+func notModified(rec *responseRecorder) {
+	rec.status = utils.StatusNotModified
+	rec.body.Reset()
+	rec.Header().Del(utils.HeaderContentLength)
+	rec.Header().Del(utils.HeaderContentType)
+}
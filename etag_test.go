@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func TestETagMaxBufferSize(t *testing.T) {
+	handler := ETag(ConfigETag{MaxBufferSize: 10})
+	for _, tt := range []struct {
+		body     string
+		withETag bool
+	}{
+		{"under cap", true},
+		{"over the cap", false},
+	} {
+		c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+			// Write in pieces, the ones past the cap go straight out
+			w := c.(*middlewaretest.MockContext).Res
+			for _, part := range strings.SplitAfter(tt.body, " ") {
+				if _, err := w.Write([]byte(part)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if c.Body() != tt.body {
+			t.Errorf("%q: body = %q", tt.body, c.Body())
+		}
+		if got := c.Recorder.Header().Get(utils.HeaderETag) != ""; got != tt.withETag {
+			t.Errorf("%q: ETag = %q", tt.body, c.Recorder.Header().Get(utils.HeaderETag))
+		}
+	}
+}
+
+func TestETagDeterministic(t *testing.T) {
+	body := "hello world"
+	etag := func(cfg ConfigETag) string {
+		c := run(t, httptest.NewRequest("GET", "/", nil), ETag(cfg), func(c http.Context) error {
+			return c.String(body)
+		})
+		return c.Recorder.Header().Get(utils.HeaderETag)
+	}
+
+	// sha1 of the body prefixed with its length, the same in every process
+	const strong = `"b-2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"`
+	if got := etag(ConfigETag{}); got != strong || etag(ConfigETag{}) != got {
+		t.Errorf("strong ETag = %q, want %q", got, strong)
+	}
+	if got := etag(ConfigETag{Weak: true}); got != "W/"+strong {
+		t.Errorf("weak ETag = %q", got)
+	}
+}
+
+func TestETagIfNoneMatch(t *testing.T) {
+	const strong = `"b-2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"`
+	for _, tt := range []struct {
+		weak        bool
+		ifNoneMatch string
+		want        int
+	}{
+		{false, strong, utils.StatusNotModified},
+		{false, "W/" + strong, utils.StatusNotModified},
+		{true, strong, utils.StatusNotModified},
+		{true, "W/" + strong, utils.StatusNotModified},
+		{false, `"other", ` + strong, utils.StatusNotModified},
+		{false, "*", utils.StatusNotModified},
+		{false, `"other"`, utils.StatusOK},
+		{false, "", utils.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.ifNoneMatch != "" {
+			req.Header.Set(utils.HeaderIfNoneMatch, tt.ifNoneMatch)
+		}
+		c := run(t, req, ETag(ConfigETag{Weak: tt.weak}), func(c http.Context) error {
+			return c.String("hello world")
+		})
+		if c.Recorder.Code != tt.want {
+			t.Errorf("weak %v, If-None-Match %q: status = %d, want %d", tt.weak, tt.ifNoneMatch, c.Recorder.Code, tt.want)
+		}
+		if tt.want == utils.StatusNotModified && c.Body() != "" {
+			t.Errorf("304 with body %q", c.Body())
+		}
+	}
+}
+
+func TestETagKeepsHandlerETag(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderIfNoneMatch, `"v1"`)
+	c := run(t, req, ETag(), func(c http.Context) error {
+		c.SetHeader(utils.HeaderETag, `"v1"`)
+		return c.String("hello world")
+	})
+	if c.Recorder.Header().Get(utils.HeaderETag) != `"v1"` || c.Recorder.Code != utils.StatusNotModified {
+		t.Errorf("status = %d, ETag = %q", c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderETag))
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/internal/defaults"
+)
+
+// ConfigExpectContinue defines the config for middleware.
+type ConfigExpectContinue struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// MaxBodySize is the largest Content-Length accepted in bytes, 0
+	// accepts any size. Bodies of unknown length pass, they can only be
+	// measured while they are read.
+	//
+	// Optional. Default: 0
+	MaxBodySize int64
+
+	// Accept decides from the headers whether the body is wanted, e.g. by
+	// checking the credentials
+	//
+	// Optional. Default: nil
+	Accept func(c http.Context) bool
+
+	// ErrorHandler is called with ErrBodyTooLarge or ErrExpectationFailed
+	// when a request is rejected, before its body was sent
+	//
+	// Optional. Default: responds with 413 Request Entity Too Large for
+	// ErrBodyTooLarge and 417 Expectation Failed otherwise
+	ErrorHandler func(c http.Context, err error) error
+}
+
+// ConfigExpectContinueDefault is the default config
+var ConfigExpectContinueDefault = ConfigExpectContinue{
+	Next: nil,
+	ErrorHandler: func(c http.Context, err error) error {
+		if errors.Is(err, ErrBodyTooLarge) {
+			c.AbortWithStatus(utils.StatusRequestEntityTooLarge)
+			return err
+		}
+		c.AbortWithStatus(utils.StatusExpectationFailed)
+		return err
+	},
+}
+
+// Helper function to set default values
+func configExpectContinueDefault(config ...ConfigExpectContinue) ConfigExpectContinue {
+	return defaults.Config(config, ConfigExpectContinueDefault, func(cfg *ConfigExpectContinue) {
+		if cfg.ErrorHandler == nil {
+			cfg.ErrorHandler = ConfigExpectContinueDefault.ErrorHandler
+		}
+	})
+}
+
+// ExpectContinue creates a new middleware handler answering requests sent
+// with "Expect: 100-continue" before their body is uploaded. The server
+// only sends "100 Continue" once the body is read, so rejecting a request
+// here spares the client the upload. Requests without the header pass.
+func ExpectContinue(config ...ConfigExpectContinue) http.HandlerFunc {
+	// Set default config
+	cfg := configExpectContinueDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if !strings.EqualFold(c.Header(utils.HeaderExpect, ""), "100-continue") {
+			return c.Next()
+		}
+		if size := c.Origin().ContentLength; cfg.MaxBodySize > 0 && size > cfg.MaxBodySize {
+			return cfg.ErrorHandler(c, fmt.Errorf("%w: %d bytes over %d", ErrBodyTooLarge, size, cfg.MaxBodySize))
+		}
+		if cfg.Accept != nil && !cfg.Accept(c) {
+			return cfg.ErrorHandler(c, ErrExpectationFailed)
+		}
+		return c.Next()
+	}
+}
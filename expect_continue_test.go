@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// trackedBody reports whether anyone read from it
+type trackedBody struct {
+	io.Reader
+	read bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	b.read = true
+	return b.Reader.Read(p)
+}
+
+func TestExpectContinue(t *testing.T) {
+	handler := ExpectContinue(ConfigExpectContinue{
+		MaxBodySize: 10,
+		Accept: func(c http.Context) bool {
+			return c.Header(utils.HeaderAuthorization, "") != ""
+		},
+	})
+	for _, tt := range []struct {
+		name          string
+		expect, auth  string
+		body          string
+		contentLength int64
+		status        int
+		err           error
+	}{
+		{"valid", "100-continue", "Bearer t", "0123456789", 10, utils.StatusOK, nil},
+		{"case insensitive", "100-Continue", "Bearer t", "0123", 4, utils.StatusOK, nil},
+		{"over the limit", "100-continue", "Bearer t", "0123456789x", 11, utils.StatusRequestEntityTooLarge, ErrBodyTooLarge},
+		{"not accepted", "100-continue", "", "0123", 4, utils.StatusExpectationFailed, ErrExpectationFailed},
+		// The size is only known while reading, the handler has to check it
+		{"unknown length", "100-continue", "Bearer t", "0123456789x", -1, utils.StatusOK, nil},
+		{"without Expect", "", "", "0123456789x", 11, utils.StatusOK, nil},
+	} {
+		body := &trackedBody{Reader: strings.NewReader(tt.body)}
+		req := httptest.NewRequest("PUT", "/upload", body)
+		req.ContentLength = tt.contentLength
+		if tt.expect != "" {
+			req.Header.Set(utils.HeaderExpect, tt.expect)
+		}
+		if tt.auth != "" {
+			req.Header.Set(utils.HeaderAuthorization, tt.auth)
+		}
+		c := run(t, req, handler, echoBody)
+		if c.Recorder.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.status)
+		}
+		if tt.err == nil {
+			if c.Body() != tt.body {
+				t.Errorf("%s: body = %q", tt.name, c.Body())
+			}
+			continue
+		}
+		if !errors.Is(c.Errors()[0], tt.err) || body.read {
+			t.Errorf("%s: err = %v, body read = %v", tt.name, c.Errors()[0], body.read)
+		}
+	}
+}
+
+func TestExpectContinueErrorHandler(t *testing.T) {
+	var got error
+	handler := ExpectContinue(ConfigExpectContinue{
+		MaxBodySize: 1,
+		ErrorHandler: func(c http.Context, err error) error {
+			got = err
+			return c.Status(utils.StatusRequestEntityTooLarge).String("upload at most 1 byte")
+		},
+	})
+	req := httptest.NewRequest("POST", "/", strings.NewReader("12"))
+	req.Header.Set(utils.HeaderExpect, "100-continue")
+	c := run(t, req, handler, echoBody)
+	if !errors.Is(got, ErrBodyTooLarge) || c.Body() != "upload at most 1 byte" {
+		t.Errorf("err = %v, body = %q", got, c.Body())
+	}
+}
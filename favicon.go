@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+const (
+	faviconAllow   = "GET, HEAD"
+	faviconMaxSize = 1 << 20
+)
+
+// ConfigFavicon defines the config for middleware.
+type ConfigFavicon struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// File holds the path to an actual favicon that will be cached
+	//
+	// Optional. Default: ""
+	File string
+
+	// Data holds the favicon itself, it takes precedence over File
+	//
+	// Optional. Default: nil
+	Data []byte
+
+	// URL for favicon handler
+	//
+	// Optional. Default: "/favicon.ico"
+	URL string
+
+	// ContentType of the favicon
+	//
+	// Optional. Default: guessed from File, "image/x-icon" otherwise
+	ContentType string
+
+	// CacheControl defines how the Cache-Control header in the response should be set
+	//
+	// Optional. Default: "public, max-age=31536000"
+	CacheControl string
+}
+
+// ConfigFaviconDefault is the default config
+var ConfigFaviconDefault = ConfigFavicon{
+	Next:         nil,
+	URL:          "/favicon.ico",
+	ContentType:  "image/x-icon",
+	CacheControl: "public, max-age=31536000",
+}
+
+// Helper function to set default values
+func configFaviconDefault(config ...ConfigFavicon) ConfigFavicon {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigFaviconDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.URL == "" {
+		cfg.URL = ConfigFaviconDefault.URL
+	}
+	if cfg.ContentType == "" && cfg.File != "" {
+		cfg.ContentType = mime.TypeByExtension(filepath.Ext(cfg.File))
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = ConfigFaviconDefault.ContentType
+	}
+	if cfg.CacheControl == "" {
+		cfg.CacheControl = ConfigFaviconDefault.CacheControl
+	}
+	return cfg
+}
+
+// Favicon creates a new middleware handler
+func Favicon(config ...ConfigFavicon) http.HandlerFunc {
+	// Set default config
+	cfg := configFaviconDefault(config...)
+
+	// Load the icon once, a misconfigured icon is a startup error
+	icon := cfg.Data
+	if icon == nil && cfg.File != "" {
+		info, err := os.Stat(cfg.File)
+		if err != nil {
+			panic(fmt.Errorf("favicon: %w", err))
+		}
+		if info.Size() > faviconMaxSize {
+			panic(fmt.Errorf("favicon: %s is larger than %d bytes", cfg.File, faviconMaxSize))
+		}
+		if icon, err = os.ReadFile(cfg.File); err != nil {
+			panic(fmt.Errorf("favicon: %w", err))
+		}
+	}
+	if len(icon) > faviconMaxSize {
+		panic(fmt.Errorf("favicon: data is larger than %d bytes", faviconMaxSize))
+	}
+	iconLen := strconv.Itoa(len(icon))
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only respond to favicon requests
+		if c.Origin().URL.Path != cfg.URL {
+			return c.Next()
+		}
+
+		// Only allow GET and HEAD methods
+		if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+			c.SetHeader(utils.HeaderAllow, faviconAllow)
+			c.AbortWithStatus(utils.StatusMethodNotAllowed)
+			return utils.ErrMethodNotAllowed
+		}
+
+		// Nothing to serve
+		if len(icon) == 0 {
+			c.AbortWithStatus(utils.StatusNoContent)
+			return nil
+		}
+
+		c.SetHeader(utils.HeaderContentType, cfg.ContentType)
+		c.SetHeader(utils.HeaderContentLength, iconLen)
+		c.SetHeader(utils.HeaderCacheControl, cfg.CacheControl)
+		c.Status(utils.StatusOK)
+		if c.Method() == utils.MethodHead {
+			return nil
+		}
+		return c.String("%s", icon)
+	}
+}
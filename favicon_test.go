@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestFaviconFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.png")
+	if err := os.WriteFile(path, []byte("\x89PNG icon"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	handler := Favicon(ConfigFavicon{File: path})
+
+	c := run(t, httptest.NewRequest("GET", "/favicon.ico", nil), handler, ok)
+	h := c.Recorder.Header()
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "\x89PNG icon" {
+		t.Errorf("status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+	if h.Get(utils.HeaderContentType) != "image/png" || h.Get(utils.HeaderContentLength) != "9" ||
+		h.Get(utils.HeaderCacheControl) != "public, max-age=31536000" {
+		t.Errorf("headers = %v", h)
+	}
+
+	// The file was read once, at construction
+	_ = os.Remove(path)
+	c = run(t, httptest.NewRequest("HEAD", "/favicon.ico", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "" || c.Recorder.Header().Get(utils.HeaderContentLength) != "9" {
+		t.Errorf("HEAD: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+
+	if c := run(t, httptest.NewRequest("GET", "/other", nil), handler, ok); c.Body() != "ok" {
+		t.Errorf("other path = %q", c.Body())
+	}
+}
+
+func TestFaviconData(t *testing.T) {
+	handler := Favicon(ConfigFavicon{Data: []byte("<svg/>"), URL: "/icon.svg", ContentType: "image/svg+xml"})
+	c := run(t, httptest.NewRequest("GET", "/icon.svg", nil), handler, ok)
+	if c.Body() != "<svg/>" || c.Recorder.Header().Get(utils.HeaderContentType) != "image/svg+xml" {
+		t.Errorf("body = %q, headers = %v", c.Body(), c.Recorder.Header())
+	}
+}
+
+func TestFaviconEmpty(t *testing.T) {
+	c := run(t, httptest.NewRequest("GET", "/favicon.ico", nil), Favicon(), ok)
+	if c.Recorder.Code != utils.StatusNoContent || c.Body() != "" {
+		t.Errorf("status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
+
+func TestFaviconWrongMethod(t *testing.T) {
+	c := run(t, httptest.NewRequest("POST", "/favicon.ico", nil), Favicon(ConfigFavicon{Data: []byte("icon")}), ok)
+	if c.Recorder.Code != utils.StatusMethodNotAllowed || c.Recorder.Header().Get(utils.HeaderAllow) != "GET, HEAD" {
+		t.Errorf("status = %d, Allow = %q", c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderAllow))
+	}
+}
+
+func TestFaviconInvalidFile(t *testing.T) {
+	large := filepath.Join(t.TempDir(), "large.ico")
+	if err := os.WriteFile(large, []byte(strings.Repeat("x", faviconMaxSize+1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []ConfigFavicon{
+		{File: filepath.Join(t.TempDir(), "missing.ico")},
+		{File: large},
+		{Data: make([]byte, faviconMaxSize+1)},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Favicon(%q) didn't panic", cfg.File)
+				}
+			}()
+			Favicon(cfg)
+		}()
+	}
+}
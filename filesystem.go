@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	stdHttp "net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigFilesystem defines the config for middleware.
+type ConfigFilesystem struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Root is the file system the files are served from, an embed.FS or
+	// os.DirFS for example
+	//
+	// Required
+	Root fs.FS
+
+	// PathPrefix is stripped from the request path before looking up the
+	// file, requests outside of it continue the stack
+	//
+	// Optional. Default: ""
+	PathPrefix string
+
+	// Index is served for requests of a directory
+	//
+	// Optional. Default: "index.html"
+	Index string
+
+	// Browse lists the files of directories without an Index
+	//
+	// Optional. Default: false
+	Browse bool
+
+	// NotFoundFile is served for paths that don't exist, e.g. the
+	// index.html of a single page application. Without it missing files
+	// continue the stack.
+	//
+	// Optional. Default: ""
+	NotFoundFile string
+
+	// MaxAge sets the max-age of the Cache-Control header in seconds
+	//
+	// Optional. Default: 0
+	MaxAge int
+}
+
+// ConfigFilesystemDefault is the default config
+var ConfigFilesystemDefault = ConfigFilesystem{
+	Next:  nil,
+	Index: "index.html",
+}
+
+// Helper function to set default values
+func configFilesystemDefault(config ...ConfigFilesystem) ConfigFilesystem {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigFilesystemDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Index == "" {
+		cfg.Index = ConfigFilesystemDefault.Index
+	}
+	cfg.PathPrefix = strings.TrimRight(cfg.PathPrefix, "/")
+	cfg.NotFoundFile = strings.TrimPrefix(cfg.NotFoundFile, "/")
+	return cfg
+}
+
+// Filesystem creates a new middleware handler
+func Filesystem(config ConfigFilesystem) http.HandlerFunc {
+	// Set default config
+	cfg := configFilesystemDefault(config)
+
+	if cfg.Root == nil {
+		panic("filesystem: Root is required")
+	}
+	cacheControl := ""
+	if cfg.MaxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", cfg.MaxAge)
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only serve GET and HEAD requests
+		if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+			return c.Next()
+		}
+
+		// Only serve requests under the prefix
+		reqPath := c.Origin().URL.Path
+		if cfg.PathPrefix != "" {
+			if reqPath != cfg.PathPrefix && !strings.HasPrefix(reqPath, cfg.PathPrefix+"/") {
+				return c.Next()
+			}
+			reqPath = strings.TrimPrefix(reqPath, cfg.PathPrefix)
+		}
+
+		// Refuse any attempt to climb out of the root
+		for _, segment := range strings.Split(reqPath, "/") {
+			if segment == ".." {
+				c.AbortWithStatus(utils.StatusBadRequest)
+				return utils.ErrBadRequest
+			}
+		}
+		name := strings.TrimPrefix(path.Clean("/"+reqPath), "/")
+		if name == "" {
+			name = "."
+		}
+		if !fs.ValidPath(name) {
+			c.AbortWithStatus(utils.StatusBadRequest)
+			return utils.ErrBadRequest
+		}
+
+		info, err := fs.Stat(cfg.Root, name)
+		if err == nil && info.IsDir() {
+			index := path.Join(name, cfg.Index)
+			if indexInfo, indexErr := fs.Stat(cfg.Root, index); indexErr == nil && !indexInfo.IsDir() {
+				name, info = index, indexInfo
+			} else if cfg.Browse {
+				return browseDirectory(c, cfg.Root, name)
+			} else {
+				err = fs.ErrNotExist
+			}
+		}
+		if err != nil {
+			if cfg.NotFoundFile == "" {
+				return c.Next()
+			}
+			name = cfg.NotFoundFile
+			if info, err = fs.Stat(cfg.Root, name); err != nil || info.IsDir() {
+				c.AbortWithStatus(utils.StatusNotFound)
+				return utils.ErrNotFound
+			}
+		}
+
+		if cacheControl != "" {
+			c.SetHeader(utils.HeaderCacheControl, cacheControl)
+		}
+		return serveFile(c, cfg.Root, name, info)
+	}
+}
+
+// serveFile writes the file with http.ServeContent, which takes care of
+// the Content-Type, conditional requests and HEAD
+func serveFile(c http.Context, root fs.FS, name string, info fs.FileInfo) error {
+	w, ok := responseWriter(c)
+	if !ok {
+		c.AbortWithStatus(utils.StatusInternalServerError)
+		return utils.ErrInternalServerError
+	}
+	f, err := root.Open(name)
+	if err != nil {
+		c.AbortWithStatus(utils.StatusNotFound)
+		return utils.ErrNotFound
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		raw, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(raw)
+	}
+	stdHttp.ServeContent(w, c.Origin(), path.Base(name), info.ModTime(), content)
+	return nil
+}
+
+// browseDirectory writes an HTML listing of the directory
+func browseDirectory(c http.Context, root fs.FS, name string) error {
+	entries, err := fs.ReadDir(root, name)
+	if err != nil {
+		c.AbortWithStatus(utils.StatusNotFound)
+		return utils.ErrNotFound
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	base := c.Origin().URL.Path
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	var b strings.Builder
+	title := html.EscapeString(base)
+	b.WriteString("<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>" + title + "</title></head><body><h1>" + title + "</h1><ul>")
+	if name != "." {
+		b.WriteString(`<li><a href="../">../</a></li>`)
+	}
+	for _, entry := range entries {
+		label := entry.Name()
+		if entry.IsDir() {
+			label += "/"
+		}
+		href := (&url.URL{Path: label}).EscapedPath()
+		b.WriteString(`<li><a href="` + html.EscapeString(base+href) + `">` + html.EscapeString(label) + "</a></li>")
+	}
+	b.WriteString("</ul></body></html>")
+
+	c.SetHeader(utils.HeaderContentType, "text/html; charset=utf-8")
+	c.Status(utils.StatusOK)
+	if c.Method() == utils.MethodHead {
+		return nil
+	}
+	return c.String("%s", b.String())
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var fsModTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>home</h1>"), ModTime: fsModTime},
+		"app.js":           {Data: []byte("console.log(1)"), ModTime: fsModTime},
+		"css/site.css":     {Data: []byte("body{}"), ModTime: fsModTime},
+		"docs/a b.txt":     {Data: []byte("a"), ModTime: fsModTime},
+		"docs/sub/x.txt":   {Data: []byte("x"), ModTime: fsModTime},
+		"docs/<script>.md": {Data: []byte("s"), ModTime: fsModTime},
+	}
+}
+
+func TestFilesystemServesFiles(t *testing.T) {
+	handler := Filesystem(ConfigFilesystem{Root: testFS(), MaxAge: 60})
+	for _, tt := range []struct {
+		path, body, contentType string
+	}{
+		{"/", "<h1>home</h1>", "text/html; charset=utf-8"},
+		{"/index.html", "<h1>home</h1>", "text/html; charset=utf-8"},
+		{"/app.js", "console.log(1)", "text/javascript; charset=utf-8"},
+		{"/css/site.css", "body{}", "text/css; charset=utf-8"},
+	} {
+		c := run(t, httptest.NewRequest("GET", tt.path, nil), handler, ok)
+		h := c.Recorder.Header()
+		if c.Body() != tt.body || h.Get(utils.HeaderContentType) != tt.contentType {
+			t.Errorf("%s: body = %q, Content-Type = %q", tt.path, c.Body(), h.Get(utils.HeaderContentType))
+		}
+		if h.Get(utils.HeaderCacheControl) != "public, max-age=60" {
+			t.Errorf("%s: Cache-Control = %q", tt.path, h.Get(utils.HeaderCacheControl))
+		}
+		if h.Get(utils.HeaderLastModified) != fsModTime.Format(stdHttp.TimeFormat) {
+			t.Errorf("%s: Last-Modified = %q", tt.path, h.Get(utils.HeaderLastModified))
+		}
+	}
+}
+
+func TestFilesystemTraversal(t *testing.T) {
+	handler := Filesystem(ConfigFilesystem{Root: testFS(), NotFoundFile: "index.html"})
+	for _, target := range []string{"/../secret", "/css/../../secret", "/%2e%2e/secret", "/css/%2E%2E/app.js"} {
+		c := run(t, httptest.NewRequest("GET", target, nil), handler, ok)
+		if c.Recorder.Code != utils.StatusBadRequest || !errors.Is(c.Errors()[0], utils.ErrBadRequest) {
+			t.Errorf("%s: status = %d, err = %v", target, c.Recorder.Code, c.Errors()[0])
+		}
+		if c.Body() == "ok" || strings.Contains(c.Body(), "home") {
+			t.Errorf("%s: body = %q", target, c.Body())
+		}
+	}
+}
+
+func TestFilesystemConditionalAndHead(t *testing.T) {
+	handler := Filesystem(ConfigFilesystem{Root: testFS()})
+
+	req := httptest.NewRequest("GET", "/app.js", nil)
+	req.Header.Set(utils.HeaderIfModifiedSince, fsModTime.Format(stdHttp.TimeFormat))
+	c := run(t, req, handler, ok)
+	if c.Recorder.Code != utils.StatusNotModified || c.Body() != "" {
+		t.Errorf("If-Modified-Since: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+
+	req = httptest.NewRequest("GET", "/app.js", nil)
+	req.Header.Set(utils.HeaderIfModifiedSince, fsModTime.Add(-time.Hour).Format(stdHttp.TimeFormat))
+	c = run(t, req, handler, ok)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "console.log(1)" {
+		t.Errorf("modified since: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+
+	c = run(t, httptest.NewRequest("HEAD", "/app.js", nil), handler, ok)
+	h := c.Recorder.Header()
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "" || h.Get(utils.HeaderContentLength) != "14" {
+		t.Errorf("HEAD: status = %d, body = %q, Content-Length = %q", c.Recorder.Code, c.Body(), h.Get(utils.HeaderContentLength))
+	}
+
+	// Other methods continue the stack
+	c = run(t, httptest.NewRequest("POST", "/app.js", nil), handler, ok)
+	if c.Body() != "ok" {
+		t.Errorf("POST: body = %q", c.Body())
+	}
+}
+
+func TestFilesystemNotFound(t *testing.T) {
+	// Without a NotFoundFile missing files and directories continue the stack
+	c := run(t, httptest.NewRequest("GET", "/missing.js", nil), Filesystem(ConfigFilesystem{Root: testFS()}), ok)
+	if c.Body() != "ok" {
+		t.Errorf("missing file: body = %q", c.Body())
+	}
+	c = run(t, httptest.NewRequest("GET", "/docs/", nil), Filesystem(ConfigFilesystem{Root: testFS()}), ok)
+	if c.Body() != "ok" {
+		t.Errorf("directory without index: body = %q", c.Body())
+	}
+
+	// A single page application gets its index.html for client routes
+	spa := Filesystem(ConfigFilesystem{Root: testFS(), NotFoundFile: "/index.html"})
+	c = run(t, httptest.NewRequest("GET", "/users/42", nil), spa, ok)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "<h1>home</h1>" {
+		t.Errorf("SPA fallback: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+
+	// A NotFoundFile that doesn't exist is a 404
+	c = run(t, httptest.NewRequest("GET", "/users/42", nil), Filesystem(ConfigFilesystem{Root: testFS(), NotFoundFile: "404.html"}), ok)
+	if c.Recorder.Code != utils.StatusNotFound || !errors.Is(c.Errors()[0], utils.ErrNotFound) {
+		t.Errorf("missing NotFoundFile: status = %d, err = %v", c.Recorder.Code, c.Errors()[0])
+	}
+}
+
+func TestFilesystemPathPrefix(t *testing.T) {
+	handler := Filesystem(ConfigFilesystem{Root: testFS(), PathPrefix: "/static/"})
+	c := run(t, httptest.NewRequest("GET", "/static/app.js", nil), handler, ok)
+	if c.Body() != "console.log(1)" {
+		t.Errorf("prefixed file: body = %q", c.Body())
+	}
+	for _, target := range []string{"/app.js", "/staticfoo/app.js"} {
+		c = run(t, httptest.NewRequest("GET", target, nil), handler, ok)
+		if c.Body() != "ok" {
+			t.Errorf("%s: body = %q, want the stack to continue", target, c.Body())
+		}
+	}
+}
+
+func TestFilesystemBrowse(t *testing.T) {
+	handler := Filesystem(ConfigFilesystem{Root: testFS(), Browse: true})
+	c := run(t, httptest.NewRequest("GET", "/docs", nil), handler, ok)
+	body := c.Body()
+	if c.Recorder.Header().Get(utils.HeaderContentType) != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", c.Recorder.Header().Get(utils.HeaderContentType))
+	}
+	for _, want := range []string{
+		`<a href="../">../</a>`,
+		`<a href="/docs/sub/">sub/</a>`,
+		`<a href="/docs/a%20b.txt">a b.txt</a>`,
+		`&lt;script&gt;.md`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing misses %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("file name not escaped:\n%s", body)
+	}
+	// Directories are listed first
+	if strings.Index(body, "sub/") > strings.Index(body, "a b.txt") {
+		t.Errorf("directories not listed first:\n%s", body)
+	}
+
+	// The root has no parent link, and directories with an index serve it
+	c = run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if c.Body() != "<h1>home</h1>" {
+		t.Errorf("root: body = %q", c.Body())
+	}
+	c = run(t, httptest.NewRequest("HEAD", "/css/", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusOK || c.Body() != "" {
+		t.Errorf("HEAD listing: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
@@ -1,8 +1,10 @@
 module github.com/sujit-baniya/middleware
 
-go 1.19
+go 1.22
 
 require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/klauspost/compress v1.18.0
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/phuslu/log v1.0.83
 	github.com/sujit-baniya/framework v1.0.17
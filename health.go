@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// Checker reports whether a dependency is ready to serve traffic
+type Checker func(ctx context.Context) error
+
+// ConfigHealth defines the config for middleware.
+type ConfigHealth struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// LivenessPath answers 200 as long as the process serves requests
+	//
+	// Optional. Default: "/healthz"
+	LivenessPath string
+
+	// ReadinessPath runs the registered checkers
+	//
+	// Optional. Default: "/readyz"
+	ReadinessPath string
+
+	// Timeout bounds each checker, a checker still running is reported failed
+	//
+	// Optional. Default: 5 * time.Second
+	Timeout time.Duration
+
+	// Detailed adds a JSON breakdown of every check to the readiness response
+	//
+	// Optional. Default: false
+	Detailed bool
+}
+
+// ConfigHealthDefault is the default config
+var ConfigHealthDefault = ConfigHealth{
+	Next:          nil,
+	LivenessPath:  "/healthz",
+	ReadinessPath: "/readyz",
+	Timeout:       5 * time.Second,
+}
+
+// Helper function to set default values
+func configHealthDefault(config ...ConfigHealth) ConfigHealth {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigHealthDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.LivenessPath == "" {
+		cfg.LivenessPath = ConfigHealthDefault.LivenessPath
+	}
+	if cfg.ReadinessPath == "" {
+		cfg.ReadinessPath = ConfigHealthDefault.ReadinessPath
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = ConfigHealthDefault.Timeout
+	}
+	return cfg
+}
+
+// HealthChecks holds the readiness checkers of a HealthCheck middleware
+type HealthChecks struct {
+	mu       sync.RWMutex
+	checkers map[string]Checker
+}
+
+// Register adds a readiness checker, registering a name again replaces it
+func (h *HealthChecks) Register(name string, check Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers[name] = check
+}
+
+// Unregister removes a readiness checker
+func (h *HealthChecks) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.checkers, name)
+}
+
+// healthResult is the outcome of one checker
+type healthResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// run executes the checkers concurrently, each bound by timeout
+func (h *HealthChecks) run(ctx context.Context, timeout time.Duration) (map[string]healthResult, bool) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checkers))
+	for name := range h.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Checker, len(names))
+	for i, name := range names {
+		checks[i] = h.checkers[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]healthResult, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runChecker(ctx, checks[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	ready := true
+	byName := make(map[string]healthResult, len(names))
+	for i, name := range names {
+		byName[name] = results[i]
+		if results[i].Error != "" {
+			ready = false
+		}
+	}
+	return byName, ready
+}
+
+// runChecker doesn't wait for a checker ignoring its context past the timeout
+func runChecker(ctx context.Context, check Checker, timeout time.Duration) healthResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.New("health: checker panicked")
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := healthResult{Status: "ok", Latency: time.Since(start).String()}
+	if err != nil {
+		res.Status = "fail"
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// HealthCheck creates a new middleware handler, it should be registered
+// before auth, limiters and logging so probes skip them
+func HealthCheck(config ConfigHealth) (http.HandlerFunc, *HealthChecks) {
+	// Set default config
+	cfg := configHealthDefault(config)
+
+	checks := &HealthChecks{checkers: make(map[string]Checker)}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		path := c.Origin().URL.Path
+		if path != cfg.LivenessPath && path != cfg.ReadinessPath {
+			return c.Next()
+		}
+		if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+			c.SetHeader(utils.HeaderAllow, "GET, HEAD")
+			c.AbortWithStatus(utils.StatusMethodNotAllowed)
+			return utils.ErrMethodNotAllowed
+		}
+		c.SetHeader(utils.HeaderCacheControl, "no-store")
+
+		if path == cfg.LivenessPath {
+			c.AbortWithStatus(utils.StatusOK)
+			return nil
+		}
+
+		results, ready := checks.run(c.Origin().Context(), cfg.Timeout)
+		status := utils.StatusOK
+		if !ready {
+			status = utils.StatusServiceUnavailable
+		}
+		if !cfg.Detailed || c.Method() == utils.MethodHead {
+			c.AbortWithStatus(status)
+			return nil
+		}
+		body, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		c.SetHeader(utils.HeaderContentType, "application/json")
+		c.Status(status)
+		return c.String("%s", body)
+	}, checks
+}
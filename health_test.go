@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestHealthLiveness(t *testing.T) {
+	handler, checks := HealthCheck(ConfigHealth{})
+	checks.Register("db", func(context.Context) error { return errors.New("down") })
+
+	c := run(t, httptest.NewRequest("GET", "/healthz", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusOK || c.Body() == "ok" {
+		t.Errorf("status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+	if c := run(t, httptest.NewRequest("GET", "/api", nil), handler, ok); c.Body() != "ok" {
+		t.Errorf("other path didn't pass through")
+	}
+	if c := run(t, httptest.NewRequest("POST", "/healthz", nil), handler, ok); c.Recorder.Code != utils.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d", c.Recorder.Code)
+	}
+}
+
+func TestHealthReadiness(t *testing.T) {
+	handler, checks := HealthCheck(ConfigHealth{Timeout: 50 * time.Millisecond, Detailed: true})
+	readiness := func() (int, map[string]healthResult) {
+		c := run(t, httptest.NewRequest("GET", "/readyz", nil), handler, ok)
+		var results map[string]healthResult
+		if err := json.Unmarshal(c.Recorder.Body.Bytes(), &results); err != nil {
+			t.Fatalf("body %q: %v", c.Body(), err)
+		}
+		return c.Recorder.Code, results
+	}
+
+	checks.Register("db", func(context.Context) error { return nil })
+	checks.Register("cache", func(context.Context) error { return nil })
+	if status, results := readiness(); status != utils.StatusOK || len(results) != 2 || results["db"].Status != "ok" {
+		t.Errorf("passing: %d %+v", status, results)
+	}
+
+	checks.Register("queue", func(context.Context) error { return errors.New("queue unreachable") })
+	status, results := readiness()
+	if status != utils.StatusServiceUnavailable || results["queue"].Status != "fail" ||
+		results["queue"].Error != "queue unreachable" || results["db"].Status != "ok" {
+		t.Errorf("failing: %d %+v", status, results)
+	}
+	checks.Unregister("queue")
+
+	// A checker ignoring its context is reported failed after the timeout,
+	// the checks run concurrently so two slow ones take a single timeout
+	block := make(chan struct{})
+	defer close(block)
+	slow := func(context.Context) error { <-block; return nil }
+	checks.Register("slow1", slow)
+	checks.Register("slow2", slow)
+	start := time.Now()
+	status, results = readiness()
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("readiness took %v", elapsed)
+	}
+	if status != utils.StatusServiceUnavailable || results["slow1"].Error != context.DeadlineExceeded.Error() ||
+		results["slow2"].Status != "fail" {
+		t.Errorf("timing out: %d %+v", status, results)
+	}
+}
+
+func TestHealthReadinessWithoutDetails(t *testing.T) {
+	handler, checks := HealthCheck(ConfigHealth{})
+	checks.Register("broken", func(context.Context) error { panic("boom") })
+	c := run(t, httptest.NewRequest("GET", "/readyz", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusServiceUnavailable || c.Body() != "" {
+		t.Errorf("status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// run runs the handlers the way the engine does and returns the context of
+// the first one, its Errors hold what each handler returned
+func run(t *testing.T, req *stdHttp.Request, handlers ...http.HandlerFunc) *middlewaretest.MockContext {
+	t.Helper()
+	c := middlewaretest.NewMockContext(req, handlers...)
+	_ = c.Run()
+	return c
+}
+
+// ok is the final handler of the tests
+func ok(c http.Context) error {
+	return c.String("ok")
+}
+
+// testStorage is a storage.Storage with a clock the tests move forward
+type testStorage struct {
+	mu      sync.Mutex
+	now     time.Time
+	entries map[string]testEntry
+	sets    int
+}
+
+type testEntry struct {
+	val []byte
+	exp time.Time
+}
+
+func newTestStorage() *testStorage {
+	return &testStorage{now: time.Unix(1700000000, 0), entries: make(map[string]testEntry)}
+}
+
+// advance moves the clock of the storage forward
+func (s *testStorage) advance(d time.Duration) {
+	s.mu.Lock()
+	s.now = s.now.Add(d)
+	s.mu.Unlock()
+}
+
+func (s *testStorage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || (!e.exp.IsZero() && !s.now.Before(e.exp)) {
+		return nil, nil
+	}
+	return e.val, nil
+}
+
+func (s *testStorage) Set(key string, val []byte, exp time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := testEntry{val: append([]byte(nil), val...)}
+	if exp > 0 {
+		e.exp = s.now.Add(exp)
+	}
+	s.entries[key] = e
+	s.sets++
+	return nil
+}
+
+func (s *testStorage) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *testStorage) Reset() error {
+	s.mu.Lock()
+	s.entries = make(map[string]testEntry)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *testStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var (
+	// ErrHMACMissing is returned when the request carries no signature
+	ErrHMACMissing = fmt.Errorf("hmac: missing signature: %w", utils.ErrUnauthorized)
+	// ErrHMACMalformed is returned when the signature header can't be parsed
+	ErrHMACMalformed = fmt.Errorf("hmac: malformed signature: %w", utils.ErrUnauthorized)
+	// ErrHMACInvalidSignature is returned when no secret produces the signature
+	ErrHMACInvalidSignature = fmt.Errorf("hmac: invalid signature: %w", utils.ErrUnauthorized)
+	// ErrHMACExpired is returned when the timestamp is outside the tolerance
+	ErrHMACExpired = fmt.Errorf("hmac: timestamp outside tolerance: %w", utils.ErrUnauthorized)
+	// ErrHMACBodyTooLarge is returned when the body exceeds MaxBodySize
+	ErrHMACBodyTooLarge = fmt.Errorf("hmac: body too large: %w", utils.ErrRequestEntityTooLarge)
+)
+
+// HMACFormat is the layout of the signature header
+type HMACFormat int
+
+const (
+	// HMACFormatHex is a hex digest, optionally prefixed like sha256=<hex>
+	HMACFormatHex HMACFormat = iota
+	// HMACFormatTimestamped is t=<unix>,v1=<hex> with the digest computed
+	// over "<unix>.<body>"
+	HMACFormatTimestamped
+)
+
+// ConfigHMAC defines the config for middleware.
+type ConfigHMAC struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Header carrying the signature
+	//
+	// Optional. Default: "X-Signature"
+	Header string
+
+	// Format of the signature header
+	//
+	// Optional. Default: HMACFormatHex
+	Format HMACFormat
+
+	// Prefix is stripped from hex signatures
+	//
+	// Optional. Default: "sha256="
+	Prefix string
+
+	// Version is the key of the signatures in timestamped headers, a
+	// header may carry several of them
+	//
+	// Optional. Default: "v1"
+	Version string
+
+	// Secrets the signature is checked against, list the new secret next
+	// to the old one while rotating
+	//
+	// Required
+	Secrets [][]byte
+
+	// Hash used for the HMAC
+	//
+	// Optional. Default: sha256.New
+	Hash func() hash.Hash
+
+	// MaxBodySize is the largest body that is verified
+	//
+	// Optional. Default: 1 MB
+	MaxBodySize int
+
+	// TimestampHeader carries the unix timestamp of hex signatures, it is
+	// then required and signed as "<unix>.<body>" like timestamped ones
+	//
+	// Optional. Default: ""
+	TimestampHeader string
+
+	// Tolerance is how far the timestamp of timestamped signatures may be
+	// from now, in either direction to allow for clock skew, -1 disables
+	// the check
+	//
+	// Optional. Default: 5 * time.Minute
+	Tolerance time.Duration
+
+	// ErrorHandler is called with one of the ErrHMAC errors
+	//
+	// Optional. Default: responds with 413 for large bodies and 401 otherwise
+	ErrorHandler func(c http.Context, err error) error
+}
+
+// ConfigHMACDefault is the default config
+var ConfigHMACDefault = ConfigHMAC{
+	Next:        nil,
+	Header:      "X-Signature",
+	Format:      HMACFormatHex,
+	Prefix:      "sha256=",
+	Version:     "v1",
+	Hash:        sha256.New,
+	MaxBodySize: defaultMaxBufferSize,
+	Tolerance:   5 * time.Minute,
+	ErrorHandler: func(c http.Context, err error) error {
+		if errors.Is(err, ErrHMACBodyTooLarge) {
+			c.AbortWithStatus(utils.StatusRequestEntityTooLarge)
+			return err
+		}
+		c.AbortWithStatus(utils.StatusUnauthorized)
+		return err
+	},
+}
+
+// Helper function to set default values
+func configHMACDefault(config ...ConfigHMAC) ConfigHMAC {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigHMACDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Header == "" {
+		cfg.Header = ConfigHMACDefault.Header
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = ConfigHMACDefault.Prefix
+	}
+	if cfg.Version == "" {
+		cfg.Version = ConfigHMACDefault.Version
+	}
+	if cfg.Hash == nil {
+		cfg.Hash = ConfigHMACDefault.Hash
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = ConfigHMACDefault.MaxBodySize
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = ConfigHMACDefault.Tolerance
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigHMACDefault.ErrorHandler
+	}
+	return cfg
+}
+
+// GitHubWebhook returns the config verifying the X-Hub-Signature-256
+// header of GitHub webhooks
+func GitHubWebhook(secret []byte) ConfigHMAC {
+	return ConfigHMAC{
+		Header:  "X-Hub-Signature-256",
+		Format:  HMACFormatHex,
+		Prefix:  "sha256=",
+		Secrets: [][]byte{secret},
+	}
+}
+
+// StripeWebhook returns the config verifying the Stripe-Signature header
+// of Stripe webhooks, including its timestamp
+func StripeWebhook(secret []byte) ConfigHMAC {
+	return ConfigHMAC{
+		Header:  "Stripe-Signature",
+		Format:  HMACFormatTimestamped,
+		Version: "v1",
+		Secrets: [][]byte{secret},
+	}
+}
+
+// VerifySignature creates a new middleware handler verifying the HMAC
+// signature of webhook requests over their raw body, e.g. with
+// GitHubWebhook or StripeWebhook. It is HMACAuth, see there.
+func VerifySignature(config ConfigHMAC) http.HandlerFunc {
+	return HMACAuth(config)
+}
+
+// HMACAuth creates a new middleware handler
+func HMACAuth(config ConfigHMAC) http.HandlerFunc {
+	// Set default config
+	cfg := configHMACDefault(config)
+
+	if len(cfg.Secrets) == 0 {
+		panic("hmac: at least one secret is required")
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		header := strings.TrimSpace(c.Header(cfg.Header, ""))
+		if header == "" {
+			return cfg.ErrorHandler(c, ErrHMACMissing)
+		}
+
+		var (
+			signatures [][]byte
+			timestamp  string
+			err        error
+		)
+		if cfg.Format == HMACFormatTimestamped {
+			timestamp, signatures, err = parseTimestampedSignature(header, cfg.Version)
+		} else {
+			signatures, err = parseHexSignature(header, cfg.Prefix)
+			if err == nil && cfg.TimestampHeader != "" {
+				if timestamp = strings.TrimSpace(c.Header(cfg.TimestampHeader, "")); timestamp == "" {
+					err = ErrHMACMissing
+				}
+			}
+		}
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+
+		if timestamp != "" {
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return cfg.ErrorHandler(c, ErrHMACMalformed)
+			}
+			if d := time.Since(time.Unix(unix, 0)); cfg.Tolerance > 0 && (d > cfg.Tolerance || d < -cfg.Tolerance) {
+				return cfg.ErrorHandler(c, ErrHMACExpired)
+			}
+		}
+
+		body, err := readHMACBody(c, cfg.MaxBodySize)
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+
+		for _, secret := range cfg.Secrets {
+			mac := hmac.New(cfg.Hash, secret)
+			if timestamp != "" {
+				mac.Write([]byte(timestamp + "."))
+			}
+			mac.Write(body)
+			sum := mac.Sum(nil)
+			for _, signature := range signatures {
+				if hmac.Equal(sum, signature) {
+					return c.Next()
+				}
+			}
+		}
+		return cfg.ErrorHandler(c, ErrHMACInvalidSignature)
+	}
+}
+
+// parseHexSignature decodes a hex signature after stripping the prefix
+func parseHexSignature(header, prefix string) ([][]byte, error) {
+	if len(header) >= len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		header = header[len(prefix):]
+	}
+	signature, err := hex.DecodeString(header)
+	if err != nil || len(signature) == 0 {
+		return nil, ErrHMACMalformed
+	}
+	return [][]byte{signature}, nil
+}
+
+// parseTimestampedSignature returns the timestamp and the signatures of
+// the version of a t=<unix>,v1=<hex> header
+func parseTimestampedSignature(header, version string) (string, [][]byte, error) {
+	var (
+		timestamp  string
+		signatures [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			timestamp = v
+		case version:
+			if signature, err := hex.DecodeString(v); err == nil && len(signature) > 0 {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return "", nil, ErrHMACMalformed
+	}
+	return timestamp, signatures, nil
+}
+
+// readHMACBody reads up to max bytes of the request body and restores it for
+// the handler
+func readHMACBody(c http.Context, max int) ([]byte, error) {
+	req := c.Origin()
+	if req == nil || req.Body == nil {
+		return nil, nil
+	}
+	if req.ContentLength > int64(max) {
+		return nil, ErrHMACBodyTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > max {
+		return nil, ErrHMACBodyTooLarge
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
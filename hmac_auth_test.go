@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// echoBody responds with the request body the handler reads
+func echoBody(c http.Context) error {
+	body, err := io.ReadAll(c.Origin().Body)
+	if err != nil {
+		return err
+	}
+	return c.String("%s", body)
+}
+
+func signedRequest(body, header, signature string) *stdHttp.Request {
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	if signature != "" {
+		req.Header.Set(header, signature)
+	}
+	return req
+}
+
+// sign returns the hex HMAC-SHA256 of the parts
+func sign(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, p := range parts {
+		mac.Write([]byte(p))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHMACAuthGitHub(t *testing.T) {
+	// The example of GitHub's webhook documentation
+	handler := HMACAuth(GitHubWebhook([]byte("It's a Secret to Everybody")))
+	for _, tt := range []struct {
+		name, body, signature string
+		want                  error
+	}{
+		{"valid", "Hello, World!", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", nil},
+		{"uppercase", "Hello, World!", "SHA256=757107EA0EB2509FC211221CCE984B8A37570B6D7586C22C46F4379C8B043E17", nil},
+		{"other body", "Hello, World?", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", ErrHMACInvalidSignature},
+		{"missing", "Hello, World!", "", ErrHMACMissing},
+		{"not hex", "Hello, World!", "sha256=xyz", ErrHMACMalformed},
+	} {
+		c := run(t, signedRequest(tt.body, "X-Hub-Signature-256", tt.signature), handler, echoBody)
+		if tt.want == nil {
+			// The handler still reads the whole body
+			if c.Recorder.Code != utils.StatusOK || c.Body() != tt.body {
+				t.Errorf("%s: status = %d, body = %q", tt.name, c.Recorder.Code, c.Body())
+			}
+			continue
+		}
+		if c.Recorder.Code != utils.StatusUnauthorized || !errors.Is(c.Errors()[0], tt.want) {
+			t.Errorf("%s: status = %d, err = %v", tt.name, c.Recorder.Code, c.Errors()[0])
+		}
+	}
+}
+
+func TestHMACAuthTimestamped(t *testing.T) {
+	body := `{"id":"evt_1"}`
+
+	// A fixture signed at a fixed time, only valid without a tolerance
+	cfg := StripeWebhook([]byte("whsec_test"))
+	cfg.Tolerance = -1
+	fixture := "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if c := run(t, signedRequest(body, "Stripe-Signature", fixture), HMACAuth(cfg), echoBody); c.Body() != body {
+		t.Errorf("fixture: status = %d, err = %v", c.Recorder.Code, c.Errors()[0])
+	}
+
+	handler := HMACAuth(StripeWebhook([]byte("whsec_test")))
+	if c := run(t, signedRequest(body, "Stripe-Signature", fixture), handler, echoBody); !errors.Is(c.Errors()[0], ErrHMACExpired) {
+		t.Errorf("fixture replayed: err = %v", c.Errors()[0])
+	}
+
+	for _, tt := range []struct {
+		name   string
+		offset time.Duration
+		header func(ts string) string
+		want   error
+	}{
+		{"fresh", 0, func(ts string) string { return "t=" + ts + ",v1=" + sign("whsec_test", ts, ".", body) }, nil},
+		{"clock skew", -4 * time.Minute, func(ts string) string { return "t=" + ts + ",v1=" + sign("whsec_test", ts, ".", body) }, nil},
+		{"several signatures", 0, func(ts string) string {
+			return "t=" + ts + ",v1=" + sign("other", ts, ".", body) + ", v1=" + sign("whsec_test", ts, ".", body) + ",v0=00"
+		}, nil},
+		{"too old", 6 * time.Minute, func(ts string) string { return "t=" + ts + ",v1=" + sign("whsec_test", ts, ".", body) }, ErrHMACExpired},
+		{"in the future", -6 * time.Minute, func(ts string) string { return "t=" + ts + ",v1=" + sign("whsec_test", ts, ".", body) }, ErrHMACExpired},
+		// The timestamp is signed, changing it breaks the signature
+		{"moved timestamp", 0, func(ts string) string { return "t=" + ts + ",v1=" + sign("whsec_test", "1700000000.", body) }, ErrHMACInvalidSignature},
+		{"other version", 0, func(ts string) string { return "t=" + ts + ",v0=" + sign("whsec_test", ts, ".", body) }, ErrHMACMalformed},
+		{"no timestamp", 0, func(ts string) string { return "v1=" + sign("whsec_test", ts, ".", body) }, ErrHMACMalformed},
+	} {
+		ts := strconv.FormatInt(time.Now().Add(-tt.offset).Unix(), 10)
+		c := run(t, signedRequest(body, "Stripe-Signature", tt.header(ts)), handler, echoBody)
+		if tt.want == nil {
+			if c.Body() != body {
+				t.Errorf("%s: status = %d, err = %v", tt.name, c.Recorder.Code, c.Errors()[0])
+			}
+			continue
+		}
+		if c.Recorder.Code != utils.StatusUnauthorized || !errors.Is(c.Errors()[0], tt.want) {
+			t.Errorf("%s: status = %d, err = %v", tt.name, c.Recorder.Code, c.Errors()[0])
+		}
+	}
+}
+
+func TestHMACAuthTimestampHeader(t *testing.T) {
+	handler := HMACAuth(ConfigHMAC{Secrets: [][]byte{[]byte("s")}, TimestampHeader: "X-Timestamp"})
+	for _, tt := range []struct {
+		name     string
+		offset   time.Duration
+		signed   bool
+		override string
+		want     error
+	}{
+		{"fresh", 0, true, "", nil},
+		{"future within tolerance", -4 * time.Minute, true, "", nil},
+		{"stale", 6 * time.Minute, true, "", ErrHMACExpired},
+		{"future beyond tolerance", -6 * time.Minute, true, "", ErrHMACExpired},
+		// The timestamp is part of the signed input
+		{"unsigned timestamp", 0, false, "", ErrHMACInvalidSignature},
+		{"not a number", 0, true, "yesterday", ErrHMACMalformed},
+		{"missing", 0, true, "-", ErrHMACMissing},
+	} {
+		ts := strconv.FormatInt(time.Now().Add(-tt.offset).Unix(), 10)
+		signature := sign("s", "payload")
+		if tt.signed {
+			signature = sign("s", ts, ".", "payload")
+		}
+		req := signedRequest("payload", "X-Signature", "sha256="+signature)
+		switch tt.override {
+		case "":
+			req.Header.Set("X-Timestamp", ts)
+		case "-":
+		default:
+			req.Header.Set("X-Timestamp", tt.override)
+		}
+		c := run(t, req, handler, echoBody)
+		if tt.want == nil {
+			if c.Body() != "payload" {
+				t.Errorf("%s: status = %d, err = %v", tt.name, c.Recorder.Code, c.Errors()[0])
+			}
+			continue
+		}
+		if c.Recorder.Code != utils.StatusUnauthorized || !errors.Is(c.Errors()[0], tt.want) {
+			t.Errorf("%s: status = %d, err = %v", tt.name, c.Recorder.Code, c.Errors()[0])
+		}
+	}
+}
+
+func TestHMACAuthRotation(t *testing.T) {
+	handler := HMACAuth(ConfigHMAC{Secrets: [][]byte{[]byte("new"), []byte("old")}})
+	for secret, want := range map[string]int{"new": utils.StatusOK, "old": utils.StatusOK, "retired": utils.StatusUnauthorized} {
+		c := run(t, signedRequest("payload", "X-Signature", sign(secret, "payload")), handler, echoBody)
+		if c.Recorder.Code != want {
+			t.Errorf("%s: status = %d, want %d", secret, c.Recorder.Code, want)
+		}
+	}
+}
+
+func TestHMACAuthBodySize(t *testing.T) {
+	handler := HMACAuth(ConfigHMAC{Secrets: [][]byte{[]byte("s")}, MaxBodySize: 8})
+	body := "0123456789"
+	c := run(t, signedRequest(body, "X-Signature", sign("s", body)), handler, echoBody)
+	if c.Recorder.Code != utils.StatusRequestEntityTooLarge || !errors.Is(c.Errors()[0], ErrHMACBodyTooLarge) {
+		t.Errorf("declared length: status = %d, err = %v", c.Recorder.Code, c.Errors()[0])
+	}
+
+	// Without a Content-Length the body is cut off while reading
+	req := signedRequest(body, "X-Signature", sign("s", body))
+	req.ContentLength = -1
+	c = run(t, req, handler, echoBody)
+	if c.Recorder.Code != utils.StatusRequestEntityTooLarge {
+		t.Errorf("chunked: status = %d", c.Recorder.Code)
+	}
+
+	c = run(t, signedRequest("01234567", "X-Signature", sign("s", "01234567")), handler, echoBody)
+	if c.Body() != "01234567" {
+		t.Errorf("at the limit: status = %d", c.Recorder.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("HMACAuth didn't panic without secrets")
+		}
+	}()
+	HMACAuth(ConfigHMAC{})
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := `{"action":"opened"}`
+	signature := "sha256=" + sign("webhook-secret", body)
+	handler := VerifySignature(GitHubWebhook([]byte("webhook-secret")))
+	for _, tt := range []struct {
+		name, body, signature string
+		want                  error
+	}{
+		{"valid", body, signature, nil},
+		{"tampered body", `{"action":"closed"}`, signature, ErrHMACInvalidSignature},
+		{"other secret", body, "sha256=" + sign("guess", body), ErrHMACInvalidSignature},
+		{"missing header", body, "", ErrHMACMissing},
+	} {
+		c := run(t, signedRequest(tt.body, "X-Hub-Signature-256", tt.signature), handler, echoBody)
+		if tt.want == nil {
+			if c.Recorder.Code != utils.StatusOK || c.Body() != tt.body {
+				t.Errorf("%s: status = %d, body = %q", tt.name, c.Recorder.Code, c.Body())
+			}
+			continue
+		}
+		if c.Recorder.Code != utils.StatusUnauthorized || !errors.Is(c.Errors()[0], tt.want) {
+			t.Errorf("%s: status = %d, err = %v", tt.name, c.Recorder.Code, c.Errors()[0])
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// hopByHopHeaders are meaningful for a single connection only and must not
+// be forwarded by proxies, see RFC 7230 section 6.1
+var hopByHopHeaders = []string{
+	utils.HeaderConnection,
+	utils.HeaderKeepAlive,
+	utils.HeaderProxyAuthenticate,
+	utils.HeaderProxyAuthorization,
+	utils.HeaderTE,
+	utils.HeaderTrailer,
+	utils.HeaderTransferEncoding,
+	utils.HeaderUpgrade,
+	"Proxy-Connection",
+}
+
+// StripHopByHop creates a new middleware handler removing the hop-by-hop
+// headers, and those listed in the Connection header, from the request
+// before it is forwarded upstream
+func StripHopByHop() http.HandlerFunc {
+	// Return new handler
+	return func(c http.Context) error {
+		if req := c.Origin(); req != nil {
+			removeHopByHop(req.Header)
+		}
+		return c.Next()
+	}
+}
+
+// removeHopByHop deletes the hop-by-hop headers from header
+func removeHopByHop(header stdHttp.Header) {
+	for _, value := range header.Values(utils.HeaderConnection) {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
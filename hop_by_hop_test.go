@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestStripHopByHop(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	for name, value := range map[string]string{
+		"Connection":          "keep-alive, X-Internal-Token , x-trace",
+		"Keep-Alive":          "timeout=5",
+		"Proxy-Authorization": "Basic Zm9vOmJhcg==",
+		"Proxy-Connection":    "keep-alive",
+		"TE":                  "trailers",
+		"Trailer":             "Expires",
+		"Transfer-Encoding":   "chunked",
+		"Upgrade":             "websocket",
+		"X-Internal-Token":    "secret",
+		"X-Trace":             "1",
+		"Accept":              "application/json",
+		"X-Request-ID":        "rid",
+	} {
+		req.Header.Set(name, value)
+	}
+	// A second Connection header counts as well
+	req.Header.Add("Connection", "X-Second")
+	req.Header.Set("X-Second", "2")
+
+	var seen []string
+	run(t, req, StripHopByHop(), func(c http.Context) error {
+		for name := range c.Origin().Header {
+			seen = append(seen, name)
+		}
+		return nil
+	})
+	if len(seen) != 2 || req.Header.Get(utils.HeaderAccept) != "application/json" || req.Header.Get("X-Request-ID") != "rid" {
+		t.Errorf("forwarded headers = %q, want Accept and X-Request-Id only", seen)
+	}
+}
+
+func TestStripHopByHopWithoutConnection(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("X-Custom", "kept")
+	c := run(t, req, StripHopByHop(), ok)
+	if c.Body() != "ok" || req.Header.Get("Upgrade") != "" || req.Header.Get("X-Custom") != "kept" {
+		t.Errorf("headers = %v", req.Header)
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigHTTPSRedirect defines the config for middleware.
+type ConfigHTTPSRedirect struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// StatusCode of the redirect, 301, 302, 307 or 308
+	//
+	// Optional. Default: 301
+	StatusCode int
+
+	// Host replaces the host of the request in the redirect. Either Host
+	// or AllowedHosts must be set, the Host header alone is never trusted.
+	//
+	// Optional. Default: ""
+	Host string
+
+	// Port of the HTTPS server, left out of the redirect when it is 443
+	//
+	// Optional. Default: 443
+	Port int
+
+	// TrustedProxies are the IPs or CIDR ranges of the proxies whose
+	// X-Forwarded-Proto is believed and whose X-Forwarded-Host is used as
+	// the host of the redirect
+	//
+	// Optional. Default: nil
+	TrustedProxies []string
+
+	// AllowedHosts the redirect may point to, checked with SafeRedirect
+	// so a forged Host or X-Forwarded-Host can't send clients elsewhere.
+	// Requests for other hosts get 400 Bad Request.
+	//
+	// Optional. Default: Host
+	AllowedHosts []string
+
+	// ExcludedPaths are path prefixes served over plain HTTP
+	//
+	// Optional. Default: "/.well-known/acme-challenge/"
+	ExcludedPaths []string
+}
+
+// ConfigHTTPSRedirectDefault is the default config
+var ConfigHTTPSRedirectDefault = ConfigHTTPSRedirect{
+	Next:          nil,
+	StatusCode:    utils.StatusMovedPermanently,
+	Port:          443,
+	ExcludedPaths: []string{"/.well-known/acme-challenge/"},
+}
+
+// Helper function to set default values
+func configHTTPSRedirectDefault(config ...ConfigHTTPSRedirect) ConfigHTTPSRedirect {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigHTTPSRedirectDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = ConfigHTTPSRedirectDefault.StatusCode
+	}
+	if cfg.Port <= 0 {
+		cfg.Port = ConfigHTTPSRedirectDefault.Port
+	}
+	if cfg.ExcludedPaths == nil {
+		cfg.ExcludedPaths = ConfigHTTPSRedirectDefault.ExcludedPaths
+	}
+	return cfg
+}
+
+// HTTPSRedirect creates a new middleware handler
+func HTTPSRedirect(config ConfigHTTPSRedirect) http.HandlerFunc {
+	// Set default config
+	cfg := configHTTPSRedirectDefault(config)
+
+	switch cfg.StatusCode {
+	case utils.StatusMovedPermanently, utils.StatusFound, utils.StatusTemporaryRedirect, utils.StatusPermanentRedirect:
+	default:
+		panic("https redirect: unsupported StatusCode " + strconv.Itoa(cfg.StatusCode))
+	}
+	allowed := cfg.AllowedHosts
+	if len(allowed) == 0 {
+		if cfg.Host == "" {
+			panic("https redirect: Host or AllowedHosts is required")
+		}
+		allowed = []string{cfg.Host}
+	}
+	trusted := newIPRanges("https redirect: trusted proxies", cfg.TrustedProxies)
+	port := ""
+	if cfg.Port != 443 {
+		port = strconv.Itoa(cfg.Port)
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if forwardedHTTPS(c, trusted) {
+			return c.Next()
+		}
+		r := c.Origin()
+		for _, prefix := range cfg.ExcludedPaths {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return c.Next()
+			}
+		}
+
+		host := cfg.Host
+		if host == "" {
+			host = forwardedHost(c, trusted)
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+		}
+		if host == "" {
+			c.AbortWithStatus(utils.StatusBadRequest)
+			return ErrHostDenied
+		}
+		if port != "" {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+		}
+
+		location, ok := SafeRedirect(c, "https://"+host+r.URL.RequestURI(), allowed)
+		if !ok {
+			c.AbortWithStatus(utils.StatusBadRequest)
+			return fmt.Errorf("%w: %q", ErrHostDenied, host)
+		}
+
+		c.SetHeader(utils.HeaderLocation, location)
+		c.AbortWithStatus(cfg.StatusCode)
+		return nil
+	}
+}
+
+// forwardedHTTPS reports whether the client connected over TLS, the
+// X-Forwarded-Proto header is only believed when the connection comes from
+// a trusted proxy so a client can't skip the redirect by sending it
+func forwardedHTTPS(c http.Context, trusted ipRanges) bool {
+	if r := c.Origin(); r != nil && r.TLS != nil {
+		return true
+	}
+	if len(trusted) == 0 || c.Header(utils.HeaderXForwardedProto, "") != "https" {
+		return false
+	}
+	ip := peerIP(c)
+	return ip != nil && trusted.contains(ip)
+}
+
+// forwardedHost returns the host the client asked for, the X-Forwarded-Host
+// header is only believed when the connection comes from a trusted proxy
+func forwardedHost(c http.Context, trusted ipRanges) string {
+	if len(trusted) > 0 {
+		if host := c.Header(utils.HeaderXForwardedHost, ""); host != "" {
+			if ip := peerIP(c); ip != nil && trusted.contains(ip) {
+				// Proxies in a chain append, the first one saw the client
+				host, _, _ = strings.Cut(host, ",")
+				if host = strings.TrimSpace(host); host != "" {
+					return host
+				}
+			}
+		}
+	}
+	return c.Origin().Host
+}
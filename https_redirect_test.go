@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestHTTPSRedirectRequiresHosts(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("HTTPSRedirect without Host or AllowedHosts didn't panic")
+		}
+	}()
+	HTTPSRedirect(ConfigHTTPSRedirect{})
+}
+
+func TestHTTPSRedirectAllowedHosts(t *testing.T) {
+	handler := HTTPSRedirect(ConfigHTTPSRedirect{AllowedHosts: []string{"example.com", "*.example.com"}})
+	for _, tt := range []struct {
+		host, location string
+		status         int
+	}{
+		{"example.com", "https://example.com/path?q=1", utils.StatusMovedPermanently},
+		{"www.example.com:80", "https://www.example.com/path?q=1", utils.StatusMovedPermanently},
+		{"evil.com", "", utils.StatusBadRequest},
+	} {
+		req := httptest.NewRequest("GET", "/path?q=1", nil)
+		req.Host = tt.host
+		c := run(t, req, handler, ok)
+		if c.Recorder.Code != tt.status || c.Recorder.Header().Get(utils.HeaderLocation) != tt.location {
+			t.Errorf("%s: %d %q, want %d %q", tt.host, c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderLocation), tt.status, tt.location)
+		}
+		if tt.status == utils.StatusBadRequest && !errors.Is(c.Errors()[0], ErrHostDenied) {
+			t.Errorf("%s: err = %v", tt.host, c.Errors()[0])
+		}
+	}
+}
+
+func TestHTTPSRedirectFixedHost(t *testing.T) {
+	req := httptest.NewRequest("GET", "/a", nil)
+	req.Host = "evil.com"
+	c := run(t, req, HTTPSRedirect(ConfigHTTPSRedirect{Host: "example.com"}), ok)
+	if got := c.Recorder.Header().Get(utils.HeaderLocation); got != "https://example.com/a" {
+		t.Errorf("Location = %q", got)
+	}
+}
+
+func TestHTTPSRedirectSecurePassthrough(t *testing.T) {
+	handler := HTTPSRedirect(ConfigHTTPSRedirect{Host: "example.com"})
+	req := httptest.NewRequest("GET", "https://example.com/a", nil)
+	if c := run(t, req, handler, ok); c.Body() != "ok" {
+		t.Errorf("TLS: status = %d", c.Recorder.Code)
+	}
+
+	handler = HTTPSRedirect(ConfigHTTPSRedirect{Host: "example.com", TrustedProxies: []string{"10.0.0.0/8"}})
+	for _, tt := range []struct {
+		remoteAddr string
+		status     int
+	}{
+		{"10.0.0.1:1000", utils.StatusOK},
+		// A client connecting directly can't claim it used TLS
+		{"203.0.113.1:1000", utils.StatusMovedPermanently},
+	} {
+		req = httptest.NewRequest("GET", "/a", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set(utils.HeaderXForwardedProto, "https")
+		if c := run(t, req, handler, ok); c.Recorder.Code != tt.status {
+			t.Errorf("X-Forwarded-Proto from %s: status = %d, want %d", tt.remoteAddr, c.Recorder.Code, tt.status)
+		}
+	}
+	// Without trusted proxies the header is never believed
+	req = httptest.NewRequest("GET", "/a", nil)
+	req.Header.Set(utils.HeaderXForwardedProto, "https")
+	if c := run(t, req, HTTPSRedirect(ConfigHTTPSRedirect{Host: "example.com"}), ok); c.Recorder.Code != utils.StatusMovedPermanently {
+		t.Errorf("untrusted X-Forwarded-Proto: status = %d", c.Recorder.Code)
+	}
+}
+
+func TestHTTPSRedirectExcludedPaths(t *testing.T) {
+	for _, tt := range []struct {
+		excluded []string
+		path     string
+		served   bool
+	}{
+		{nil, "/.well-known/acme-challenge/token", true},
+		{nil, "/.well-known/acme-challengeX", false},
+		{nil, "/a", false},
+		{[]string{"/health"}, "/health/live", true},
+		{[]string{"/health"}, "/.well-known/acme-challenge/token", false},
+	} {
+		handler := HTTPSRedirect(ConfigHTTPSRedirect{Host: "example.com", ExcludedPaths: tt.excluded})
+		c := run(t, httptest.NewRequest("GET", tt.path, nil), handler, ok)
+		if (c.Body() == "ok") != tt.served {
+			t.Errorf("%v %s: status = %d", tt.excluded, tt.path, c.Recorder.Code)
+		}
+	}
+}
+
+func TestHTTPSRedirectPortAndStatus(t *testing.T) {
+	for _, tt := range []struct {
+		cfg      ConfigHTTPSRedirect
+		method   string
+		host     string
+		status   int
+		location string
+	}{
+		{ConfigHTTPSRedirect{Host: "example.com", Port: 8443}, "GET", "example.com:8080", utils.StatusMovedPermanently, "https://example.com:8443/a?b=1"},
+		{ConfigHTTPSRedirect{AllowedHosts: []string{"staging.example.com"}, Port: 8443}, "GET", "staging.example.com:8080", utils.StatusMovedPermanently, "https://staging.example.com:8443/a?b=1"},
+		{ConfigHTTPSRedirect{Host: "example.com", Port: 443}, "GET", "example.com:8080", utils.StatusMovedPermanently, "https://example.com/a?b=1"},
+		{ConfigHTTPSRedirect{Host: "example.com", StatusCode: utils.StatusPermanentRedirect}, "POST", "example.com", utils.StatusPermanentRedirect, "https://example.com/a?b=1"},
+		{ConfigHTTPSRedirect{Host: "example.com", StatusCode: utils.StatusFound}, "GET", "example.com", utils.StatusFound, "https://example.com/a?b=1"},
+	} {
+		req := httptest.NewRequest(tt.method, "/a?b=1", nil)
+		req.Host = tt.host
+		c := run(t, req, HTTPSRedirect(tt.cfg), ok)
+		if c.Recorder.Code != tt.status || c.Recorder.Header().Get(utils.HeaderLocation) != tt.location {
+			t.Errorf("%+v: %d %q, want %d %q", tt.cfg, c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderLocation), tt.status, tt.location)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("HTTPSRedirect accepted StatusCode 200")
+		}
+	}()
+	HTTPSRedirect(ConfigHTTPSRedirect{Host: "example.com", StatusCode: utils.StatusOK})
+}
+
+func TestHTTPSRedirectTrustedProxies(t *testing.T) {
+	handler := HTTPSRedirect(ConfigHTTPSRedirect{
+		AllowedHosts:   []string{"example.com"},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	})
+	for _, tt := range []struct {
+		remoteAddr string
+		status     int
+		location   string
+	}{
+		{"10.0.0.1:1000", utils.StatusMovedPermanently, "https://example.com/"},
+		// Untrusted clients can't pick the host, the Host header is used
+		{"203.0.113.1:1000", utils.StatusBadRequest, ""},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "internal.example.com"
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set(utils.HeaderXForwardedHost, "example.com, proxy.internal")
+		c := run(t, req, handler, ok)
+		if c.Recorder.Code != tt.status || c.Recorder.Header().Get(utils.HeaderLocation) != tt.location {
+			t.Errorf("%s: %d %q, want %d %q", tt.remoteAddr, c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderLocation), tt.status, tt.location)
+		}
+	}
+}
+
+func TestForwardedHost(t *testing.T) {
+	trusted := newIPRanges("test", []string{"10.0.0.0/8", "::1"})
+	for _, tt := range []struct {
+		name, remoteAddr, forwarded string
+		trusted                     ipRanges
+		want                        string
+	}{
+		{"trusted", "10.1.2.3:1000", "example.com", trusted, "example.com"},
+		{"trusted IPv6", "[::1]:1000", "example.com:8443", trusted, "example.com:8443"},
+		{"proxy chain", "10.1.2.3:1000", " example.com , proxy.internal", trusted, "example.com"},
+		{"empty first entry", "10.1.2.3:1000", ", proxy.internal", trusted, "internal.example.com"},
+		{"no header", "10.1.2.3:1000", "", trusted, "internal.example.com"},
+		{"untrusted peer", "203.0.113.1:1000", "example.com", trusted, "internal.example.com"},
+		{"no trusted proxies", "10.1.2.3:1000", "example.com", nil, "internal.example.com"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "internal.example.com"
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set(utils.HeaderXForwardedHost, tt.forwarded)
+		}
+		var got string
+		run(t, req, func(c http.Context) error {
+			got = forwardedHost(c, tt.trusted)
+			return nil
+		})
+		if got != tt.want {
+			t.Errorf("%s: host = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
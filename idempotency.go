@@ -0,0 +1,293 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	stdHttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+const headerIdempotentReplayed = "Idempotent-Replayed"
+
+// ConfigIdempotency defines the config for middleware.
+type ConfigIdempotency struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Header is the header key carrying the idempotency key
+	//
+	// Optional. Default: "Idempotency-Key"
+	Header string
+
+	// Methods defines the methods the idempotency key is honoured for
+	//
+	// Optional. Default: POST, PUT, PATCH, DELETE
+	Methods []string
+
+	// Lifetime is how long a stored response is replayed for
+	//
+	// Optional. Default: 30 * time.Minute
+	Lifetime time.Duration
+
+	// Fingerprint identifies the request a key was first used with. A stored
+	// key replayed with a different fingerprint is answered with 422.
+	//
+	// Optional. Default: sha256 of method, path and body, keyed requests
+	// with a body larger than MaxBufferSize are answered with 413
+	Fingerprint func(c http.Context) string
+
+	// MaxBufferSize is the largest response body that is stored, larger
+	// responses are streamed through and not replayed. It also caps the
+	// request body read by the default Fingerprint.
+	//
+	// Optional. Default: 1 MB
+	MaxBufferSize int
+
+	// KeyPrefix is prepended to the idempotency key in the storage
+	//
+	// Optional. Default: "idempotency_"
+	KeyPrefix string
+
+	// Storage is used to store the responses
+	//
+	// Optional. Default: an in memory store for this process only
+	Storage storage.Storage
+}
+
+// ConfigIdempotencyDefault is the default config
+var ConfigIdempotencyDefault = ConfigIdempotency{
+	Next:   nil,
+	Header: "Idempotency-Key",
+	Methods: []string{
+		utils.MethodPost,
+		utils.MethodPut,
+		utils.MethodPatch,
+		utils.MethodDelete,
+	},
+	Lifetime:      30 * time.Minute,
+	Fingerprint:   defaultIdempotencyFingerprint,
+	MaxBufferSize: defaultMaxBufferSize,
+	KeyPrefix:     "idempotency_",
+}
+
+// Helper function to set default values
+func configIdempotencyDefault(config ...ConfigIdempotency) ConfigIdempotency {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigIdempotencyDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Header == "" {
+		cfg.Header = ConfigIdempotencyDefault.Header
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = ConfigIdempotencyDefault.Methods
+	}
+	if cfg.Lifetime <= 0 {
+		cfg.Lifetime = ConfigIdempotencyDefault.Lifetime
+	}
+	if cfg.Fingerprint == nil {
+		cfg.Fingerprint = ConfigIdempotencyDefault.Fingerprint
+	}
+	if cfg.MaxBufferSize <= 0 {
+		cfg.MaxBufferSize = ConfigIdempotencyDefault.MaxBufferSize
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = ConfigIdempotencyDefault.KeyPrefix
+	}
+	return cfg
+}
+
+// storedResponse is the serialized form of a replayable response
+type storedResponse struct {
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	Status      int            `json:"status,omitempty"`
+	Header      stdHttp.Header `json:"header,omitempty"`
+	Body        []byte         `json:"body,omitempty"`
+	Vary        []string       `json:"vary,omitempty"`
+}
+
+// Idempotency creates a new middleware handler
+func Idempotency(config ConfigIdempotency) http.HandlerFunc {
+	// Set default config
+	cfg := configIdempotencyDefault(config)
+
+	store := cfg.Storage
+	if store == nil {
+		store = newMemoryStorage()
+	}
+	locks := newKeyLocker()
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+		if !containsMethod(cfg.Methods, c.Method()) {
+			return c.Next()
+		}
+
+		// Requests without a key are not idempotent
+		key := c.Header(cfg.Header, "")
+		if key == "" {
+			return c.Next()
+		}
+		key = cfg.KeyPrefix + key
+
+		// Concurrent duplicates wait for the first execution
+		locks.lock(key)
+		defer locks.unlock(key)
+
+		// The default fingerprint hashes the body, so it must fit the buffer
+		if config.Fingerprint == nil {
+			if err := bufferRequestBody(c, cfg.MaxBufferSize); errors.Is(err, ErrBodyTooLarge) {
+				c.AbortWithStatus(utils.StatusRequestEntityTooLarge)
+				return err
+			} else if err != nil {
+				// A body we couldn't read would be fingerprinted as cut short
+				c.AbortWithStatus(utils.StatusBadRequest)
+				return fmt.Errorf("%w: %v", utils.ErrBadRequest, err)
+			}
+		}
+		fingerprint := cfg.Fingerprint(c)
+		if raw, _ := store.Get(key); raw != nil {
+			var res storedResponse
+			if err := json.Unmarshal(raw, &res); err == nil {
+				if res.Fingerprint != fingerprint {
+					c.AbortWithStatus(utils.StatusUnprocessableEntity)
+					return ErrIdempotencyKeyReused
+				}
+				if res.Header == nil {
+					res.Header = stdHttp.Header{}
+				}
+				res.Header.Set(headerIdempotentReplayed, "true")
+				return writeResponse(c, res.Status, res.Header, res.Body)
+			}
+		}
+
+		rec, ok := recordResponse(c, cfg.MaxBufferSize)
+		if !ok {
+			// Without the response a retry would execute the request again
+			c.AbortWithStatus(utils.StatusInternalServerError)
+			return utils.ErrInternalServerError
+		}
+		err := rec.next(c)
+
+		// Server errors and oversized bodies are executed again on retry
+		if err != nil || rec.Status() >= utils.StatusInternalServerError || rec.overflow {
+			return err
+		}
+		raw, mErr := json.Marshal(storedResponse{
+			Fingerprint: fingerprint,
+			Status:      rec.Status(),
+			Header:      rec.Header().Clone(),
+			Body:        rec.Body(),
+		})
+		if mErr == nil {
+			_ = store.Set(key, raw, cfg.Lifetime)
+		}
+		return err
+	}
+}
+
+func defaultIdempotencyFingerprint(c http.Context) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Path()))
+	h.Write([]byte{0})
+	if req := c.Origin(); req != nil && req.Body != nil {
+		// Idempotency buffered the body within MaxBufferSize
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bufferRequestBody replaces the request body with a buffered copy, it
+// fails with ErrBodyTooLarge when the body is larger than max bytes
+func bufferRequestBody(c http.Context, max int) error {
+	req := c.Origin()
+	if req == nil || req.Body == nil {
+		return nil
+	}
+	tooLarge := fmt.Errorf("%w: over %d bytes", ErrBodyTooLarge, max)
+	if req.ContentLength > int64(max) {
+		return tooLarge
+	}
+	// Read one byte more to tell a body of exactly max from a larger one
+	body, err := io.ReadAll(io.LimitReader(req.Body, int64(max)+1))
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	if err != nil {
+		return err
+	}
+	if len(body) > max {
+		return tooLarge
+	}
+	return nil
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// keyLocker hands out one mutex per key and forgets it once unused
+type keyLocker struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyLocker() *keyLocker {
+	return &keyLocker{locks: make(map[string]*keyLock)}
+}
+
+func (k *keyLocker) lock(key string) {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+	l.Lock()
+}
+
+func (k *keyLocker) unlock(key string) {
+	k.mu.Lock()
+	l := k.locks[key]
+	l.refs--
+	if l.refs == 0 {
+		delete(k.locks, key)
+	}
+	k.mu.Unlock()
+	l.Unlock()
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// IdempotencyOption sets a field of ConfigIdempotency, see IdempotencyWith
+type IdempotencyOption func(cfg *ConfigIdempotency)
+
+// IdempotencyWith creates an Idempotency middleware from options applied to
+// ConfigIdempotencyDefault in order, a later option overrides an earlier one
+//
+//	middleware.IdempotencyWith(
+//		middleware.IdempotencyLifetime(24*time.Hour),
+//		middleware.IdempotencyStorage(redisStore),
+//	)
+func IdempotencyWith(opts ...IdempotencyOption) http.HandlerFunc {
+	cfg := ConfigIdempotencyDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Idempotency(cfg)
+}
+
+// IdempotencyOptions bundles options into one, e.g. to share a preset
+// between services
+func IdempotencyOptions(opts ...IdempotencyOption) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// IdempotencyNext sets ConfigIdempotency.Next
+func IdempotencyNext(next func(c http.Context) bool) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.Next = next }
+}
+
+// IdempotencyHeader sets ConfigIdempotency.Header
+func IdempotencyHeader(header string) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.Header = header }
+}
+
+// IdempotencyMethods sets ConfigIdempotency.Methods
+func IdempotencyMethods(methods ...string) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.Methods = methods }
+}
+
+// IdempotencyLifetime sets ConfigIdempotency.Lifetime
+func IdempotencyLifetime(d time.Duration) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.Lifetime = d }
+}
+
+// IdempotencyFingerprint sets ConfigIdempotency.Fingerprint
+func IdempotencyFingerprint(fingerprint func(c http.Context) string) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.Fingerprint = fingerprint }
+}
+
+// IdempotencyMaxBufferSize sets ConfigIdempotency.MaxBufferSize
+func IdempotencyMaxBufferSize(n int) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.MaxBufferSize = n }
+}
+
+// IdempotencyKeyPrefix sets ConfigIdempotency.KeyPrefix
+func IdempotencyKeyPrefix(prefix string) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.KeyPrefix = prefix }
+}
+
+// IdempotencyStorage sets ConfigIdempotency.Storage
+func IdempotencyStorage(store storage.Storage) IdempotencyOption {
+	return func(cfg *ConfigIdempotency) { cfg.Storage = store }
+}
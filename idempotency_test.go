@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// paymentHandler creates a payment, counting how often it runs
+func paymentHandler(calls *int32) http.HandlerFunc {
+	return func(c http.Context) error {
+		n := atomic.AddInt32(calls, 1)
+		c.SetHeader("X-Payment", strconv.Itoa(int(n)))
+		return c.Status(utils.StatusCreated).String("payment " + strconv.Itoa(int(n)))
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls int32
+	handler := Idempotency(ConfigIdempotency{})
+	request := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/payments", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		return run(t, req, handler, paymentHandler(&calls)).Recorder
+	}
+
+	first := request("k1", `{"amount":10}`)
+	if first.Code != utils.StatusCreated || first.Body.String() != "payment 1" {
+		t.Fatalf("first = %d %q", first.Code, first.Body.String())
+	}
+	replay := request("k1", `{"amount":10}`)
+	if replay.Code != utils.StatusCreated || replay.Body.String() != "payment 1" ||
+		replay.Header().Get("X-Payment") != "1" || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay = %d %q %v", replay.Code, replay.Body.String(), replay.Header())
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times", calls)
+	}
+
+	// Other keys and requests without a key run the handler
+	request("k2", `{"amount":10}`)
+	request("", `{"amount":10}`)
+	request("", `{"amount":10}`)
+	if calls != 4 {
+		t.Errorf("handler ran %d times", calls)
+	}
+}
+
+func TestIdempotencyFingerprintMismatch(t *testing.T) {
+	var calls int32
+	handler := Idempotency(ConfigIdempotency{})
+	for i, body := range []string{`{"amount":10}`, `{"amount":99}`} {
+		req := httptest.NewRequest("POST", "/payments", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "k")
+		c := run(t, req, handler, paymentHandler(&calls))
+		if i == 1 && (c.Recorder.Code != utils.StatusUnprocessableEntity || c.Errors()[0] != ErrIdempotencyKeyReused) {
+			t.Errorf("status = %d, errors = %v", c.Recorder.Code, c.Errors())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times", calls)
+	}
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	var calls int32
+	handler := Idempotency(ConfigIdempotency{})
+	slow := func(c http.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return paymentHandler(&calls)(c)
+	}
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/payments", strings.NewReader("pay"))
+			req.Header.Set("Idempotency-Key", "k")
+			bodies[i] = run(t, req, handler, slow).Body()
+		}(i)
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("handler ran %d times", calls)
+	}
+	for _, body := range bodies {
+		if body != "payment 1" {
+			t.Errorf("body = %q", body)
+		}
+	}
+}
+
+func TestIdempotencyNotStored(t *testing.T) {
+	store := newTestStorage()
+	handler := Idempotency(ConfigIdempotency{Storage: store, MaxBufferSize: 10, Lifetime: time.Minute})
+	request := func(method string, h http.HandlerFunc) {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set("Idempotency-Key", method)
+		run(t, req, handler, h)
+	}
+
+	// Safe methods, server errors and bodies over MaxBufferSize
+	request("GET", ok)
+	request("POST", func(c http.Context) error { return c.Status(utils.StatusInternalServerError).String("") })
+	request("PUT", func(c http.Context) error { return c.String(strings.Repeat("x", 11)) })
+	if store.sets != 0 {
+		t.Errorf("stored %d responses", store.sets)
+	}
+
+	// Stored responses expire after the Lifetime
+	request("PATCH", ok)
+	if raw, _ := store.Get("idempotency_PATCH"); raw == nil {
+		t.Fatal("response not stored")
+	}
+	store.advance(time.Minute)
+	if raw, _ := store.Get("idempotency_PATCH"); raw != nil {
+		t.Error("response kept after its Lifetime")
+	}
+}
+
+func TestIdempotencyFingerprintBodySize(t *testing.T) {
+	body := `{"amount":10}`
+	var calls int32
+	request := func(cfg ConfigIdempotency, key string, length int64) *middlewaretest.MockContext {
+		req := httptest.NewRequest("POST", "/payments", strings.NewReader(body))
+		req.ContentLength = length
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		return run(t, req, Idempotency(cfg), paymentHandler(&calls))
+	}
+
+	// An unknown length is caught while reading
+	for _, length := range []int64{int64(len(body)), -1} {
+		c := request(ConfigIdempotency{MaxBufferSize: len(body) - 1}, "k", length)
+		if c.Recorder.Code != utils.StatusRequestEntityTooLarge || !errors.Is(c.Errors()[0], ErrBodyTooLarge) {
+			t.Errorf("length %d: status = %d, errors = %v", length, c.Recorder.Code, c.Errors())
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("handler ran %d times", calls)
+	}
+
+	// Bodies within the limit, requests without a key and custom
+	// fingerprints aren't limited
+	custom := ConfigIdempotency{MaxBufferSize: 1, Fingerprint: func(c http.Context) string { return c.Path() }}
+	for name, c := range map[string]*middlewaretest.MockContext{
+		"within": request(ConfigIdempotency{MaxBufferSize: len(body)}, "k", -1),
+		"no key": request(ConfigIdempotency{MaxBufferSize: 1}, "", -1),
+		"custom": request(custom, "k", -1),
+	} {
+		if c.Recorder.Code != utils.StatusCreated {
+			t.Errorf("%s: status = %d, errors = %v", name, c.Recorder.Code, c.Errors())
+		}
+	}
+
+	// The handler still reads the whole body
+	req := httptest.NewRequest("POST", "/payments", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "k")
+	c := run(t, req, Idempotency(ConfigIdempotency{}), func(c http.Context) error {
+		raw, _ := io.ReadAll(c.Origin().Body)
+		return c.String(string(raw))
+	})
+	if c.Body() != body {
+		t.Errorf("handler read %q", c.Body())
+	}
+}
+
+func TestIdempotencyBodyReadError(t *testing.T) {
+	var calls int32
+	body := io.MultiReader(strings.NewReader(`{"amount":`), iotest.ErrReader(errors.New("connection reset")))
+	req := httptest.NewRequest("POST", "/payments", body)
+	req.Header.Set("Idempotency-Key", "k")
+	c := run(t, req, Idempotency(ConfigIdempotency{}), paymentHandler(&calls))
+	if c.Recorder.Code != utils.StatusBadRequest || !errors.Is(c.Errors()[0], utils.ErrBadRequest) || calls != 0 {
+		t.Errorf("status = %d, errors = %v, calls = %d", c.Recorder.Code, c.Errors(), calls)
+	}
+}
+
+func TestIdempotencyWithoutWriter(t *testing.T) {
+	var calls int32
+	req := httptest.NewRequest("POST", "/payments", nil)
+	req.Header.Set("Idempotency-Key", "k")
+	c := middlewaretest.NewMockContext(req, paymentHandler(&calls))
+	err := Idempotency(ConfigIdempotency{})(noEngineContext{c})
+	if !errors.Is(err, utils.ErrInternalServerError) || c.Recorder.Code != utils.StatusInternalServerError || calls != 0 {
+		t.Errorf("err = %v, status = %d, calls = %d", err, c.Recorder.Code, calls)
+	}
+}
@@ -0,0 +1,43 @@
+// Package defaults fills the unset fields of middleware configs with their
+// documented defaults, so every middleware treats zero values the same way.
+//
+//	func configFooDefault(config ...ConfigFoo) ConfigFoo {
+//		return defaults.Config(config, ConfigFooDefault, func(cfg *ConfigFoo) {
+//			defaults.Value(&cfg.Name, ConfigFooDefault.Name)
+//			defaults.Positive(&cfg.Timeout, ConfigFooDefault.Timeout)
+//		})
+//	}
+package defaults
+
+// Config returns def when no config is provided, the first config with the
+// defaults set by fill otherwise
+func Config[T any](config []T, def T, fill func(cfg *T)) T {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return def
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if fill != nil {
+		fill(&cfg)
+	}
+	return cfg
+}
+
+// Value sets field to def when it's the zero value
+func Value[T comparable](field *T, def T) {
+	var zero T
+	if *field == zero {
+		*field = def
+	}
+}
+
+// Positive sets field to def when it's zero or negative
+func Positive[T ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64](field *T, def T) {
+	if *field <= 0 {
+		*field = def
+	}
+}
@@ -0,0 +1,39 @@
+package defaults
+
+import (
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name    string
+	Timeout time.Duration
+	Max     int
+}
+
+var testConfigDefault = testConfig{Name: "default", Timeout: time.Second, Max: 5}
+
+func fillTestConfig(cfg *testConfig) {
+	Value(&cfg.Name, testConfigDefault.Name)
+	Positive(&cfg.Timeout, testConfigDefault.Timeout)
+	Positive(&cfg.Max, testConfigDefault.Max)
+}
+
+func TestConfig(t *testing.T) {
+	if got := Config(nil, testConfigDefault, fillTestConfig); got != testConfigDefault {
+		t.Errorf("no config = %+v", got)
+	}
+	if got := Config([]testConfig{{}}, testConfigDefault, fillTestConfig); got != testConfigDefault {
+		t.Errorf("zero config = %+v", got)
+	}
+	explicit := testConfig{Name: "custom", Timeout: time.Minute, Max: 1}
+	if got := Config([]testConfig{explicit, {}}, testConfigDefault, fillTestConfig); got != explicit {
+		t.Errorf("explicit config = %+v", got)
+	}
+	if got := Config([]testConfig{{Timeout: -1, Max: -1}}, testConfigDefault, fillTestConfig); got != testConfigDefault {
+		t.Errorf("negative values = %+v", got)
+	}
+	if got := Config([]testConfig{{}}, testConfigDefault, nil); got != (testConfig{}) {
+		t.Errorf("nil fill = %+v", got)
+	}
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigIPFilter defines the config for middleware.
+type ConfigIPFilter struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Allow are the IPs or CIDR ranges served, an empty list allows all
+	//
+	// Optional. Default: nil
+	Allow []string
+
+	// Deny are the IPs or CIDR ranges rejected, they take precedence
+	// over Allow
+	//
+	// Optional. Default: nil
+	Deny []string
+
+	// ContextKey is the context key of the client IP resolved by a real
+	// IP middleware, the address of the connection is used when it is unset
+	//
+	// Optional. Default: "realip"
+	ContextKey string
+
+	// FailOpen serves requests whose client IP can't be parsed instead of
+	// rejecting them
+	//
+	// Optional. Default: false
+	FailOpen bool
+
+	// BlockedHandler is called for rejected requests
+	//
+	// Optional. Default: responds with 403 Forbidden
+	BlockedHandler http.HandlerFunc
+}
+
+// ConfigIPFilterDefault is the default config
+var ConfigIPFilterDefault = ConfigIPFilter{
+	Next:           nil,
+	ContextKey:     "realip",
+	BlockedHandler: ipDeniedHandler("realip"),
+}
+
+// ipDeniedHandler responds with 403 Forbidden, naming the IP the filter
+// evaluated rather than one the client can forge
+func ipDeniedHandler(key string) http.HandlerFunc {
+	return func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusForbidden)
+		return fmt.Errorf("%w: %s", ErrIPDenied, filterClientIP(c, key))
+	}
+}
+
+// Helper function to set default values
+func configIPFilterDefault(config ...ConfigIPFilter) ConfigIPFilter {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigIPFilterDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigIPFilterDefault.ContextKey
+	}
+	if cfg.BlockedHandler == nil {
+		cfg.BlockedHandler = ipDeniedHandler(cfg.ContextKey)
+	}
+	return cfg
+}
+
+// IPFilter creates a new middleware handler
+func IPFilter(config ConfigIPFilter) http.HandlerFunc {
+	// Set default config
+	cfg := configIPFilterDefault(config)
+
+	allow := newIPRanges("ip filter: allow", cfg.Allow)
+	deny := newIPRanges("ip filter: deny", cfg.Deny)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		ip := filterClientIP(c, cfg.ContextKey)
+		if ip == nil {
+			if cfg.FailOpen {
+				return c.Next()
+			}
+			return cfg.BlockedHandler(c)
+		}
+		if deny.contains(ip) || (len(allow) > 0 && !allow.contains(ip)) {
+			return cfg.BlockedHandler(c)
+		}
+		return c.Next()
+	}
+}
+
+// filterClientIP returns the client IP in its 16 byte form, nil when it
+// can't be parsed
+func filterClientIP(c http.Context, key string) net.IP {
+	addr, _ := c.Value(key).(string)
+	if addr == "" {
+		return peerIP(c)
+	}
+	return parseIP(addr)
+}
+
+// peerIP returns the address of the connection in its 16 byte form
+func peerIP(c http.Context) net.IP {
+	if req := c.Origin(); req != nil {
+		return parseIP(req.RemoteAddr)
+	}
+	return nil
+}
+
+// peerAddr returns the address of the connection, "" when it can't be
+// parsed. Unlike c.Ip() it ignores the headers clients can forge.
+func peerAddr(c http.Context) string {
+	if ip := peerIP(c); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// parseIP parses an IP with an optional port into its 16 byte form
+func parseIP(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(strings.TrimSpace(addr)).To16()
+}
+
+// ipRange is an inclusive range of 16 byte addresses, IPv4 is kept in its
+// IPv4-mapped IPv6 form so both families share one sorted list
+type ipRange struct {
+	lo, hi net.IP
+}
+
+// ipRanges are sorted, non overlapping ranges searched in O(log n)
+type ipRanges []ipRange
+
+// newIPRanges parses IPs and CIDR ranges into sorted, merged ranges
+func newIPRanges(name string, entries []string) ipRanges {
+	ranges := make(ipRanges, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry).To16()
+			if ip == nil {
+				panic(fmt.Errorf("%s: invalid entry %q", name, entry))
+			}
+			ranges = append(ranges, ipRange{lo: ip, hi: ip})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			panic(fmt.Errorf("%s: invalid entry %q: %w", name, entry, err))
+		}
+		mask := network.Mask
+		if len(mask) == net.IPv4len {
+			mask = append(net.CIDRMask(96, 128)[:12], mask...)
+		}
+		lo, hi := network.IP.To16(), make(net.IP, net.IPv6len)
+		for i := range hi {
+			hi[i] = lo[i] | ^mask[i]
+		}
+		ranges = append(ranges, ipRange{lo: lo, hi: hi})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].lo, ranges[j].lo) < 0
+	})
+
+	// Merge overlapping ranges so a binary search finds the only candidate
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && bytes.Compare(r.lo, merged[n-1].hi) <= 0 {
+			if bytes.Compare(r.hi, merged[n-1].hi) > 0 {
+				merged[n-1].hi = r.hi
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// contains reports whether ip is within one of the ranges
+func (r ipRanges) contains(ip net.IP) bool {
+	i := sort.Search(len(r), func(i int) bool {
+		return bytes.Compare(r[i].hi, ip) >= 0
+	})
+	return i < len(r) && bytes.Compare(r[i].lo, ip) <= 0
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ipFilterStatus runs a request from remoteAddr through the filter
+func ipFilterStatus(t *testing.T, handler http.HandlerFunc, remoteAddr string) int {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remoteAddr
+	return run(t, req, handler, ok).Recorder.Code
+}
+
+func TestIPFilterPrecedence(t *testing.T) {
+	handler := IPFilter(ConfigIPFilter{
+		Allow: []string{"10.0.0.0/8", "192.0.2.7"},
+		Deny:  []string{"10.1.0.0/16"},
+	})
+	for _, tt := range []struct {
+		addr string
+		want int
+	}{
+		{"10.2.3.4:1000", utils.StatusOK},
+		{"192.0.2.7:1000", utils.StatusOK},
+		{"10.1.2.3:1000", utils.StatusForbidden},
+		{"192.0.2.8:1000", utils.StatusForbidden},
+		{"203.0.113.1:1000", utils.StatusForbidden},
+	} {
+		if got := ipFilterStatus(t, handler, tt.addr); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.addr, got, tt.want)
+		}
+	}
+
+	// An empty Allow serves everything not denied
+	handler = IPFilter(ConfigIPFilter{Deny: []string{"10.1.0.0/16"}})
+	if got := ipFilterStatus(t, handler, "203.0.113.1:1000"); got != utils.StatusOK {
+		t.Errorf("allow all: status = %d", got)
+	}
+	if got := ipFilterStatus(t, handler, "10.1.0.1:1000"); got != utils.StatusForbidden {
+		t.Errorf("denied: status = %d", got)
+	}
+}
+
+func TestIPFilterIPv6(t *testing.T) {
+	handler := IPFilter(ConfigIPFilter{Allow: []string{"2001:db8::/32", "192.0.2.0/24"}})
+	for _, tt := range []struct {
+		addr string
+		want int
+	}{
+		{"[2001:db8::1]:1000", utils.StatusOK},
+		{"[2001:db9::1]:1000", utils.StatusForbidden},
+		// IPv4-mapped addresses match the IPv4 ranges
+		{"[::ffff:192.0.2.1]:1000", utils.StatusOK},
+		{"[::ffff:198.51.100.1]:1000", utils.StatusForbidden},
+		// An IPv4 range doesn't cover the IPv6 addresses sharing its low bytes
+		{"[::c000:201]:1000", utils.StatusForbidden},
+	} {
+		if got := ipFilterStatus(t, handler, tt.addr); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestIPFilterContextIP(t *testing.T) {
+	handler := IPFilter(ConfigIPFilter{Allow: []string{"203.0.113.0/24"}})
+	setRealIP := func(ip string) http.HandlerFunc {
+		return func(c http.Context) error {
+			c.WithValue("realip", ip)
+			return c.Next()
+		}
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+	if c := run(t, req, setRealIP("203.0.113.9"), handler, ok); c.Body() != "ok" {
+		t.Errorf("real IP not used: body = %q", c.Body())
+	}
+
+	// Forwarded headers alone don't count, only the connection does
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	c := run(t, req, handler, ok)
+	if c.Recorder.Code != utils.StatusForbidden || !errors.Is(c.Errors()[0], ErrIPDenied) {
+		t.Errorf("forged header: status = %d, err = %v", c.Recorder.Code, c.Errors()[0])
+	}
+	// The error names the IP that was rejected
+	if err := c.Errors()[0]; !strings.HasSuffix(err.Error(), ": 10.0.0.1") {
+		t.Errorf("forged header: err = %v", err)
+	}
+}
+
+func TestIPFilterUnresolvedIP(t *testing.T) {
+	for _, failOpen := range []bool{false, true} {
+		var blocked bool
+		handler := IPFilter(ConfigIPFilter{
+			Deny:     []string{"192.0.2.0/24"},
+			FailOpen: failOpen,
+			BlockedHandler: func(c http.Context) error {
+				blocked = true
+				return c.String("blocked")
+			},
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "not-an-ip"
+		c := run(t, req, handler, ok)
+		if blocked == failOpen || (c.Body() == "ok") != failOpen {
+			t.Errorf("FailOpen %v: blocked = %v, body = %q", failOpen, blocked, c.Body())
+		}
+	}
+}
+
+func TestIPRanges(t *testing.T) {
+	ranges := newIPRanges("test", []string{"10.0.0.0/16", "10.0.128.0/17", "10.0.255.255", " 10.1.0.0/16 "})
+	// The /17 and the single address lie within the /16
+	if len(ranges) != 2 {
+		t.Fatalf("ranges = %v, want the overlaps merged", ranges)
+	}
+	for ip, want := range map[string]bool{
+		"10.0.0.0":      true,
+		"10.0.255.255":  true,
+		"10.1.200.1":    true,
+		"9.255.255.255": false,
+		"10.2.0.0":      false,
+	} {
+		if got := ranges.contains(parseIP(ip)); got != want {
+			t.Errorf("contains(%s) = %v", ip, got)
+		}
+	}
+
+	for _, entry := range []string{"10.0.0.300", "10.0.0.0/33", "example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q didn't panic", entry)
+				}
+			}()
+			newIPRanges("test", []string{entry})
+		}()
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/internal/defaults"
+)
+
+var (
+	// ErrJSONTooDeep is passed to the ErrorHandler when objects and arrays
+	// are nested deeper than MaxDepth
+	ErrJSONTooDeep = errors.New("json guard: nesting too deep")
+	// ErrJSONTooManyTokens is passed to the ErrorHandler when the body has
+	// more than MaxTokens tokens
+	ErrJSONTooManyTokens = errors.New("json guard: too many tokens")
+	// ErrJSONTooLarge is passed to the ErrorHandler when the body is larger
+	// than MaxBodySize
+	ErrJSONTooLarge = errors.New("json guard: body too large")
+)
+
+// ConfigJSONGuard defines the config for middleware.
+type ConfigJSONGuard struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// MaxDepth is the deepest nesting of objects and arrays allowed, a
+	// negative value disables the check
+	//
+	// Optional. Default: 32
+	MaxDepth int
+
+	// MaxTokens is the largest number of tokens allowed: delimiters, keys
+	// and values. A negative value disables the check.
+	//
+	// Optional. Default: 100000
+	MaxTokens int
+
+	// MaxBodySize is the largest body scanned in bytes, the scan buffers
+	// what it reads for the handler. A negative value disables the check.
+	//
+	// Optional. Default: 1 MB
+	MaxBodySize int
+
+	// ErrorHandler is called with ErrJSONTooDeep, ErrJSONTooManyTokens,
+	// ErrJSONTooLarge or the syntax error of a malformed body
+	//
+	// Optional. Default: responds with 422 Unprocessable Entity when the
+	// body is too deep, 413 Request Entity Too Large when it has too many
+	// tokens or is too large and 400 Bad Request when it's malformed
+	ErrorHandler func(c http.Context, err error) error
+}
+
+// ConfigJSONGuardDefault is the default config
+var ConfigJSONGuardDefault = ConfigJSONGuard{
+	Next:        nil,
+	MaxDepth:    32,
+	MaxTokens:   100000,
+	MaxBodySize: defaultMaxBufferSize,
+	ErrorHandler: func(c http.Context, err error) error {
+		switch {
+		case errors.Is(err, ErrJSONTooDeep):
+			c.AbortWithStatus(utils.StatusUnprocessableEntity)
+			return fmt.Errorf("%w: %v", ErrBodyTooComplex, err)
+		case errors.Is(err, ErrJSONTooManyTokens), errors.Is(err, ErrJSONTooLarge):
+			c.AbortWithStatus(utils.StatusRequestEntityTooLarge)
+			return fmt.Errorf("%w: %v", ErrBodyTooLarge, err)
+		}
+		c.AbortWithStatus(utils.StatusBadRequest)
+		return fmt.Errorf("%w: %v", ErrMalformedBody, err)
+	},
+}
+
+// Helper function to set default values
+func configJSONGuardDefault(config ...ConfigJSONGuard) ConfigJSONGuard {
+	return defaults.Config(config, ConfigJSONGuardDefault, func(cfg *ConfigJSONGuard) {
+		defaults.Value(&cfg.MaxDepth, ConfigJSONGuardDefault.MaxDepth)
+		defaults.Value(&cfg.MaxTokens, ConfigJSONGuardDefault.MaxTokens)
+		defaults.Value(&cfg.MaxBodySize, ConfigJSONGuardDefault.MaxBodySize)
+		if cfg.ErrorHandler == nil {
+			cfg.ErrorHandler = ConfigJSONGuardDefault.ErrorHandler
+		}
+	})
+}
+
+// JSONGuard creates a new middleware handler scanning JSON request bodies
+// token by token before any handler decodes them, so deeply nested or huge
+// documents are rejected without building them in memory. The scan stops
+// at the first violation. Bodies that pass are handed on unchanged.
+func JSONGuard(config ...ConfigJSONGuard) http.HandlerFunc {
+	// Set default config
+	cfg := configJSONGuardDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		req := c.Origin()
+		if req.Body == nil || req.ContentLength == 0 || !isJSONMediaType(req.Header.Get(utils.HeaderContentType)) {
+			return c.Next()
+		}
+
+		if cfg.MaxBodySize >= 0 && req.ContentLength > int64(cfg.MaxBodySize) {
+			return cfg.ErrorHandler(c, ErrJSONTooLarge)
+		}
+
+		var buf bytes.Buffer
+		var body io.Reader = req.Body
+		if cfg.MaxBodySize >= 0 {
+			// Read one byte more to tell a body of exactly MaxBodySize from
+			// a larger one
+			body = io.LimitReader(body, int64(cfg.MaxBodySize)+1)
+		}
+		err := scanJSON(io.TeeReader(body, &buf), cfg.MaxDepth, cfg.MaxTokens)
+		// Leave the body for the handler
+		req.Body = io.NopCloser(io.MultiReader(&buf, req.Body))
+		if cfg.MaxBodySize >= 0 && buf.Len() > cfg.MaxBodySize {
+			err = ErrJSONTooLarge
+		}
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+		return c.Next()
+	}
+}
+
+// isJSONMediaType reports whether the Content-Type is application/json or
+// a +json suffix type like application/problem+json
+func isJSONMediaType(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// scanJSON reads a single JSON value from r without decoding it, failing
+// once it nests deeper than maxDepth or has more than maxTokens tokens.
+// Negative limits are ignored.
+func scanJSON(r io.Reader, maxDepth, maxTokens int) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	depth, tokens := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		tokens++
+		if maxTokens >= 0 && tokens > maxTokens {
+			return ErrJSONTooManyTokens
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+				if maxDepth >= 0 && depth > maxDepth {
+					return ErrJSONTooDeep
+				}
+			default:
+				depth--
+			}
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	// Only whitespace may follow the value
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("json guard: invalid data after top-level value")
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestJSONGuard(t *testing.T) {
+	echo := func(c http.Context) error {
+		body, _ := io.ReadAll(c.Origin().Body)
+		return c.String(string(body))
+	}
+	for _, tt := range []struct {
+		name   string
+		cfg    ConfigJSONGuard
+		body   string
+		status int
+		err    error
+	}{
+		{"normal", ConfigJSONGuard{}, `{"user":{"name":"Jane","roles":["admin"]}}`, utils.StatusOK, nil},
+		{"at depth limit", ConfigJSONGuard{MaxDepth: 3}, `{"a":{"b":[1]}}`, utils.StatusOK, nil},
+		{"deeply nested", ConfigJSONGuard{}, strings.Repeat("[", 33) + strings.Repeat("]", 33), utils.StatusUnprocessableEntity, ErrBodyTooComplex},
+		{"too many tokens", ConfigJSONGuard{MaxTokens: 4}, `[1,2,3,4]`, utils.StatusRequestEntityTooLarge, ErrBodyTooLarge},
+		{"limits disabled", ConfigJSONGuard{MaxDepth: -1, MaxTokens: -1}, strings.Repeat("[", 100) + strings.Repeat("]", 100), utils.StatusOK, nil},
+		{"at size limit", ConfigJSONGuard{MaxBodySize: 9}, `[1,2,3,4]`, utils.StatusOK, nil},
+		{"too large", ConfigJSONGuard{MaxBodySize: 8}, `[1,2,3,4]`, utils.StatusRequestEntityTooLarge, ErrBodyTooLarge},
+		{"trailing data", ConfigJSONGuard{}, `{} {}`, utils.StatusBadRequest, ErrMalformedBody},
+	} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		req.Header.Set(utils.HeaderContentType, "application/json")
+		c := run(t, req, JSONGuard(tt.cfg), echo)
+		if c.Recorder.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.status)
+		}
+		if tt.err == nil {
+			if c.Body() != tt.body {
+				t.Errorf("%s: handler got %q", tt.name, c.Body())
+			}
+		} else if err := c.Errors()[0]; !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestJSONGuardUnknownLength(t *testing.T) {
+	// A chunked body has no Content-Length, the scan stops at MaxBodySize
+	body := `{"data":"` + strings.Repeat("x", 100) + `"}`
+	req := httptest.NewRequest("POST", "/", io.MultiReader(strings.NewReader(body)))
+	req.ContentLength = -1
+	req.Header.Set(utils.HeaderContentType, "application/json")
+	c := run(t, req, JSONGuard(ConfigJSONGuard{MaxBodySize: 50}), ok)
+	if c.Recorder.Code != utils.StatusRequestEntityTooLarge || !errors.Is(c.Errors()[0], ErrBodyTooLarge) {
+		t.Errorf("status = %d, errors = %v", c.Recorder.Code, c.Errors())
+	}
+}
+
+func TestJSONGuardSkipsOtherTypes(t *testing.T) {
+	for _, contentType := range []string{"text/plain", "application/problem+json; charset=utf-8"} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":`))
+		req.Header.Set(utils.HeaderContentType, contentType)
+		c := run(t, req, JSONGuard(), ok)
+		if passed := c.Body() == "ok"; passed != (contentType == "text/plain") {
+			t.Errorf("%s: passed = %v", contentType, passed)
+		}
+	}
+}
+
+func TestJSONGuardErrorHandler(t *testing.T) {
+	var got []error
+	handler := JSONGuard(ConfigJSONGuard{
+		MaxDepth:    1,
+		MaxTokens:   3,
+		MaxBodySize: 20,
+		ErrorHandler: func(c http.Context, err error) error {
+			got = append(got, err)
+			c.AbortWithStatus(utils.StatusBadRequest)
+			return nil
+		},
+	})
+	for _, body := range []string{`[[1]]`, `[1,2,3]`, `"` + strings.Repeat("x", 30) + `"`} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set(utils.HeaderContentType, "application/json")
+		run(t, req, handler, ok)
+	}
+	want := []error{ErrJSONTooDeep, ErrJSONTooManyTokens, ErrJSONTooLarge}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	stdHttp "net/http"
+	"sync"
+	"time"
+)
+
+// jwksMinRefetch limits how often an unknown kid triggers a new fetch
+const jwksMinRefetch = 1 * time.Minute
+
+// jwks caches the keys of a JSON Web Key Set endpoint
+type jwks struct {
+	mu      sync.Mutex
+	url     string
+	refresh time.Duration
+	client  *stdHttp.Client
+	keys    map[string]any
+	fetched time.Time
+	// err is the error of the last fetch
+	err error
+	// inflight is closed when the running fetch is done, nil when none is
+	inflight chan struct{}
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newJWKS(url string, refresh time.Duration) *jwks {
+	return &jwks{
+		url:     url,
+		refresh: refresh,
+		client:  &stdHttp.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// key returns the public key for the kid of the token. The set is fetched
+// without holding the lock, concurrent requests wait for the running fetch
+// instead of starting their own.
+func (j *jwks) key(header JWTHeader) (any, error) {
+	j.mu.Lock()
+	age := time.Since(j.fetched)
+	key, ok := j.keys[header.KeyID]
+	// Refetch when the cache is stale or the key was rotated in, a missing
+	// key may also be on its way with the running fetch
+	if age > j.refresh || (!ok && (age > jwksMinRefetch || j.inflight != nil)) {
+		inflight := j.inflight
+		if inflight == nil {
+			inflight = make(chan struct{})
+			j.inflight = inflight
+			j.fetched = time.Now()
+			j.mu.Unlock()
+
+			keys, err := j.fetch()
+
+			j.mu.Lock()
+			if err == nil {
+				j.keys = keys
+			}
+			j.err = err
+			j.inflight = nil
+			close(inflight)
+		} else {
+			j.mu.Unlock()
+			<-inflight
+			j.mu.Lock()
+		}
+		if j.keys == nil && j.err != nil {
+			err := j.err
+			j.mu.Unlock()
+			return nil, err
+		}
+		key, ok = j.keys[header.KeyID]
+	}
+	j.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("jwks: unknown kid %q", header.KeyID)
+	}
+	return key, nil
+}
+
+// fetch downloads the key set, keys it can't decode are skipped
+func (j *jwks) fetch() (map[string]any, error) {
+	res, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != stdHttp.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", res.StatusCode)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwks: unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errors.New("jwks: unsupported key type " + k.Kty)
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves the public keys by kid, fetches block while gate is
+// non-nil until it is closed
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	gate    chan struct{}
+	fetches atomic.Int32
+}
+
+func newJWKSServer(keys map[string]*rsa.PublicKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(stdHttp.HandlerFunc(func(w stdHttp.ResponseWriter, r *stdHttp.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		gate := s.gate
+		set := make([]jsonWebKey, 0, len(s.keys))
+		for kid, key := range s.keys {
+			set = append(set, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		s.mu.Unlock()
+		if gate != nil {
+			<-gate
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": set})
+	}))
+	return s
+}
+
+func TestJWKSKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newJWKSServer(map[string]*rsa.PublicKey{"a": &key.PublicKey})
+	defer srv.Close()
+	set := newJWKS(srv.URL, time.Hour)
+
+	got, err := set.key(JWTHeader{KeyID: "a"})
+	if err != nil || got.(*rsa.PublicKey).N.Cmp(key.N) != 0 {
+		t.Fatalf("key = %v, %v", got, err)
+	}
+	if _, err = set.key(JWTHeader{KeyID: "a"}); err != nil || srv.fetches.Load() != 1 {
+		t.Errorf("cached key refetched: %d fetches, %v", srv.fetches.Load(), err)
+	}
+	// An unknown kid doesn't refetch right after a fetch
+	if _, err = set.key(JWTHeader{KeyID: "b"}); err == nil || srv.fetches.Load() != 1 {
+		t.Errorf("unknown kid: %d fetches, %v", srv.fetches.Load(), err)
+	}
+}
+
+func TestJWKSFetchesOutsideTheLock(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newJWKSServer(map[string]*rsa.PublicKey{"a": &key.PublicKey})
+	defer srv.Close()
+	set := newJWKS(srv.URL, time.Hour)
+	if _, err = set.key(JWTHeader{KeyID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate in kid b and block the fetch it triggers
+	srv.mu.Lock()
+	srv.keys["b"] = &key.PublicKey
+	srv.gate = make(chan struct{})
+	srv.mu.Unlock()
+	set.mu.Lock()
+	set.fetched = time.Now().Add(-2 * jwksMinRefetch)
+	set.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := set.key(JWTHeader{KeyID: "b"})
+			errs <- err
+		}()
+	}
+	for srv.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Known keys are served while the fetch is running
+	done := make(chan error, 1)
+	go func() {
+		_, err := set.key(JWTHeader{KeyID: "a"})
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("cached key: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached key blocked behind the fetch")
+	}
+
+	close(srv.gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("rotated key: %v", err)
+		}
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, concurrent requests should share one", n)
+	}
+}
+
+func TestJWKSFetchError(t *testing.T) {
+	srv := httptest.NewServer(stdHttp.HandlerFunc(func(w stdHttp.ResponseWriter, r *stdHttp.Request) {
+		w.WriteHeader(stdHttp.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	if _, err := newJWKS(srv.URL, time.Hour).key(JWTHeader{KeyID: "a"}); err == nil {
+		t.Error("no error for a failed fetch")
+	}
+}
@@ -0,0 +1,404 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var (
+	// ErrJWTMissing is returned when the request carries no token
+	ErrJWTMissing = fmt.Errorf("jwt: missing token: %w", utils.ErrUnauthorized)
+	// ErrJWTMalformed is returned when the token can't be decoded
+	ErrJWTMalformed = fmt.Errorf("jwt: malformed token: %w", utils.ErrUnauthorized)
+	// ErrJWTInvalidSignature is returned when the algorithm or signature is not accepted
+	ErrJWTInvalidSignature = fmt.Errorf("jwt: invalid signature: %w", utils.ErrUnauthorized)
+	// ErrJWTExpired is returned when the exp claim is in the past
+	ErrJWTExpired = fmt.Errorf("jwt: token expired: %w", utils.ErrUnauthorized)
+	// ErrJWTNotValidYet is returned when the nbf claim is in the future
+	ErrJWTNotValidYet = fmt.Errorf("jwt: token not valid yet: %w", utils.ErrUnauthorized)
+	// ErrJWTInvalidClaims is returned when iss, aud or the ClaimsValidator reject the token
+	ErrJWTInvalidClaims = fmt.Errorf("jwt: invalid claims: %w", utils.ErrUnauthorized)
+)
+
+// JWTHeader is the decoded JOSE header of a token
+type JWTHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// JWTClaims are the decoded claims of a token
+type JWTClaims map[string]any
+
+// ConfigJWT defines the config for middleware.
+type ConfigJWT struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// TokenLookup is a string in the form of "<source>:<name>" that is used
+	// to extract the token from the request.
+	// Possible values:
+	// - "header:<name>"
+	// - "query:<name>"
+	// - "cookie:<name>"
+	//
+	// Optional. Default: "header:Authorization"
+	TokenLookup string
+
+	// AuthScheme is the scheme expected in front of a token read from a header
+	//
+	// Optional. Default: "Bearer"
+	AuthScheme string
+
+	// SigningMethod pins the accepted algorithm, e.g. "HS256", "RS256" or
+	// "ES256". Tokens using another algorithm are rejected.
+	//
+	// Optional. Default: "HS256"
+	SigningMethod string
+
+	// SigningKey verifies the signature, a []byte for HMAC, *rsa.PublicKey
+	// for RSA and *ecdsa.PublicKey for ECDSA algorithms
+	//
+	// Required unless KeyFunc or JWKSURL is set.
+	SigningKey any
+
+	// KeyFunc returns the verification key for a token, e.g. by its kid
+	//
+	// Optional. Default: nil
+	KeyFunc func(header JWTHeader) (any, error)
+
+	// JWKSURL is fetched for the verification keys, selected by kid
+	//
+	// Optional. Default: ""
+	JWKSURL string
+
+	// JWKSRefresh is how long fetched keys are cached
+	//
+	// Optional. Default: 1 * time.Hour
+	JWKSRefresh time.Duration
+
+	// Issuer is compared to the iss claim when set
+	//
+	// Optional. Default: ""
+	Issuer string
+
+	// Audience must be contained in the aud claim when set
+	//
+	// Optional. Default: ""
+	Audience string
+
+	// Leeway allows for clock skew when validating exp and nbf
+	//
+	// Optional. Default: 0
+	Leeway time.Duration
+
+	// ClaimsValidator runs application checks on the claims
+	//
+	// Optional. Default: nil
+	ClaimsValidator func(claims JWTClaims) error
+
+	// ContextClaims is the key to store the JWTClaims in the context
+	//
+	// Optional. Default: "claims"
+	ContextClaims string
+
+	// ErrorHandler is called with one of the ErrJWT errors
+	//
+	// Optional. Default: responds with 401 Unauthorized
+	ErrorHandler func(c http.Context, err error) error
+}
+
+// ConfigJWTDefault is the default config
+var ConfigJWTDefault = ConfigJWT{
+	Next:          nil,
+	TokenLookup:   "header:" + utils.HeaderAuthorization,
+	AuthScheme:    "Bearer",
+	SigningMethod: "HS256",
+	JWKSRefresh:   1 * time.Hour,
+	ContextClaims: "claims",
+	ErrorHandler: func(c http.Context, err error) error {
+		c.AbortWithStatus(utils.StatusUnauthorized)
+		return err
+	},
+}
+
+// Helper function to set default values
+func configJWTDefault(config ...ConfigJWT) ConfigJWT {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigJWTDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.TokenLookup == "" {
+		cfg.TokenLookup = ConfigJWTDefault.TokenLookup
+	}
+	if cfg.AuthScheme == "" {
+		cfg.AuthScheme = ConfigJWTDefault.AuthScheme
+	}
+	if cfg.SigningMethod == "" {
+		cfg.SigningMethod = ConfigJWTDefault.SigningMethod
+	}
+	if cfg.JWKSRefresh <= 0 {
+		cfg.JWKSRefresh = ConfigJWTDefault.JWKSRefresh
+	}
+	if cfg.ContextClaims == "" {
+		cfg.ContextClaims = ConfigJWTDefault.ContextClaims
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigJWTDefault.ErrorHandler
+	}
+	return cfg
+}
+
+// JWT creates a new middleware handler
+func JWT(config ConfigJWT) http.HandlerFunc {
+	// Set default config
+	cfg := configJWTDefault(config)
+
+	if _, ok := jwtAlgorithms[cfg.SigningMethod]; !ok {
+		panic("jwt: unsupported SigningMethod " + cfg.SigningMethod)
+	}
+	keyFunc := cfg.KeyFunc
+	if keyFunc == nil && cfg.JWKSURL != "" {
+		keyFunc = newJWKS(cfg.JWKSURL, cfg.JWKSRefresh).key
+	}
+	if keyFunc == nil {
+		if cfg.SigningKey == nil {
+			panic("jwt: SigningKey, KeyFunc or JWKSURL is required")
+		}
+		keyFunc = func(JWTHeader) (any, error) {
+			return cfg.SigningKey, nil
+		}
+	}
+	extractor := jwtExtractor(cfg.TokenLookup, cfg.AuthScheme)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		token := extractor(c)
+		if token == "" {
+			return cfg.ErrorHandler(c, ErrJWTMissing)
+		}
+		claims, err := parseJWT(token, &cfg, keyFunc)
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+
+		c.WithValue(cfg.ContextClaims, claims)
+		if sub, ok := claims["sub"].(string); ok {
+			SetPrincipal(c, sub)
+		}
+		return c.Next()
+	}
+}
+
+// parseJWT verifies the token and returns its claims
+func parseJWT(token string, cfg *ConfigJWT, keyFunc func(JWTHeader) (any, error)) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header JWTHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, ErrJWTMalformed
+	}
+	// Never trust the algorithm the token claims, alg=none included
+	if header.Algorithm != cfg.SigningMethod {
+		return nil, ErrJWTInvalidSignature
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	key, err := keyFunc(header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWTInvalidSignature, err)
+	}
+	if !verifyJWTSignature(header.Algorithm, parts[0]+"."+parts[1], signature, key) {
+		return nil, ErrJWTInvalidSignature
+	}
+
+	var claims JWTClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, ErrJWTMalformed
+	}
+
+	now := time.Now()
+	exp, hasExp, err := claims.time("exp")
+	if err != nil {
+		return nil, err
+	}
+	if hasExp && !now.Before(exp.Add(cfg.Leeway)) {
+		return nil, ErrJWTExpired
+	}
+	nbf, hasNbf, err := claims.time("nbf")
+	if err != nil {
+		return nil, err
+	}
+	if hasNbf && now.Add(cfg.Leeway).Before(nbf) {
+		return nil, ErrJWTNotValidYet
+	}
+	if cfg.Issuer != "" && claims["iss"] != cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrJWTInvalidClaims)
+	}
+	if cfg.Audience != "" && !claims.hasAudience(cfg.Audience) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrJWTInvalidClaims)
+	}
+	if cfg.ClaimsValidator != nil {
+		if err = cfg.ClaimsValidator(claims); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrJWTInvalidClaims, err)
+		}
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+type jwtAlgorithm struct {
+	hash crypto.Hash
+	kind string
+	size int
+}
+
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"HS256": {crypto.SHA256, "HS", 0},
+	"HS384": {crypto.SHA384, "HS", 0},
+	"HS512": {crypto.SHA512, "HS", 0},
+	"RS256": {crypto.SHA256, "RS", 0},
+	"RS384": {crypto.SHA384, "RS", 0},
+	"RS512": {crypto.SHA512, "RS", 0},
+	"ES256": {crypto.SHA256, "ES", 32},
+	"ES384": {crypto.SHA384, "ES", 48},
+	"ES512": {crypto.SHA512, "ES", 66},
+}
+
+// verifyJWTSignature checks the signature, the key type has to match the
+// algorithm family so an RSA public key can't be used as an HMAC secret
+func verifyJWTSignature(alg, signed string, signature []byte, key any) bool {
+	a, ok := jwtAlgorithms[alg]
+	if !ok {
+		return false
+	}
+	switch a.kind {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return false
+		}
+		mac := hmac.New(a.hash.New, secret)
+		mac.Write([]byte(signed))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		h := a.hash.New()
+		h.Write([]byte(signed))
+		return rsa.VerifyPKCS1v15(pub, a.hash, h.Sum(nil), signature) == nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 2*a.size {
+			return false
+		}
+		h := a.hash.New()
+		h.Write([]byte(signed))
+		r := new(big.Int).SetBytes(signature[:a.size])
+		s := new(big.Int).SetBytes(signature[a.size:])
+		return ecdsa.Verify(pub, h.Sum(nil), r, s)
+	}
+	return false
+}
+
+// time returns a NumericDate claim and whether it is present, a claim of
+// another type is malformed rather than absent so it can't be used to
+// skip the check
+func (c JWTClaims) time(name string) (time.Time, bool, error) {
+	switch v := c[name].(type) {
+	case nil:
+		if _, ok := c[name]; !ok {
+			return time.Time{}, false, nil
+		}
+	case float64:
+		return time.Unix(int64(v), 0), true, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return time.Unix(i, 0), true, nil
+		}
+	}
+	return time.Time{}, false, ErrJWTMalformed
+}
+
+// hasAudience reports whether the aud claim, a string or an array of
+// strings, contains the audience
+func (c JWTClaims) hasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jwtExtractor returns a function reading the token from the configured source
+func jwtExtractor(lookup, scheme string) func(c http.Context) string {
+	parts := strings.SplitN(lookup, ":", 2)
+	if len(parts) != 2 {
+		panic("jwt: TokenLookup must be in the form of <source>:<name>")
+	}
+	name := parts[1]
+	switch parts[0] {
+	case "header":
+		return func(c http.Context) string {
+			auth := c.Header(name, "")
+			if len(auth) > len(scheme)+1 && strings.EqualFold(auth[:len(scheme)], scheme) && auth[len(scheme)] == ' ' {
+				return strings.TrimSpace(auth[len(scheme)+1:])
+			}
+			return ""
+		}
+	case "query":
+		return func(c http.Context) string {
+			return c.Query(name, "")
+		}
+	case "cookie":
+		return func(c http.Context) string {
+			return c.Cookies(name)
+		}
+	}
+	panic("jwt: unsupported TokenLookup source " + parts[0])
+}
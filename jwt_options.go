@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// JWTOption sets a field of ConfigJWT, see JWTWith
+type JWTOption func(cfg *ConfigJWT)
+
+// JWTWith creates a JWT middleware from options applied to ConfigJWTDefault
+// in order, a later option overrides an earlier one
+//
+//	middleware.JWTWith(
+//		middleware.JWTSigningKey("HS256", secret),
+//		middleware.JWTIssuer("https://auth.example.com"),
+//	)
+func JWTWith(opts ...JWTOption) http.HandlerFunc {
+	cfg := ConfigJWTDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return JWT(cfg)
+}
+
+// JWTOptions bundles options into one, e.g. to share a preset between
+// services
+func JWTOptions(opts ...JWTOption) JWTOption {
+	return func(cfg *ConfigJWT) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// JWTNext sets ConfigJWT.Next
+func JWTNext(next func(c http.Context) bool) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.Next = next }
+}
+
+// JWTTokenLookup sets ConfigJWT.TokenLookup and AuthScheme
+func JWTTokenLookup(lookup, scheme string) JWTOption {
+	return func(cfg *ConfigJWT) {
+		cfg.TokenLookup = lookup
+		cfg.AuthScheme = scheme
+	}
+}
+
+// JWTSigningKey sets ConfigJWT.SigningMethod and SigningKey
+func JWTSigningKey(method string, key any) JWTOption {
+	return func(cfg *ConfigJWT) {
+		cfg.SigningMethod = method
+		cfg.SigningKey = key
+	}
+}
+
+// JWTKeyFunc sets ConfigJWT.KeyFunc
+func JWTKeyFunc(keyFunc func(header JWTHeader) (any, error)) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.KeyFunc = keyFunc }
+}
+
+// JWTKeySet sets ConfigJWT.JWKSURL and JWKSRefresh
+func JWTKeySet(url string, refresh time.Duration) JWTOption {
+	return func(cfg *ConfigJWT) {
+		cfg.JWKSURL = url
+		cfg.JWKSRefresh = refresh
+	}
+}
+
+// JWTIssuer sets ConfigJWT.Issuer
+func JWTIssuer(issuer string) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.Issuer = issuer }
+}
+
+// JWTAudience sets ConfigJWT.Audience
+func JWTAudience(audience string) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.Audience = audience }
+}
+
+// JWTLeeway sets ConfigJWT.Leeway
+func JWTLeeway(d time.Duration) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.Leeway = d }
+}
+
+// JWTClaimsValidator sets ConfigJWT.ClaimsValidator
+func JWTClaimsValidator(validator func(claims JWTClaims) error) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.ClaimsValidator = validator }
+}
+
+// JWTContextClaims sets ConfigJWT.ContextClaims
+func JWTContextClaims(key string) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.ContextClaims = key }
+}
+
+// JWTErrorHandler sets ConfigJWT.ErrorHandler
+func JWTErrorHandler(handler func(c http.Context, err error) error) JWTOption {
+	return func(cfg *ConfigJWT) { cfg.ErrorHandler = handler }
+}
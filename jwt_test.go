@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var jwtSecret = []byte("secret")
+
+// signJWT encodes the header and claims and signs them with sign
+func signJWT(header map[string]any, claims JWTClaims, sign func(signed string) []byte) string {
+	h, _ := json.Marshal(header)
+	p, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func hs256(signed string) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func rs256(key *rsa.PrivateKey) func(string) []byte {
+	return func(signed string) []byte {
+		sum := sha256.Sum256([]byte(signed))
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		return sig
+	}
+}
+
+func TestJWT(t *testing.T) {
+	now := time.Now()
+	hs := map[string]any{"alg": "HS256", "typ": "JWT"}
+	cfg := ConfigJWT{SigningKey: jwtSecret, Issuer: "issuer", Audience: "api", Leeway: time.Minute}
+	valid := JWTClaims{"sub": "john", "iss": "issuer", "aud": []string{"web", "api"}, "exp": now.Add(time.Hour).Unix()}
+	with := func(k string, v any) JWTClaims {
+		claims := JWTClaims{}
+		for key, val := range valid {
+			claims[key] = val
+		}
+		claims[k] = v
+		return claims
+	}
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", signJWT(hs, valid, hs256), nil},
+		{"missing", "", ErrJWTMissing},
+		{"malformed", "not.a-token", ErrJWTMalformed},
+		{"expired", signJWT(hs, with("exp", now.Add(-2*time.Minute).Unix()), hs256), ErrJWTExpired},
+		{"expired within leeway", signJWT(hs, with("exp", now.Add(-30*time.Second).Unix()), hs256), nil},
+		{"not valid yet", signJWT(hs, with("nbf", now.Add(2*time.Minute).Unix()), hs256), ErrJWTNotValidYet},
+		// Dates of another type don't skip the checks
+		{"string exp", signJWT(hs, with("exp", "2000-01-01"), hs256), ErrJWTMalformed},
+		{"null exp", signJWT(hs, with("exp", nil), hs256), ErrJWTMalformed},
+		{"string nbf", signJWT(hs, with("nbf", "later"), hs256), ErrJWTMalformed},
+		{"alg none", signJWT(map[string]any{"alg": "none"}, valid, func(string) []byte { return nil }), ErrJWTInvalidSignature},
+		{"wrong algorithm", signJWT(map[string]any{"alg": "HS512"}, valid, hs256), ErrJWTInvalidSignature},
+		{"bad signature", signJWT(hs, valid, func(string) []byte { return []byte("forged") }), ErrJWTInvalidSignature},
+		{"audience mismatch", signJWT(hs, with("aud", "other"), hs256), ErrJWTInvalidClaims},
+		{"issuer mismatch", signJWT(hs, with("iss", "other"), hs256), ErrJWTInvalidClaims},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.token != "" {
+			req.Header.Set(utils.HeaderAuthorization, "Bearer "+tt.token)
+		}
+		var claims JWTClaims
+		c := run(t, req, JWT(cfg), func(c http.Context) error {
+			claims, _ = c.Value("claims").(JWTClaims)
+			return c.String("ok")
+		})
+		err := c.Errors()[0]
+		if tt.want == nil {
+			if err != nil || c.Recorder.Code != utils.StatusOK || claims["sub"] != "john" {
+				t.Errorf("%s: err = %v, status = %d, claims = %v", tt.name, err, c.Recorder.Code, claims)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+		if c.Recorder.Code != utils.StatusUnauthorized || c.Body() == "ok" {
+			t.Errorf("%s: response = %d %q", tt.name, c.Recorder.Code, c.Body())
+		}
+	}
+}
+
+func TestJWTClaimsValidatorAndLookup(t *testing.T) {
+	errAdmin := errors.New("not an admin")
+	handler := JWT(ConfigJWT{
+		SigningKey:  jwtSecret,
+		TokenLookup: "cookie:token",
+		ClaimsValidator: func(claims JWTClaims) error {
+			if claims["role"] != "admin" {
+				return errAdmin
+			}
+			return nil
+		},
+	})
+	hs := map[string]any{"alg": "HS256"}
+	for role, want := range map[string]error{"admin": nil, "user": ErrJWTInvalidClaims} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", "token="+signJWT(hs, JWTClaims{"role": role}, hs256))
+		c := run(t, req, handler, ok)
+		if err := c.Errors()[0]; !errors.Is(err, want) {
+			t.Errorf("%s: err = %v, want %v", role, err, want)
+		}
+	}
+}
+
+func TestJWTRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := JWT(ConfigJWT{SigningMethod: "RS256", SigningKey: &key.PublicKey})
+	token := signJWT(map[string]any{"alg": "RS256"}, JWTClaims{"sub": "john"}, rs256(key))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAuthorization, "Bearer "+token)
+	if c := run(t, req, handler, ok); c.Errors()[0] != nil {
+		t.Errorf("err = %v", c.Errors()[0])
+	}
+
+	// An HMAC token signed with the public key as secret is refused
+	pub, _ := json.Marshal(key.PublicKey)
+	forged := signJWT(map[string]any{"alg": "HS256"}, JWTClaims{"sub": "john"}, func(signed string) []byte {
+		mac := hmac.New(sha256.New, pub)
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	})
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAuthorization, "Bearer "+forged)
+	if c := run(t, req, handler, ok); !errors.Is(c.Errors()[0], ErrJWTInvalidSignature) {
+		t.Errorf("err = %v", c.Errors()[0])
+	}
+}
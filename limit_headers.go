@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// LimitHeaders creates a new middleware handler rejecting requests with
+// more than maxCount header fields, or whose names and values add up to
+// more than maxBytes, with 431 Request Header Fields Too Large. A limit
+// of 0 disables the check.
+func LimitHeaders(maxCount int, maxBytes int) http.HandlerFunc {
+	// Return new handler
+	return func(c http.Context) error {
+		count, size := 0, 0
+		for name, values := range c.Origin().Header {
+			for _, value := range values {
+				count++
+				size += len(name) + len(value)
+			}
+		}
+		if (maxCount > 0 && count > maxCount) || (maxBytes > 0 && size > maxBytes) {
+			c.AbortWithStatus(utils.StatusRequestHeaderFieldsTooLarge)
+			return fmt.Errorf("%w: %d fields, %d bytes", ErrHeadersTooLarge, count, size)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestLimitHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		maxCount, maxBytes int
+		headers            map[string][]string
+		want               int
+	}{
+		{"normal", 3, 64, map[string][]string{"Accept": {"*/*"}, "X-A": {"1"}}, utils.StatusOK},
+		{"at the limits", 3, 12, map[string][]string{"X-A": {"1", "2"}, "X-B": {"3"}}, utils.StatusOK},
+		{"too many", 3, 0, map[string][]string{"X-A": {"1", "2"}, "X-B": {"3"}, "X-C": {"4"}}, utils.StatusRequestHeaderFieldsTooLarge},
+		{"too large", 0, 100, map[string][]string{"Cookie": {strings.Repeat("a", 100)}}, utils.StatusRequestHeaderFieldsTooLarge},
+		// The names count towards the size
+		{"long name", 0, 12, map[string][]string{"X-Long-Name": {"12"}}, utils.StatusRequestHeaderFieldsTooLarge},
+		{"no limits", 0, 0, map[string][]string{"X-A": {strings.Repeat("a", 1<<16)}}, utils.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		for name, values := range tt.headers {
+			for _, v := range values {
+				req.Header.Add(name, v)
+			}
+		}
+		c := run(t, req, LimitHeaders(tt.maxCount, tt.maxBytes), ok)
+		if c.Recorder.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.want)
+		}
+		if tt.want != utils.StatusOK && (c.Body() == "ok" || !errors.Is(c.Errors()[0], ErrHeadersTooLarge)) {
+			t.Errorf("%s: body = %q, err = %v", tt.name, c.Body(), c.Errors()[0])
+		}
+	}
+}
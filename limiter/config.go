@@ -1,9 +1,12 @@
 package limiter
 
 import (
+	"fmt"
+
 	"github.com/sujit-baniya/framework/contracts/http"
 	"github.com/sujit-baniya/framework/contracts/storage"
 	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/internal/defaults"
 	"time"
 )
 
@@ -26,12 +29,25 @@ type Config struct {
 	// }
 	KeyGenerator func(http.Context) string
 
+	// SeparateByMethod counts requests of each HTTP method separately, by
+	// prefixing the generated key with the method
+	//
+	// Default: false
+	SeparateByMethod bool
+
 	// Expiration is the time on how long to keep records of requests in memory
 	//
 	// Default: 1 * time.Minute
 	Expiration time.Duration
 
-	// LimitReached is called when a request hits the limit
+	// ExpirationFunc computes the window of a request, e.g. from the tier
+	// of the client. Results under a second fall back to Expiration.
+	//
+	// Default: nil
+	ExpirationFunc func(c http.Context) time.Duration
+
+	// LimitReached is called when a request hits the limit, RetryAfter
+	// returns the time until the limit resets
 	//
 	// Default: func(c http.Context) error {
 	//   return c.SendStatus(utils.StatusTooManyRequests)
@@ -48,17 +64,47 @@ type Config struct {
 	// Default: false
 	SkipSuccessfulRequests bool
 
+	// CountPredicate is called after the handler and decides whether the
+	// request is kept in the count, e.g. only for cache misses. It applies
+	// in addition to SkipFailedRequests and SkipSuccessfulRequests.
+	//
+	// Default: nil
+	CountPredicate func(c http.Context) bool
+
+	// StandardHeaders sends the RateLimit-Limit, RateLimit-Remaining,
+	// RateLimit-Reset and RateLimit-Policy headers of the IETF draft in
+	// addition to the X-RateLimit-* headers
+	//
+	// Default: false
+	StandardHeaders bool
+
 	// Store is used to store the state of the middleware
 	//
 	// Default: an in memory store for this process only
 	Storage storage.Storage
 
+	// Inspector exposes the current state of the FixedWindow and
+	// SlidingWindow limiters
+	//
+	// Default: nil
+	Inspector *Inspector
+
+	// Persister saves and restores the counters of the in-memory store
+	// across restarts, it is ignored with a Storage
+	//
+	// Default: nil
+	Persister *Persister
+
 	// LimiterMiddleware is the struct that implements a limiter middleware.
 	//
 	// Default: a new Fixed Window Rate Limiter
 	LimiterMiddleware LimiterHandler
 }
 
+// ErrRateLimited is returned by the default LimitReached, it wraps
+// utils.ErrTooManyRequests
+var ErrRateLimited = fmt.Errorf("%w: rate limited", utils.ErrTooManyRequests)
+
 // ConfigDefault is the default config
 var ConfigDefault = Config{
 	Max:        5,
@@ -68,7 +114,7 @@ var ConfigDefault = Config{
 	},
 	LimitReached: func(c http.Context) error {
 		c.AbortWithStatus(utils.StatusTooManyRequests)
-		return utils.ErrTooManyRequests
+		return ErrRateLimited
 	},
 	SkipFailedRequests:     false,
 	SkipSuccessfulRequests: false,
@@ -77,30 +123,47 @@ var ConfigDefault = Config{
 
 // Helper function to set default values
 func configDefault(config ...Config) Config {
-	// Return default config if nothing provided
-	if len(config) < 1 {
-		return ConfigDefault
-	}
+	return defaults.Config(config, ConfigDefault, func(cfg *Config) {
+		if cfg.Next == nil {
+			cfg.Next = ConfigDefault.Next
+		}
+		defaults.Positive(&cfg.Max, ConfigDefault.Max)
+		if int(cfg.Expiration.Seconds()) <= 0 {
+			cfg.Expiration = ConfigDefault.Expiration
+		}
+		if cfg.KeyGenerator == nil {
+			cfg.KeyGenerator = ConfigDefault.KeyGenerator
+		}
+		if cfg.SeparateByMethod {
+			keyGenerator := cfg.KeyGenerator
+			cfg.KeyGenerator = func(c http.Context) string {
+				return c.Method() + ":" + keyGenerator(c)
+			}
+		}
+		if cfg.LimitReached == nil {
+			cfg.LimitReached = ConfigDefault.LimitReached
+		}
+		if cfg.LimiterMiddleware == nil {
+			cfg.LimiterMiddleware = ConfigDefault.LimiterMiddleware
+		}
+	})
+}
 
-	// Override default config
-	cfg := config[0]
-	if cfg.Next == nil {
-		cfg.Next = ConfigDefault.Next
+// uncounted reports whether the hit of a finished request is taken back
+func (cfg Config) uncounted(c http.Context) bool {
+	if (cfg.SkipSuccessfulRequests && c.StatusCode() < utils.StatusBadRequest) ||
+		(cfg.SkipFailedRequests && c.StatusCode() >= utils.StatusBadRequest) {
+		return true
 	}
-	if cfg.Max <= 0 {
-		cfg.Max = ConfigDefault.Max
-	}
-	if int(cfg.Expiration.Seconds()) <= 0 {
-		cfg.Expiration = ConfigDefault.Expiration
-	}
-	if cfg.KeyGenerator == nil {
-		cfg.KeyGenerator = ConfigDefault.KeyGenerator
-	}
-	if cfg.LimitReached == nil {
-		cfg.LimitReached = ConfigDefault.LimitReached
-	}
-	if cfg.LimiterMiddleware == nil {
-		cfg.LimiterMiddleware = ConfigDefault.LimiterMiddleware
+	return cfg.CountPredicate != nil && !cfg.CountPredicate(c)
+}
+
+// window returns the expiration of the request's window
+func (cfg Config) window(c http.Context) time.Duration {
+	if cfg.ExpirationFunc != nil {
+		if d := cfg.ExpirationFunc(c); d >= time.Second {
+			return d
+		}
 	}
-	return cfg
+	return cfg.Expiration
 }
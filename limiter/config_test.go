@@ -0,0 +1,50 @@
+package limiter
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func TestConfigDefault(t *testing.T) {
+	cfg := configDefault()
+	if cfg.Max != 5 || cfg.Expiration != time.Minute || cfg.Next != nil {
+		t.Errorf("default = %+v", cfg)
+	}
+
+	for _, config := range []Config{{}, {Max: -1, Expiration: 500 * time.Millisecond}} {
+		cfg = configDefault(config)
+		if cfg.Max != ConfigDefault.Max || cfg.Expiration != ConfigDefault.Expiration {
+			t.Errorf("%+v: max, expiration = %d, %v", config, cfg.Max, cfg.Expiration)
+		}
+		if cfg.KeyGenerator == nil || cfg.LimitReached == nil {
+			t.Errorf("%+v: functions not defaulted", config)
+		}
+		if _, ok := cfg.LimiterMiddleware.(FixedWindow); !ok {
+			t.Errorf("%+v: LimiterMiddleware = %T", config, cfg.LimiterMiddleware)
+		}
+	}
+
+	cfg = configDefault(Config{Max: 10, Expiration: time.Hour, LimiterMiddleware: SlidingWindow{}})
+	if cfg.Max != 10 || cfg.Expiration != time.Hour {
+		t.Errorf("explicit = %+v", cfg)
+	}
+	if _, ok := cfg.LimiterMiddleware.(SlidingWindow); !ok {
+		t.Errorf("LimiterMiddleware = %T", cfg.LimiterMiddleware)
+	}
+}
+
+func TestConfigDefaultKeyGenerator(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Real-IP", "192.0.2.1")
+	c := middlewaretest.NewMockContext(req)
+
+	if key := configDefault(Config{}).KeyGenerator(c); key != "192.0.2.1" {
+		t.Errorf("key = %q", key)
+	}
+	if key := configDefault(Config{SeparateByMethod: true}).KeyGenerator(c); key != "POST:192.0.2.1" {
+		t.Errorf("separated key = %q", key)
+	}
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// Entry is the state of one limiter key
+type Entry struct {
+	Key   string    `json:"key"`
+	Hits  int       `json:"hits"`
+	Reset time.Time `json:"reset"`
+}
+
+// Inspector gives a read-only view of a limiter's keys, pass it in the
+// Config and call Snapshot or mount Handler
+type Inspector struct {
+	mu      sync.RWMutex
+	manager *manager
+}
+
+// NewInspector creates an inspector to be passed as Config.Inspector
+func NewInspector() *Inspector {
+	return &Inspector{}
+}
+
+// attach connects the inspector to the manager of a limiter
+func (i *Inspector) attach(m *manager) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	i.manager = m
+	i.mu.Unlock()
+}
+
+// Snapshot returns the keys that haven't expired, sorted by key
+func (i *Inspector) Snapshot() []Entry {
+	i.mu.RLock()
+	m := i.manager
+	i.mu.RUnlock()
+	if m == nil {
+		return nil
+	}
+
+	ts := uint64(atomic.LoadUint32(&utils.Timestamp))
+	m.mux.Lock()
+	items := m.snapshot(ts)
+	m.mux.Unlock()
+
+	entries := make([]Entry, 0, len(items))
+	for key, it := range items {
+		entries = append(entries, Entry{
+			Key:   key,
+			Hits:  it.currHits,
+			Reset: time.Unix(int64(it.exp), 0),
+		})
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Key < entries[b].Key
+	})
+	return entries
+}
+
+// Handler renders the snapshot as JSON, requests guard rejects get a
+// 403 Forbidden
+func (i *Inspector) Handler(guard func(c http.Context) bool) http.HandlerFunc {
+	if guard == nil {
+		panic("limiter: Inspector.Handler requires a guard")
+	}
+	return func(c http.Context) error {
+		if !guard(c) {
+			c.AbortWithStatus(utils.StatusForbidden)
+			return utils.ErrForbidden
+		}
+		body, err := json.Marshal(i.Snapshot())
+		if err != nil {
+			return err
+		}
+		c.SetHeader(utils.HeaderContentType, "application/json")
+		c.SetHeader(utils.HeaderCacheControl, "no-store")
+		c.Status(utils.StatusOK)
+		return c.String("%s", body)
+	}
+}
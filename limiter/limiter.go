@@ -1,6 +1,9 @@
 package limiter
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/sujit-baniya/framework/contracts/http"
 )
 
@@ -9,6 +12,16 @@ const (
 	xRateLimitLimit     = "X-RateLimit-Limit"
 	xRateLimitRemaining = "X-RateLimit-Remaining"
 	xRateLimitReset     = "X-RateLimit-Reset"
+
+	// RateLimit-* headers of the IETF draft
+	rateLimitLimit     = "RateLimit-Limit"
+	rateLimitRemaining = "RateLimit-Remaining"
+	rateLimitReset     = "RateLimit-Reset"
+	rateLimitPolicy    = "RateLimit-Policy"
+
+	// retryAfterContextKey stores the time until the window resets for
+	// LimitReached handlers
+	retryAfterContextKey = "limiter_retry_after"
 )
 
 type LimiterHandler interface {
@@ -23,3 +36,25 @@ func New(config ...Config) http.HandlerFunc {
 	// Return the specified middleware handler.
 	return cfg.LimiterMiddleware.New(cfg)
 }
+
+// RetryAfter returns the time until the limit resets, for use in custom
+// LimitReached handlers. It is 0 outside of LimitReached.
+func RetryAfter(c http.Context) time.Duration {
+	d, _ := c.Value(retryAfterContextKey).(time.Duration)
+	return d
+}
+
+// setHeaders sets the X-RateLimit-* headers, and the RateLimit-* headers
+// when StandardHeaders is on
+func (cfg Config) setHeaders(c http.Context, max string, remaining int, resetInSec uint64, policy string) {
+	reset := strconv.FormatUint(resetInSec, 10)
+	c.SetHeader(xRateLimitLimit, max)
+	c.SetHeader(xRateLimitRemaining, strconv.Itoa(remaining))
+	c.SetHeader(xRateLimitReset, reset)
+	if cfg.StandardHeaders {
+		c.SetHeader(rateLimitLimit, max)
+		c.SetHeader(rateLimitRemaining, strconv.Itoa(remaining))
+		c.SetHeader(rateLimitReset, reset)
+		c.SetHeader(rateLimitPolicy, policy)
+	}
+}
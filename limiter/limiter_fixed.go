@@ -4,8 +4,8 @@ import (
 	"github.com/sujit-baniya/framework/contracts/http"
 	"github.com/sujit-baniya/framework/utils"
 	"strconv"
-	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type FixedWindow struct{}
@@ -14,13 +14,16 @@ type FixedWindow struct{}
 func (FixedWindow) New(cfg Config) http.HandlerFunc {
 	var (
 		// Limiter variables
-		mux        = &sync.RWMutex{}
-		max        = strconv.Itoa(cfg.Max)
-		expiration = uint64(cfg.Expiration.Seconds())
+		max = strconv.Itoa(cfg.Max)
+		// Quota of the RateLimit-Policy header, e.g. 100;w=60
+		policy = max + ";w=" + strconv.FormatUint(uint64(cfg.Expiration.Seconds()), 10)
 	)
 
 	// Create manager to simplify storage operations ( see manager.go )
 	manager := newManager(cfg.Storage)
+	mux := &manager.mux
+	cfg.Inspector.attach(manager)
+	cfg.Persister.attach(manager)
 
 	// Update timestamp every second
 	utils.StartTimeStampUpdater()
@@ -35,6 +38,10 @@ func (FixedWindow) New(cfg Config) http.HandlerFunc {
 		// Get key from request
 		key := cfg.KeyGenerator(c)
 
+		// Get the window of this request
+		window := cfg.window(c)
+		expiration := uint64(window.Seconds())
+
 		// Lock entry
 		mux.Lock()
 
@@ -63,7 +70,7 @@ func (FixedWindow) New(cfg Config) http.HandlerFunc {
 		remaining := cfg.Max - e.currHits
 
 		// Update storage
-		manager.set(key, e, cfg.Expiration)
+		manager.set(key, e, window)
 
 		// Unlock entry
 		mux.Unlock()
@@ -74,32 +81,36 @@ func (FixedWindow) New(cfg Config) http.HandlerFunc {
 			// https://tools.ietf.org/html/rfc6584
 			c.SetHeader(utils.HeaderRetryAfter, strconv.FormatUint(resetInSec, 10))
 
+			// Custom handlers may replace the headers, keep the reset at hand
+			c.WithValue(retryAfterContextKey, time.Duration(resetInSec)*time.Second)
+
 			// Call LimitReached handler
 			return cfg.LimitReached(c)
 		}
 
+		// The headers can't change once the rest of the chain wrote the
+		// response, a request that turns out uncounted reports one hit more
+		quota := policy
+		if cfg.ExpirationFunc != nil {
+			quota = max + ";w=" + strconv.FormatUint(expiration, 10)
+		}
+		cfg.setHeaders(c, max, remaining, resetInSec, quota)
+
 		// Continue stack for reaching c.Response().StatusCode()
 		// Store err for returning
 		err := c.Next()
 
-		// Check for SkipFailedRequests and SkipSuccessfulRequests
-		if (cfg.SkipSuccessfulRequests && c.StatusCode() < utils.StatusBadRequest) ||
-			(cfg.SkipFailedRequests && c.StatusCode() >= utils.StatusBadRequest) {
+		// Check for SkipFailedRequests, SkipSuccessfulRequests and CountPredicate
+		if cfg.uncounted(c) {
 			// Lock entry
 			mux.Lock()
 			e = manager.get(key)
 			e.currHits--
-			remaining++
-			manager.set(key, e, cfg.Expiration)
+			manager.set(key, e, window)
 			// Unlock entry
 			mux.Unlock()
 		}
 
-		// We can continue, update RateLimit headers
-		c.SetHeader(xRateLimitLimit, max)
-		c.SetHeader(xRateLimitRemaining, strconv.Itoa(remaining))
-		c.SetHeader(xRateLimitReset, strconv.FormatUint(resetInSec, 10))
-
 		return err
 	}
 }
@@ -4,7 +4,6 @@ import (
 	"github.com/sujit-baniya/framework/contracts/http"
 	"github.com/sujit-baniya/framework/utils"
 	"strconv"
-	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -15,13 +14,16 @@ type SlidingWindow struct{}
 func (SlidingWindow) New(cfg Config) http.HandlerFunc {
 	var (
 		// Limiter variables
-		mux        = &sync.RWMutex{}
-		max        = strconv.Itoa(cfg.Max)
-		expiration = uint64(cfg.Expiration.Seconds())
+		max = strconv.Itoa(cfg.Max)
+		// Quota of the RateLimit-Policy header, e.g. 100;w=60
+		policy = max + ";w=" + strconv.FormatUint(uint64(cfg.Expiration.Seconds()), 10)
 	)
 
 	// Create manager to simplify storage operations ( see manager.go )
 	manager := newManager(cfg.Storage)
+	mux := &manager.mux
+	cfg.Inspector.attach(manager)
+	cfg.Persister.attach(manager)
 
 	// Update timestamp every second
 	utils.StartTimeStampUpdater()
@@ -36,6 +38,10 @@ func (SlidingWindow) New(cfg Config) http.HandlerFunc {
 		// Get key from request
 		key := cfg.KeyGenerator(c)
 
+		// Get the window of this request
+		window := cfg.window(c)
+		expiration := uint64(window.Seconds())
+
 		// Lock entry
 		mux.Lock()
 
@@ -105,28 +111,36 @@ func (SlidingWindow) New(cfg Config) http.HandlerFunc {
 			// https://tools.ietf.org/html/rfc6584
 			c.SetHeader(utils.HeaderRetryAfter, strconv.FormatUint(resetInSec, 10))
 
+			// Custom handlers may replace the headers, keep the reset at hand
+			c.WithValue(retryAfterContextKey, time.Duration(resetInSec)*time.Second)
+
 			// Call LimitReached handler
 			return cfg.LimitReached(c)
 		}
 
+		// The headers can't change once the rest of the chain wrote the
+		// response, a request that turns out uncounted reports one hit more
+		quota := policy
+		if cfg.ExpirationFunc != nil {
+			quota = max + ";w=" + strconv.FormatUint(expiration, 10)
+		}
+		cfg.setHeaders(c, max, remaining, resetInSec, quota)
+
 		// Continue stack for reaching c.StatusCode()
 		// Store err for returning
 		err := c.Next()
 
-		// Check for SkipFailedRequests and SkipSuccessfulRequests
-		if (cfg.SkipSuccessfulRequests && c.StatusCode() < utils.StatusBadRequest) ||
-			(cfg.SkipFailedRequests && c.StatusCode() >= utils.StatusBadRequest) {
+		// Check for SkipFailedRequests, SkipSuccessfulRequests and CountPredicate
+		if cfg.uncounted(c) {
+			// Lock entry, the storage may hold a copy only
 			mux.Lock()
+			e = manager.get(key)
 			e.currHits--
-			remaining++
+			manager.set(key, e, time.Duration(resetInSec+expiration)*time.Second)
+			// Unlock entry
 			mux.Unlock()
 		}
 
-		// We can continue, update RateLimit headers
-		c.SetHeader(xRateLimitLimit, max)
-		c.SetHeader(xRateLimitRemaining, strconv.Itoa(remaining))
-		c.SetHeader(xRateLimitReset, strconv.FormatUint(resetInSec, 10))
-
 		return err
 	}
 }
@@ -0,0 +1,233 @@
+package limiter
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// hit sends a request through the limiter and returns the response
+func hit(handler, final http.HandlerFunc) *httptest.ResponseRecorder {
+	return hitMethod("GET", handler, final)
+}
+
+// hitMethod is hit with a method other than GET
+func hitMethod(method string, handler, final http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set("X-Real-IP", "192.0.2.10")
+	c := middlewaretest.NewMockContext(req, handler, final)
+	_ = c.Run()
+	return c.Recorder
+}
+
+// responseHeader reads a header set by the rest of the chain
+func responseHeader(c http.Context, key string) string {
+	return c.(*middlewaretest.MockContext).Res.Header().Get(key)
+}
+
+func TestCountPredicate(t *testing.T) {
+	// Cache hits are free, only misses count
+	cached := func(hit bool) http.HandlerFunc {
+		return func(c http.Context) error {
+			if hit {
+				c.SetHeader("X-Cache", "HIT")
+			}
+			return c.String("ok")
+		}
+	}
+	for _, tt := range []struct {
+		middleware LimiterHandler
+		storage    *mapStorage
+	}{
+		{FixedWindow{}, nil},
+		{SlidingWindow{}, nil},
+		{FixedWindow{}, newMapStorage()},
+		{SlidingWindow{}, newMapStorage()},
+	} {
+		middleware := tt.middleware
+		cfg := Config{
+			Max:               2,
+			LimiterMiddleware: middleware,
+			CountPredicate: func(c http.Context) bool {
+				return responseHeader(c, "X-Cache") != "HIT"
+			},
+		}
+		if tt.storage != nil {
+			// The retraction has to reach storages holding copies
+			cfg.Storage = tt.storage
+		}
+		handler := New(cfg)
+		for i := 0; i < 5; i++ {
+			if res := hit(handler, cached(true)); res.Code != stdHttp.StatusOK {
+				t.Fatalf("%T storage %v: cache hit %d got %d", middleware, tt.storage != nil, i, res.Code)
+			}
+		}
+		hit(handler, cached(false))
+		hit(handler, cached(false))
+		if res := hit(handler, cached(true)); res.Code != stdHttp.StatusTooManyRequests {
+			t.Errorf("%T storage %v: status = %d after two misses", middleware, tt.storage != nil, res.Code)
+		}
+	}
+}
+
+func TestSeparateByMethod(t *testing.T) {
+	ok := func(c http.Context) error { return c.String("ok") }
+	for _, separate := range []bool{false, true} {
+		for _, middleware := range []LimiterHandler{FixedWindow{}, SlidingWindow{}} {
+			handler := New(Config{Max: 1, SeparateByMethod: separate, LimiterMiddleware: middleware})
+			if res := hitMethod("GET", handler, ok); res.Code != stdHttp.StatusOK {
+				t.Fatalf("%T %v: first GET got %d", middleware, separate, res.Code)
+			}
+			want := stdHttp.StatusTooManyRequests
+			if separate {
+				want = stdHttp.StatusOK
+			}
+			if res := hitMethod("POST", handler, ok); res.Code != want {
+				t.Errorf("%T %v: POST got %d, want %d", middleware, separate, res.Code, want)
+			}
+			// The GET counter is used up either way
+			if res := hitMethod("GET", handler, ok); res.Code != stdHttp.StatusTooManyRequests {
+				t.Errorf("%T %v: second GET got %d", middleware, separate, res.Code)
+			}
+		}
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	ok := func(c http.Context) error { return c.String("ok") }
+	perTier := func(c http.Context) time.Duration { return 2 * time.Minute }
+	for _, middleware := range []LimiterHandler{FixedWindow{}, SlidingWindow{}} {
+		for _, tt := range []struct {
+			name   string
+			cfg    Config
+			policy string
+		}{
+			{"standard", Config{Max: 100, Expiration: time.Minute, StandardHeaders: true}, "100;w=60"},
+			{"window per request", Config{Max: 5, ExpirationFunc: perTier, StandardHeaders: true}, "5;w=120"},
+			{"legacy only", Config{Max: 100, Expiration: time.Minute}, ""},
+		} {
+			tt.cfg.LimiterMiddleware = middleware
+			handler := New(tt.cfg)
+			hit(handler, ok)
+			// Result holds the headers as they were when the response was
+			// written, later changes don't reach the client
+			h := hit(handler, ok).Result().Header
+			limit := strconv.Itoa(tt.cfg.Max)
+			remaining := strconv.Itoa(tt.cfg.Max - 2)
+			if h.Get("X-RateLimit-Limit") != limit || h.Get("X-RateLimit-Remaining") != remaining || h.Get("X-RateLimit-Reset") == "" {
+				t.Errorf("%T %s: X-RateLimit headers = %v", middleware, tt.name, h)
+			}
+			if got := h.Get("RateLimit-Policy"); got != tt.policy {
+				t.Errorf("%T %s: RateLimit-Policy = %q, want %q", middleware, tt.name, got, tt.policy)
+			}
+			if tt.policy == "" {
+				if h.Get("RateLimit-Limit") != "" {
+					t.Errorf("%T %s: standard headers without StandardHeaders", middleware, tt.name)
+				}
+				continue
+			}
+			if h.Get("RateLimit-Limit") != limit || h.Get("RateLimit-Remaining") != remaining || h.Get("RateLimit-Reset") != h.Get("X-RateLimit-Reset") {
+				t.Errorf("%T %s: RateLimit headers = %v", middleware, tt.name, h)
+			}
+		}
+	}
+}
+
+// ttlStorage records the expiration of the entries it stores
+type ttlStorage struct {
+	*mapStorage
+	ttls map[string]time.Duration
+}
+
+func (s *ttlStorage) Set(key string, val []byte, exp time.Duration) error {
+	s.ttls[key] = exp
+	return s.mapStorage.Set(key, val, exp)
+}
+
+func TestExpirationFunc(t *testing.T) {
+	ok := func(c http.Context) error { return c.String("ok") }
+	tier := func(c http.Context) time.Duration {
+		switch c.Header("X-Real-IP", "") {
+		case "192.0.2.1":
+			return 10 * time.Minute
+		case "192.0.2.2":
+			// Under a second falls back to Expiration
+			return time.Millisecond
+		}
+		return 30 * time.Second
+	}
+	for _, middleware := range []LimiterHandler{FixedWindow{}, SlidingWindow{}} {
+		storage := &ttlStorage{mapStorage: newMapStorage(), ttls: make(map[string]time.Duration)}
+		handler := New(Config{
+			Max:               10,
+			Expiration:        time.Minute,
+			ExpirationFunc:    tier,
+			Storage:           storage,
+			LimiterMiddleware: middleware,
+		})
+		for ip, want := range map[string]string{"192.0.2.1": "600", "192.0.2.2": "60", "192.0.2.3": "30"} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Real-IP", ip)
+			c := middlewaretest.NewMockContext(req, handler, ok)
+			_ = c.Run()
+			if got := c.Recorder.Header().Get("X-RateLimit-Reset"); got != want {
+				t.Errorf("%T %s: reset = %s, want %s", middleware, ip, got, want)
+			}
+			seconds, _ := strconv.Atoi(want)
+			wantTTL := time.Duration(seconds) * time.Second
+			if _, sliding := middleware.(SlidingWindow); sliding {
+				// The sliding window keeps the entry for the next window too
+				wantTTL *= 2
+			}
+			if ttl := storage.ttls[ip]; ttl != wantTTL {
+				t.Errorf("%T %s: stored for %v, want %v", middleware, ip, ttl, wantTTL)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, middleware := range []LimiterHandler{FixedWindow{}, SlidingWindow{}} {
+		var passed, reached time.Duration
+		handler := New(Config{
+			Max:               1,
+			Expiration:        30 * time.Second,
+			LimiterMiddleware: middleware,
+			LimitReached: func(c http.Context) error {
+				reached = RetryAfter(c)
+				// A custom handler dropping the header still knows the reset
+				c.(*middlewaretest.MockContext).Res.Header().Del("Retry-After")
+				return c.String("retry in %d", int(reached.Seconds()))
+			},
+		})
+		final := func(c http.Context) error {
+			passed = RetryAfter(c)
+			return c.String("ok")
+		}
+		hit(handler, final)
+		rec := hit(handler, final)
+		if passed != 0 {
+			t.Errorf("%T: RetryAfter = %v outside of LimitReached", middleware, passed)
+		}
+		// The sliding window waits for its current window to end
+		if reached <= 0 || reached > 30*time.Second || reached%time.Second != 0 {
+			t.Errorf("%T: RetryAfter = %v", middleware, reached)
+		}
+		if want := "retry in " + strconv.Itoa(int(reached.Seconds())); rec.Body.String() != want {
+			t.Errorf("%T: body = %q, want %q", middleware, rec.Body.String(), want)
+		}
+	}
+
+	// The default LimitReached sends the same reset as Retry-After
+	handler := New(Config{Max: 1, Expiration: 30 * time.Second})
+	ok := func(c http.Context) error { return c.String("ok") }
+	hit(handler, ok)
+	if rec := hit(handler, ok); rec.Code != stdHttp.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
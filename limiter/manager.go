@@ -2,12 +2,19 @@ package limiter
 
 import (
 	"github.com/sujit-baniya/middleware/limiter/memory"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	contractStorage "github.com/sujit-baniya/framework/contracts/storage"
+	"github.com/sujit-baniya/framework/utils"
 )
 
+// minPruneKeys is the number of tracked keys before set first prunes the
+// expired ones
+const minPruneKeys = 1024
+
 // go:generate msgp
 // msgp -file="manager.go" -o="manager_msgp.go" -tests=false -unexported
 // don't forget to replace the msgp import path to:
@@ -20,9 +27,18 @@ type item struct {
 
 //msgp:ignore manager
 type manager struct {
+	// mux guards the entries, it is held by the limiter while it updates
+	// an entry and by snapshot
+	mux     sync.Mutex
 	pool    sync.Pool
 	memory  *memory.Storage
 	storage contractStorage.Storage
+
+	// keys seen by this process and when they expire, the storage
+	// contract can't list them
+	keys map[string]uint64
+	// pruneAt is the number of keys at which set drops the expired ones
+	pruneAt int
 }
 
 func newManager(storage contractStorage.Storage) *manager {
@@ -37,6 +53,8 @@ func newManager(storage contractStorage.Storage) *manager {
 	if storage != nil {
 		// Use provided storage if provided
 		manager.storage = storage
+		manager.keys = make(map[string]uint64)
+		manager.pruneAt = minPruneKeys
 	} else {
 		// Fallback too memory storage
 		manager.memory = memory.New()
@@ -89,6 +107,7 @@ func (m *manager) set(key string, it *item, exp time.Duration) {
 	if m.storage != nil {
 		if raw, err := it.MarshalMsg(nil); err == nil {
 			_ = m.storage.Set(key, raw, exp)
+			m.track(key, exp)
 		}
 		// we can release data because it's serialized to database
 		m.release(it)
@@ -101,16 +120,71 @@ func (m *manager) set(key string, it *item, exp time.Duration) {
 func (m *manager) setRaw(key string, raw []byte, exp time.Duration) {
 	if m.storage != nil {
 		_ = m.storage.Set(key, raw, exp)
+		m.track(key, exp)
 	} else {
 		m.memory.Set(key, raw, exp)
 	}
 }
 
+// track records a key written to the storage, dropping the expired keys
+// once there are pruneAt of them so the map doesn't grow with every key
+// ever seen. The caller must hold mux.
+func (m *manager) track(key string, exp time.Duration) {
+	ts := uint64(atomic.LoadUint32(&utils.Timestamp))
+	deadline := ts + uint64(exp.Seconds())
+	if exp <= 0 {
+		// The key never expires
+		deadline = math.MaxUint64
+	}
+	m.keys[key] = deadline
+	if len(m.keys) < m.pruneAt {
+		return
+	}
+	for k, deadline := range m.keys {
+		if deadline <= ts {
+			delete(m.keys, k)
+		}
+	}
+	// Wait for the map to double before the next pass, keeping set
+	// amortized O(1) when most keys are still live
+	m.pruneAt = 2 * len(m.keys)
+	if m.pruneAt < minPruneKeys {
+		m.pruneAt = minPruneKeys
+	}
+}
+
 // delete data from storage or memory
 func (m *manager) delete(key string) {
 	if m.storage != nil {
 		_ = m.storage.Delete(key)
+		delete(m.keys, key)
 	} else {
 		m.memory.Delete(key)
 	}
 }
+
+// snapshot returns the entries that haven't expired at ts, the caller
+// must hold mux
+func (m *manager) snapshot(ts uint64) map[string]item {
+	entries := make(map[string]item)
+	if m.storage == nil {
+		m.memory.Range(func(key string, val interface{}) {
+			if it, ok := val.(*item); ok && it.exp > ts {
+				entries[key] = *it
+			}
+		})
+		return entries
+	}
+	for key := range m.keys {
+		raw, _ := m.storage.Get(key)
+		if raw == nil {
+			delete(m.keys, key)
+			continue
+		}
+		var it item
+		if _, err := it.UnmarshalMsg(raw); err == nil && it.exp > ts {
+			entries[key] = it
+		}
+	}
+	return entries
+}
@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// mapStorage is a storage.Storage keeping the values without expiring them
+type mapStorage struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newMapStorage() *mapStorage {
+	return &mapStorage{entries: make(map[string][]byte)}
+}
+
+func (s *mapStorage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *mapStorage) Set(key string, val []byte, _ time.Duration) error {
+	s.mu.Lock()
+	s.entries[key] = append([]byte(nil), val...)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *mapStorage) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *mapStorage) Reset() error {
+	s.mu.Lock()
+	s.entries = make(map[string][]byte)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *mapStorage) Close() error { return nil }
+
+func TestInspectorSnapshot(t *testing.T) {
+	for _, storage := range []*mapStorage{nil, newMapStorage()} {
+		inspector := NewInspector()
+		cfg := Config{Max: 10, Inspector: inspector}
+		if storage != nil {
+			cfg.Storage = storage
+		}
+		handler := New(cfg)
+		for _, ip := range []string{"192.0.2.1", "192.0.2.1", "192.0.2.2"} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Real-IP", ip)
+			_ = middlewaretest.NewMockContext(req, handler).Run()
+		}
+
+		entries := inspector.Snapshot()
+		if len(entries) != 2 || entries[0].Hits != 2 || entries[1].Hits != 1 {
+			t.Fatalf("storage %v: entries = %+v", storage != nil, entries)
+		}
+		if reset := time.Until(entries[0].Reset); reset <= 0 || reset > time.Minute+time.Second {
+			t.Errorf("reset in %v", reset)
+		}
+	}
+}
+
+func TestSnapshotExcludesExpired(t *testing.T) {
+	utils.StartTimeStampUpdater()
+	m := newManager(newMapStorage())
+	ts := uint64(atomic.LoadUint32(&utils.Timestamp))
+	m.set("live", &item{currHits: 1, exp: ts + 60}, time.Minute)
+	m.set("expired", &item{currHits: 1, exp: ts - 1}, time.Minute)
+
+	entries := m.snapshot(ts)
+	if _, ok := entries["live"]; !ok || len(entries) != 1 {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestManagerPrunesExpiredKeys(t *testing.T) {
+	m := newManager(newMapStorage())
+	for i := 0; i < minPruneKeys-1; i++ {
+		// Keys from earlier windows the storage already expired
+		m.keys[fmt.Sprintf("key-%d", i)] = 1
+	}
+	m.set("live", &item{currHits: 1}, time.Minute)
+	if len(m.keys) != 1 {
+		t.Fatalf("tracked keys = %d, want the live key only", len(m.keys))
+	}
+	if m.pruneAt != minPruneKeys {
+		t.Errorf("pruneAt = %d", m.pruneAt)
+	}
+
+	m.delete("live")
+	if len(m.keys) != 0 {
+		t.Errorf("deleted key still tracked")
+	}
+}
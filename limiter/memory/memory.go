@@ -58,6 +58,48 @@ func (s *Storage) Delete(key string) {
 	s.Unlock()
 }
 
+// Range calls fn for every key that hasn't expired
+func (s *Storage) Range(fn func(key string, val interface{})) {
+	ts := atomic.LoadUint32(&utils.Timestamp)
+	s.RLock()
+	defer s.RUnlock()
+	for key, v := range s.data {
+		if v.e != 0 && v.e <= ts {
+			continue
+		}
+		fn(key, v.v)
+	}
+}
+
+// RangeExpiry calls fn for every key that hasn't expired with its
+// expiration, the zero time for keys without one
+func (s *Storage) RangeExpiry(fn func(key string, val interface{}, exp time.Time)) {
+	ts := atomic.LoadUint32(&utils.Timestamp)
+	s.RLock()
+	defer s.RUnlock()
+	for key, v := range s.data {
+		if v.e != 0 && v.e <= ts {
+			continue
+		}
+		var exp time.Time
+		if v.e != 0 {
+			exp = time.Unix(int64(v.e), 0)
+		}
+		fn(key, v.v, exp)
+	}
+}
+
+// SetExpiry sets key with value expiring at exp, the zero time for none
+func (s *Storage) SetExpiry(key string, val interface{}, exp time.Time) {
+	var e uint32
+	if !exp.IsZero() {
+		e = uint32(exp.Unix())
+	}
+	s.Lock()
+	s.data[key] = item{e, val}
+	s.Unlock()
+}
+
 // Reset all keys
 func (s *Storage) Reset() {
 	s.Lock()
@@ -0,0 +1,117 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// Option sets a field of Config, see NewWith
+type Option func(cfg *Config)
+
+// NewWith creates a limiter from options applied to ConfigDefault in
+// order, a later option overrides an earlier one
+//
+//	limiter.NewWith(
+//		limiter.WithMax(100),
+//		limiter.WithExpiration(time.Minute),
+//		limiter.WithStandardHeaders(),
+//	)
+func NewWith(opts ...Option) http.HandlerFunc {
+	cfg := ConfigDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return New(cfg)
+}
+
+// Options bundles options into one, e.g. to share a preset between
+// services
+func Options(opts ...Option) Option {
+	return func(cfg *Config) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// WithNext sets Config.Next
+func WithNext(next func(c http.Context) bool) Option {
+	return func(cfg *Config) { cfg.Next = next }
+}
+
+// WithMax sets Config.Max
+func WithMax(max int) Option {
+	return func(cfg *Config) { cfg.Max = max }
+}
+
+// WithKeyGenerator sets Config.KeyGenerator
+func WithKeyGenerator(keyGenerator func(http.Context) string) Option {
+	return func(cfg *Config) { cfg.KeyGenerator = keyGenerator }
+}
+
+// WithSeparateByMethod sets Config.SeparateByMethod
+func WithSeparateByMethod() Option {
+	return func(cfg *Config) { cfg.SeparateByMethod = true }
+}
+
+// WithExpiration sets Config.Expiration
+func WithExpiration(d time.Duration) Option {
+	return func(cfg *Config) { cfg.Expiration = d }
+}
+
+// WithExpirationFunc sets Config.ExpirationFunc
+func WithExpirationFunc(fn func(c http.Context) time.Duration) Option {
+	return func(cfg *Config) { cfg.ExpirationFunc = fn }
+}
+
+// WithLimitReached sets Config.LimitReached
+func WithLimitReached(handler http.HandlerFunc) Option {
+	return func(cfg *Config) { cfg.LimitReached = handler }
+}
+
+// WithSkipFailedRequests sets Config.SkipFailedRequests
+func WithSkipFailedRequests() Option {
+	return func(cfg *Config) { cfg.SkipFailedRequests = true }
+}
+
+// WithSkipSuccessfulRequests sets Config.SkipSuccessfulRequests
+func WithSkipSuccessfulRequests() Option {
+	return func(cfg *Config) { cfg.SkipSuccessfulRequests = true }
+}
+
+// WithCountPredicate sets Config.CountPredicate
+func WithCountPredicate(predicate func(c http.Context) bool) Option {
+	return func(cfg *Config) { cfg.CountPredicate = predicate }
+}
+
+// WithStandardHeaders sets Config.StandardHeaders
+func WithStandardHeaders() Option {
+	return func(cfg *Config) { cfg.StandardHeaders = true }
+}
+
+// WithStorage sets Config.Storage
+func WithStorage(store storage.Storage) Option {
+	return func(cfg *Config) { cfg.Storage = store }
+}
+
+// WithInspector sets Config.Inspector
+func WithInspector(inspector *Inspector) Option {
+	return func(cfg *Config) { cfg.Inspector = inspector }
+}
+
+// WithPersister sets Config.Persister
+func WithPersister(persister *Persister) Option {
+	return func(cfg *Config) { cfg.Persister = persister }
+}
+
+// WithSlidingWindow sets Config.LimiterMiddleware to SlidingWindow
+func WithSlidingWindow() Option {
+	return func(cfg *Config) { cfg.LimiterMiddleware = SlidingWindow{} }
+}
+
+// WithLimiterMiddleware sets Config.LimiterMiddleware
+func WithLimiterMiddleware(handler LimiterHandler) Option {
+	return func(cfg *Config) { cfg.LimiterMiddleware = handler }
+}
@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func TestOptions(t *testing.T) {
+	store := newMapStorage()
+	inspector := NewInspector()
+	persister := NewPersister("limiter.json")
+	cfg := ConfigDefault
+	Options(
+		WithMax(10),
+		WithSeparateByMethod(),
+		WithExpiration(time.Hour),
+		WithSkipFailedRequests(),
+		WithSkipSuccessfulRequests(),
+		WithStandardHeaders(),
+		WithStorage(store),
+		WithInspector(inspector),
+		WithPersister(persister),
+		WithSlidingWindow(),
+	)(&cfg)
+	if cfg.Max != 10 || !cfg.SeparateByMethod || cfg.Expiration != time.Hour || !cfg.SkipFailedRequests ||
+		!cfg.SkipSuccessfulRequests || !cfg.StandardHeaders || cfg.Storage != store ||
+		cfg.Inspector != inspector || cfg.Persister != persister {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if _, ok := cfg.LimiterMiddleware.(SlidingWindow); !ok {
+		t.Errorf("LimiterMiddleware = %T", cfg.LimiterMiddleware)
+	}
+
+	cfg = ConfigDefault
+	key := func(http.Context) string { return "k" }
+	Options(
+		WithKeyGenerator(key),
+		WithExpirationFunc(func(http.Context) time.Duration { return time.Second }),
+		WithCountPredicate(func(http.Context) bool { return true }),
+		WithLimitReached(func(http.Context) error { return nil }),
+		WithNext(func(http.Context) bool { return false }),
+		WithLimiterMiddleware(FixedWindow{}),
+	)(&cfg)
+	if cfg.KeyGenerator == nil || cfg.KeyGenerator(nil) != "k" || cfg.ExpirationFunc == nil ||
+		cfg.CountPredicate == nil || cfg.LimitReached == nil || cfg.Next == nil {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestNewWith(t *testing.T) {
+	handler := NewWith(WithMax(2))
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", "192.0.2.50")
+		c := middlewaretest.NewMockContext(req, handler, func(c http.Context) error { return c.String("ok") })
+		_ = c.Run()
+		codes = append(codes, c.Recorder.Code)
+		if i == 2 {
+			// Unspecified options keep the defaults, e.g. the one minute window
+			if retry, _ := strconv.Atoi(c.Recorder.Header().Get("Retry-After")); retry > 60 || retry <= 0 {
+				t.Errorf("Retry-After = %q", c.Recorder.Header().Get("Retry-After"))
+			}
+		}
+	}
+	if codes[0] != 200 || codes[1] != 200 || codes[2] != 429 {
+		t.Errorf("codes = %v", codes)
+	}
+}
@@ -0,0 +1,155 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errPersisterDetached is returned by Save and Restore before the
+// persister was passed to New
+var errPersisterDetached = errors.New("limiter: persister is not attached to an in-memory limiter")
+
+// persistedEntry is the file format of a key
+type persistedEntry struct {
+	Key      string `json:"key"`
+	CurrHits int    `json:"curr_hits"`
+	PrevHits int    `json:"prev_hits"`
+	// Reset is the end of the window as unix seconds
+	Reset uint64 `json:"reset"`
+	// Expires is when the key is dropped from memory as unix seconds, 0
+	// when it never is
+	Expires int64 `json:"expires"`
+}
+
+// Persister saves the counters of a limiter using the in-memory store to
+// a file and restores them, so a restart doesn't give every client a fresh
+// quota. Limiters with a Storage don't need it, the storage outlives the
+// process. Each limiter needs its own persister.
+//
+//	persister := limiter.NewPersister("/var/lib/app/limiter.json")
+//	app.Use(limiter.New(limiter.Config{Persister: persister}))
+//	if err := persister.Restore(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+//		log.Print(err)
+//	}
+//	// on shutdown
+//	err := persister.Save()
+type Persister struct {
+	path    string
+	mu      sync.RWMutex
+	manager *manager
+}
+
+// NewPersister creates a persister to be passed as Config.Persister
+func NewPersister(path string) *Persister {
+	return &Persister{path: path}
+}
+
+// attach connects the persister to the manager of a limiter. A persister
+// saves a single limiter, sharing it would have Save write the counters of
+// whichever limiter was created last.
+func (p *Persister) attach(m *manager) {
+	if p == nil || m.memory == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.manager != nil && p.manager != m {
+		panic("limiter: persister is already attached to another limiter")
+	}
+	p.manager = m
+}
+
+// Save writes the keys that haven't expired to the file, replacing it
+// atomically
+func (p *Persister) Save() error {
+	p.mu.RLock()
+	m := p.manager
+	p.mu.RUnlock()
+	if m == nil {
+		return errPersisterDetached
+	}
+
+	entries := make([]persistedEntry, 0)
+	m.mux.Lock()
+	m.memory.RangeExpiry(func(key string, val interface{}, exp time.Time) {
+		it, ok := val.(*item)
+		if !ok {
+			return
+		}
+		entry := persistedEntry{
+			Key:      key,
+			CurrHits: it.currHits,
+			PrevHits: it.prevHits,
+			Reset:    it.exp,
+		}
+		if !exp.IsZero() {
+			entry.Expires = exp.Unix()
+		}
+		entries = append(entries, entry)
+	})
+	m.mux.Unlock()
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.path)
+}
+
+// Restore loads the keys saved by Save that haven't expired since. Keys the
+// limiter already counted are left as they are. A missing file returns an
+// error matching fs.ErrNotExist.
+func (p *Persister) Restore() error {
+	p.mu.RLock()
+	m := p.manager
+	p.mu.RUnlock()
+	if m == nil {
+		return errPersisterDetached
+	}
+
+	body, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	var entries []persistedEntry
+	if err = json.Unmarshal(body, &entries); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, entry := range entries {
+		var exp time.Time
+		if entry.Expires != 0 {
+			if exp = time.Unix(entry.Expires, 0); !exp.After(now) {
+				continue
+			}
+		}
+		if m.memory.Get(entry.Key) != nil {
+			continue
+		}
+		m.memory.SetExpiry(entry.Key, &item{
+			currHits: entry.CurrHits,
+			prevHits: entry.PrevHits,
+			exp:      entry.Reset,
+		}, exp)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func limitedStatus(handler http.HandlerFunc) int {
+	c := middlewaretest.NewMockContext(httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		return c.String("ok")
+	})
+	_ = c.Run()
+	return c.Recorder.Code
+}
+
+func TestPersisterSaveRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.json")
+
+	before, inspector := NewPersister(path), NewInspector()
+	handler := New(Config{Max: 2, Expiration: time.Minute, Persister: before, Inspector: inspector})
+	limitedStatus(handler)
+	limitedStatus(handler)
+	saved := inspector.Snapshot()
+	if err := before.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted process
+	after, inspector := NewPersister(path), NewInspector()
+	handler = New(Config{Max: 2, Expiration: time.Minute, Persister: after, Inspector: inspector})
+	if err := after.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	restored := inspector.Snapshot()
+	if len(restored) != 1 || restored[0].Key != saved[0].Key || restored[0].Hits != 2 ||
+		!restored[0].Reset.Equal(saved[0].Reset) {
+		t.Errorf("restored %+v, saved %+v", restored, saved)
+	}
+	if status := limitedStatus(handler); status != utils.StatusTooManyRequests {
+		t.Errorf("status = %d, the restored quota was used up", status)
+	}
+}
+
+func TestPersisterRestoreSkipsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.json")
+	now := time.Now()
+	body, _ := json.Marshal([]persistedEntry{
+		{Key: "live", CurrHits: 3, Reset: uint64(now.Add(time.Minute).Unix()), Expires: now.Add(time.Minute).Unix()},
+		{Key: "expired", CurrHits: 5, Reset: uint64(now.Add(-time.Minute).Unix()), Expires: now.Add(-time.Second).Unix()},
+	})
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	persister, inspector := NewPersister(path), NewInspector()
+	New(Config{Persister: persister, Inspector: inspector})
+	if err := persister.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	entries := inspector.Snapshot()
+	if len(entries) != 1 || entries[0].Key != "live" || entries[0].Hits != 3 {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	// The restored key keeps its expiration
+	persister.manager.memory.RangeExpiry(func(key string, _ interface{}, exp time.Time) {
+		if want := time.Unix(now.Add(time.Minute).Unix(), 0); !exp.Equal(want) {
+			t.Errorf("%s expires at %v, want %v", key, exp, want)
+		}
+	})
+}
+
+func TestPersisterErrors(t *testing.T) {
+	persister := NewPersister(filepath.Join(t.TempDir(), "limiter.json"))
+	if err := persister.Save(); err != errPersisterDetached {
+		t.Errorf("Save before New = %v", err)
+	}
+	New(Config{Persister: persister})
+	if err := persister.Restore(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Restore of a missing file = %v", err)
+	}
+
+	// Limiters with a Storage don't attach it
+	detached := NewPersister("unused.json")
+	New(Config{Persister: detached, Storage: newMapStorage()})
+	if err := detached.Save(); err != errPersisterDetached {
+		t.Errorf("Save with a Storage = %v", err)
+	}
+}
+
+func TestPersisterSharedPanics(t *testing.T) {
+	persister := NewPersister("unused.json")
+	New(Config{Persister: persister})
+	defer func() {
+		if recover() == nil {
+			t.Error("attaching a persister to a second limiter didn't panic")
+		}
+	}()
+	New(Config{Persister: persister})
+}
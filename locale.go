@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// LocaleContextKey is the context key the negotiated locale is stored under
+const LocaleContextKey = "locale"
+
+// ConfigLocale defines the config for middleware.
+type ConfigLocale struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Supported are the locales the application is translated to
+	//
+	// Required
+	Supported []string
+
+	// Default is used when no source names a supported locale
+	//
+	// Optional. Default: the first Supported locale
+	Default string
+
+	// Sources are consulted in order, possible values are "query",
+	// "cookie" and "header"
+	//
+	// Optional. Default: query, cookie, header
+	Sources []string
+
+	// QueryParam carrying the locale
+	//
+	// Optional. Default: "lang"
+	QueryParam string
+
+	// CookieName carrying the locale
+	//
+	// Optional. Default: "lang"
+	CookieName string
+
+	// Persist stores the negotiated locale in the cookie so it sticks
+	// after it was picked with the query parameter
+	//
+	// Optional. Default: false
+	Persist bool
+}
+
+// ConfigLocaleDefault is the default config
+var ConfigLocaleDefault = ConfigLocale{
+	Next:       nil,
+	Sources:    []string{"query", "cookie", "header"},
+	QueryParam: "lang",
+	CookieName: "lang",
+}
+
+// Helper function to set default values
+func configLocaleDefault(config ...ConfigLocale) ConfigLocale {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigLocaleDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Default == "" && len(cfg.Supported) > 0 {
+		cfg.Default = cfg.Supported[0]
+	}
+	if cfg.Sources == nil {
+		cfg.Sources = ConfigLocaleDefault.Sources
+	}
+	if cfg.QueryParam == "" {
+		cfg.QueryParam = ConfigLocaleDefault.QueryParam
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = ConfigLocaleDefault.CookieName
+	}
+	return cfg
+}
+
+// Locale creates a new middleware handler
+func Locale(config ConfigLocale) http.HandlerFunc {
+	// Set default config
+	cfg := configLocaleDefault(config)
+
+	if len(cfg.Supported) == 0 {
+		panic("locale: Supported is required")
+	}
+	for _, source := range cfg.Sources {
+		if source != "query" && source != "cookie" && source != "header" {
+			panic("locale: unsupported source " + source)
+		}
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Caches must tell apart responses for the request headers the
+		// locale was looked up in
+		locale := ""
+		for _, source := range cfg.Sources {
+			switch source {
+			case "query":
+				locale = matchLocale(c.Query(cfg.QueryParam, ""), cfg.Supported)
+			case "cookie":
+				vary(c, utils.HeaderCookie)
+				locale = matchLocale(c.Cookies(cfg.CookieName), cfg.Supported)
+			case "header":
+				vary(c, utils.HeaderAcceptLanguage)
+				locale = negotiateLocale(c.Header(utils.HeaderAcceptLanguage, ""), cfg.Supported, "")
+			}
+			if locale != "" {
+				break
+			}
+		}
+		if locale == "" {
+			locale = cfg.Default
+		}
+
+		if cfg.Persist && c.Cookies(cfg.CookieName) != locale {
+			c.Cookie(&http.Cookie{
+				Name:     cfg.CookieName,
+				Value:    locale,
+				Path:     "/",
+				MaxAge:   365 * 24 * 60 * 60,
+				SameSite: "Lax",
+			})
+		}
+
+		c.WithValue(LocaleContextKey, locale)
+		return c.Next()
+	}
+}
+
+// LocaleFromContext returns the locale negotiated by Locale
+func LocaleFromContext(c http.Context) string {
+	locale, _ := c.Value(LocaleContextKey).(string)
+	return locale
+}
+
+// parseAcceptLanguage returns the language ranges of an Accept-Language
+// header ordered by quality, ranges with q=0 are dropped
+func parseAcceptLanguage(header string) []string {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		tag, q := parseQuality(part)
+		if tag != "" && q > 0 {
+			ranges = append(ranges, languageRange{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// negotiateLocale returns the supported locale best matching the header,
+// the wildcard matches fallback
+func negotiateLocale(header string, supported []string, fallback string) string {
+	for _, tag := range parseAcceptLanguage(header) {
+		if tag == "*" {
+			if fallback != "" {
+				return fallback
+			}
+			continue
+		}
+		if locale := matchLocale(tag, supported); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// matchLocale returns the supported locale for a language tag, falling
+// back from a regional tag to its language (en-GB to en) and from a
+// language to its first regional variant (en to en-US)
+func matchLocale(tag string, supported []string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	for _, s := range supported {
+		if strings.EqualFold(s, tag) {
+			return s
+		}
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, s := range supported {
+		if strings.EqualFold(s, base) {
+			return s
+		}
+	}
+	for _, s := range supported {
+		if sBase, _, _ := strings.Cut(s, "-"); strings.EqualFold(sBase, base) {
+			return s
+		}
+	}
+	return ""
+}
+
+// languageContextKey is the context key Language stores its match under
+const languageContextKey = "language"
+
+// Language creates a new middleware handler picking the supported language
+// best matching the Accept-Language header, the first one is the default
+func Language(supported ...string) http.HandlerFunc {
+	if len(supported) == 0 {
+		panic("language: at least one supported language is required")
+	}
+	fallback := supported[0]
+
+	// Return new handler
+	return func(c http.Context) error {
+		vary(c, utils.HeaderAcceptLanguage)
+		language := negotiateLocale(c.Header(utils.HeaderAcceptLanguage, ""), supported, fallback)
+		if language == "" {
+			language = fallback
+		}
+		c.WithValue(languageContextKey, language)
+		return c.Next()
+	}
+}
+
+// LanguageFrom returns the language picked by Language
+func LanguageFrom(c http.Context) string {
+	language, _ := c.Value(languageContextKey).(string)
+	return language
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func localeHandler(c http.Context) error {
+	return c.String(LocaleFromContext(c))
+}
+
+func TestLocaleAcceptLanguage(t *testing.T) {
+	handler := Locale(ConfigLocale{Supported: []string{"en", "de", "fr-CA", "pt-BR"}})
+	for _, tt := range []struct {
+		header, want string
+	}{
+		{"de", "de"},
+		{"fr-CA;q=0.5, de;q=0.8", "de"},
+		{"de;q=0.8, fr-CA;q=0.9", "fr-CA"},
+		// Equal qualities keep the order of the header
+		{"de, fr-CA", "de"},
+		{"es, de;q=0.1", "de"},
+		{"de;q=0, fr-ca", "fr-CA"},
+		// Regional tags fall back to the language and the other way round
+		{"de-AT", "de"},
+		{"en_GB", "en"},
+		{"pt", "pt-BR"},
+		{"FR", "fr-CA"},
+		// Nothing matches, the first supported locale is the default
+		{"es, ja;q=0.5", "en"},
+		{"*", "en"},
+		{"", "en"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set(utils.HeaderAcceptLanguage, tt.header)
+		}
+		if c := run(t, req, handler, localeHandler); c.Body() != tt.want {
+			t.Errorf("%q: locale = %q, want %q", tt.header, c.Body(), tt.want)
+		}
+	}
+}
+
+func TestLocaleSources(t *testing.T) {
+	newRequest := func(query, cookie, header string) *stdHttp.Request {
+		req := httptest.NewRequest("GET", "/?lang="+query, nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", "lang="+cookie)
+		}
+		if header != "" {
+			req.Header.Set(utils.HeaderAcceptLanguage, header)
+		}
+		return req
+	}
+	supported := []string{"en", "de", "fr"}
+	for _, tt := range []struct {
+		name                  string
+		sources               []string
+		query, cookie, header string
+		want                  string
+		// vary lists the request headers consulted
+		vary string
+	}{
+		{"query first", nil, "fr", "de", "de", "fr", ""},
+		{"cookie second", nil, "", "de", "fr", "de", "Cookie"},
+		{"header last", nil, "", "", "fr", "fr", "Cookie, Accept-Language"},
+		{"unsupported query skipped", nil, "es", "de", "fr", "de", "Cookie"},
+		{"custom order", []string{"header", "query"}, "fr", "de", "de", "de", "Accept-Language"},
+		{"unused source", []string{"header"}, "fr", "fr", "", "en", "Accept-Language"},
+	} {
+		handler := Locale(ConfigLocale{Supported: supported, Sources: tt.sources})
+		c := run(t, newRequest(tt.query, tt.cookie, tt.header), handler, localeHandler)
+		if c.Body() != tt.want {
+			t.Errorf("%s: locale = %q, want %q", tt.name, c.Body(), tt.want)
+		}
+		if got := strings.Join(c.Recorder.Header().Values(utils.HeaderVary), ", "); got != tt.vary {
+			t.Errorf("%s: Vary = %q, want %q", tt.name, got, tt.vary)
+		}
+	}
+}
+
+func TestLocalePersist(t *testing.T) {
+	handler := Locale(ConfigLocale{Supported: []string{"en", "de"}, Default: "de", Persist: true})
+
+	c := run(t, httptest.NewRequest("GET", "/?lang=en", nil), handler, localeHandler)
+	cookie := c.Recorder.Header().Get("Set-Cookie")
+	if c.Body() != "en" || !strings.HasPrefix(cookie, "lang=en;") {
+		t.Errorf("locale = %q, Set-Cookie = %q", c.Body(), cookie)
+	}
+
+	// The cookie already holds the locale
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", "lang=en")
+	c = run(t, req, handler, localeHandler)
+	if c.Body() != "en" || c.Recorder.Header().Get("Set-Cookie") != "" {
+		t.Errorf("locale = %q, Set-Cookie = %q", c.Body(), c.Recorder.Header().Get("Set-Cookie"))
+	}
+
+	c = run(t, httptest.NewRequest("GET", "/", nil), handler, localeHandler)
+	if c.Body() != "de" || !strings.HasPrefix(c.Recorder.Header().Get("Set-Cookie"), "lang=de;") {
+		t.Errorf("default: locale = %q, Set-Cookie = %q", c.Body(), c.Recorder.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestLocaleConfigPanics(t *testing.T) {
+	for name, cfg := range map[string]ConfigLocale{
+		"no supported":   {},
+		"unknown source": {Supported: []string{"en"}, Sources: []string{"path"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Locale didn't panic", name)
+				}
+			}()
+			Locale(cfg)
+		}()
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	handler := Language("en", "de", "fr")
+	languageHandler := func(c http.Context) error {
+		return c.String(LanguageFrom(c))
+	}
+	for _, tt := range []struct {
+		header, want string
+	}{
+		{"fr;q=0.7, de;q=0.9", "de"},
+		{"fr, de", "fr"},
+		{"de-CH", "de"},
+		// The wildcard accepts the default
+		{"es, *;q=0.5", "en"},
+		{"es, *;q=0.5, fr;q=0.8", "fr"},
+		{"es, ja", "en"},
+		{"de;q=0", "en"},
+		{"", "en"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set(utils.HeaderAcceptLanguage, tt.header)
+		}
+		c := run(t, req, handler, languageHandler)
+		if c.Body() != tt.want {
+			t.Errorf("%q: language = %q, want %q", tt.header, c.Body(), tt.want)
+		}
+		if got := c.Recorder.Header().Get(utils.HeaderVary); got != utils.HeaderAcceptLanguage {
+			t.Errorf("%q: Vary = %q", tt.header, got)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Language didn't panic without languages")
+		}
+	}()
+	Language()
+}
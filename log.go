@@ -34,10 +34,21 @@ func Log(config ConfigLog) http.HandlerFunc {
 			rid = config.RequestID()
 			c.SetHeader(utils.HeaderXRequestID, rid)
 		}
-		nextHandler := c.Next()
+		rec, captured := captureResponse(c)
+		var nextHandler error
+		if captured {
+			nextHandler = rec.next(c)
+		} else {
+			nextHandler = c.Next()
+		}
 		if c.Path() == "/" && c.Path() != c.Path() {
 			return nextHandler
 		}
+		// The recorder sees what the later handlers wrote
+		status, size := c.StatusCode(), 0
+		if captured {
+			status, size = rec.Status(), rec.Size()
+		}
 
 		if config.Logger == nil {
 			config.Logger = &log.Logger{
@@ -60,7 +71,8 @@ func Log(config ConfigLog) http.HandlerFunc {
 			Str("host", c.Origin().Host).
 			Str("path", c.Path()).
 			Str("protocol", c.Origin().Proto).
-			Int("status", c.StatusCode()).
+			Int("status", status).
+			Int("bytes_out", size).
 			Str("latency", fmt.Sprintf("%s", time.Since(start))).
 			Str("ua", c.Header(utils.HeaderUserAgent, ""))
 
@@ -70,7 +82,8 @@ func Log(config ConfigLog) http.HandlerFunc {
 			Str("host", c.Origin().Host).
 			Str("path", c.Path()).
 			Str("protocol", c.Origin().Proto).
-			Int("status", c.StatusCode()).
+			Int("status", status).
+			Int("bytes_out", size).
 			Str("latency", fmt.Sprintf("%s", time.Since(start))).
 			Str("ua", c.Header(utils.HeaderUserAgent, ""))
 
@@ -81,19 +94,19 @@ func Log(config ConfigLog) http.HandlerFunc {
 
 		ctx := logging.Value()
 		switch {
-		case c.StatusCode() >= 500:
+		case status >= 500:
 			config.Logger.Error().Context(ctx).Msg("server error")
 			log.Error().Context(ctx).Msg("server error")
-		case c.StatusCode() >= 400:
+		case status >= 400:
 			config.Logger.Error().Context(ctx).Msg("client error")
 			log.Error().Context(ctx).Msg("client error")
-		case c.StatusCode() >= 300:
+		case status >= 300:
 			config.Logger.Warn().Context(ctx).Msg("redirect")
 			log.Info().Context(ctx).Msg("redirect")
-		case c.StatusCode() >= 200:
+		case status >= 200:
 			config.Logger.Info().Context(ctx).Msg("success")
 			log.Info().Context(ctx).Msg("success")
-		case c.StatusCode() >= 100:
+		case status >= 100:
 			config.Logger.Info().Context(ctx).Msg("informative")
 			log.Info().Context(ctx).Msg("informative")
 		default:
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/phuslu/log"
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestLogStatusAndSize(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Log(ConfigLog{LogWriter: &log.IOWriter{Writer: &buf}})
+	run(t, httptest.NewRequest("GET", "/a", nil), handler, func(c http.Context) error {
+		return c.Status(utils.StatusCreated).String("created")
+	})
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &entry); err != nil {
+		t.Fatalf("entry %q: %v", buf.String(), err)
+	}
+	if entry["status"] != float64(utils.StatusCreated) || entry["bytes_out"] != float64(7) {
+		t.Errorf("entry = %v", entry)
+	}
+	if entry["request_id"] == "" || entry["path"] != "/a" {
+		t.Errorf("entry = %v", entry)
+	}
+}
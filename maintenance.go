@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigMaintenance defines the config for middleware.
+type ConfigMaintenance struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Enabled starts the middleware in maintenance mode
+	//
+	// Optional. Default: false
+	Enabled bool
+
+	// RetryAfter is sent in the Retry-After header, 0 leaves it out
+	//
+	// Optional. Default: 0
+	RetryAfter time.Duration
+
+	// Body is the response body during maintenance
+	//
+	// Optional. Default: "Service Unavailable"
+	Body string
+
+	// ContentType of the Body
+	//
+	// Optional. Default: "text/plain; charset=utf-8"
+	ContentType string
+
+	// AllowedIPs are IPs or CIDR ranges still served during maintenance
+	//
+	// Optional. Default: nil
+	AllowedIPs []string
+
+	// ContextKey is the context key of the client IP resolved by a real
+	// IP middleware, the address of the connection is used when it is
+	// unset. Forwarding headers sent by clients are never trusted.
+	//
+	// Optional. Default: "realip"
+	ContextKey string
+
+	// ExcludedPaths are path prefixes still served during maintenance,
+	// e.g. health checks. They match whole segments, "/health" excludes
+	// /health/live but not /healthcare
+	//
+	// Optional. Default: nil
+	ExcludedPaths []string
+}
+
+// ConfigMaintenanceDefault is the default config
+var ConfigMaintenanceDefault = ConfigMaintenance{
+	Next:        nil,
+	Body:        "Service Unavailable",
+	ContentType: "text/plain; charset=utf-8",
+	ContextKey:  "realip",
+}
+
+// Helper function to set default values
+func configMaintenanceDefault(config ...ConfigMaintenance) ConfigMaintenance {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigMaintenanceDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Body == "" {
+		cfg.Body = ConfigMaintenanceDefault.Body
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = ConfigMaintenanceDefault.ContentType
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigMaintenanceDefault.ContextKey
+	}
+	return cfg
+}
+
+// MaintenanceController switches the maintenance mode at runtime
+type MaintenanceController struct {
+	enabled atomic.Bool
+}
+
+// Enable turns the maintenance mode on
+func (m *MaintenanceController) Enable() {
+	m.enabled.Store(true)
+}
+
+// Disable turns the maintenance mode off
+func (m *MaintenanceController) Disable() {
+	m.enabled.Store(false)
+}
+
+// IsEnabled reports whether the maintenance mode is on
+func (m *MaintenanceController) IsEnabled() bool {
+	return m.enabled.Load()
+}
+
+// Maintenance creates a new middleware handler
+func Maintenance(config ConfigMaintenance) (http.HandlerFunc, *MaintenanceController) {
+	// Set default config
+	cfg := configMaintenanceDefault(config)
+
+	allowed := make([]*net.IPNet, 0, len(cfg.AllowedIPs))
+	for _, entry := range cfg.AllowedIPs {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			panic(fmt.Errorf("maintenance: invalid AllowedIPs entry %q: %w", entry, err))
+		}
+		allowed = append(allowed, network)
+	}
+	retryAfter := ""
+	if cfg.RetryAfter > 0 {
+		retryAfter = strconv.Itoa(int(cfg.RetryAfter.Seconds()))
+	}
+	contentLength := strconv.Itoa(len(cfg.Body))
+
+	controller := &MaintenanceController{}
+	controller.enabled.Store(cfg.Enabled)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Serve requests as usual outside of maintenance
+		if !controller.enabled.Load() {
+			return c.Next()
+		}
+
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		path := c.Origin().URL.Path
+		for _, prefix := range cfg.ExcludedPaths {
+			prefix = strings.TrimSuffix(prefix, "/")
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return c.Next()
+			}
+		}
+		if len(allowed) > 0 {
+			if ip := filterClientIP(c, cfg.ContextKey); ip != nil {
+				for _, network := range allowed {
+					if network.Contains(ip) {
+						return c.Next()
+					}
+				}
+			}
+		}
+
+		if retryAfter != "" {
+			c.SetHeader(utils.HeaderRetryAfter, retryAfter)
+		}
+		c.SetHeader(utils.HeaderContentType, cfg.ContentType)
+		c.SetHeader(utils.HeaderContentLength, contentLength)
+		c.SetHeader(utils.HeaderCacheControl, "no-store")
+		c.Status(utils.StatusServiceUnavailable)
+		if c.Method() == utils.MethodHead {
+			return nil
+		}
+		return c.String("%s", cfg.Body)
+	}, controller
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestMaintenanceToggle(t *testing.T) {
+	handler, controller := Maintenance(ConfigMaintenance{RetryAfter: 2 * time.Minute, Body: "down", ContentType: "text/html"})
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("disabled status = %d", c.Recorder.Code)
+	}
+
+	controller.Enable()
+	if !controller.IsEnabled() {
+		t.Fatal("IsEnabled = false after Enable")
+	}
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusServiceUnavailable || c.Body() != "down" {
+		t.Errorf("enabled response = %d %q", c.Recorder.Code, c.Body())
+	}
+	h := c.Recorder.Header()
+	if h.Get(utils.HeaderRetryAfter) != "120" || h.Get(utils.HeaderContentType) != "text/html" {
+		t.Errorf("headers = %v", h)
+	}
+
+	controller.Disable()
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("disabled again status = %d", c.Recorder.Code)
+	}
+}
+
+func TestMaintenanceBypass(t *testing.T) {
+	handler, _ := Maintenance(ConfigMaintenance{
+		Enabled:       true,
+		AllowedIPs:    []string{"10.0.0.0/8", "2001:db8::1"},
+		ExcludedPaths: []string{"/health"},
+	})
+	for _, tt := range []struct {
+		name, path, remote, forwarded string
+		status                        int
+	}{
+		{"allowed range", "/", "10.1.2.3:1000", "", utils.StatusOK},
+		{"allowed ipv6", "/", "[2001:db8::1]:1000", "", utils.StatusOK},
+		{"excluded path", "/health/live", "192.0.2.1:1000", "", utils.StatusOK},
+		{"excluded path exactly", "/health", "192.0.2.1:1000", "", utils.StatusOK},
+		// Prefixes match whole segments
+		{"path sharing the prefix", "/healthcare", "192.0.2.1:1000", "", utils.StatusServiceUnavailable},
+		{"other ip", "/", "192.0.2.1:1000", "", utils.StatusServiceUnavailable},
+		{"forged header", "/", "192.0.2.1:1000", "10.1.2.3", utils.StatusServiceUnavailable},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			req.Header.Set("X-Real-IP", tt.forwarded)
+		}
+		if c := run(t, req, handler, ok); c.Recorder.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.status)
+		}
+	}
+}
+
+func TestMaintenanceResolvedClientIP(t *testing.T) {
+	handler, _ := Maintenance(ConfigMaintenance{Enabled: true, AllowedIPs: []string{"203.0.113.5"}})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+	c := run(t, req, func(c http.Context) error {
+		c.WithValue("realip", "203.0.113.5")
+		return c.Next()
+	}, handler, ok)
+	if c.Recorder.Code != utils.StatusOK {
+		t.Errorf("status = %d", c.Recorder.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigMaxConnPerIP defines the config for middleware.
+type ConfigMaxConnPerIP struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Max is the number of requests a client may have in flight
+	//
+	// Optional. Default: 10
+	Max int
+
+	// KeyGenerator identifies the client. Behind a proxy it should return
+	// the client IP the proxy resolved, forwarding headers sent by clients
+	// can't be trusted.
+	//
+	// Optional. Default: the address of the connection
+	KeyGenerator func(c http.Context) string
+
+	// LimitReached is called for requests beyond Max
+	//
+	// Optional. Default: responds with 429 Too Many Requests
+	LimitReached http.HandlerFunc
+}
+
+// ConfigMaxConnPerIPDefault is the default config
+var ConfigMaxConnPerIPDefault = ConfigMaxConnPerIP{
+	Next: nil,
+	Max:  10,
+	KeyGenerator: func(c http.Context) string {
+		return peerAddr(c)
+	},
+	LimitReached: func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusTooManyRequests)
+		return ErrTooManyConnections
+	},
+}
+
+// Helper function to set default values
+func configMaxConnPerIPDefault(config ...ConfigMaxConnPerIP) ConfigMaxConnPerIP {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigMaxConnPerIPDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Max <= 0 {
+		cfg.Max = ConfigMaxConnPerIPDefault.Max
+	}
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigMaxConnPerIPDefault.KeyGenerator
+	}
+	if cfg.LimitReached == nil {
+		cfg.LimitReached = ConfigMaxConnPerIPDefault.LimitReached
+	}
+	return cfg
+}
+
+// MaxConnPerIP creates a new middleware handler capping the requests a
+// client has in flight at once, so slow clients can't hold every worker.
+// Unlike the limiter it counts concurrent requests, not requests per time.
+func MaxConnPerIP(config ...ConfigMaxConnPerIP) http.HandlerFunc {
+	// Set default config
+	cfg := configMaxConnPerIPDefault(config...)
+
+	var (
+		mu     sync.Mutex
+		active = make(map[string]int)
+	)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		key := cfg.KeyGenerator(c)
+		mu.Lock()
+		if active[key] >= cfg.Max {
+			mu.Unlock()
+			return cfg.LimitReached(c)
+		}
+		active[key]++
+		mu.Unlock()
+
+		// Release even when the handler panics, drop idle clients
+		defer func() {
+			mu.Lock()
+			if active[key]--; active[key] <= 0 {
+				delete(active, key)
+			}
+			mu.Unlock()
+		}()
+		return c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// blockingHandler holds requests until release is closed
+func blockingHandler(started *sync.WaitGroup, release chan struct{}) http.HandlerFunc {
+	return func(c http.Context) error {
+		started.Done()
+		<-release
+		return c.String("ok")
+	}
+}
+
+func TestMaxConnPerIPCap(t *testing.T) {
+	handler := MaxConnPerIP(ConfigMaxConnPerIP{Max: 2})
+	var started sync.WaitGroup
+	release := make(chan struct{})
+	started.Add(2)
+
+	var done sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.0.2.1:1000"
+			run(t, req, handler, blockingHandler(&started, release))
+		}()
+	}
+	started.Wait()
+
+	// The third request from the same address is over the cap, even with
+	// a forged forwarding header
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:2000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	c := run(t, req, handler, ok)
+	if c.Recorder.Code != utils.StatusTooManyRequests {
+		t.Errorf("over cap status = %d", c.Recorder.Code)
+	}
+	if err := c.Errors()[0]; !errors.Is(err, ErrTooManyConnections) {
+		t.Errorf("err = %v", err)
+	}
+
+	// Other addresses are counted on their own
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.2:1000"
+	if c := run(t, req, handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("other ip status = %d", c.Recorder.Code)
+	}
+
+	close(release)
+	done.Wait()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:3000"
+	if c := run(t, req, handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("after release status = %d", c.Recorder.Code)
+	}
+}
+
+func TestMaxConnPerIPReleasesOnPanic(t *testing.T) {
+	handler := MaxConnPerIP(ConfigMaxConnPerIP{Max: 1})
+	func() {
+		defer func() { _ = recover() }()
+		run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+			panic("boom")
+		})
+	}()
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("status after panic = %d", c.Recorder.Code)
+	}
+}
@@ -0,0 +1,368 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// MeterUsage is the usage of a key during a time bucket
+type MeterUsage struct {
+	Key      string    `json:"key"`
+	Bucket   time.Time `json:"bucket"`
+	Requests int64     `json:"requests"`
+	Cost     int64     `json:"cost"`
+}
+
+// MeterFlusher persists aggregated usage, e.g. by adding the deltas to
+// counters in a database. Usage of failed flushes is retried with the next.
+type MeterFlusher interface {
+	Flush(ctx context.Context, usage []MeterUsage) error
+}
+
+// ConfigMeter defines the config for middleware.
+type ConfigMeter struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Aggregator the usage is recorded in, create it with
+	// NewMeterAggregator and shut it down on exit to flush the usage
+	// still held in memory
+	//
+	// Required unless Flusher is set
+	Aggregator *MeterAggregator
+
+	// Flusher is used with an aggregator of its own when Aggregator is nil,
+	// usage not flushed at exit is lost
+	//
+	// Optional. Default: nil
+	Flusher MeterFlusher
+
+	// KeyGenerator returns the key usage is billed to, e.g. the API key,
+	// requests with an empty key aren't metered. The default can't be moved
+	// to another client's bill with a forged X-Forwarded-For.
+	//
+	// Optional. Default: the address of the connection
+	KeyGenerator func(c http.Context) string
+
+	// Cost weighs a request once the response was written, e.g. by its
+	// size in kilobytes
+	//
+	// Optional. Default: 1 per request
+	Cost func(c http.Context, status int, size int) int64
+
+	// Bucket is the length of the time buckets usage is counted in
+	//
+	// Optional. Default: 1 * time.Hour
+	Bucket time.Duration
+}
+
+// ConfigMeterDefault is the default config
+var ConfigMeterDefault = ConfigMeter{
+	Next: nil,
+	KeyGenerator: func(c http.Context) string {
+		return peerAddr(c)
+	},
+	Cost: func(c http.Context, status int, size int) int64 {
+		return 1
+	},
+	Bucket: 1 * time.Hour,
+}
+
+// Helper function to set default values
+func configMeterDefault(config ...ConfigMeter) ConfigMeter {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigMeterDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigMeterDefault.KeyGenerator
+	}
+	if cfg.Cost == nil {
+		cfg.Cost = ConfigMeterDefault.Cost
+	}
+	if cfg.Bucket <= 0 {
+		cfg.Bucket = ConfigMeterDefault.Bucket
+	}
+	return cfg
+}
+
+// Meter creates a new middleware handler counting the requests and their
+// cost per key and time bucket for billing. Unlike the limiter it never
+// rejects or delays a request, usage is aggregated in memory and flushed
+// in the background.
+func Meter(config ConfigMeter) http.HandlerFunc {
+	// Set default config
+	cfg := configMeterDefault(config)
+
+	if cfg.Aggregator == nil {
+		if cfg.Flusher == nil {
+			panic("meter: Aggregator or Flusher is required")
+		}
+		cfg.Aggregator = NewMeterAggregator(cfg.Flusher)
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		rec, ok := captureResponse(c)
+		if !ok {
+			return c.Next()
+		}
+		err := rec.next(c)
+
+		if key := cfg.KeyGenerator(c); key != "" {
+			bucket := time.Now().UTC().Truncate(cfg.Bucket)
+			cfg.Aggregator.Record(key, bucket, cfg.Cost(c, rec.Status(), rec.Size()))
+		}
+		return err
+	}
+}
+
+// meterEvent is a request waiting to be aggregated
+type meterEvent struct {
+	key    string
+	bucket time.Time
+	cost   int64
+}
+
+// meterBucket identifies an aggregated counter
+type meterBucket struct {
+	key    string
+	bucket time.Time
+}
+
+// MeterAggregator sums usage in memory and hands the deltas to a
+// MeterFlusher periodically. Usage is dropped when the queue in front of
+// the aggregation is full, so recording never blocks requests.
+type MeterAggregator struct {
+	flusher MeterFlusher
+	events  chan meterEvent
+	onError func(err error)
+	closing sync.RWMutex
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[meterBucket]*MeterUsage
+
+	// flushing serializes the flushes
+	flushing sync.Mutex
+}
+
+// ConfigMeterAggregator defines the config of a MeterAggregator
+type ConfigMeterAggregator struct {
+	// QueueSize is the number of requests waiting to be aggregated before
+	// new ones are dropped
+	//
+	// Optional. Default: 4096
+	QueueSize int
+
+	// Interval between flushes
+	//
+	// Optional. Default: 1 * time.Minute
+	Interval time.Duration
+
+	// OnError receives the errors of the flusher
+	//
+	// Optional. Default: nil
+	OnError func(err error)
+}
+
+// ConfigMeterAggregatorDefault is the default config
+var ConfigMeterAggregatorDefault = ConfigMeterAggregator{
+	QueueSize: 4096,
+	Interval:  1 * time.Minute,
+}
+
+// Helper function to set default values
+func configMeterAggregatorDefault(config ...ConfigMeterAggregator) ConfigMeterAggregator {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigMeterAggregatorDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = ConfigMeterAggregatorDefault.QueueSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = ConfigMeterAggregatorDefault.Interval
+	}
+	return cfg
+}
+
+// NewMeterAggregator starts an aggregator flushing to flusher
+func NewMeterAggregator(flusher MeterFlusher, config ...ConfigMeterAggregator) *MeterAggregator {
+	// Set default config
+	cfg := configMeterAggregatorDefault(config...)
+
+	a := &MeterAggregator{
+		flusher: flusher,
+		events:  make(chan meterEvent, cfg.QueueSize),
+		onError: cfg.OnError,
+		done:    make(chan struct{}),
+		pending: make(map[meterBucket]*MeterUsage),
+	}
+	go a.run(cfg.Interval)
+	return a
+}
+
+// Record adds a request without blocking, it returns false when it was
+// dropped because the queue is full or the aggregator shut down
+func (a *MeterAggregator) Record(key string, bucket time.Time, cost int64) bool {
+	a.closing.RLock()
+	defer a.closing.RUnlock()
+	if !a.closed {
+		select {
+		case a.events <- meterEvent{key: key, bucket: bucket, cost: cost}:
+			return true
+		default:
+		}
+	}
+	a.dropped.Add(1)
+	return false
+}
+
+// Dropped returns the number of requests dropped so far
+func (a *MeterAggregator) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Totals returns the usage recorded since the last successful flush
+func (a *MeterAggregator) Totals() []MeterUsage {
+	a.drain()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	usage := make([]MeterUsage, 0, len(a.pending))
+	for _, u := range a.pending {
+		usage = append(usage, *u)
+	}
+	return usage
+}
+
+// Flush hands the usage recorded so far to the flusher right away
+func (a *MeterAggregator) Flush(ctx context.Context) error {
+	a.drain()
+	return a.flush(ctx)
+}
+
+// Shutdown flushes the remaining usage and stops the aggregator, it
+// returns the context's error when ctx is done first
+func (a *MeterAggregator) Shutdown(ctx context.Context) error {
+	a.closing.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.closing.Unlock()
+
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return a.flush(ctx)
+}
+
+// run aggregates the queued requests and flushes every interval
+func (a *MeterAggregator) run(interval time.Duration) {
+	defer close(a.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-a.events:
+			if !ok {
+				return
+			}
+			a.add(event)
+		case <-ticker.C:
+			if err := a.flush(context.Background()); err != nil && a.onError != nil {
+				a.onError(err)
+			}
+		}
+	}
+}
+
+// drain aggregates the requests queued so far
+func (a *MeterAggregator) drain() {
+	for {
+		select {
+		case event, ok := <-a.events:
+			if !ok {
+				return
+			}
+			a.add(event)
+		default:
+			return
+		}
+	}
+}
+
+// add counts a request
+func (a *MeterAggregator) add(event meterEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	id := meterBucket{key: event.key, bucket: event.bucket}
+	u, ok := a.pending[id]
+	if !ok {
+		u = &MeterUsage{Key: event.key, Bucket: event.bucket}
+		a.pending[id] = u
+	}
+	u.Requests++
+	u.Cost += event.cost
+}
+
+// flush hands the pending usage to the flusher, it's merged back for the
+// next flush when the flusher fails
+func (a *MeterAggregator) flush(ctx context.Context) error {
+	a.flushing.Lock()
+	defer a.flushing.Unlock()
+
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[meterBucket]*MeterUsage)
+	a.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usage := make([]MeterUsage, 0, len(pending))
+	for _, u := range pending {
+		usage = append(usage, *u)
+	}
+	err := a.flusher.Flush(ctx, usage)
+	if err != nil {
+		a.mu.Lock()
+		for id, u := range pending {
+			if cur, ok := a.pending[id]; ok {
+				cur.Requests += u.Requests
+				cur.Cost += u.Cost
+			} else {
+				a.pending[id] = u
+			}
+		}
+		a.mu.Unlock()
+	}
+	return err
+}
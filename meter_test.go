@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// testFlusher records the batches it is handed
+type testFlusher struct {
+	mu      sync.Mutex
+	batches [][]MeterUsage
+	err     error
+	// called receives a value per flush and release holds it, when set
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (f *testFlusher) Flush(ctx context.Context, usage []MeterUsage) error {
+	if f.called != nil {
+		f.called <- struct{}{}
+	}
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.batches = append(f.batches, sortUsage(usage))
+	return nil
+}
+
+func (f *testFlusher) flushed() [][]MeterUsage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]MeterUsage(nil), f.batches...)
+}
+
+// sortUsage orders usage by key for comparisons
+func sortUsage(usage []MeterUsage) []MeterUsage {
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Key < usage[j].Key })
+	return usage
+}
+
+func TestMeterAggregation(t *testing.T) {
+	flusher := &testFlusher{}
+	aggregator := NewMeterAggregator(flusher, ConfigMeterAggregator{Interval: time.Hour})
+	defer aggregator.Shutdown(context.Background())
+	handler := Meter(ConfigMeter{
+		Aggregator: aggregator,
+		KeyGenerator: func(c http.Context) string {
+			return c.Header("X-Api-Key", "")
+		},
+		Cost: func(c http.Context, status int, size int) int64 {
+			return int64(size)
+		},
+	})
+	for _, key := range []string{"a", "b", "a", "", "a"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		// Metering doesn't touch the response
+		if c := run(t, req, handler, ok); c.Body() != "ok" {
+			t.Errorf("body = %q", c.Body())
+		}
+	}
+
+	bucket := time.Now().UTC().Truncate(time.Hour)
+	want := []MeterUsage{
+		{Key: "a", Bucket: bucket, Requests: 3, Cost: 6},
+		{Key: "b", Bucket: bucket, Requests: 1, Cost: 2},
+	}
+	if got := sortUsage(aggregator.Totals()); !equalUsage(got, want) {
+		t.Fatalf("totals = %+v, want %+v", got, want)
+	}
+
+	// One batch holds everything, the totals start over
+	if err := aggregator.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if batches := flusher.flushed(); len(batches) != 1 || !equalUsage(batches[0], want) {
+		t.Errorf("batches = %+v", batches)
+	}
+	if totals := aggregator.Totals(); len(totals) != 0 {
+		t.Errorf("totals after flush = %+v", totals)
+	}
+	if err := aggregator.Flush(context.Background()); err != nil || len(flusher.flushed()) != 1 {
+		t.Errorf("empty flush: err = %v, %d batches", err, len(flusher.flushed()))
+	}
+}
+
+func TestMeterFlushRetry(t *testing.T) {
+	flusher := &testFlusher{err: errors.New("database down")}
+	aggregator := NewMeterAggregator(flusher, ConfigMeterAggregator{Interval: time.Hour})
+	bucket := time.Unix(1700000000, 0).UTC()
+
+	aggregator.Record("a", bucket, 1)
+	if err := aggregator.Flush(context.Background()); err == nil {
+		t.Fatal("Flush didn't report the error")
+	}
+	// The failed usage is merged with the new
+	aggregator.Record("a", bucket, 2)
+	aggregator.Record("b", bucket, 1)
+	flusher.mu.Lock()
+	flusher.err = nil
+	flusher.mu.Unlock()
+
+	aggregator.Record("b", bucket, 1)
+	if err := aggregator.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []MeterUsage{
+		{Key: "a", Bucket: bucket, Requests: 2, Cost: 3},
+		{Key: "b", Bucket: bucket, Requests: 2, Cost: 2},
+	}
+	if batches := flusher.flushed(); len(batches) != 1 || !equalUsage(batches[0], want) {
+		t.Errorf("batches = %+v", batches)
+	}
+
+	// After the shutdown usage is dropped
+	if aggregator.Record("a", bucket, 1) || aggregator.Dropped() != 1 {
+		t.Errorf("recorded after shutdown, dropped = %d", aggregator.Dropped())
+	}
+}
+
+func TestMeterOverflow(t *testing.T) {
+	flusher := &testFlusher{called: make(chan struct{}, 16), release: make(chan struct{})}
+	aggregator := NewMeterAggregator(flusher, ConfigMeterAggregator{QueueSize: 2, Interval: 10 * time.Millisecond})
+	bucket := time.Unix(1700000000, 0).UTC()
+
+	// The periodic flush holds the aggregation up
+	aggregator.Record("a", bucket, 1)
+	<-flusher.called
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		aggregator.Record("a", bucket, 1)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("recording blocked for %v", time.Since(start))
+	}
+	if aggregator.Dropped() != 3 {
+		t.Errorf("dropped = %d, want 3", aggregator.Dropped())
+	}
+
+	close(flusher.release)
+	if err := aggregator.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var requests int64
+	for _, batch := range flusher.flushed() {
+		for _, u := range batch {
+			requests += u.Requests
+		}
+	}
+	if requests != 3 {
+		t.Errorf("flushed %d requests, want 3", requests)
+	}
+}
+
+func TestMeterDefaultKey(t *testing.T) {
+	aggregator := NewMeterAggregator(&testFlusher{}, ConfigMeterAggregator{Interval: time.Hour})
+	defer aggregator.Shutdown(context.Background())
+	handler := Meter(ConfigMeter{Aggregator: aggregator})
+	for _, forwarded := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.1:1000"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		run(t, req, handler, ok)
+	}
+	// Usage is billed to the connection, not to the forged addresses
+	if got := aggregator.Totals(); len(got) != 1 || got[0].Key != "203.0.113.1" || got[0].Requests != 2 {
+		t.Errorf("totals = %+v", got)
+	}
+}
+
+func TestMeterConfig(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Meter didn't panic without Aggregator or Flusher")
+		}
+	}()
+	Meter(ConfigMeter{})
+}
+
+func equalUsage(a, b []MeterUsage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || !a[i].Bucket.Equal(b[i].Bucket) || a[i].Requests != b[i].Requests || a[i].Cost != b[i].Cost {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigMethodOverride defines the config for middleware.
+type ConfigMethodOverride struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Header carrying the overriding method
+	//
+	// Optional. Default: "X-HTTP-Method-Override"
+	Header string
+
+	// FormField carrying the overriding method in url encoded and
+	// multipart form bodies, read when the header is missing
+	//
+	// Optional. Default: "_method"
+	FormField string
+
+	// Methods a POST may be turned into
+	//
+	// Optional. Default: PUT, PATCH, DELETE
+	Methods []string
+
+	// ContextKey is the key to store the original method in the context
+	//
+	// Optional. Default: "original_method"
+	ContextKey string
+}
+
+// ConfigMethodOverrideDefault is the default config
+var ConfigMethodOverrideDefault = ConfigMethodOverride{
+	Next:      nil,
+	Header:    "X-HTTP-Method-Override",
+	FormField: "_method",
+	Methods: []string{
+		utils.MethodPut,
+		utils.MethodPatch,
+		utils.MethodDelete,
+	},
+	ContextKey: "original_method",
+}
+
+// Helper function to set default values
+func configMethodOverrideDefault(config ...ConfigMethodOverride) ConfigMethodOverride {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigMethodOverrideDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Header == "" {
+		cfg.Header = ConfigMethodOverrideDefault.Header
+	}
+	if cfg.FormField == "" {
+		cfg.FormField = ConfigMethodOverrideDefault.FormField
+	}
+	if cfg.Methods == nil {
+		cfg.Methods = ConfigMethodOverrideDefault.Methods
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigMethodOverrideDefault.ContextKey
+	}
+	return cfg
+}
+
+// MethodOverride creates a new middleware handler
+func MethodOverride(config ...ConfigMethodOverride) http.HandlerFunc {
+	// Set default config
+	cfg := configMethodOverrideDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only POST requests may be tunneled
+		if c.Method() != utils.MethodPost {
+			return c.Next()
+		}
+
+		method := c.Header(cfg.Header, "")
+		if method == "" && isFormBody(c.Header(utils.HeaderContentType, "")) {
+			method = c.Origin().PostFormValue(cfg.FormField)
+		}
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" || !containsMethod(cfg.Methods, method) {
+			return c.Next()
+		}
+
+		// WithValue may replace the request, rewrite the method afterwards
+		c.WithValue(cfg.ContextKey, c.Method())
+		c.Origin().Method = method
+		return c.Next()
+	}
+}
+
+// isFormBody reports whether a content type is one net/http parses forms of
+func isFormBody(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data")
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// methodHandler reports the method and the original method it saw
+func methodHandler(c http.Context) error {
+	return c.String(fmt.Sprintf("%s %v", c.Method(), c.Value("original_method")))
+}
+
+func TestMethodOverride(t *testing.T) {
+	handler := MethodOverride()
+	for _, tt := range []struct {
+		name, method, header, form, want string
+	}{
+		{"header", "POST", "DELETE", "", "DELETE POST"},
+		{"lowercase header", "POST", " patch ", "", "PATCH POST"},
+		{"form field", "POST", "", "put", "PUT POST"},
+		{"header wins over form", "POST", "PATCH", "DELETE", "PATCH POST"},
+		{"not allowed", "POST", "CONNECT", "", "POST <nil>"},
+		{"not allowed form", "POST", "", "GET", "POST <nil>"},
+		{"no override", "POST", "", "", "POST <nil>"},
+		{"not a POST", "GET", "DELETE", "", "GET <nil>"},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.form != "" {
+			req = httptest.NewRequest(tt.method, "/", strings.NewReader("_method="+tt.form))
+			req.Header.Set(utils.HeaderContentType, "application/x-www-form-urlencoded; charset=utf-8")
+		}
+		if tt.header != "" {
+			req.Header.Set("X-HTTP-Method-Override", tt.header)
+		}
+		if c := run(t, req, handler, methodHandler); c.Body() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, c.Body(), tt.want)
+		}
+	}
+}
+
+func TestMethodOverrideFormOnlyForForms(t *testing.T) {
+	// A JSON body isn't parsed for the form field
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"_method":"DELETE"}`))
+	req.Header.Set(utils.HeaderContentType, "application/json")
+	if c := run(t, req, MethodOverride(), methodHandler); c.Body() != "POST <nil>" {
+		t.Errorf("got %q", c.Body())
+	}
+}
+
+func TestMethodOverrideConfig(t *testing.T) {
+	handler := MethodOverride(ConfigMethodOverride{
+		Header:     "X-Method",
+		FormField:  "verb",
+		Methods:    []string{utils.MethodDelete},
+		ContextKey: "via",
+	})
+	report := func(c http.Context) error {
+		return c.String(fmt.Sprintf("%s %v", c.Method(), c.Value("via")))
+	}
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Method", "DELETE")
+	if c := run(t, req, handler, report); c.Body() != "DELETE POST" {
+		t.Errorf("custom header: got %q", c.Body())
+	}
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader("verb=DELETE"))
+	req.Header.Set(utils.HeaderContentType, "application/x-www-form-urlencoded")
+	if c := run(t, req, handler, report); c.Body() != "DELETE POST" {
+		t.Errorf("custom form field: got %q", c.Body())
+	}
+
+	// PUT is allowed by default only
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Method", "PUT")
+	if c := run(t, req, handler, report); c.Body() != "POST <nil>" {
+		t.Errorf("method outside Methods: got %q", c.Body())
+	}
+}
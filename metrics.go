@@ -0,0 +1,320 @@
+package middleware
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigMetrics defines the config for middleware.
+type ConfigMetrics struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// RouteLabel returns the route a request is recorded under. Return the
+	// route pattern rather than the raw path when paths carry ids, every
+	// distinct label is a new series.
+	//
+	// Optional. Default: "unmatched" for every request
+	RouteLabel func(c http.Context) string
+
+	// MaxSeries caps the distinct method, route and status label sets of
+	// the registry, requests beyond it are recorded under the route
+	// "overflow" with the method "other". A negative value disables the cap.
+	//
+	// Optional. Default: 1000
+	MaxSeries int
+
+	// Buckets are the upper bounds in seconds of the latency histogram
+	//
+	// Optional. Default: 5ms, 10ms, 25ms, 50ms, 100ms, 250ms, 500ms, 1s, 2.5s, 5s, 10s
+	Buckets []float64
+
+	// Registry the metrics are recorded into
+	//
+	// Optional. Default: the registry rendered by MetricsHandler
+	Registry *MetricsRegistry
+}
+
+// ConfigMetricsDefault is the default config
+var ConfigMetricsDefault = ConfigMetrics{
+	Next: nil,
+	RouteLabel: func(c http.Context) string {
+		return "unmatched"
+	},
+	MaxSeries: 1000,
+	Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}
+
+// Helper function to set default values
+func configMetricsDefault(config ...ConfigMetrics) ConfigMetrics {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigMetricsDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.RouteLabel == nil {
+		cfg.RouteLabel = ConfigMetricsDefault.RouteLabel
+	}
+	if cfg.MaxSeries == 0 {
+		cfg.MaxSeries = ConfigMetricsDefault.MaxSeries
+	}
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = ConfigMetricsDefault.Buckets
+	}
+	return cfg
+}
+
+var defaultMetricsRegistry = NewMetricsRegistry()
+
+// statusClasses labels the status code of a response by its first digit
+var statusClasses = [...]string{"unknown", "1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// MetricsRegistry holds the request metrics recorded by Metrics
+type MetricsRegistry struct {
+	mu       sync.RWMutex
+	series   map[metricsKey]*metricsSeries
+	inFlight atomic.Int64
+}
+
+type metricsKey struct {
+	method string
+	route  string
+	class  uint8
+}
+
+// metricsSeries is the histogram of one label set, counts are per bucket
+// and made cumulative when rendered
+type metricsSeries struct {
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Int64
+}
+
+// NewMetricsRegistry creates an empty registry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{series: make(map[metricsKey]*metricsSeries)}
+}
+
+// observe records one request, it doesn't allocate once the series exists.
+// Once the registry holds maxSeries series new label sets are folded into
+// the overflow series of their status class.
+func (r *MetricsRegistry) observe(key metricsKey, buckets []float64, maxSeries int, d time.Duration) {
+	r.mu.RLock()
+	s := r.series[key]
+	r.mu.RUnlock()
+	if s == nil {
+		r.mu.Lock()
+		if s = r.series[key]; s == nil {
+			if maxSeries >= 0 && len(r.series) >= maxSeries {
+				key = metricsKey{method: "other", route: "overflow", class: key.class}
+				s = r.series[key]
+			}
+			if s == nil {
+				s = &metricsSeries{buckets: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+				r.series[key] = s
+			}
+		}
+		r.mu.Unlock()
+	}
+
+	seconds := d.Seconds()
+	i := 0
+	for i < len(s.buckets) && seconds > s.buckets[i] {
+		i++
+	}
+	s.counts[i].Add(1)
+	s.count.Add(1)
+	s.sum.Add(int64(d))
+}
+
+// Metrics creates a new middleware handler
+func Metrics(config ConfigMetrics) http.HandlerFunc {
+	// Set default config
+	cfg := configMetricsDefault(config)
+
+	registry := cfg.Registry
+	if registry == nil {
+		registry = defaultMetricsRegistry
+	}
+	buckets := append([]float64(nil), cfg.Buckets...)
+	sort.Float64s(buckets)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		registry.inFlight.Add(1)
+		defer registry.inFlight.Add(-1)
+
+		start := time.Now()
+		rec, captured := captureResponse(c)
+		var err error
+		if captured {
+			err = rec.next(c)
+		} else {
+			err = c.Next()
+		}
+		duration := time.Since(start)
+
+		status := c.StatusCode()
+		if captured {
+			status = rec.Status()
+			rec.release()
+		}
+		class := uint8(status / 100)
+		if class >= uint8(len(statusClasses)) {
+			class = 0
+		}
+		registry.observe(metricsKey{method: c.Method(), route: cfg.RouteLabel(c), class: class}, buckets, cfg.MaxSeries, duration)
+		return err
+	}
+}
+
+// MetricsHandler renders the default registry
+func MetricsHandler() http.HandlerFunc {
+	return defaultMetricsRegistry.Handler()
+}
+
+// Handler renders the registry in the Prometheus text format, or as JSON
+// when asked for with ?format=json or an Accept: application/json header
+func (r *MetricsRegistry) Handler() http.HandlerFunc {
+	return func(c http.Context) error {
+		keys, series := r.snapshot()
+		if c.Query("format", "") == "json" || strings.Contains(c.Header(utils.HeaderAccept, ""), "application/json") {
+			body, err := json.Marshal(r.jsonMetrics(keys, series))
+			if err != nil {
+				return err
+			}
+			c.SetHeader(utils.HeaderContentType, "application/json")
+			c.Status(utils.StatusOK)
+			return c.String("%s", body)
+		}
+		c.SetHeader(utils.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(utils.StatusOK)
+		return c.String("%s", r.prometheus(keys, series))
+	}
+}
+
+// snapshot returns the series sorted by their labels
+func (r *MetricsRegistry) snapshot() ([]metricsKey, []*metricsSeries) {
+	r.mu.RLock()
+	keys := make([]metricsKey, 0, len(r.series))
+	for key := range r.series {
+		keys = append(keys, key)
+	}
+	r.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].class < keys[j].class
+	})
+
+	r.mu.RLock()
+	series := make([]*metricsSeries, len(keys))
+	for i, key := range keys {
+		series[i] = r.series[key]
+	}
+	r.mu.RUnlock()
+	return keys, series
+}
+
+func (r *MetricsRegistry) prometheus(keys []metricsKey, series []*metricsSeries) string {
+	var b strings.Builder
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		labels[i] = `method="` + escapeLabel(key.method) + `",route="` + escapeLabel(key.route) + `",status="` + statusClasses[key.class] + `"`
+	}
+
+	b.WriteString("# HELP http_requests_total Total number of HTTP requests.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for i, s := range series {
+		b.WriteString("http_requests_total{" + labels[i] + "} " + strconv.FormatUint(s.count.Load(), 10) + "\n")
+	}
+
+	b.WriteString("# HELP http_request_duration_seconds Latency of HTTP requests.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for i, s := range series {
+		var cumulative uint64
+		for j := range s.counts {
+			cumulative += s.counts[j].Load()
+			le := "+Inf"
+			if j < len(s.buckets) {
+				le = strconv.FormatFloat(s.buckets[j], 'g', -1, 64)
+			}
+			b.WriteString("http_request_duration_seconds_bucket{" + labels[i] + `,le="` + le + `"} ` + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		b.WriteString("http_request_duration_seconds_sum{" + labels[i] + "} " + strconv.FormatFloat(time.Duration(s.sum.Load()).Seconds(), 'g', -1, 64) + "\n")
+		b.WriteString("http_request_duration_seconds_count{" + labels[i] + "} " + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+
+	b.WriteString("# HELP http_requests_in_flight Number of HTTP requests being served.\n")
+	b.WriteString("# TYPE http_requests_in_flight gauge\n")
+	b.WriteString("http_requests_in_flight " + strconv.FormatInt(r.inFlight.Load(), 10) + "\n")
+	return b.String()
+}
+
+type jsonMetricsSeries struct {
+	Method  string            `json:"method"`
+	Route   string            `json:"route"`
+	Status  string            `json:"status"`
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+	Buckets map[string]uint64 `json:"buckets"`
+}
+
+func (r *MetricsRegistry) jsonMetrics(keys []metricsKey, series []*metricsSeries) any {
+	out := make([]jsonMetricsSeries, len(keys))
+	for i, s := range series {
+		out[i] = jsonMetricsSeries{
+			Method:  keys[i].method,
+			Route:   keys[i].route,
+			Status:  statusClasses[keys[i].class],
+			Count:   s.count.Load(),
+			Sum:     time.Duration(s.sum.Load()).Seconds(),
+			Buckets: make(map[string]uint64, len(s.counts)),
+		}
+		var cumulative uint64
+		for j := range s.counts {
+			cumulative += s.counts[j].Load()
+			le := "+Inf"
+			if j < len(s.buckets) {
+				le = strconv.FormatFloat(s.buckets[j], 'g', -1, 64)
+			}
+			out[i].Buckets[le] = cumulative
+		}
+	}
+	return map[string]any{
+		"requests":  out,
+		"in_flight": r.inFlight.Load(),
+	}
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(value string) string {
+	if !strings.ContainsAny(value, "\\\"\n") {
+		return value
+	}
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
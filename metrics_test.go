@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"encoding/json"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestMetricsStatusClasses(t *testing.T) {
+	registry := NewMetricsRegistry()
+	handler := Metrics(ConfigMetrics{Registry: registry, RouteLabel: func(c http.Context) string { return c.Origin().URL.Path }})
+	run(t, httptest.NewRequest("GET", "/a", nil), handler, ok)
+	run(t, httptest.NewRequest("GET", "/a", nil), handler, func(c http.Context) error {
+		return c.Status(utils.StatusNotFound).String("missing")
+	})
+	run(t, httptest.NewRequest("POST", "/b", nil), handler, func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusServiceUnavailable)
+		return nil
+	})
+
+	c := run(t, httptest.NewRequest("GET", "/metrics", nil), registry.Handler())
+	body := c.Body()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/a",status="2xx"} 1`,
+		`http_requests_total{method="GET",route="/a",status="4xx"} 1`,
+		`http_requests_total{method="POST",route="/b",status="5xx"} 1`,
+		`http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsBuckets(t *testing.T) {
+	registry := NewMetricsRegistry()
+	handler := Metrics(ConfigMetrics{
+		Registry: registry,
+		// Unsorted on purpose
+		Buckets:    []float64{10, 0.5},
+		RouteLabel: func(c http.Context) string { return "/users/:id" },
+	})
+	for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+		run(t, httptest.NewRequest("GET", path, nil), handler, ok)
+	}
+	// A slow request lands in the last bucket
+	key := metricsKey{method: "GET", route: "/users/:id", class: 2}
+	registry.observe(key, []float64{0.5, 10}, -1, 2*time.Second)
+
+	body := run(t, httptest.NewRequest("GET", "/metrics", nil), registry.Handler()).Body()
+	labels := `method="GET",route="/users/:id",status="2xx"`
+	for _, want := range []string{
+		`http_requests_total{` + labels + `} 4`,
+		`http_request_duration_seconds_bucket{` + labels + `,le="0.5"} 3`,
+		`http_request_duration_seconds_bucket{` + labels + `,le="10"} 4`,
+		`http_request_duration_seconds_bucket{` + labels + `,le="+Inf"} 4`,
+		`http_request_duration_seconds_count{` + labels + `} 4`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/users/1") {
+		t.Error("raw path recorded")
+	}
+
+	req := httptest.NewRequest("GET", "/metrics?format=json", nil)
+	c := run(t, req, registry.Handler())
+	var res struct {
+		Requests []jsonMetricsSeries `json:"requests"`
+		InFlight int64               `json:"in_flight"`
+	}
+	if err := json.Unmarshal(c.Recorder.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Requests) != 1 || res.Requests[0].Count != 4 || res.Requests[0].Buckets["0.5"] != 3 ||
+		res.Requests[0].Buckets["+Inf"] != 4 || c.Recorder.Header().Get(utils.HeaderContentType) != "application/json" {
+		t.Errorf("json = %+v", res)
+	}
+}
+
+func TestMetricsMaxSeries(t *testing.T) {
+	registry := NewMetricsRegistry()
+	run(t, httptest.NewRequest("GET", "/users/1", nil), Metrics(ConfigMetrics{Registry: registry}), ok)
+	handler := Metrics(ConfigMetrics{
+		Registry:   registry,
+		MaxSeries:  2,
+		RouteLabel: func(c http.Context) string { return c.Origin().URL.Path },
+	})
+	for _, path := range []string{"/users/2", "/users/3", "/users/4"} {
+		run(t, httptest.NewRequest("GET", path, nil), handler, ok)
+	}
+
+	body := run(t, httptest.NewRequest("GET", "/metrics", nil), registry.Handler()).Body()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="unmatched",status="2xx"} 1`,
+		`http_requests_total{method="GET",route="/users/2",status="2xx"} 1`,
+		`http_requests_total{method="other",route="overflow",status="2xx"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/users/1") || strings.Contains(body, "/users/3") {
+		t.Errorf("series beyond the cap:\n%s", body)
+	}
+}
+
+func TestMetricsObserveDoesNotAllocate(t *testing.T) {
+	registry := NewMetricsRegistry()
+	key := metricsKey{method: "GET", route: "/", class: 2}
+	buckets := ConfigMetricsDefault.Buckets
+	registry.observe(key, buckets, -1, time.Millisecond)
+	if allocs := testing.AllocsPerRun(100, func() {
+		registry.observe(key, buckets, -1, time.Millisecond)
+	}); allocs != 0 {
+		t.Errorf("observe allocates %v times", allocs)
+	}
+}
+
+// engineStub exposes its writer like the chi engine's context
+type engineStub struct {
+	Res stdHttp.ResponseWriter
+}
+
+// discardWriter drops the response
+type discardWriter struct {
+	header stdHttp.Header
+}
+
+func (w *discardWriter) Header() stdHttp.Header      { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// allocFreeContext is a context whose own methods don't allocate, so the
+// allocations of a middleware can be counted
+type allocFreeContext struct {
+	engineContext
+	engine *engineStub
+}
+
+func (c *allocFreeContext) EngineContext() any { return c.engine }
+func (c *allocFreeContext) Method() string     { return "GET" }
+func (c *allocFreeContext) StatusCode() int    { return utils.StatusOK }
+
+// Next writes the response through the engine's writer
+func (c *allocFreeContext) Next() error {
+	c.engine.Res.WriteHeader(utils.StatusNotFound)
+	return nil
+}
+
+func TestMetricsDoesNotAllocate(t *testing.T) {
+	registry := NewMetricsRegistry()
+	handler := Metrics(ConfigMetrics{Registry: registry})
+	c := &allocFreeContext{engine: &engineStub{Res: &discardWriter{header: stdHttp.Header{}}}}
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		_ = handler(c)
+	}); allocs != 0 {
+		t.Errorf("Metrics allocates %v times per request", allocs)
+	}
+	// The status is still read from the response
+	if body := run(t, httptest.NewRequest("GET", "/metrics", nil), registry.Handler()).Body(); !strings.Contains(body, `status="4xx"} 102`) {
+		t.Errorf("requests not recorded:\n%s", body)
+	}
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	registry := NewMetricsRegistry()
+	handler := Metrics(ConfigMetrics{Registry: registry})
+	run(t, httptest.NewRequest("GET", "/slow", nil), handler, func(c http.Context) error {
+		body := run(t, httptest.NewRequest("GET", "/metrics", nil), registry.Handler()).Body()
+		if !strings.Contains(body, "http_requests_in_flight 1\n") {
+			t.Errorf("in flight gauge:\n%s", body)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,465 @@
+// Package middlewaretest provides a test double of the framework's
+// http.Context, so middlewares can be unit tested without starting a
+// server.
+//
+//	req := httptest.NewRequest("GET", "/", nil)
+//	req.Header.Set("Origin", "https://example.com")
+//	c := middlewaretest.NewMockContext(req, middleware.Cors(), func(c http.Context) error {
+//		return c.String("ok")
+//	})
+//	err := c.Run()
+//	// c.Recorder holds the response, c.Calls() the methods called on the
+//	// contexts of the chain
+package middlewaretest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// ErrNotSupported is returned by the methods the mock can't emulate
+var ErrNotSupported = errors.New("middlewaretest: not supported")
+
+// HeaderWrite is a call of SetHeader
+type HeaderWrite struct {
+	Key   string
+	Value string
+}
+
+// MockContext implements http.Context over a net/http request and an
+// httptest.ResponseRecorder. It behaves like the chi engine:
+//
+//   - every handler of the chain gets its own context, values stored with
+//     WithValue are seen by the later handlers only
+//   - Next wraps the response writer, runs the rest of the chain and
+//     returns nil, the errors returned by the handlers are dropped
+//   - StatusCode is only known once Next returned
+//   - Context returns context.Background()
+//
+// Like the engine it keeps the request and writer in the Req and Res
+// fields, so the middlewares wrapping the response writer work with it.
+type MockContext struct {
+	// Req is the request, replaced by WithValue like in the engine
+	Req *stdHttp.Request
+	// Res is the writer the handler writes to, middlewares may wrap it
+	Res stdHttp.ResponseWriter
+	// Recorder receives the response
+	Recorder *httptest.ResponseRecorder
+
+	// IP returned by Ip, resolved from the headers and Req.RemoteAddr like
+	// the engine does when empty
+	IP string
+	// RouteParams are returned by Params
+	RouteParams map[string]string
+
+	chain      *mockChain
+	index      int
+	statusCode int
+}
+
+// mockChain is shared by the contexts of a chain
+type mockChain struct {
+	handlers     []http.HandlerFunc
+	errs         []error
+	calls        []string
+	headerWrites []HeaderWrite
+}
+
+// statusWriter records the status written by the rest of the chain, like
+// the engine's ChiResponseWriter
+type statusWriter struct {
+	stdHttp.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before passing it on
+func (w *statusWriter) WriteHeader(status int) {
+	w.ResponseWriter.WriteHeader(status)
+	w.status = status
+}
+
+// NewMockContext returns the context of the first of the handlers, each
+// one's Next runs the following one with a new context
+func NewMockContext(req *stdHttp.Request, handlers ...http.HandlerFunc) *MockContext {
+	rec := httptest.NewRecorder()
+	return &MockContext{
+		Req:         req,
+		Res:         rec,
+		Recorder:    rec,
+		RouteParams: make(map[string]string),
+		chain: &mockChain{
+			handlers: handlers,
+			errs:     make([]error, len(handlers)),
+		},
+	}
+}
+
+// Run starts the chain and returns the error of the first handler, the
+// one the engine drops. It can only be called once.
+func (c *MockContext) Run() error {
+	if len(c.chain.handlers) == 0 {
+		return nil
+	}
+	c.chain.errs[0] = c.chain.handlers[0](c)
+	return c.chain.errs[0]
+}
+
+// Errors returns the error returned by each handler, nil for the ones
+// that returned none or didn't run
+func (c *MockContext) Errors() []error {
+	return append([]error(nil), c.chain.errs...)
+}
+
+// Calls returns the names of the methods called so far, in order
+func (c *MockContext) Calls() []string {
+	return append([]string(nil), c.chain.calls...)
+}
+
+// CalledInOrder reports whether the methods were called in this order,
+// other calls in between are ignored
+func (c *MockContext) CalledInOrder(names ...string) bool {
+	i := 0
+	for _, call := range c.chain.calls {
+		if i < len(names) && call == names[i] {
+			i++
+		}
+	}
+	return i == len(names)
+}
+
+// HeaderWrites returns the calls of SetHeader, in order
+func (c *MockContext) HeaderWrites() []HeaderWrite {
+	return append([]HeaderWrite(nil), c.chain.headerWrites...)
+}
+
+// Body returns the response body written so far
+func (c *MockContext) Body() string {
+	return c.Recorder.Body.String()
+}
+
+func (c *MockContext) record(name string) {
+	c.chain.calls = append(c.chain.calls, name)
+}
+
+// Deadline implements context.Context with the request's context
+func (c *MockContext) Deadline() (time.Time, bool) {
+	return c.Req.Context().Deadline()
+}
+
+// Done implements context.Context with the request's context
+func (c *MockContext) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+// Err implements context.Context with the request's context
+func (c *MockContext) Err() error {
+	return c.Req.Context().Err()
+}
+
+// Value returns a value stored with WithValue
+func (c *MockContext) Value(key any) any {
+	c.record("Value")
+	return c.Req.Context().Value(key)
+}
+
+// Context returns context.Background(), like the engine
+func (c *MockContext) Context() context.Context {
+	return context.Background()
+}
+
+// WithValue stores a value in the request's context
+func (c *MockContext) WithValue(key string, value any) {
+	c.record("WithValue")
+	c.Req = c.Req.WithContext(context.WithValue(c.Req.Context(), key, value))
+}
+
+// EngineContext returns the mock itself, like the chi engine does
+func (c *MockContext) EngineContext() any {
+	return c
+}
+
+// Header returns a request header or defaultValue
+func (c *MockContext) Header(key, defaultValue string) string {
+	c.record("Header")
+	if value := c.Req.Header.Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Headers returns the request headers
+func (c *MockContext) Headers() stdHttp.Header {
+	c.record("Headers")
+	return c.Req.Header
+}
+
+// Method returns the request method
+func (c *MockContext) Method() string {
+	c.record("Method")
+	return c.Req.Method
+}
+
+// Path returns the request URI
+func (c *MockContext) Path() string {
+	c.record("Path")
+	return c.Req.RequestURI
+}
+
+// Secure reports whether the request came over TLS
+func (c *MockContext) Secure() bool {
+	return c.Req.TLS != nil
+}
+
+// Url returns the request URI
+func (c *MockContext) Url() string {
+	return c.Req.RequestURI
+}
+
+// FullUrl returns the scheme, host and request URI, "" without a host
+func (c *MockContext) FullUrl() string {
+	if c.Req.Host == "" {
+		return ""
+	}
+	scheme := "http://"
+	if c.Req.TLS != nil {
+		scheme = "https://"
+	}
+	return scheme + c.Req.Host + c.Req.RequestURI
+}
+
+// Ip returns IP, or like the engine the first address of the
+// True-Client-IP, X-Real-IP or X-Forwarded-For header, falling back to
+// the host of the remote address. It's "" when that isn't a valid IP.
+func (c *MockContext) Ip() string {
+	c.record("Ip")
+	if c.IP != "" {
+		return c.IP
+	}
+	ip := c.Req.Header.Get("True-Client-IP")
+	if ip == "" {
+		ip = c.Req.Header.Get("X-Real-IP")
+	}
+	if ip == "" {
+		ip, _, _ = strings.Cut(c.Req.Header.Get("X-Forwarded-For"), ",")
+	}
+	if ip == "" {
+		host, _, err := net.SplitHostPort(c.Req.RemoteAddr)
+		if err != nil {
+			return ""
+		}
+		ip = host
+	}
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+// Params returns a RouteParams entry
+func (c *MockContext) Params(key string) string {
+	return c.RouteParams[key]
+}
+
+// Query returns a query parameter or defaultValue
+func (c *MockContext) Query(key, defaultValue string) string {
+	if value := c.Req.URL.Query().Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Form returns a field of the parsed form or defaultValue, like the
+// engine it doesn't parse the body itself
+func (c *MockContext) Form(key, defaultValue string) string {
+	if value := c.Req.Form.Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Bind decodes a JSON request body into obj
+func (c *MockContext) Bind(obj any) error {
+	if !strings.Contains(c.Req.Header.Get("Content-Type"), "json") {
+		return ErrNotSupported
+	}
+	return json.NewDecoder(c.Req.Body).Decode(obj)
+}
+
+// Status writes the status code
+func (c *MockContext) Status(code int) http.Context {
+	c.record("Status")
+	c.Res.WriteHeader(code)
+	return c
+}
+
+// AbortWithStatus writes the status code
+func (c *MockContext) AbortWithStatus(code int) {
+	c.record("AbortWithStatus")
+	c.Res.WriteHeader(code)
+}
+
+// Next runs the next handler of the chain with a new context sharing the
+// request and a wrapped writer. It always returns nil like the engine, see
+// Errors for what the handlers returned.
+func (c *MockContext) Next() error {
+	c.record("Next")
+	index := c.index + 1
+	if index >= len(c.chain.handlers) {
+		return nil
+	}
+	w := &statusWriter{ResponseWriter: c.Res}
+	next := &MockContext{
+		Req:         c.Req,
+		Res:         w,
+		Recorder:    c.Recorder,
+		IP:          c.IP,
+		RouteParams: c.RouteParams,
+		chain:       c.chain,
+		index:       index,
+	}
+	c.chain.errs[index] = c.chain.handlers[index](next)
+	c.statusCode = w.status
+	return nil
+}
+
+// Cookies returns a request cookie or the default value
+func (c *MockContext) Cookies(key string, defaultValue ...string) string {
+	value := ""
+	if len(defaultValue) > 0 {
+		value = defaultValue[0]
+	}
+	if cookie, err := c.Req.Cookie(key); err == nil && cookie.Value != "" {
+		value = cookie.Value
+	}
+	return value
+}
+
+// Cookie sets a response cookie
+func (c *MockContext) Cookie(co *http.Cookie) {
+	c.record("Cookie")
+	cookie := &stdHttp.Cookie{
+		Name:     co.Name,
+		Value:    co.Value,
+		Path:     co.Path,
+		Domain:   co.Domain,
+		Expires:  co.Expires,
+		MaxAge:   co.MaxAge,
+		Secure:   co.Secure,
+		HttpOnly: co.HTTPOnly,
+	}
+	switch co.SameSite {
+	default:
+		cookie.SameSite = stdHttp.SameSiteDefaultMode
+	case "Lax":
+		cookie.SameSite = stdHttp.SameSiteLaxMode
+	case "None":
+		cookie.SameSite = stdHttp.SameSiteNoneMode
+	case "Strict":
+		cookie.SameSite = stdHttp.SameSiteStrictMode
+	}
+	stdHttp.SetCookie(c.Res, cookie)
+}
+
+// SaveFile saves an uploaded file to dst
+func (c *MockContext) SaveFile(name string, dst string) error {
+	header, err := c.File(name)
+	if err != nil {
+		return err
+	}
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = out.ReadFrom(src)
+	return err
+}
+
+// File returns an uploaded file
+func (c *MockContext) File(name string) (*multipart.FileHeader, error) {
+	_, header, err := c.Req.FormFile(name)
+	return header, err
+}
+
+// Origin returns the request
+func (c *MockContext) Origin() *stdHttp.Request {
+	return c.Req
+}
+
+// Render isn't supported
+func (c *MockContext) Render(name string, bind any, layouts ...string) error {
+	return ErrNotSupported
+}
+
+// String writes a formatted body
+func (c *MockContext) String(format string, values ...any) error {
+	c.record("String")
+	_, err := c.Res.Write([]byte(fmt.Sprintf(format, values...)))
+	return err
+}
+
+// Json writes obj as a JSON body
+func (c *MockContext) Json(obj any) error {
+	c.record("Json")
+	c.Res.Header().Set("Content-Type", "application/json")
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = c.Res.Write(body)
+	return err
+}
+
+// SendFile writes a file
+func (c *MockContext) SendFile(filepath string, compress ...bool) error {
+	stdHttp.ServeFile(c.Res, c.Req, filepath)
+	return nil
+}
+
+// Download writes a file as an attachment
+func (c *MockContext) Download(filepath, filename string) error {
+	c.Res.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	stdHttp.ServeFile(c.Res, c.Req, filepath)
+	return nil
+}
+
+// StatusCode returns the status written by the rest of the chain once Next
+// returned, 200 before or when none was written
+func (c *MockContext) StatusCode() int {
+	if c.statusCode == 0 {
+		return stdHttp.StatusOK
+	}
+	return c.statusCode
+}
+
+// SetHeader sets a response header
+func (c *MockContext) SetHeader(key, value string) http.Context {
+	c.record("SetHeader")
+	c.chain.headerWrites = append(c.chain.headerWrites, HeaderWrite{Key: key, Value: value})
+	c.Res.Header().Set(key, value)
+	return c
+}
+
+// Vary does nothing, like the engine which appends no values to the
+// header named key
+func (c *MockContext) Vary(key string, value ...string) {
+	c.record("Vary")
+}
+
+var _ http.Context = (*MockContext)(nil)
@@ -0,0 +1,142 @@
+package middlewaretest
+
+import (
+	"context"
+	"errors"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+func TestMockContextValuesFlowDownstreamOnly(t *testing.T) {
+	var upstream, downstream any
+	c := NewMockContext(httptest.NewRequest("GET", "/", nil),
+		func(c http.Context) error {
+			c.WithValue("outer", "a")
+			err := c.Next()
+			upstream = c.Value("inner")
+			return err
+		},
+		func(c http.Context) error {
+			downstream = c.Value("outer")
+			c.WithValue("inner", "b")
+			return nil
+		},
+	)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if downstream != "a" {
+		t.Errorf("downstream value = %v, want a", downstream)
+	}
+	if upstream != nil {
+		t.Errorf("upstream saw %v, want nil like the engine", upstream)
+	}
+}
+
+func TestMockContextNextDropsErrors(t *testing.T) {
+	errInner := errors.New("inner")
+	var nextErr error
+	c := NewMockContext(httptest.NewRequest("GET", "/", nil),
+		func(c http.Context) error {
+			nextErr = c.Next()
+			return nil
+		},
+		func(c http.Context) error {
+			return errInner
+		},
+	)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if nextErr != nil {
+		t.Errorf("Next returned %v, want nil", nextErr)
+	}
+	if errs := c.Errors(); len(errs) != 2 || errs[0] != nil || errs[1] != errInner {
+		t.Errorf("Errors() = %v", errs)
+	}
+}
+
+func TestMockContextStatusCodeAfterNext(t *testing.T) {
+	var before, after int
+	c := NewMockContext(httptest.NewRequest("GET", "/", nil),
+		func(c http.Context) error {
+			before = c.StatusCode()
+			err := c.Next()
+			after = c.StatusCode()
+			return err
+		},
+		func(c http.Context) error {
+			return c.Status(stdHttp.StatusTeapot).String("short and stout")
+		},
+	)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if before != stdHttp.StatusOK || after != stdHttp.StatusTeapot {
+		t.Errorf("StatusCode before/after Next = %d/%d, want 200/418", before, after)
+	}
+	if c.Recorder.Code != stdHttp.StatusTeapot || c.Body() != "short and stout" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+}
+
+func TestMockContextEngineQuirks(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	c := NewMockContext(req, func(c http.Context) error {
+		c.Vary("Origin")
+		return nil
+	})
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Context() != context.Background() {
+		t.Error("Context() should be context.Background()")
+	}
+	if ip := c.Ip(); ip != "203.0.113.9" {
+		t.Errorf("Ip() = %q, want the X-Forwarded-For address", ip)
+	}
+	if vary := c.Recorder.Header().Get("Vary"); vary != "" {
+		t.Errorf("Vary wrote %q, the engine's Vary writes nothing", vary)
+	}
+}
+
+func TestMockContextCallOrderAndHeaderWrites(t *testing.T) {
+	c := NewMockContext(httptest.NewRequest("GET", "/", nil),
+		func(c http.Context) error {
+			c.SetHeader("X-A", "1")
+			return c.Next()
+		},
+		func(c http.Context) error {
+			c.SetHeader("X-B", "2")
+			return c.String("ok")
+		},
+	)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.CalledInOrder("SetHeader", "Next", "SetHeader", "String") {
+		t.Errorf("calls = %v", c.Calls())
+	}
+	writes := c.HeaderWrites()
+	if len(writes) != 2 || writes[0] != (HeaderWrite{"X-A", "1"}) || writes[1] != (HeaderWrite{"X-B", "2"}) {
+		t.Errorf("header writes = %v", writes)
+	}
+}
+
+func TestMockContextJson(t *testing.T) {
+	c := NewMockContext(httptest.NewRequest("GET", "/", nil), func(c http.Context) error {
+		return c.Status(stdHttp.StatusCreated).Json(map[string]any{"id": 1})
+	})
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Recorder.Code != stdHttp.StatusCreated || c.Body() != `{"id":1}` ||
+		c.Recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("response = %d %q %v", c.Recorder.Code, c.Body(), c.Recorder.Header())
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigNoCache defines the config for middleware.
+type ConfigNoCache struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// StripHeaders are removed from the response, validators would let
+	// clients revalidate a copy they shouldn't have kept
+	//
+	// Optional. Default: ETag, Last-Modified
+	StripHeaders []string
+}
+
+// ConfigNoCacheDefault is the default config
+var ConfigNoCacheDefault = ConfigNoCache{
+	Next:         nil,
+	StripHeaders: []string{utils.HeaderETag, utils.HeaderLastModified},
+}
+
+// Helper function to set default values
+func configNoCacheDefault(config ...ConfigNoCache) ConfigNoCache {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigNoCacheDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.StripHeaders == nil {
+		cfg.StripHeaders = ConfigNoCacheDefault.StripHeaders
+	}
+	return cfg
+}
+
+// NoCache creates a new middleware handler forbidding every cache to store
+// the response, for auth pages and personal API responses. The headers are
+// set once the handler wrote its response, so they replace its own.
+func NoCache(config ...ConfigNoCache) http.HandlerFunc {
+	// Set default config
+	cfg := configNoCacheDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		rec, ok := onHeaders(c, func(status int, header stdHttp.Header) {
+			header.Set(utils.HeaderCacheControl, "no-store, no-cache, must-revalidate")
+			header.Set(utils.HeaderPragma, "no-cache")
+			header.Set(utils.HeaderExpires, "0")
+			for _, name := range cfg.StripHeaders {
+				header.Del(name)
+			}
+		})
+		if !ok {
+			c.SetHeader(utils.HeaderCacheControl, "no-store, no-cache, must-revalidate")
+			c.SetHeader(utils.HeaderPragma, "no-cache")
+			c.SetHeader(utils.HeaderExpires, "0")
+			return c.Next()
+		}
+		return rec.next(c)
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// cachingHandler sets the caching headers NoCache replaces
+func cachingHandler(c http.Context) error {
+	c.SetHeader(utils.HeaderCacheControl, "public, max-age=3600")
+	c.SetHeader(utils.HeaderExpires, "Wed, 01 Jan 2031 00:00:00 GMT")
+	c.SetHeader(utils.HeaderETag, `"v1"`)
+	c.SetHeader(utils.HeaderLastModified, "Mon, 01 Jan 2024 00:00:00 GMT")
+	return c.String("account")
+}
+
+func TestNoCache(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		final   http.HandlerFunc
+		etag    string
+		skipped bool
+	}{
+		{"handler headers replaced", NoCache(), cachingHandler, "", false},
+		{"empty response", NoCache(), func(c http.Context) error { return nil }, "", false},
+		{"custom strip list", NoCache(ConfigNoCache{StripHeaders: []string{utils.HeaderLastModified}}), cachingHandler, `"v1"`, false},
+		{"skipped", NoCache(ConfigNoCache{Next: func(c http.Context) bool { return true }}), cachingHandler, `"v1"`, true},
+	} {
+		c := run(t, httptest.NewRequest("GET", "/account", nil), tt.handler, tt.final)
+		// The headers as they reached the client
+		h := c.Recorder.Result().Header
+		if tt.skipped {
+			if h.Get(utils.HeaderCacheControl) != "public, max-age=3600" || h.Get(utils.HeaderPragma) != "" {
+				t.Errorf("%s: headers = %v", tt.name, h)
+			}
+			continue
+		}
+		if h.Get(utils.HeaderCacheControl) != "no-store, no-cache, must-revalidate" || h.Get(utils.HeaderPragma) != "no-cache" || h.Get(utils.HeaderExpires) != "0" {
+			t.Errorf("%s: headers = %v", tt.name, h)
+		}
+		if h.Get(utils.HeaderETag) != tt.etag || h.Get(utils.HeaderLastModified) != "" {
+			t.Errorf("%s: ETag = %q, Last-Modified = %q", tt.name, h.Get(utils.HeaderETag), h.Get(utils.HeaderLastModified))
+		}
+	}
+}
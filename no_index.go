@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigNoIndex defines the config for middleware.
+type ConfigNoIndex struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Enabled turns the middleware on, e.g. outside production. Requests
+	// pass through untouched while it returns false
+	//
+	// Optional. Default: nil, always enabled
+	Enabled func(c http.Context) bool
+
+	// Directives sent in the X-Robots-Tag header
+	//
+	// Optional. Default: "noindex, nofollow, noarchive"
+	Directives string
+
+	// RobotsTxt is served for GET and HEAD /robots.txt
+	//
+	// Optional. Default: "User-agent: *\nDisallow: /\n"
+	RobotsTxt string
+}
+
+// ConfigNoIndexDefault is the default config
+var ConfigNoIndexDefault = ConfigNoIndex{
+	Next:       nil,
+	Directives: "noindex, nofollow, noarchive",
+	RobotsTxt:  "User-agent: *\nDisallow: /\n",
+}
+
+// Helper function to set default values
+func configNoIndexDefault(config ...ConfigNoIndex) ConfigNoIndex {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigNoIndexDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Directives == "" {
+		cfg.Directives = ConfigNoIndexDefault.Directives
+	}
+	if cfg.RobotsTxt == "" {
+		cfg.RobotsTxt = ConfigNoIndexDefault.RobotsTxt
+	}
+	return cfg
+}
+
+// headerXRobotsTag asks crawlers not to index a response
+const headerXRobotsTag = "X-Robots-Tag"
+
+// NoIndex creates a new middleware handler keeping search engines away from
+// environments that shouldn't be indexed, such as staging. Every response
+// gets an X-Robots-Tag header and /robots.txt disallows everything.
+func NoIndex(config ...ConfigNoIndex) http.HandlerFunc {
+	// Set default config
+	cfg := configNoIndexDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if cfg.Enabled != nil && !cfg.Enabled(c) {
+			return c.Next()
+		}
+
+		c.SetHeader(headerXRobotsTag, cfg.Directives)
+		if c.Origin().URL.Path != "/robots.txt" || (c.Method() != utils.MethodGet && c.Method() != utils.MethodHead) {
+			return c.Next()
+		}
+
+		c.SetHeader(utils.HeaderContentType, "text/plain; charset=utf-8")
+		c.Status(utils.StatusOK)
+		if c.Method() == utils.MethodHead {
+			return nil
+		}
+		return c.String("%s", cfg.RobotsTxt)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestNoIndex(t *testing.T) {
+	handler := NoIndex()
+	for _, tt := range []struct {
+		method, target, body string
+	}{
+		{"GET", "/robots.txt", "User-agent: *\nDisallow: /\n"},
+		{"HEAD", "/robots.txt", ""},
+		// Only the exact path is answered
+		{"GET", "/robots.txt/", "ok"},
+		{"GET", "/static/robots.txt", "ok"},
+		{"POST", "/robots.txt", "ok"},
+		{"GET", "/", "ok"},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, tt.target, nil), handler, ok)
+		if c.Recorder.Code != utils.StatusOK || c.Body() != tt.body {
+			t.Errorf("%s %s: status = %d, body = %q", tt.method, tt.target, c.Recorder.Code, c.Body())
+		}
+		if got := c.Recorder.Header().Get("X-Robots-Tag"); got != "noindex, nofollow, noarchive" {
+			t.Errorf("%s %s: X-Robots-Tag = %q", tt.method, tt.target, got)
+		}
+	}
+}
+
+func TestNoIndexConfig(t *testing.T) {
+	staging := func(c http.Context) bool {
+		return c.Origin().Host == "staging.example.com"
+	}
+	handler := NoIndex(ConfigNoIndex{Enabled: staging, Directives: "none", RobotsTxt: "User-agent: *\nDisallow: /admin\n"})
+	for _, tt := range []struct {
+		host, target, tag, body string
+	}{
+		{"staging.example.com", "/robots.txt", "none", "User-agent: *\nDisallow: /admin\n"},
+		{"staging.example.com", "/", "none", "ok"},
+		// Disabled, the application serves its own robots.txt
+		{"example.com", "/robots.txt", "", "ok"},
+		{"example.com", "/", "", "ok"},
+	} {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		req.Host = tt.host
+		c := run(t, req, handler, ok)
+		if c.Body() != tt.body || c.Recorder.Header().Get("X-Robots-Tag") != tt.tag {
+			t.Errorf("%s%s: body = %q, X-Robots-Tag = %q", tt.host, tt.target, c.Body(), c.Recorder.Header().Get("X-Robots-Tag"))
+		}
+	}
+}
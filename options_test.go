@@ -0,0 +1,320 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestCorsOptions(t *testing.T) {
+	cfg := ConfigCorsDefault
+	CorsOptions(
+		CorsAllowOrigins("https://a.com", "https://b.com"),
+		CorsAllowMethods("GET", "POST"),
+		CorsAllowHeaders("X-A", "X-B"),
+		CorsExposeHeaders("X-Total"),
+		CorsCredentials(),
+		CorsMaxAge(90*time.Second),
+		CorsEnforceMethods(),
+		CorsMaxOrigins(3),
+		CorsSkipWithoutOrigin(),
+		CorsContextKey("origin"),
+	)(&cfg)
+	want := ConfigCorsDefault
+	want.AllowOrigins = "https://a.com,https://b.com"
+	want.AllowMethods = "GET,POST"
+	want.AllowHeaders = "X-A,X-B"
+	want.ExposeHeaders = "X-Total"
+	want.AllowCredentials = true
+	want.MaxAge = 90
+	want.EnforceMethods = true
+	want.MaxOrigins = 3
+	want.SkipWithoutOrigin = true
+	want.ContextKey = "origin"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("cfg = %+v\nwant %+v", cfg, want)
+	}
+
+	cfg = ConfigCorsDefault
+	CorsMaxAge(0)(&cfg)
+	if cfg.MaxAge >= 0 {
+		t.Errorf("CorsMaxAge(0) = %d, want an explicit zero", cfg.MaxAge)
+	}
+	cfg = ConfigCorsDefault
+	CorsReflectAllOrigins()(&cfg)
+	if !cfg.ReflectAllOrigins {
+		t.Error("ReflectAllOrigins not set")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://a.com")
+	c := run(t, req, CorsWith(CorsAllowOrigins("https://a.com"), CorsCredentials()), ok)
+	h := c.Recorder.Header()
+	if h.Get(utils.HeaderAccessControlAllowOrigin) != "https://a.com" || h.Get(utils.HeaderAccessControlAllowCredentials) != "true" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestSecureOptions(t *testing.T) {
+	cfg := ConfigSecureDefault
+	SecureOptions(
+		SecureXSSProtection("0"),
+		SecureContentTypeNosniff("x"),
+		SecureFrameOptions("DENY"),
+		SecureHSTS(time.Hour, true),
+		SecureHSTSExcludeSubdomains(),
+		SecureCSP("default-src 'self'", true),
+		SecureReferrerPolicy("no-referrer"),
+		SecurePermissionPolicy("camera=()"),
+	)(&cfg)
+	want := ConfigSecure{
+		XSSProtection:         "0",
+		ContentTypeNosniff:    "x",
+		XFrameOptions:         "DENY",
+		HSTSMaxAge:            3600,
+		HSTSPreloadEnabled:    true,
+		HSTSExcludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'",
+		CSPReportOnly:         true,
+		ReferrerPolicy:        "no-referrer",
+		PermissionPolicy:      "camera=()",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("cfg = %+v\nwant %+v", cfg, want)
+	}
+
+	cfg = ConfigSecureDefault
+	SecureHSTS(0, false)(&cfg)
+	if !cfg.HSTSDisable {
+		t.Error("SecureHSTS(0) doesn't send max-age=0")
+	}
+
+	// Unspecified options keep the defaults
+	c := run(t, httptest.NewRequest("GET", "/", nil), SecureWith(SecureReferrerPolicy("no-referrer")), ok)
+	h := c.Recorder.Header()
+	if h.Get(utils.HeaderXFrameOptions) != "SAMEORIGIN" || h.Get(utils.HeaderReferrerPolicy) != "no-referrer" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestBasicAuthOptions(t *testing.T) {
+	users := map[string]string{"john": "doe"}
+	cfg := ConfigBasicAuthDefault
+	BasicAuthOptions(
+		BasicAuthUsers(users),
+		BasicAuthRealm("Admin"),
+		BasicAuthScheme("x-basic"),
+		BasicAuthOmitChallenge(),
+		BasicAuthContextKeys("user", "pass"),
+		BasicAuthLenientBase64(),
+		BasicAuthLockout(3, time.Minute),
+	)(&cfg)
+	if !reflect.DeepEqual(cfg.Users, users) || cfg.Realm != "Admin" || cfg.Scheme != "x-basic" || !cfg.OmitChallenge ||
+		cfg.ContextUsername != "user" || cfg.ContextPassword != "pass" || !cfg.LenientBase64 ||
+		cfg.MaxFailures != 3 || cfg.LockoutDuration != time.Minute {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	users["jane"] = "x"
+	if len(cfg.Users) != 1 || len(ConfigBasicAuthDefault.Users) != 0 {
+		t.Error("BasicAuthUsers doesn't copy the users")
+	}
+
+	handler := BasicAuthWith(BasicAuthUsers(map[string]string{"john": "doe"}))
+	if c := run(t, basicAuthRequest("john", "doe"), handler, ok); c.Body() != "ok" {
+		t.Errorf("valid credentials: %d", c.Recorder.Code)
+	}
+	c := run(t, basicAuthRequest("john", "x"), handler, ok)
+	if c.Recorder.Header().Get("WWW-Authenticate") != "basic realm=Restricted" {
+		t.Errorf("challenge = %q, want the default", c.Recorder.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestCompressOptions(t *testing.T) {
+	var enc CompressEncoder = func(w io.Writer, _ CompressLevel) (io.WriteCloser, error) { return nil, nil }
+	cfg := ConfigCompressDefault
+	CompressOptions(
+		CompressAtLevel(CompressLevelBestSpeed),
+		CompressMinLength(0),
+		CompressMaxBufferSize(10),
+		CompressEncoding("BR", enc),
+		CompressOrder("br", "gzip"),
+	)(&cfg)
+	if cfg.Level != CompressLevelBestSpeed || cfg.MinLength != 1 || cfg.MaxBufferSize != 10 ||
+		cfg.Encoders["br"] == nil || !reflect.DeepEqual(cfg.Order, []string{"br", "gzip"}) {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if ConfigCompressDefault.Encoders != nil {
+		t.Error("CompressEncoding changed the default config")
+	}
+
+	// Unspecified options keep the defaults: short bodies aren't compressed
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAcceptEncoding, "gzip")
+	c := run(t, req, CompressWith(CompressAtLevel(CompressLevelBestCompression)), func(c http.Context) error {
+		c.SetHeader(utils.HeaderContentType, "text/plain")
+		return c.String("short")
+	})
+	if c.Recorder.Header().Get(utils.HeaderContentEncoding) != "" {
+		t.Error("short body compressed")
+	}
+	c = run(t, req, CompressWith(CompressMinLength(0)), func(c http.Context) error {
+		c.SetHeader(utils.HeaderContentType, "text/plain")
+		return c.String(strings.Repeat("a", 500))
+	})
+	if c.Recorder.Header().Get(utils.HeaderContentEncoding) != "gzip" {
+		t.Error("CompressMinLength(0) didn't compress a short body")
+	}
+}
+
+func TestCSRFOptions(t *testing.T) {
+	store := newTestStorage()
+	cfg := ConfigCSRFDefault
+	CSRFOptions(
+		CSRFKeyLookup("form:_csrf"),
+		CSRFCookieName("token"),
+		CSRFCookieDomain("example.com"),
+		CSRFCookiePath("/app"),
+		CSRFSecureCookie(),
+		CSRFCookieSameSite("Strict"),
+		CSRFExpiration(time.Minute),
+		CSRFRotate(),
+		CSRFContextKey("token"),
+		CSRFStorage(store),
+	)(&cfg)
+	if cfg.KeyLookup != "form:_csrf" || cfg.CookieName != "token" || cfg.CookieDomain != "example.com" ||
+		cfg.CookiePath != "/app" || !cfg.CookieSecure || !cfg.CookieHTTPOnly || cfg.CookieSameSite != "Strict" ||
+		cfg.Expiration != time.Minute || !cfg.Rotate || cfg.ContextKey != "token" || cfg.Storage != store {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	c := run(t, httptest.NewRequest("GET", "/", nil), CSRFWith(CSRFCookieName("token")), ok)
+	cookie := c.Recorder.Header().Get("Set-Cookie")
+	if len(cookie) < 6 || cookie[:6] != "token=" {
+		t.Errorf("Set-Cookie = %q", cookie)
+	}
+}
+
+func TestJWTOptions(t *testing.T) {
+	cfg := ConfigJWTDefault
+	JWTOptions(
+		JWTTokenLookup("cookie:jwt", ""),
+		JWTSigningKey("HS512", jwtSecret),
+		JWTKeySet("https://auth.example.com/jwks", time.Minute),
+		JWTIssuer("issuer"),
+		JWTAudience("api"),
+		JWTLeeway(time.Second),
+		JWTContextClaims("user"),
+	)(&cfg)
+	if cfg.TokenLookup != "cookie:jwt" || cfg.AuthScheme != "" || cfg.SigningMethod != "HS512" ||
+		!reflect.DeepEqual(cfg.SigningKey, jwtSecret) || cfg.JWKSURL != "https://auth.example.com/jwks" ||
+		cfg.JWKSRefresh != time.Minute || cfg.Issuer != "issuer" || cfg.Audience != "api" ||
+		cfg.Leeway != time.Second || cfg.ContextClaims != "user" || cfg.ErrorHandler == nil {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	// Unspecified options keep the defaults: a bearer token signed with HS256
+	token := signJWT(map[string]any{"alg": "HS256"}, JWTClaims{"sub": "john"}, hs256)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(utils.HeaderAuthorization, "Bearer "+token)
+	if c := run(t, req, JWTWith(JWTSigningKey("HS256", jwtSecret)), ok); c.Body() != "ok" {
+		t.Errorf("valid token: %d", c.Recorder.Code)
+	}
+}
+
+func TestCacheOptions(t *testing.T) {
+	store := newTestStorage()
+	cfg := ConfigCacheDefault
+	CacheOptions(
+		CacheExpiration(time.Hour),
+		CacheStatusHeader("X-Cache-Status"),
+		CacheMaxBufferSize(10),
+		CacheStorage(store),
+	)(&cfg)
+	if cfg.Expiration != time.Hour || cfg.CacheHeader != "X-Cache-Status" || cfg.MaxBufferSize != 10 ||
+		cfg.Storage != store || cfg.KeyGenerator == nil {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	handler, _ := CacheWith(CacheStorage(store))
+	run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if got := c.Recorder.Header().Get("X-Cache"); got != cacheHit {
+		t.Errorf("X-Cache = %q, want the default header", got)
+	}
+}
+
+func TestIdempotencyOptions(t *testing.T) {
+	store := newTestStorage()
+	cfg := ConfigIdempotencyDefault
+	IdempotencyOptions(
+		IdempotencyHeader("X-Request-Key"),
+		IdempotencyMethods("POST"),
+		IdempotencyLifetime(time.Hour),
+		IdempotencyMaxBufferSize(10),
+		IdempotencyKeyPrefix("idem:"),
+		IdempotencyStorage(store),
+	)(&cfg)
+	if cfg.Header != "X-Request-Key" || !reflect.DeepEqual(cfg.Methods, []string{"POST"}) || cfg.Lifetime != time.Hour ||
+		cfg.MaxBufferSize != 10 || cfg.KeyPrefix != "idem:" || cfg.Storage != store || cfg.Fingerprint == nil {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	var calls int32
+	handler := IdempotencyWith(IdempotencyLifetime(time.Hour))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/payments", nil)
+		req.Header.Set("Idempotency-Key", "k")
+		run(t, req, handler, paymentHandler(&calls))
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want the default header honoured", calls)
+	}
+}
+
+func TestRequestIDOptions(t *testing.T) {
+	cfg := ConfigRequestIDDefault
+	RequestIDOptions(
+		RequestIDHeader("X-Correlation-ID"),
+		RequestIDGenerator(func() string { return "id" }),
+		RequestIDContextKey("correlation"),
+	)(&cfg)
+	if cfg.Header != "X-Correlation-ID" || cfg.Generator() != "id" || cfg.ContextKey != "correlation" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	c := run(t, httptest.NewRequest("GET", "/", nil), RequestIDWith(RequestIDGenerator(func() string { return "id" })), ok)
+	if got := c.Recorder.Header().Get(utils.HeaderXRequestID); got != "id" {
+		t.Errorf("%s = %q", utils.HeaderXRequestID, got)
+	}
+}
+
+func TestRecoverOptions(t *testing.T) {
+	var out strings.Builder
+	cfg := ConfigRecoverDefault
+	RecoverOptions(
+		RecoverStackTrace(&out),
+		RecoverDebug(),
+		RecoverPassthrough(),
+		RecoverPrincipalKeys("user"),
+		RecoverRequestIDKey("rid"),
+	)(&cfg)
+	if !cfg.EnableStackTrace || cfg.Output != &out || !cfg.Debug || !cfg.Passthrough ||
+		!reflect.DeepEqual(cfg.PrincipalKeys, []string{"user"}) || cfg.RequestIDKey != "rid" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	var reported bool
+	handler := RecoverWith(RecoverStackTrace(&out), RecoverReportFunc(func(PanicEvent) { reported = true }))
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		panic("boom")
+	})
+	if c.Recorder.Code != utils.StatusInternalServerError || !reported || !strings.Contains(out.String(), "panic: boom") {
+		t.Errorf("status = %d, reported = %v, trace = %q", c.Recorder.Code, reported, out.String())
+	}
+}
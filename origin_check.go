@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigOriginCheck defines the config for middleware.
+type ConfigOriginCheck struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// TrustedOrigins are accepted besides the request's own origin, as in
+	// Cors: "https://example.com", "https://*.example.com" or "*"
+	//
+	// Optional. Default: nil
+	TrustedOrigins []string
+
+	// AllowMissing accepts requests sending neither Origin nor Referer,
+	// e.g. from clients that aren't browsers
+	//
+	// Optional. Default: false
+	AllowMissing bool
+
+	// FailureHandler is called for rejected requests
+	//
+	// Optional. Default: responds with 403 Forbidden
+	FailureHandler http.HandlerFunc
+}
+
+// ConfigOriginCheckDefault is the default config
+var ConfigOriginCheckDefault = ConfigOriginCheck{
+	Next: nil,
+	FailureHandler: func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusForbidden)
+		return ErrOriginDenied
+	},
+}
+
+// Helper function to set default values
+func configOriginCheckDefault(config ...ConfigOriginCheck) ConfigOriginCheck {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigOriginCheckDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.FailureHandler == nil {
+		cfg.FailureHandler = ConfigOriginCheckDefault.FailureHandler
+	}
+	return cfg
+}
+
+// OriginCheck creates a new middleware handler rejecting state-changing
+// requests whose Origin, or the origin of their Referer when Origin is
+// missing, is neither the request's own origin nor trusted. It is a
+// defense in depth against CSRF for endpoints that can't use tokens.
+// Safe methods, including preflight requests, pass through.
+func OriginCheck(config ConfigOriginCheck) http.HandlerFunc {
+	// Set default config
+	cfg := configOriginCheckDefault(config)
+
+	trusted := make([]string, len(cfg.TrustedOrigins))
+	for i, o := range cfg.TrustedOrigins {
+		trusted[i] = strings.ToLower(strings.TrimRight(strings.TrimSpace(o), "/"))
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		switch c.Method() {
+		case utils.MethodGet, utils.MethodHead, utils.MethodOptions, utils.MethodTrace:
+			return c.Next()
+		}
+
+		origin := c.Header(utils.HeaderOrigin, "")
+		if origin == "" {
+			if referer := c.Header(utils.HeaderReferer, ""); referer != "" {
+				// An unparsable Referer leaves origin empty and is rejected
+				if u, err := url.Parse(referer); err == nil && u.Scheme != "" && u.Host != "" {
+					origin = u.Scheme + "://" + u.Host
+				} else {
+					return cfg.FailureHandler(c)
+				}
+			} else if cfg.AllowMissing {
+				return c.Next()
+			} else {
+				return cfg.FailureHandler(c)
+			}
+		}
+		origin = strings.ToLower(origin)
+
+		// Browsers send "null" for opaque origins, it never matches
+		if origin != "null" && originTrusted(c, origin, trusted) {
+			return c.Next()
+		}
+		return cfg.FailureHandler(c)
+	}
+}
+
+// originTrusted reports whether origin is the request's own or trusted
+func originTrusted(c http.Context, origin string, trusted []string) bool {
+	if sameOrigin(c, origin) {
+		return true
+	}
+	for _, o := range trusted {
+		if o == "*" || o == origin || matchSubdomain(origin, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether origin is the scheme and host of the request
+func sameOrigin(c http.Context, origin string) bool {
+	scheme := "http"
+	if isHTTPS(c) {
+		scheme = "https"
+	}
+	return origin == scheme+"://"+strings.ToLower(c.Origin().Host)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestOriginCheck(t *testing.T) {
+	handler := OriginCheck(ConfigOriginCheck{TrustedOrigins: []string{"https://admin.example.com/", "https://*.example.org"}})
+	for _, tt := range []struct {
+		name, method, origin, referer string
+		want                          int
+	}{
+		{"own origin", "POST", "http://app.example.com", "", utils.StatusOK},
+		{"trusted", "POST", "https://admin.example.com", "", utils.StatusOK},
+		{"case insensitive", "DELETE", "HTTPS://Admin.Example.com", "", utils.StatusOK},
+		{"subdomain wildcard", "PUT", "https://api.example.org", "", utils.StatusOK},
+		{"wildcard needs a subdomain", "PUT", "https://example.org", "", utils.StatusForbidden},
+		{"other scheme", "POST", "http://admin.example.com", "", utils.StatusForbidden},
+		{"foreign", "POST", "https://evil.example", "", utils.StatusForbidden},
+		{"opaque origin", "POST", "null", "", utils.StatusForbidden},
+		// Origin wins over the Referer
+		{"origin first", "POST", "https://evil.example", "http://app.example.com/form", utils.StatusForbidden},
+		{"referer fallback", "POST", "", "http://app.example.com/form?x=1", utils.StatusOK},
+		{"trusted referer", "PATCH", "", "https://www.example.org/page", utils.StatusOK},
+		{"foreign referer", "POST", "", "https://app.example.com.evil.example/", utils.StatusForbidden},
+		{"relative referer", "POST", "", "/form", utils.StatusForbidden},
+		{"missing", "POST", "", "", utils.StatusForbidden},
+		// Safe methods and preflights pass through
+		{"get", "GET", "https://evil.example", "", utils.StatusOK},
+		{"preflight", "OPTIONS", "https://evil.example", "", utils.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, "http://app.example.com/orders", nil)
+		if tt.origin != "" {
+			req.Header.Set(utils.HeaderOrigin, tt.origin)
+		}
+		if tt.referer != "" {
+			req.Header.Set(utils.HeaderReferer, tt.referer)
+		}
+		c := run(t, req, handler, ok)
+		if c.Recorder.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, c.Recorder.Code, tt.want)
+		}
+		if tt.want == utils.StatusForbidden && !errors.Is(c.Errors()[0], ErrOriginDenied) {
+			t.Errorf("%s: err = %v", tt.name, c.Errors()[0])
+		}
+	}
+}
+
+func TestOriginCheckConfig(t *testing.T) {
+	// The own origin follows the scheme the client used
+	req := httptest.NewRequest("POST", "http://app.example.com/", nil)
+	req.Header.Set(utils.HeaderXForwardedProto, "https")
+	req.Header.Set(utils.HeaderOrigin, "https://app.example.com")
+	if c := run(t, req, OriginCheck(ConfigOriginCheck{}), ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("behind a proxy: status = %d", c.Recorder.Code)
+	}
+
+	handler := OriginCheck(ConfigOriginCheck{
+		AllowMissing: true,
+		FailureHandler: func(c http.Context) error {
+			return c.Status(utils.StatusBadRequest).String("cross-site request")
+		},
+	})
+	if c := run(t, httptest.NewRequest("POST", "/", nil), handler, ok); c.Recorder.Code != utils.StatusOK {
+		t.Errorf("missing allowed: status = %d", c.Recorder.Code)
+	}
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set(utils.HeaderOrigin, "https://evil.example")
+	if c := run(t, req, handler, ok); c.Recorder.Code != utils.StatusBadRequest || c.Body() != "cross-site request" {
+		t.Errorf("custom failure: status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
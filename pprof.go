@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// pprofStdPrefix is the path the stdlib index expects profiles under
+const pprofStdPrefix = "/debug/pprof/"
+
+// ConfigPprof defines the config for middleware.
+type ConfigPprof struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Prefix is the path the profiles are served under
+	//
+	// Optional. Default: "/debug/pprof"
+	Prefix string
+
+	// Guard allows access to the profiles, requests it rejects get a
+	// 403 Forbidden. Profiles leak memory contents and can be used to
+	// load the server, so Pprof panics without one; return true to serve
+	// them to everyone, e.g. behind a private listener.
+	//
+	// Required.
+	Guard func(c http.Context) bool
+}
+
+// ConfigPprofDefault is the default config
+var ConfigPprofDefault = ConfigPprof{
+	Next:   nil,
+	Prefix: "/debug/pprof",
+}
+
+// Helper function to set default values
+func configPprofDefault(config ...ConfigPprof) ConfigPprof {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigPprofDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Prefix == "" {
+		cfg.Prefix = ConfigPprofDefault.Prefix
+	}
+	cfg.Prefix = strings.TrimRight(cfg.Prefix, "/")
+	return cfg
+}
+
+// Pprof creates a new middleware handler serving the net/http/pprof profiles
+func Pprof(config ...ConfigPprof) http.HandlerFunc {
+	// Set default config
+	cfg := configPprofDefault(config...)
+
+	if cfg.Guard == nil {
+		panic("pprof: Guard is required")
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Only respond to requests under the prefix
+		path := c.Origin().URL.Path
+		if path != cfg.Prefix && !strings.HasPrefix(path, cfg.Prefix+"/") {
+			return c.Next()
+		}
+
+		if !cfg.Guard(c) {
+			c.AbortWithStatus(utils.StatusForbidden)
+			return utils.ErrForbidden
+		}
+
+		w, ok := responseWriter(c)
+		if !ok {
+			c.AbortWithStatus(utils.StatusInternalServerError)
+			return utils.ErrInternalServerError
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(path, cfg.Prefix), "/")
+		var handler stdHttp.Handler
+		switch name {
+		case "":
+			// The index resolves the profile names relative to the request
+			if path == cfg.Prefix {
+				c.SetHeader(utils.HeaderLocation, cfg.Prefix+"/")
+				c.AbortWithStatus(utils.StatusMovedPermanently)
+				return nil
+			}
+			handler = stdHttp.HandlerFunc(pprof.Index)
+		case "cmdline":
+			handler = stdHttp.HandlerFunc(pprof.Cmdline)
+		case "profile":
+			handler = stdHttp.HandlerFunc(pprof.Profile)
+		case "symbol":
+			handler = stdHttp.HandlerFunc(pprof.Symbol)
+		case "trace":
+			handler = stdHttp.HandlerFunc(pprof.Trace)
+		default:
+			handler = pprof.Handler(name)
+		}
+
+		// pprof.Index reads the profile name from the stdlib path
+		r := c.Origin().Clone(c.Origin().Context())
+		r.URL.Path = pprofStdPrefix + name
+		handler.ServeHTTP(w, r)
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestPprof(t *testing.T) {
+	handler := Pprof(ConfigPprof{Prefix: "/internal/pprof/", Guard: func(http.Context) bool { return true }})
+
+	c := run(t, httptest.NewRequest("GET", "/internal/pprof/", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusOK || !strings.Contains(c.Body(), "heap") || !strings.Contains(c.Body(), "goroutine") {
+		t.Errorf("index: %d %.100q", c.Recorder.Code, c.Body())
+	}
+
+	// Profiles are gzipped protobufs
+	c = run(t, httptest.NewRequest("GET", "/internal/pprof/heap", nil), handler, ok)
+	if _, err := gzip.NewReader(bytes.NewReader(c.Recorder.Body.Bytes())); c.Recorder.Code != utils.StatusOK || err != nil {
+		t.Errorf("heap: %d %v", c.Recorder.Code, err)
+	}
+
+	c = run(t, httptest.NewRequest("GET", "/internal/pprof/goroutine?debug=1", nil), handler, ok)
+	if !strings.Contains(c.Body(), "goroutine profile") {
+		t.Errorf("goroutine: %.100q", c.Body())
+	}
+
+	c = run(t, httptest.NewRequest("GET", "/internal/pprof", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusMovedPermanently || c.Recorder.Header().Get(utils.HeaderLocation) != "/internal/pprof/" {
+		t.Errorf("prefix without slash: %d %v", c.Recorder.Code, c.Recorder.Header())
+	}
+
+	for _, path := range []string{"/", "/internal/pprofx", "/debug/pprof/"} {
+		if c := run(t, httptest.NewRequest("GET", path, nil), handler, ok); c.Body() != "ok" {
+			t.Errorf("%s didn't pass through", path)
+		}
+	}
+}
+
+func TestPprofGuard(t *testing.T) {
+	handler := Pprof(ConfigPprof{Guard: func(c http.Context) bool {
+		return c.Header("X-Internal-Token", "") == "secret"
+	}})
+
+	c := run(t, httptest.NewRequest("GET", "/debug/pprof/", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusForbidden || strings.Contains(c.Body(), "heap") {
+		t.Errorf("unauthenticated: %d", c.Recorder.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/debug/pprof/cmdline", nil)
+	req.Header.Set("X-Internal-Token", "secret")
+	if c := run(t, req, handler, ok); c.Recorder.Code != utils.StatusOK || c.Body() == "" {
+		t.Errorf("authenticated: %d %q", c.Recorder.Code, c.Body())
+	}
+
+	// The guard only applies under the prefix
+	if c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok); c.Body() != "ok" {
+		t.Errorf("other path blocked")
+	}
+}
+
+func TestPprofRequiresGuard(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Pprof without Guard didn't panic")
+		}
+	}()
+	Pprof()
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var (
+	// ErrRangeMalformed is returned for a Range header that can't be parsed
+	ErrRangeMalformed = errors.New("range: malformed range header")
+	// ErrRangeUnsatisfiable is returned when no range overlaps the resource
+	ErrRangeUnsatisfiable = errors.New("range: unsatisfiable range")
+)
+
+// ByteRange is an inclusive range of bytes of a resource
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// Length returns the number of bytes in the range
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// ContentRange returns the Content-Range header value for a resource of size bytes
+func (r ByteRange) ContentRange(size int64) string {
+	return "bytes " + strconv.FormatInt(r.Start, 10) + "-" + strconv.FormatInt(r.End, 10) + "/" + strconv.FormatInt(size, 10)
+}
+
+// ParseRange parses a "bytes=" Range header for a resource of size bytes.
+// Ranges that start beyond the resource are skipped, the ones that extend
+// beyond it are clamped.
+func ParseRange(header string, size int64) ([]ByteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, ErrRangeMalformed
+	}
+	var ranges []ByteRange
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, ErrRangeMalformed
+		}
+		first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+		var r ByteRange
+		if first == "" {
+			// Suffix range, the last n bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, ErrRangeMalformed
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = ByteRange{Start: size - n, End: size - 1}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, ErrRangeMalformed
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, ErrRangeMalformed
+				}
+				if end > size-1 {
+					end = size - 1
+				}
+			}
+			if start >= size {
+				continue
+			}
+			r = ByteRange{Start: start, End: end}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, ErrRangeUnsatisfiable
+	}
+	return ranges, nil
+}
+
+// RequestRange negotiates the Range header of the request for a resource of
+// size bytes. When a single satisfiable range was requested it sets the
+// 206 Partial Content status and the Content-Range and Content-Length
+// headers and returns the range with ok set, the handler then writes only
+// those bytes. Requests without a usable Range header return ok false and
+// should get the full resource. An unsatisfiable range is answered with
+// 416 and ErrRangeUnsatisfiable is returned.
+func RequestRange(c http.Context, size int64) (r ByteRange, ok bool, err error) {
+	c.SetHeader(utils.HeaderAcceptRanges, "bytes")
+
+	header := c.Header(utils.HeaderRange, "")
+	if header == "" || (c.Method() != utils.MethodGet && c.Method() != utils.MethodHead) {
+		return ByteRange{}, false, nil
+	}
+	ranges, err := ParseRange(header, size)
+	switch {
+	case errors.Is(err, ErrRangeUnsatisfiable):
+		c.SetHeader(utils.HeaderContentRange, "bytes */"+strconv.FormatInt(size, 10))
+		c.AbortWithStatus(utils.StatusRequestedRangeNotSatisfiable)
+		return ByteRange{}, false, err
+	case err != nil, len(ranges) != 1:
+		// Malformed and multipart ranges may be ignored
+		return ByteRange{}, false, nil
+	}
+
+	r = ranges[0]
+	c.SetHeader(utils.HeaderContentRange, r.ContentRange(size))
+	c.SetHeader(utils.HeaderContentLength, strconv.FormatInt(r.Length(), 10))
+	c.Status(utils.StatusPartialContent)
+	return r, true, nil
+}
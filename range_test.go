@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		want   []ByteRange
+		err    error
+	}{
+		{"bytes=0-99", []ByteRange{{0, 99}}, nil},
+		{"bytes=900-", []ByteRange{{900, 999}}, nil},
+		{"bytes=-100", []ByteRange{{900, 999}}, nil},
+		{"bytes=-5000", []ByteRange{{0, 999}}, nil},
+		{"bytes=990-2000", []ByteRange{{990, 999}}, nil},
+		{"bytes=0-0, 10-19", []ByteRange{{0, 0}, {10, 19}}, nil},
+		{"bytes=1000-", nil, ErrRangeUnsatisfiable},
+		{"bytes=-0", nil, ErrRangeUnsatisfiable},
+		{"items=0-1", nil, ErrRangeMalformed},
+		{"bytes=5-1", nil, ErrRangeMalformed},
+		{"bytes=a-1", nil, ErrRangeMalformed},
+		{"bytes=1", nil, ErrRangeMalformed},
+	} {
+		got, err := ParseRange(tt.header, 1000)
+		if !reflect.DeepEqual(got, tt.want) || !errors.Is(err, tt.err) {
+			t.Errorf("%q: %v, %v", tt.header, got, err)
+		}
+	}
+}
+
+// rangeHandler serves a resource honouring the Range header
+func rangeHandler(resource string) http.HandlerFunc {
+	return func(c http.Context) error {
+		r, ok, err := RequestRange(c, int64(len(resource)))
+		switch {
+		case err != nil:
+			return err
+		case ok:
+			return c.String(resource[r.Start : r.End+1])
+		}
+		return c.String(resource)
+	}
+}
+
+func TestRequestRange(t *testing.T) {
+	const resource = "0123456789"
+	for _, tt := range []struct {
+		method, header string
+		status         int
+		body, content  string
+	}{
+		{"GET", "bytes=2-5", utils.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"GET", "bytes=7-", utils.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"GET", "bytes=-3", utils.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"GET", "bytes=10-", utils.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"GET", "", utils.StatusOK, resource, ""},
+		{"GET", "bytes=x", utils.StatusOK, resource, ""},
+		{"GET", "bytes=0-1,3-4", utils.StatusOK, resource, ""},
+		{"POST", "bytes=2-5", utils.StatusOK, resource, ""},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(utils.HeaderRange, tt.header)
+		}
+		c := run(t, req, rangeHandler(resource))
+		h := c.Recorder.Header()
+		if c.Recorder.Code != tt.status || c.Body() != tt.body || h.Get(utils.HeaderContentRange) != tt.content {
+			t.Errorf("%s %q: %d %q %q", tt.method, tt.header, c.Recorder.Code, c.Body(), h.Get(utils.HeaderContentRange))
+		}
+		if h.Get(utils.HeaderAcceptRanges) != "bytes" {
+			t.Errorf("%s %q: Accept-Ranges = %q", tt.method, tt.header, h.Get(utils.HeaderAcceptRanges))
+		}
+		if tt.status == utils.StatusPartialContent && h.Get(utils.HeaderContentLength) != strconv.Itoa(len(tt.body)) {
+			t.Errorf("%q: Content-Length = %q", tt.header, h.Get(utils.HeaderContentLength))
+		}
+	}
+}
@@ -1,15 +1,46 @@
 package middleware
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/sujit-baniya/framework/contracts/http"
 	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/internal/defaults"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// PanicEvent describes a recovered panic for error reporters
+type PanicEvent struct {
+	// Error is the recovered value as an error
+	Error error
+	// Value is the original recovered value
+	Value interface{}
+	// ValueType is the Go type of Value, e.g. "runtime.boundsError"
+	ValueType string
+	// Stack is the trace of the panicking goroutine
+	Stack []byte
+	// Time the panic was recovered at
+	Time time.Time
+
+	Method string
+	Path   string
+	Query  string
+	// IP is the address of the connection
+	IP        string
+	UserAgent string
+	RequestID string
+	// User is the authenticated principal, empty for anonymous requests
+	User string
+	// Status of the error response
+	Status int
+}
+
 // ConfigRecover defines the config for middleware.
 type ConfigRecover struct {
 	// Next defines a function to skip this middleware when returned true.
@@ -24,12 +55,52 @@ type ConfigRecover struct {
 
 	Debug bool
 
+	// Passthrough panics again after the error response was written, so
+	// the server or test harness prints the original panic during
+	// development
+	//
+	// Optional. Default: false
+	Passthrough bool
+
+	// Output receives the stack traces of the default StackTraceHandler,
+	// e.g. a rotating file writer on servers without central logging
+	//
+	// Optional. Default: os.Stderr
+	Output io.Writer
+
 	// StackTraceHandler defines a function to handle stack trace
 	//
-	// Optional. Default: defaultStackTraceHandler
+	// Optional. Default: writes the stack trace to Output
 	StackTraceHandler func(c http.Context, e interface{})
 
 	ErrorHandler func(c http.Context, status int, e interface{}) error
+
+	// Formatter turns the recovered value into the error passed on, e.g. to
+	// extract the message of custom panic types. A nil result falls back
+	// to the default.
+	//
+	// Optional. Default: the value if it's an error, fmt.Errorf("%+v") else
+	Formatter func(r interface{}) error
+
+	// ReportFunc receives every recovered panic, e.g. to send it to an
+	// error tracker, before the error response is written
+	//
+	// Optional. Default: nil
+	ReportFunc func(event PanicEvent)
+
+	// PrincipalKeys are the context keys the authenticated principal is
+	// looked up under for PanicEvent.User when authentication runs before
+	// Recover, authentication after it reports it with SetPrincipal
+	//
+	// Optional. Default: "username", "claims"
+	PrincipalKeys []string
+
+	// RequestIDKey is the context key of the request id when RequestID
+	// runs before Recover. The id of a RequestID middleware after it is
+	// picked up as well, the X-Request-ID header is used without either.
+	//
+	// Optional. Default: "requestid"
+	RequestIDKey string
 }
 
 var defaultStackTraceBufLen = 1 << 20
@@ -38,28 +109,30 @@ var defaultStackTraceBufLen = 1 << 20
 var ConfigRecoverDefault = ConfigRecover{
 	Next:              nil,
 	EnableStackTrace:  false,
+	Output:            os.Stderr,
 	StackTraceHandler: defaultStackTraceHandler,
 	ErrorHandler:      defaultErrorHandler,
+	PrincipalKeys:     []string{"username", "claims"},
+	RequestIDKey:      "requestid",
 }
 
 // Helper function to set default values
 func configRecoverDefault(config ...ConfigRecover) ConfigRecover {
-	// Return default config if nothing provided
-	if len(config) < 1 {
-		return ConfigRecoverDefault
-	}
-
-	// Override default config
-	cfg := config[0]
-
-	if cfg.EnableStackTrace && cfg.StackTraceHandler == nil {
-		cfg.StackTraceHandler = defaultStackTraceHandler
-	}
-	if cfg.ErrorHandler == nil {
-		cfg.ErrorHandler = defaultErrorHandler
-	}
-
-	return cfg
+	return defaults.Config(config, ConfigRecoverDefault, func(cfg *ConfigRecover) {
+		if cfg.Output == nil {
+			cfg.Output = ConfigRecoverDefault.Output
+		}
+		if cfg.EnableStackTrace && cfg.StackTraceHandler == nil {
+			cfg.StackTraceHandler = stackTraceWriter(cfg.Output)
+		}
+		if cfg.ErrorHandler == nil {
+			cfg.ErrorHandler = defaultErrorHandler
+		}
+		if cfg.PrincipalKeys == nil {
+			cfg.PrincipalKeys = ConfigRecoverDefault.PrincipalKeys
+		}
+		defaults.Value(&cfg.RequestIDKey, ConfigRecoverDefault.RequestIDKey)
+	})
 }
 
 func getStackTrace(e interface{}) []byte {
@@ -75,19 +148,42 @@ func getStackTraceWithoutPath(buf []byte, e interface{}) string {
 }
 
 func defaultStackTraceHandler(c http.Context, e interface{}) {
-	buf := getStackTrace(e)
-	stackTrace := getStackTraceWithoutPath(buf, e)
-	_, _ = os.Stderr.WriteString(stackTrace)
+	stackTraceWriter(os.Stderr)(c, e)
 }
 
+// stackTraceWriter returns a StackTraceHandler writing each trace to w in
+// a single call, so concurrent panics don't interleave
+func stackTraceWriter(w io.Writer) func(c http.Context, e interface{}) {
+	return func(c http.Context, e interface{}) {
+		buf := getStackTrace(e)
+		stackTrace := getStackTraceWithoutPath(buf, e)
+		_, _ = io.WriteString(w, stackTrace)
+	}
+}
+
+// defaultErrorHandler writes e with a Content-Length, so keep-alive clients
+// don't have to wait for the connection to close to find the body's end
 func defaultErrorHandler(c http.Context, status int, e interface{}) error {
+	var body []byte
 	switch e := e.(type) {
 	case []byte:
-		return c.Status(status).String(string(e))
+		body = e
 	case string:
-		return c.Status(status).String(e)
+		body = []byte(e)
+	default:
+		var err error
+		if body, err = json.Marshal(e); err != nil {
+			return err
+		}
+		c.SetHeader(utils.HeaderContentType, "application/json")
+	}
+	c.SetHeader(utils.HeaderContentLength, strconv.Itoa(len(body)))
+	c.Status(status)
+	// Responses to HEAD carry the headers of the GET response only
+	if c.Method() == utils.MethodHead {
+		return nil
 	}
-	return c.Status(status).Json(e)
+	return c.String("%s", body)
 }
 
 // Recover creates a new middleware handler
@@ -102,6 +198,9 @@ func Recover(config ...ConfigRecover) http.HandlerFunc {
 			return c.Next()
 		}
 
+		// Let the later middlewares report the principal and request id
+		info := trackRequest(c)
+
 		// Catch panics
 		defer func() error {
 			if r := recover(); r != nil {
@@ -109,16 +208,32 @@ func Recover(config ...ConfigRecover) http.HandlerFunc {
 					cfg.StackTraceHandler(c, r)
 				}
 
-				var ok bool
-				if err, ok = r.(error); !ok {
-					// Set error that will call the global error handler
-					err = fmt.Errorf("%+v", r)
+				if cfg.Formatter != nil {
+					err = cfg.Formatter(r)
+				}
+				if err == nil {
+					var ok bool
+					if err, ok = r.(error); !ok {
+						// Set error that will call the global error handler
+						err = fmt.Errorf("%+v", r)
+					}
 				}
 				if err != nil {
+					setRequestError(c, err)
+					if cfg.ReportFunc != nil {
+						cfg.ReportFunc(newPanicEvent(c, cfg, info, err, r))
+					}
+					var hErr error
 					if cfg.EnableStackTrace && cfg.Debug {
-						return cfg.ErrorHandler(c, utils.StatusInternalServerError, fmt.Sprintf("panic: %v\n%s\n", err, getStackTraceWithoutPath(getStackTrace(r), r)))
+						hErr = cfg.ErrorHandler(c, utils.StatusInternalServerError, fmt.Sprintf("panic: %v\n%s\n", err, getStackTraceWithoutPath(getStackTrace(r), r)))
+					} else {
+						hErr = cfg.ErrorHandler(c, utils.StatusInternalServerError, err.Error())
+					}
+					// The response is written, hand the panic on untouched
+					if cfg.Passthrough {
+						panic(r)
 					}
-					return cfg.ErrorHandler(c, utils.StatusInternalServerError, err.Error())
+					return hErr
 				}
 			}
 			return err
@@ -127,3 +242,34 @@ func Recover(config ...ConfigRecover) http.HandlerFunc {
 		return c.Next()
 	}
 }
+
+// newPanicEvent collects the request metadata of a recovered panic
+func newPanicEvent(c http.Context, cfg ConfigRecover, info *requestInfo, err error, r interface{}) PanicEvent {
+	req := c.Origin()
+	event := PanicEvent{
+		Error:     err,
+		Value:     r,
+		ValueType: fmt.Sprintf("%T", r),
+		Stack:     getStackTrace(r),
+		Time:      time.Now(),
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		IP:        peerAddr(c),
+		UserAgent: req.UserAgent(),
+		User:      info.loadPrincipal(),
+		RequestID: info.loadRequestID(),
+		Status:    utils.StatusInternalServerError,
+	}
+	if event.User == "" {
+		event.User = auditPrincipal(c, cfg.PrincipalKeys)
+	}
+	if event.RequestID == "" {
+		if rid, ok := c.Value(cfg.RequestIDKey).(string); ok {
+			event.RequestID = rid
+		} else {
+			event.RequestID = c.Header(utils.HeaderXRequestID, "")
+		}
+	}
+	return event
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// RecoverOption sets a field of ConfigRecover, see RecoverWith
+type RecoverOption func(cfg *ConfigRecover)
+
+// RecoverWith creates a Recover middleware from options applied to
+// ConfigRecoverDefault in order, a later option overrides an earlier one
+//
+//	middleware.RecoverWith(
+//		middleware.RecoverStackTrace(logFile),
+//		middleware.RecoverReportFunc(reportToTracker),
+//	)
+func RecoverWith(opts ...RecoverOption) http.HandlerFunc {
+	cfg := ConfigRecoverDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Recover(cfg)
+}
+
+// RecoverOptions bundles options into one, e.g. to share a preset between
+// services
+func RecoverOptions(opts ...RecoverOption) RecoverOption {
+	return func(cfg *ConfigRecover) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// RecoverNext sets ConfigRecover.Next
+func RecoverNext(next func(c http.Context) bool) RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.Next = next }
+}
+
+// RecoverStackTrace sets ConfigRecover.EnableStackTrace and Output, with
+// the default StackTraceHandler writing the traces to w
+func RecoverStackTrace(w io.Writer) RecoverOption {
+	return func(cfg *ConfigRecover) {
+		cfg.EnableStackTrace = true
+		cfg.Output = w
+		cfg.StackTraceHandler = stackTraceWriter(w)
+	}
+}
+
+// RecoverStackTraceHandler sets ConfigRecover.EnableStackTrace and
+// StackTraceHandler
+func RecoverStackTraceHandler(handler func(c http.Context, e interface{})) RecoverOption {
+	return func(cfg *ConfigRecover) {
+		cfg.EnableStackTrace = true
+		cfg.StackTraceHandler = handler
+	}
+}
+
+// RecoverDebug sets ConfigRecover.Debug
+func RecoverDebug() RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.Debug = true }
+}
+
+// RecoverPassthrough sets ConfigRecover.Passthrough
+func RecoverPassthrough() RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.Passthrough = true }
+}
+
+// RecoverErrorHandler sets ConfigRecover.ErrorHandler
+func RecoverErrorHandler(handler func(c http.Context, status int, e interface{}) error) RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.ErrorHandler = handler }
+}
+
+// RecoverFormatter sets ConfigRecover.Formatter
+func RecoverFormatter(formatter func(r interface{}) error) RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.Formatter = formatter }
+}
+
+// RecoverReportFunc sets ConfigRecover.ReportFunc
+func RecoverReportFunc(report func(event PanicEvent)) RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.ReportFunc = report }
+}
+
+// RecoverPrincipalKeys sets ConfigRecover.PrincipalKeys
+func RecoverPrincipalKeys(keys ...string) RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.PrincipalKeys = keys }
+}
+
+// RecoverRequestIDKey sets ConfigRecover.RequestIDKey
+func RecoverRequestIDKey(key string) RecoverOption {
+	return func(cfg *ConfigRecover) { cfg.RequestIDKey = key }
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+type orderPanic struct {
+	OrderID int
+}
+
+func TestRecoverErrorResponse(t *testing.T) {
+	c := run(t, httptest.NewRequest("GET", "/", nil), Recover(), func(c http.Context) error {
+		panic("boom")
+	})
+	if c.Recorder.Code != utils.StatusInternalServerError || c.Body() != "boom" {
+		t.Errorf("response = %d %q", c.Recorder.Code, c.Body())
+	}
+	if got := c.Recorder.Header().Get(utils.HeaderContentLength); got != "4" {
+		t.Errorf("Content-Length = %q", got)
+	}
+}
+
+func TestRecoverHeadRequest(t *testing.T) {
+	for _, tt := range []struct {
+		method, body string
+	}{
+		{"GET", "boom"},
+		{"HEAD", ""},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, "/", nil), Recover(), func(c http.Context) error {
+			panic("boom")
+		})
+		h := c.Recorder.Header()
+		if c.Recorder.Code != utils.StatusInternalServerError || c.Body() != tt.body {
+			t.Errorf("%s: response = %d %q", tt.method, c.Recorder.Code, c.Body())
+		}
+		// HEAD gets the headers the GET would have had
+		if h.Get(utils.HeaderContentLength) != "4" {
+			t.Errorf("%s: headers = %v", tt.method, h)
+		}
+	}
+}
+
+func TestRecoverPanicEvent(t *testing.T) {
+	var event PanicEvent
+	req := basicAuthRequest("john", "doe")
+	req.Method = "PUT"
+	req.URL.Path = "/orders/7"
+	req.URL.RawQuery = "force=1"
+	req.RemoteAddr = "192.0.2.9:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	req.Header.Set(utils.HeaderXRequestID, "rid-7")
+	value := orderPanic{OrderID: 7}
+	run(t, req,
+		Recover(ConfigRecover{ReportFunc: func(e PanicEvent) { event = e }}),
+		RequestID(),
+		BasicAuth(ConfigBasicAuth{Users: map[string]string{"john": "doe"}}),
+		func(c http.Context) error { panic(value) },
+	)
+
+	if event.Method != "PUT" || event.Path != "/orders/7" || event.Query != "force=1" {
+		t.Errorf("request metadata = %+v", event)
+	}
+	if event.Value != value || event.ValueType != "middleware.orderPanic" {
+		t.Errorf("value = %#v (%s)", event.Value, event.ValueType)
+	}
+	if event.User != "john" || event.RequestID != "rid-7" {
+		t.Errorf("user = %q, request id = %q", event.User, event.RequestID)
+	}
+	if event.IP != "192.0.2.9" {
+		t.Errorf("ip = %q, want the peer address", event.IP)
+	}
+	if event.Status != utils.StatusInternalServerError || event.Error == nil || len(event.Stack) == 0 || event.Time.IsZero() {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestRecoverPanicEventKeepsErrorValue(t *testing.T) {
+	errPanic := errors.New("typed")
+	var event PanicEvent
+	run(t, httptest.NewRequest("GET", "/", nil),
+		Recover(ConfigRecover{ReportFunc: func(e PanicEvent) { event = e }}),
+		func(c http.Context) error { panic(errPanic) },
+	)
+	if event.Error != errPanic || event.Value != errPanic {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestRecoverDefaultErrorHandlerContentLength(t *testing.T) {
+	for _, tt := range []struct {
+		method      string
+		e           interface{}
+		body        string
+		contentType string
+	}{
+		{"GET", "boom", "boom", ""},
+		{"GET", []byte("bytes"), "bytes", ""},
+		{"GET", map[string]string{"error": "boom"}, `{"error":"boom"}`, "application/json"},
+		{"HEAD", "boom", "", ""},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, "/", nil), func(c http.Context) error {
+			return defaultErrorHandler(c, utils.StatusInternalServerError, tt.e)
+		})
+		h := c.Recorder.Header()
+		wantLength := strconv.Itoa(len(tt.body))
+		if tt.method == "HEAD" {
+			wantLength = "4"
+		}
+		if c.Body() != tt.body || h.Get(utils.HeaderContentLength) != wantLength || h.Get(utils.HeaderContentType) != tt.contentType {
+			t.Errorf("%s %v: %q, headers %v", tt.method, tt.e, c.Body(), h)
+		}
+		if c.Recorder.Code != utils.StatusInternalServerError {
+			t.Errorf("%s %v: status = %d", tt.method, tt.e, c.Recorder.Code)
+		}
+	}
+}
+
+func TestRecoverPassthrough(t *testing.T) {
+	for _, passthrough := range []bool{false, true} {
+		var reports int
+		c := middlewaretest.NewMockContext(httptest.NewRequest("GET", "/", nil),
+			Recover(ConfigRecover{Passthrough: passthrough, ReportFunc: func(PanicEvent) { reports++ }}),
+			func(c http.Context) error { panic("boom") },
+		)
+		repanicked := func() (r interface{}) {
+			defer func() { r = recover() }()
+			_ = c.Run()
+			return nil
+		}()
+		if passthrough && repanicked != "boom" {
+			t.Errorf("Passthrough: recovered %v, want the original panic", repanicked)
+		}
+		if !passthrough && repanicked != nil {
+			t.Errorf("normal mode panicked with %v", repanicked)
+		}
+		// The response is written once either way
+		if c.Recorder.Code != utils.StatusInternalServerError || c.Body() != "boom" || reports != 1 {
+			t.Errorf("Passthrough %v: response = %d %q, reports = %d", passthrough, c.Recorder.Code, c.Body(), reports)
+		}
+	}
+}
+
+func TestRecoverOutput(t *testing.T) {
+	// Catch anything written to stderr
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = stderr
+
+	panicking := func(c http.Context) error {
+		panic(orderPanic{OrderID: 7})
+	}
+	var out bytes.Buffer
+	run(t, httptest.NewRequest("GET", "/", nil), Recover(ConfigRecover{EnableStackTrace: true, Output: &out}), panicking)
+	trace := out.String()
+	if !strings.HasPrefix(trace, "panic: {7}\n") || !strings.Contains(trace, "goroutine ") {
+		t.Errorf("trace = %q", trace)
+	}
+
+	// A custom handler replaces the writer
+	out.Reset()
+	var handled interface{}
+	run(t, httptest.NewRequest("GET", "/", nil), Recover(ConfigRecover{
+		EnableStackTrace:  true,
+		Output:            &out,
+		StackTraceHandler: func(c http.Context, e interface{}) { handled = e },
+	}), panicking)
+	if out.Len() != 0 || handled != (orderPanic{OrderID: 7}) {
+		t.Errorf("custom handler: wrote %q, handled %v", out.String(), handled)
+	}
+
+	// Without EnableStackTrace nothing is written
+	run(t, httptest.NewRequest("GET", "/", nil), Recover(ConfigRecover{Output: &out}), panicking)
+	if out.Len() != 0 {
+		t.Errorf("disabled: wrote %q", out.String())
+	}
+
+	if info, _ := stderr.Stat(); info.Size() != 0 {
+		t.Errorf("wrote %d bytes to stderr", info.Size())
+	}
+}
+
+func TestRecoverFormatter(t *testing.T) {
+	formatter := func(r interface{}) error {
+		if p, ok := r.(orderPanic); ok {
+			return fmt.Errorf("order %d failed", p.OrderID)
+		}
+		return nil
+	}
+	for _, tt := range []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"struct", orderPanic{OrderID: 7}, "order 7 failed"},
+		// A nil result keeps the default formatting
+		{"other", "boom", "boom"},
+		{"error", errors.New("typed"), "typed"},
+	} {
+		var event PanicEvent
+		c := run(t, httptest.NewRequest("GET", "/", nil),
+			Recover(ConfigRecover{Formatter: formatter, ReportFunc: func(e PanicEvent) { event = e }}),
+			func(c http.Context) error { panic(tt.value) },
+		)
+		if c.Body() != tt.want || event.Error == nil || event.Error.Error() != tt.want {
+			t.Errorf("%s: body = %q, error = %v", tt.name, c.Body(), event.Error)
+		}
+	}
+}
@@ -80,6 +80,7 @@ func RequestID(config ...ConfigRequestID) http.HandlerFunc {
 
 		// Add the request ID to locals
 		c.WithValue(cfg.ContextKey, rid)
+		setRequestID(c, rid)
 
 		// Continue stack
 		return c.Next()
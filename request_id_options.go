@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// RequestIDOption sets a field of ConfigRequestID, see RequestIDWith
+type RequestIDOption func(cfg *ConfigRequestID)
+
+// RequestIDWith creates a RequestID middleware from options applied to
+// ConfigRequestIDDefault in order, a later option overrides an earlier one
+//
+//	middleware.RequestIDWith(
+//		middleware.RequestIDHeader("X-Correlation-ID"),
+//	)
+func RequestIDWith(opts ...RequestIDOption) http.HandlerFunc {
+	cfg := ConfigRequestIDDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return RequestID(cfg)
+}
+
+// RequestIDOptions bundles options into one, e.g. to share a preset
+// between services
+func RequestIDOptions(opts ...RequestIDOption) RequestIDOption {
+	return func(cfg *ConfigRequestID) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// RequestIDNext sets ConfigRequestID.Next
+func RequestIDNext(next func(c http.Context) bool) RequestIDOption {
+	return func(cfg *ConfigRequestID) { cfg.Next = next }
+}
+
+// RequestIDHeader sets ConfigRequestID.Header
+func RequestIDHeader(header string) RequestIDOption {
+	return func(cfg *ConfigRequestID) { cfg.Header = header }
+}
+
+// RequestIDGenerator sets ConfigRequestID.Generator
+func RequestIDGenerator(generator func() string) RequestIDOption {
+	return func(cfg *ConfigRequestID) { cfg.Generator = generator }
+}
+
+// RequestIDContextKey sets ConfigRequestID.ContextKey
+func RequestIDContextKey(key string) RequestIDOption {
+	return func(cfg *ConfigRequestID) { cfg.ContextKey = key }
+}
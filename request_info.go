@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// requestInfoKey is the context key of the requestInfo
+const requestInfoKey = "middleware.request_info"
+
+// requestInfo collects what the later handlers learn about a request for
+// the middlewares before them. Every middleware runs with its own context
+// and values stored with WithValue only reach the handlers after it, so a
+// middleware needing them stores a requestInfo before calling Next and the
+// later handlers fill it in.
+type requestInfo struct {
+	mu        sync.Mutex
+	principal string
+	requestID string
+	route     string
+	err       error
+}
+
+// trackRequest returns the requestInfo stored by an earlier middleware, or
+// stores a new one for the later handlers
+func trackRequest(c http.Context) *requestInfo {
+	if info := requestInfoOf(c); info != nil {
+		return info
+	}
+	info := &requestInfo{}
+	c.WithValue(requestInfoKey, info)
+	return info
+}
+
+// requestInfoOf returns the requestInfo stored by an earlier middleware,
+// nil when none did
+func requestInfoOf(c http.Context) *requestInfo {
+	info, _ := c.Value(requestInfoKey).(*requestInfo)
+	return info
+}
+
+// SetPrincipal reports the authenticated principal to the middlewares
+// before the caller, like Audit and Recover. BasicAuth and JWT call it,
+// custom authentication middlewares should too.
+func SetPrincipal(c http.Context, principal string) {
+	if info := requestInfoOf(c); info != nil {
+		info.mu.Lock()
+		info.principal = principal
+		info.mu.Unlock()
+	}
+}
+
+// loadPrincipal returns the principal set with SetPrincipal
+func (i *requestInfo) loadPrincipal() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.principal
+}
+
+// setRequestID reports the request id to the middlewares before the caller
+func setRequestID(c http.Context, rid string) {
+	if info := requestInfoOf(c); info != nil {
+		info.mu.Lock()
+		info.requestID = rid
+		info.mu.Unlock()
+	}
+}
+
+// loadRequestID returns the request id set by the RequestID middleware
+func (i *requestInfo) loadRequestID() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.requestID
+}
+
+// setRequestError reports the error a later handler failed with, like a
+// panic recovered by Recover, to the middlewares before the caller
+func setRequestError(c http.Context, err error) {
+	if info := requestInfoOf(c); info != nil {
+		info.mu.Lock()
+		info.err = err
+		info.mu.Unlock()
+	}
+}
+
+// loadError returns the error set with setRequestError
+func (i *requestInfo) loadError() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.err
+}
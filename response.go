@@ -0,0 +1,298 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	stdHttp "net/http"
+	"reflect"
+	"sync"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// defaultMaxBufferSize is the largest response body buffered by middlewares
+// that need to see the whole body, larger bodies are streamed through
+const defaultMaxBufferSize = 1 << 20
+
+var responseWriterType = reflect.TypeOf((*stdHttp.ResponseWriter)(nil)).Elem()
+
+// engineResponseWriter returns the settable field holding the engine's
+// net/http response writer. The chi engine keeps it in the exported Res
+// field of the context returned by EngineContext.
+func engineResponseWriter(c http.Context) (reflect.Value, bool) {
+	v := reflect.ValueOf(c.EngineContext())
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}, false
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	f := v.FieldByName("Res")
+	if !f.IsValid() || !f.CanSet() || f.Type() != responseWriterType || f.IsNil() {
+		return reflect.Value{}, false
+	}
+	return f, true
+}
+
+// responseWriter returns the engine's net/http response writer
+func responseWriter(c http.Context) (stdHttp.ResponseWriter, bool) {
+	f, ok := engineResponseWriter(c)
+	if !ok {
+		return nil, false
+	}
+	return f.Interface().(stdHttp.ResponseWriter), true
+}
+
+// responseRecorder wraps the engine's response writer so a middleware can
+// observe the status and body written by the rest of the chain.
+type responseRecorder struct {
+	stdHttp.ResponseWriter
+	field       reflect.Value
+	status      int
+	size        int
+	limit       int
+	keep        bool
+	hold        bool
+	overflow    bool
+	wroteHeader bool
+	body        bytes.Buffer
+
+	// beforeHeaders is called once right before the headers are sent
+	beforeHeaders func(status int, header stdHttp.Header)
+}
+
+// recorderPool holds the recorders given back with release, so middlewares
+// on the hot path don't allocate one per request
+var recorderPool = sync.Pool{
+	New: func() any { return new(responseRecorder) },
+}
+
+// recordResponse installs a recorder keeping at most limit bytes of the body
+// (0 means no limit). It returns false when the engine does not expose its
+// response writer.
+func recordResponse(c http.Context, limit int) (*responseRecorder, bool) {
+	f, ok := engineResponseWriter(c)
+	if !ok {
+		return nil, false
+	}
+	rec := recorderPool.Get().(*responseRecorder)
+	rec.ResponseWriter = f.Interface().(stdHttp.ResponseWriter)
+	rec.field = f
+	rec.limit = limit
+	rec.keep = true
+	f.Set(reflect.ValueOf(rec))
+	return rec, true
+}
+
+// release returns the recorder to the pool once next returned and the
+// middleware is done with it, it must not be used afterwards
+func (r *responseRecorder) release() {
+	*r = responseRecorder{}
+	recorderPool.Put(r)
+}
+
+// captureResponse installs a recorder that only counts the status and the
+// bytes written, for middlewares that don't need the body.
+func captureResponse(c http.Context) (*responseRecorder, bool) {
+	rec, ok := recordResponse(c, 0)
+	if ok {
+		rec.keep = false
+	}
+	return rec, ok
+}
+
+// bufferResponse installs a recorder that holds the response back until
+// flush, so the status, headers and body can still be changed once the
+// chain returned. Bodies larger than limit are streamed through unbuffered
+// and the recorder reports an overflow.
+func bufferResponse(c http.Context, limit int) (*responseRecorder, bool) {
+	rec, ok := recordResponse(c, limit)
+	if ok {
+		rec.hold = true
+	}
+	return rec, ok
+}
+
+// onHeaders installs a recorder that calls fn right before the headers are
+// sent, so a middleware can rewrite the headers set by the rest of the chain
+// without buffering the body.
+func onHeaders(c http.Context, fn func(status int, header stdHttp.Header)) (*responseRecorder, bool) {
+	rec, ok := captureResponse(c)
+	if ok {
+		rec.beforeHeaders = fn
+	}
+	return rec, ok
+}
+
+// next continues the stack and restores the original writer afterwards
+func (r *responseRecorder) next(c http.Context) error {
+	defer r.field.Set(reflect.ValueOf(r.ResponseWriter))
+	err := c.Next()
+	if !r.hold && !r.wroteHeader && r.beforeHeaders != nil {
+		// Nothing was written, the engine sends the headers once we return
+		r.wroteHeader = true
+		r.beforeHeaders(r.Status(), r.Header())
+	}
+	return err
+}
+
+// sendHeader passes the status on to the engine's writer once
+func (r *responseRecorder) sendHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	if r.beforeHeaders != nil {
+		r.beforeHeaders(status, r.Header())
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// WriteHeader records the status before passing it on
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	if !r.hold || r.overflow {
+		r.sendHeader(status)
+	}
+}
+
+// Write keeps a copy of the body until the limit is exceeded
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = utils.StatusOK
+	}
+	if r.keep && !r.overflow {
+		if r.limit <= 0 || r.body.Len()+len(b) <= r.limit {
+			r.body.Write(b)
+			if r.hold {
+				r.size += len(b)
+				return len(b), nil
+			}
+		} else if r.hold {
+			// Too large to buffer, send what we held back and stream the rest
+			r.overflow = true
+			r.sendHeader(r.status)
+			if _, err := r.ResponseWriter.Write(r.body.Bytes()); err != nil {
+				return 0, err
+			}
+			r.body.Reset()
+		} else {
+			r.overflow = true
+			r.body.Reset()
+		}
+	}
+	r.sendHeader(r.status)
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// flush sends a held back response to the client
+func (r *responseRecorder) flush() error {
+	if !r.hold || r.overflow {
+		return nil
+	}
+	r.hold = false
+	if r.status == 0 && r.body.Len() == 0 {
+		if !r.wroteHeader && r.beforeHeaders != nil {
+			r.wroteHeader = true
+			r.beforeHeaders(r.Status(), r.Header())
+		}
+		return nil
+	}
+	r.sendHeader(r.Status())
+	_, err := r.ResponseWriter.Write(r.body.Bytes())
+	return err
+}
+
+// Flush sends the headers and the body written so far to the client,
+// unless the response is held back to be changed once the chain returned
+func (r *responseRecorder) Flush() {
+	if r.hold && !r.overflow {
+		return
+	}
+	r.sendHeader(r.Status())
+	if f, ok := r.ResponseWriter.(stdHttp.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, e.g. for WebSockets.
+// Nothing is written to the response afterwards.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(stdHttp.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		r.hold = false
+		r.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the engine's writer for http.ResponseController
+func (r *responseRecorder) Unwrap() stdHttp.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the written status, 200 when nothing was written explicitly
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return utils.StatusOK
+	}
+	return r.status
+}
+
+// Size returns the number of body bytes written by the chain
+func (r *responseRecorder) Size() int {
+	return r.size
+}
+
+// Body returns the recorded body, nil when it exceeded the limit
+func (r *responseRecorder) Body() []byte {
+	if r.overflow {
+		return nil
+	}
+	return r.body.Bytes()
+}
+
+// vary adds the names to the Vary response header, the engine's Vary
+// doesn't write it
+func vary(c http.Context, names ...string) {
+	w, ok := responseWriter(c)
+	for _, name := range names {
+		if ok {
+			addVary(w.Header(), name)
+		} else {
+			c.Vary(name)
+		}
+	}
+}
+
+// writeResponse writes a previously stored response to the client
+func writeResponse(c http.Context, status int, header stdHttp.Header, body []byte) error {
+	w, ok := responseWriter(c)
+	if !ok {
+		for k, v := range header {
+			if len(v) > 0 {
+				c.SetHeader(k, v[0])
+			}
+		}
+		return c.Status(status).String("%s", body)
+	}
+	h := w.Header()
+	for k, v := range header {
+		h[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
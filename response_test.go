@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func TestCaptureResponse(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		size    int
+	}{
+		{"implicit status", func(c http.Context) error {
+			return c.String("hello")
+		}, utils.StatusOK, 5},
+		{"explicit status", func(c http.Context) error {
+			return c.Status(utils.StatusCreated).String("created")
+		}, utils.StatusCreated, 7},
+		{"several writes", func(c http.Context) error {
+			w, _ := responseWriter(c)
+			_, _ = w.Write([]byte("ab"))
+			_, _ = w.Write([]byte("cde"))
+			w.WriteHeader(utils.StatusTeapot)
+			return nil
+		}, utils.StatusOK, 5},
+		{"nothing written", func(c http.Context) error {
+			return nil
+		}, utils.StatusOK, 0},
+	} {
+		var status, size int
+		var captured bool
+		c := run(t, httptest.NewRequest("GET", "/", nil), func(c http.Context) error {
+			rec, ok := captureResponse(c)
+			captured = ok
+			if !ok {
+				return c.Next()
+			}
+			err := rec.next(c)
+			status, size = rec.Status(), rec.Size()
+			return err
+		}, tt.handler)
+		if !captured {
+			t.Fatal("captureResponse failed")
+		}
+		if status != tt.status || size != tt.size {
+			t.Errorf("%s: status, size = %d, %d, want %d, %d", tt.name, status, size, tt.status, tt.size)
+		}
+		if c.Recorder.Code != tt.status || c.Recorder.Body.Len() != tt.size {
+			t.Errorf("%s: response = %d with %d bytes", tt.name, c.Recorder.Code, c.Recorder.Body.Len())
+		}
+	}
+}
+
+func TestCaptureResponseRestoresWriter(t *testing.T) {
+	run(t, httptest.NewRequest("GET", "/", nil), func(c http.Context) error {
+		before, _ := responseWriter(c)
+		rec, _ := captureResponse(c)
+		if w, _ := responseWriter(c); w != rec {
+			t.Error("recorder not installed")
+		}
+		err := rec.next(c)
+		if w, _ := responseWriter(c); w != before {
+			t.Error("writer not restored")
+		}
+		return err
+	}, ok)
+}
+
+func TestBufferResponseMaxBufferSize(t *testing.T) {
+	for _, tt := range []struct {
+		body     string
+		buffered bool
+	}{
+		{"under cap", true},
+		{"over the cap", false},
+	} {
+		var held bool
+		c := run(t, httptest.NewRequest("GET", "/", nil), func(c http.Context) error {
+			rec, ok := bufferResponse(c, 10)
+			if !ok {
+				t.Fatal("bufferResponse failed")
+			}
+			err := rec.next(c)
+			// A buffered body is only sent by flush
+			held = rec.Body() != nil && c.(*middlewaretest.MockContext).Recorder.Body.Len() == 0
+			if err == nil {
+				err = rec.flush()
+			}
+			return err
+		}, func(c http.Context) error {
+			// Write in pieces, the ones past the cap go straight out
+			w, _ := responseWriter(c)
+			for _, part := range strings.SplitAfter(tt.body, " ") {
+				if _, err := w.Write([]byte(part)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if c.Body() != tt.body {
+			t.Errorf("%q: body = %q", tt.body, c.Body())
+		}
+		if held != tt.buffered {
+			t.Errorf("%q: buffered = %v", tt.body, held)
+		}
+	}
+}
+
+func TestResponseRecorderFlush(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		record  func(c http.Context) (*responseRecorder, bool)
+		flushed bool
+	}{
+		{"capture", captureResponse, true},
+		{"buffer", func(c http.Context) (*responseRecorder, bool) { return bufferResponse(c, 0) }, false},
+	} {
+		var flushed bool
+		run(t, httptest.NewRequest("GET", "/", nil), func(c http.Context) error {
+			rec, ok := tt.record(c)
+			if !ok {
+				t.Fatal("recorder not installed")
+			}
+			err := rec.next(c)
+			rec.Flush()
+			flushed = c.(*middlewaretest.MockContext).Recorder.Flushed
+			if err == nil {
+				err = rec.flush()
+			}
+			return err
+		}, func(c http.Context) error {
+			return c.String("event")
+		})
+		if flushed != tt.flushed {
+			t.Errorf("%s: flushed = %v", tt.name, flushed)
+		}
+	}
+}
+
+// hijackWriter is a response writer over a connection
+type hijackWriter struct {
+	stdHttp.ResponseWriter
+	conn net.Conn
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
+
+func TestResponseRecorderHijack(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	w := httptest.NewRecorder()
+	rec := &responseRecorder{ResponseWriter: hijackWriter{w, server}, hold: true}
+	if rec.Unwrap() != (hijackWriter{w, server}) {
+		t.Error("Unwrap doesn't return the engine's writer")
+	}
+	conn, _, err := rec.Hijack()
+	if err != nil || conn != server {
+		t.Fatalf("Hijack = %v, %v", conn, err)
+	}
+	// The connection is the handler's now
+	if err := rec.flush(); err != nil || w.Code != utils.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("flush wrote %d %q, err = %v", w.Code, w.Body.String(), err)
+	}
+
+	rec = &responseRecorder{ResponseWriter: w}
+	if _, _, err := rec.Hijack(); err == nil {
+		t.Error("hijacked a writer without a connection")
+	}
+}
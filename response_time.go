@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+const (
+	// HeaderServerTiming is the header of the Server-Timing specification
+	HeaderServerTiming = "Server-Timing"
+
+	// serverTimingContextKey is the context key of the segments added with
+	// AddServerTiming
+	serverTimingContextKey = "server_timing"
+)
+
+// ConfigResponseTime defines the config for middleware.
+type ConfigResponseTime struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Header carrying the response time
+	//
+	// Optional. Default: "X-Response-Time"
+	Header string
+
+	// MetricName of the Server-Timing entry of the handler
+	//
+	// Optional. Default: "app"
+	MetricName string
+
+	// OmitResponseTime leaves out the X-Response-Time header
+	//
+	// Optional. Default: false
+	OmitResponseTime bool
+
+	// OmitServerTiming leaves out the Server-Timing header
+	//
+	// Optional. Default: false
+	OmitServerTiming bool
+}
+
+// ConfigResponseTimeDefault is the default config
+var ConfigResponseTimeDefault = ConfigResponseTime{
+	Next:       nil,
+	Header:     "X-Response-Time",
+	MetricName: "app",
+}
+
+// Helper function to set default values
+func configResponseTimeDefault(config ...ConfigResponseTime) ConfigResponseTime {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigResponseTimeDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Header == "" {
+		cfg.Header = ConfigResponseTimeDefault.Header
+	}
+	if cfg.MetricName == "" {
+		cfg.MetricName = ConfigResponseTimeDefault.MetricName
+	}
+	return cfg
+}
+
+// ResponseTime creates a new middleware handler measuring the time until
+// the response headers are sent. The headers are set right before they are
+// written, so the duration covers the handler but not the body transfer.
+func ResponseTime(config ...ConfigResponseTime) http.HandlerFunc {
+	// Set default config
+	cfg := configResponseTimeDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		timings := &serverTimings{}
+		if !cfg.OmitServerTiming {
+			c.WithValue(serverTimingContextKey, timings)
+		}
+
+		rec, ok := onHeaders(c, func(_ int, header stdHttp.Header) {
+			elapsed := time.Since(start)
+			if !cfg.OmitResponseTime {
+				header.Set(cfg.Header, formatMilliseconds(elapsed)+"ms")
+			}
+			if !cfg.OmitServerTiming {
+				timings.add(cfg.MetricName, elapsed, "")
+				header.Set(HeaderServerTiming, timings.String())
+			}
+		})
+		if !ok {
+			return c.Next()
+		}
+		return rec.next(c)
+	}
+}
+
+// AddServerTiming adds a segment to the Server-Timing header, e.g. the time
+// spent in the database. Segments are merged into the header written by
+// ResponseTime, without it they are added to the response directly.
+func AddServerTiming(c http.Context, name string, dur time.Duration, desc string) {
+	if timings, ok := c.Value(serverTimingContextKey).(*serverTimings); ok {
+		timings.add(name, dur, desc)
+		return
+	}
+	if w, ok := responseWriter(c); ok {
+		w.Header().Add(HeaderServerTiming, serverTiming{name, dur, desc}.String())
+	}
+}
+
+// serverTiming is one metric of the Server-Timing header
+type serverTiming struct {
+	name string
+	dur  time.Duration
+	desc string
+}
+
+// String formats the metric as name;desc="...";dur=12.4
+func (t serverTiming) String() string {
+	s := t.name
+	if t.desc != "" {
+		s += ";desc=" + strconv.Quote(t.desc)
+	}
+	return s + ";dur=" + formatMilliseconds(t.dur)
+}
+
+// serverTimings collects the metrics of a request, handlers may add them
+// from several goroutines
+type serverTimings struct {
+	mu      sync.Mutex
+	metrics []serverTiming
+}
+
+func (t *serverTimings) add(name string, dur time.Duration, desc string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = append(t.metrics, serverTiming{name, dur, desc})
+}
+
+// String joins the metrics into one header value
+func (t *serverTimings) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := make([]string, len(t.metrics))
+	for i, m := range t.metrics {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatMilliseconds formats a duration in milliseconds with microsecond
+// precision, e.g. 12.437
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// headerMilliseconds parses a value like 12.4ms or app;dur=12.4
+func headerMilliseconds(t *testing.T, value, prefix, suffix string) float64 {
+	t.Helper()
+	if !strings.HasPrefix(value, prefix) || !strings.HasSuffix(value, suffix) {
+		t.Fatalf("value = %q, want %s...%s", value, prefix, suffix)
+	}
+	ms, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(value, prefix), suffix), 64)
+	if err != nil {
+		t.Fatalf("value = %q: %v", value, err)
+	}
+	return ms
+}
+
+func TestResponseTime(t *testing.T) {
+	c := run(t, httptest.NewRequest("GET", "/", nil), ResponseTime(), func(c http.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		if err := c.String("ok"); err != nil {
+			return err
+		}
+		// Time after the headers went out isn't measured
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	// Result holds the headers as they were sent
+	h := c.Recorder.Result().Header
+	ms := headerMilliseconds(t, h.Get("X-Response-Time"), "", "ms")
+	if ms < 20 || ms >= 70 {
+		t.Errorf("X-Response-Time = %vms", ms)
+	}
+	if dur := headerMilliseconds(t, h.Get(HeaderServerTiming), "app;dur=", ""); dur != ms {
+		t.Errorf("Server-Timing = %q, X-Response-Time = %vms", h.Get(HeaderServerTiming), ms)
+	}
+}
+
+func TestResponseTimeServerTimingSegments(t *testing.T) {
+	handler := ResponseTime(ConfigResponseTime{MetricName: "total", OmitResponseTime: true})
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		AddServerTiming(c, "db", 12437*time.Microsecond, "")
+		AddServerTiming(c, "cache", 300*time.Microsecond, `hit "warm"`)
+		return c.String("ok")
+	})
+	h := c.Recorder.Result().Header
+	if h.Get("X-Response-Time") != "" {
+		t.Errorf("X-Response-Time = %q", h.Get("X-Response-Time"))
+	}
+	values := h.Values(HeaderServerTiming)
+	if len(values) != 1 {
+		t.Fatalf("Server-Timing = %q, want one merged header", values)
+	}
+	parts := strings.Split(values[0], ", ")
+	if len(parts) != 3 || parts[0] != "db;dur=12.437" || parts[1] != `cache;desc="hit \"warm\"";dur=0.3` || !strings.HasPrefix(parts[2], "total;dur=") {
+		t.Errorf("Server-Timing = %q", values[0])
+	}
+}
+
+func TestResponseTimeOmitServerTiming(t *testing.T) {
+	handler := ResponseTime(ConfigResponseTime{Header: "X-Elapsed", OmitServerTiming: true})
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		// Without a collector the segment goes straight to the response
+		AddServerTiming(c, "db", time.Millisecond, "")
+		return c.String("ok")
+	})
+	h := c.Recorder.Result().Header
+	if h.Get("X-Elapsed") == "" || h.Get("X-Response-Time") != "" {
+		t.Errorf("headers = %v", h)
+	}
+	if got := h.Values(HeaderServerTiming); len(got) != 1 || got[0] != "db;dur=1" {
+		t.Errorf("Server-Timing = %q", got)
+	}
+}
+
+func TestFormatMilliseconds(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		12437 * time.Microsecond: "12.437",
+		2 * time.Second:          "2000",
+		1500 * time.Nanosecond:   "0.001",
+		0:                        "0",
+	} {
+		if got := formatMilliseconds(d); got != want {
+			t.Errorf("%v: %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// SafeRedirect checks a redirect target taken from user input. Paths on
+// the same site are kept, absolute URLs only when they point to one of
+// allowedHosts, where "*.example.com" allows the subdomains of example.com.
+// The Host header is sent by the client, so absolute URLs to the site
+// itself are only kept when its host is listed too. Rejected targets are
+// replaced with "/" and false is returned.
+func SafeRedirect(c http.Context, target string, allowedHosts []string) (string, bool) {
+	target = strings.TrimSpace(target)
+	// Browsers read a backslash as a slash, /\evil.com is off-site
+	if target == "" || strings.ContainsAny(target, "\\\x00\r\n\t") {
+		return "/", false
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "/", false
+	}
+	if u.Scheme == "" && u.Host == "" && u.User == nil {
+		// A path on our site. Browsers and the handlers that decode it
+		// read ///evil.com and /%2F/evil.com as the host evil.com.
+		if strings.HasPrefix(target, "//") || strings.HasPrefix(u.Path, "//") || strings.Contains(u.Path, "\\") {
+			return "/", false
+		}
+		return target, true
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "/", false
+	}
+	if u.User != nil {
+		return "/", false
+	}
+
+	host := normalizeHost(u.Host)
+	if host != "" && hostAllowed(host, allowedHosts) {
+		return u.String(), true
+	}
+	return "/", false
+}
+
+// normalizeHost lowercases a host and strips its port and trailing dot
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+}
+
+// hostAllowed reports whether host is listed, "*.example.com" matching the
+// subdomains of example.com
+func hostAllowed(host string, allowed []string) bool {
+	for _, a := range allowed {
+		a = normalizeHost(strings.TrimSpace(a))
+		if strings.HasPrefix(a, "*.") {
+			if len(host) > len(a)-1 && strings.HasSuffix(host, a[1:]) {
+				return true
+			}
+		} else if a == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "example.com"
+	c := middlewaretest.NewMockContext(req)
+	allowed := []string{"example.com", "*.trusted.org"}
+
+	for _, tt := range []struct {
+		target, want string
+		ok           bool
+	}{
+		{"/account?tab=1", "/account?tab=1", true},
+		{"https://example.com/a", "https://example.com/a", true},
+		{"https://EXAMPLE.com./a", "https://EXAMPLE.com./a", true},
+		{"https://api.trusted.org/x", "https://api.trusted.org/x", true},
+		{"https://trusted.org/x", "/", false},
+		{"https://evil.com/", "/", false},
+		{"//evil.com/", "/", false},
+		{"///evil.com", "/", false},
+		{"/%2F/evil.com", "/", false},
+		{"/%2f%2fevil.com", "/", false},
+		{"/%5Cevil.com", "/", false},
+		{"/\\evil.com", "/", false},
+		{"javascript:alert(1)", "/", false},
+		{"https://example.com@evil.com/", "/", false},
+		{"", "/", false},
+	} {
+		got, ok := SafeRedirect(c, tt.target, allowed)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("SafeRedirect(%q) = %q, %v, want %q, %v", tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSafeRedirectDoesNotTrustHostHeader(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "evil.com"
+	c := middlewaretest.NewMockContext(req)
+	if got, ok := SafeRedirect(c, "https://evil.com/phish", nil); ok || got != "/" {
+		t.Errorf("SafeRedirect = %q, %v, the request host must not be allowed", got, ok)
+	}
+	if got, ok := SafeRedirect(c, "/home", nil); !ok || got != "/home" {
+		t.Errorf("SafeRedirect = %q, %v, same-origin paths are allowed", got, ok)
+	}
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// SanitizeTransform selects the transformations of Sanitize
+type SanitizeTransform int
+
+// Transformations applied by Sanitize, in this order
+const (
+	// SanitizeControl strips control characters except tab and newlines
+	SanitizeControl SanitizeTransform = 1 << iota
+	// SanitizeStripTags removes anything looking like an HTML tag
+	SanitizeStripTags
+	// SanitizeTrim trims leading and trailing whitespace
+	SanitizeTrim
+	// SanitizeEscapeHTML escapes <, >, &, ' and "
+	SanitizeEscapeHTML
+)
+
+// ConfigSanitize defines the config for middleware.
+type ConfigSanitize struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Transforms applied to every value
+	//
+	// Optional. Default: SanitizeControl | SanitizeTrim
+	Transforms SanitizeTransform
+
+	// Include limits sanitizing to these parameters
+	//
+	// Optional. Default: nil, all parameters
+	Include []string
+
+	// Exclude are parameters left untouched, e.g. passwords
+	//
+	// Optional. Default: nil
+	Exclude []string
+
+	// Custom is applied after the transforms
+	//
+	// Optional. Default: nil
+	Custom func(key, value string) string
+
+	// MaxParamLength rejects requests with a longer value with 400 Bad
+	// Request, 0 disables the check
+	//
+	// Optional. Default: 0
+	MaxParamLength int
+}
+
+// ConfigSanitizeDefault is the default config
+var ConfigSanitizeDefault = ConfigSanitize{
+	Next:       nil,
+	Transforms: SanitizeControl | SanitizeTrim,
+}
+
+// Helper function to set default values
+func configSanitizeDefault(config ...ConfigSanitize) ConfigSanitize {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigSanitizeDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Transforms == 0 {
+		cfg.Transforms = ConfigSanitizeDefault.Transforms
+	}
+	return cfg
+}
+
+// htmlTag matches HTML tags for SanitizeStripTags
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// Sanitize creates a new middleware handler cleaning the query parameters
+// and the fields of urlencoded and multipart forms before the handler reads
+// them. Urlencoded bodies are rewritten, multipart fields are cleaned in
+// the parsed form. JSON bodies are not touched, validate them instead.
+func Sanitize(config ConfigSanitize) http.HandlerFunc {
+	// Set default config
+	cfg := configSanitizeDefault(config)
+
+	include := make(map[string]struct{}, len(cfg.Include))
+	for _, key := range cfg.Include {
+		include[key] = struct{}{}
+	}
+	exclude := make(map[string]struct{}, len(cfg.Exclude))
+	for _, key := range cfg.Exclude {
+		exclude[key] = struct{}{}
+	}
+	// clean sanitizes the values in place, reporting whether any changed
+	// and false for ok when one is longer than MaxParamLength
+	clean := func(values url.Values) (changed, ok bool) {
+		for key, vs := range values {
+			for i, v := range vs {
+				if cfg.MaxParamLength > 0 && len(v) > cfg.MaxParamLength {
+					return changed, false
+				}
+				if _, ok := exclude[key]; ok {
+					continue
+				}
+				if _, ok := include[key]; len(include) > 0 && !ok {
+					continue
+				}
+				if vs[i] = sanitizeValue(key, v, cfg.Transforms, cfg.Custom); vs[i] != v {
+					changed = true
+				}
+			}
+		}
+		return changed, true
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		req := c.Origin()
+		query := req.URL.Query()
+		changed, ok := clean(query)
+		if !ok {
+			c.AbortWithStatus(utils.StatusBadRequest)
+			return ErrInvalidParam
+		}
+		if changed {
+			// Encode sorts the parameters, keep the query as sent otherwise
+			req.URL.RawQuery = query.Encode()
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get(utils.HeaderContentType))
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			if err := req.ParseForm(); err != nil {
+				c.AbortWithStatus(utils.StatusBadRequest)
+				return fmt.Errorf("%w: %v", ErrMalformedBody, err)
+			}
+			if _, ok := clean(req.PostForm); !ok {
+				c.AbortWithStatus(utils.StatusBadRequest)
+				return ErrInvalidParam
+			}
+			// Handlers reading the body see the cleaned fields too
+			body := req.PostForm.Encode()
+			req.Body = io.NopCloser(strings.NewReader(body))
+			req.ContentLength = int64(len(body))
+			req.Header.Set(utils.HeaderContentLength, strconv.Itoa(len(body)))
+			req.Form = nil
+			req.PostForm = nil
+		case "multipart/form-data":
+			if err := req.ParseMultipartForm(32 << 20); err != nil {
+				c.AbortWithStatus(utils.StatusBadRequest)
+				return fmt.Errorf("%w: %v", ErrMalformedBody, err)
+			}
+			if _, ok := clean(req.MultipartForm.Value); !ok {
+				c.AbortWithStatus(utils.StatusBadRequest)
+				return ErrInvalidParam
+			}
+			// Form merges the query and the fields, parse it again from them
+			req.Form = url.Values{}
+			for key, vs := range req.MultipartForm.Value {
+				req.Form[key] = append(req.Form[key], vs...)
+			}
+			for key, vs := range query {
+				req.Form[key] = append(req.Form[key], vs...)
+			}
+			req.PostForm = req.MultipartForm.Value
+		}
+		return c.Next()
+	}
+}
+
+// sanitizeValue applies the transforms and the custom hook to a value
+func sanitizeValue(key, v string, transforms SanitizeTransform, custom func(string, string) string) string {
+	if transforms&SanitizeControl != 0 {
+		v = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+				return -1
+			}
+			return r
+		}, v)
+	}
+	if transforms&SanitizeStripTags != 0 {
+		v = htmlTag.ReplaceAllString(v, "")
+	}
+	if transforms&SanitizeTrim != 0 {
+		v = strings.TrimSpace(v)
+	}
+	if transforms&SanitizeEscapeHTML != 0 {
+		v = html.EscapeString(v)
+	}
+	if custom != nil {
+		v = custom(key, v)
+	}
+	return v
+}
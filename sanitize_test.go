@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// sanitizedQuery runs Sanitize over the query and returns what the handler
+// sees
+func sanitizedQuery(t *testing.T, cfg ConfigSanitize, query url.Values) url.Values {
+	t.Helper()
+	var got url.Values
+	run(t, httptest.NewRequest("GET", "/?"+query.Encode(), nil), Sanitize(cfg), func(c http.Context) error {
+		got = c.Origin().URL.Query()
+		return nil
+	})
+	return got
+}
+
+func TestSanitizeTransforms(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		transforms SanitizeTransform
+		in, want   string
+	}{
+		{"default", 0, "  a\x00b\x1bc\td  ", "abc\td"},
+		{"control", SanitizeControl, " a\x07b ", " ab "},
+		{"trim", SanitizeTrim, " \ta b\n", "a b"},
+		{"strip tags", SanitizeStripTags, `<script>alert(1)</script><b>bold</b>`, "alert(1)bold"},
+		{"escape html", SanitizeEscapeHTML, `<a href="x">'&'</a>`, "&lt;a href=&#34;x&#34;&gt;&#39;&amp;&#39;&lt;/a&gt;"},
+		{"strip then escape", SanitizeStripTags | SanitizeEscapeHTML, `<i>Tom & Jerry</i>`, "Tom &amp; Jerry"},
+	} {
+		got := sanitizedQuery(t, ConfigSanitize{Transforms: tt.transforms}, url.Values{"q": {tt.in}})
+		if got.Get("q") != tt.want {
+			t.Errorf("%s: q = %q, want %q", tt.name, got.Get("q"), tt.want)
+		}
+	}
+}
+
+func TestSanitizeIncludeExclude(t *testing.T) {
+	query := url.Values{"name": {" Jane "}, "password": {" secret "}, "note": {" hi "}}
+
+	got := sanitizedQuery(t, ConfigSanitize{Exclude: []string{"password"}}, query)
+	if got.Get("name") != "Jane" || got.Get("note") != "hi" || got.Get("password") != " secret " {
+		t.Errorf("exclude: %v", got)
+	}
+
+	got = sanitizedQuery(t, ConfigSanitize{Include: []string{"name"}}, query)
+	if got.Get("name") != "Jane" || got.Get("note") != " hi " || got.Get("password") != " secret " {
+		t.Errorf("include: %v", got)
+	}
+}
+
+func TestSanitizeCustom(t *testing.T) {
+	got := sanitizedQuery(t, ConfigSanitize{Custom: func(key, value string) string {
+		if key == "email" {
+			return strings.ToLower(value)
+		}
+		return value
+	}}, url.Values{"email": {" Jane@Example.COM "}, "name": {" Jane "}})
+	// The transforms run first
+	if got.Get("email") != "jane@example.com" || got.Get("name") != "Jane" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestSanitizeMaxParamLength(t *testing.T) {
+	handler := Sanitize(ConfigSanitize{MaxParamLength: 5})
+	c := run(t, httptest.NewRequest("GET", "/?q=12345", nil), handler, ok)
+	if c.Body() != "ok" {
+		t.Errorf("value at the limit rejected")
+	}
+
+	c = run(t, httptest.NewRequest("GET", "/?q=123456", nil), handler, ok)
+	if c.Recorder.Code != utils.StatusBadRequest || c.Body() == "ok" {
+		t.Errorf("status = %d", c.Recorder.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("q=123456"))
+	req.Header.Set(utils.HeaderContentType, "application/x-www-form-urlencoded")
+	c = run(t, req, handler, ok)
+	if c.Recorder.Code != utils.StatusBadRequest {
+		t.Errorf("form status = %d", c.Recorder.Code)
+	}
+}
+
+func TestSanitizeKeepsCleanQuery(t *testing.T) {
+	// Nothing to clean, the parameters keep their order and encoding
+	const raw = "z=1&a=%20x"
+	var got string
+	run(t, httptest.NewRequest("GET", "/?"+raw, nil), Sanitize(ConfigSanitize{Include: []string{"z"}}), func(c http.Context) error {
+		got = c.Origin().URL.RawQuery
+		return nil
+	})
+	if got != raw {
+		t.Errorf("RawQuery = %q, want %q", got, raw)
+	}
+}
+
+func TestSanitizeURLEncodedForm(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("name=+%3Cb%3EJane%3C%2Fb%3E+"))
+	req.Header.Set(utils.HeaderContentType, "application/x-www-form-urlencoded")
+	run(t, req, Sanitize(ConfigSanitize{Transforms: SanitizeStripTags | SanitizeTrim}), func(c http.Context) error {
+		r := c.Origin()
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "name=Jane" || r.ContentLength != int64(len(body)) {
+			t.Errorf("body = %q, length = %d", body, r.ContentLength)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if r.FormValue("name") != "Jane" {
+			t.Errorf("name = %q", r.FormValue("name"))
+		}
+		return nil
+	})
+}
+
+func TestSanitizeMultipartForm(t *testing.T) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	_ = w.WriteField("name", " Jane\x00 ")
+	_ = w.Close()
+	req := httptest.NewRequest("POST", "/?page=%201%20", &buf)
+	req.Header.Set(utils.HeaderContentType, w.FormDataContentType())
+	run(t, req, Sanitize(ConfigSanitize{}), func(c http.Context) error {
+		r := c.Origin()
+		if r.FormValue("name") != "Jane" || r.PostFormValue("name") != "Jane" || r.FormValue("page") != "1" {
+			t.Errorf("form = %v", r.Form)
+		}
+		return nil
+	})
+}
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/sujit-baniya/framework/contracts/http"
 	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/internal/defaults"
 )
 
 // ConfigSecure ...
@@ -17,6 +20,9 @@ type ConfigSecure struct {
 	// ContentTypeNosniff
 	// Optional. Default value "nosniff".
 	ContentTypeNosniff string
+	// NosniffSkip defines a function to leave out ContentTypeNosniff for a request.
+	// Optional. Default: nil
+	NosniffSkip func(http.Context) bool
 	// XFrameOptions
 	// Optional. Default value "SAMEORIGIN".
 	// Possible values: "SAMEORIGIN", "DENY", "ALLOW-FROM uri"
@@ -24,6 +30,13 @@ type ConfigSecure struct {
 	// HSTSMaxAge
 	// Optional. Default value 0.
 	HSTSMaxAge int
+	// HSTSMaxAgeFunc computes the max-age per request and takes precedence
+	// over HSTSMaxAge, e.g. HSTSRamp to raise it gradually
+	// Optional. Default: nil
+	HSTSMaxAgeFunc func() int
+	// HSTSDisable sends "max-age=0" so clients forget a previous policy
+	// Optional. Default value false.
+	HSTSDisable bool
 	// HSTSExcludeSubdomains
 	// Optional. Default value false.
 	HSTSExcludeSubdomains bool
@@ -45,23 +58,27 @@ type ConfigSecure struct {
 	PermissionPolicy string
 }
 
+// ConfigSecureDefault is the default config
+var ConfigSecureDefault = ConfigSecure{
+	XSSProtection:      "1; mode=block",
+	ContentTypeNosniff: "nosniff",
+	XFrameOptions:      "SAMEORIGIN",
+}
+
+// Helper function to set default values
+func configSecureDefault(config ...ConfigSecure) ConfigSecure {
+	return defaults.Config(config, ConfigSecureDefault, func(cfg *ConfigSecure) {
+		defaults.Value(&cfg.XSSProtection, ConfigSecureDefault.XSSProtection)
+		defaults.Value(&cfg.ContentTypeNosniff, ConfigSecureDefault.ContentTypeNosniff)
+		defaults.Value(&cfg.XFrameOptions, ConfigSecureDefault.XFrameOptions)
+	})
+}
+
 // Secure ...
 func Secure(config ...ConfigSecure) http.HandlerFunc {
-	// Init config
-	var cfg ConfigSecure
-	if len(config) > 0 {
-		cfg = config[0]
-	}
-	// Set config default values
-	if cfg.XSSProtection == "" {
-		cfg.XSSProtection = "1; mode=block"
-	}
-	if cfg.ContentTypeNosniff == "" {
-		cfg.ContentTypeNosniff = "nosniff"
-	}
-	if cfg.XFrameOptions == "" {
-		cfg.XFrameOptions = "SAMEORIGIN"
-	}
+	// Set default config
+	cfg := configSecureDefault(config...)
+
 	// Return middleware handler
 	return func(c http.Context) error {
 		// Filter request to skip middleware
@@ -71,15 +88,19 @@ func Secure(config ...ConfigSecure) http.HandlerFunc {
 		if cfg.XSSProtection != "" {
 			c.SetHeader(utils.HeaderXXSSProtection, cfg.XSSProtection)
 		}
-		if cfg.ContentTypeNosniff != "" {
+		if cfg.ContentTypeNosniff != "" && (cfg.NosniffSkip == nil || !cfg.NosniffSkip(c)) {
 			c.SetHeader(utils.HeaderXContentTypeOptions, cfg.ContentTypeNosniff)
 		}
 		if cfg.XFrameOptions != "" {
 			c.SetHeader(utils.HeaderXFrameOptions, cfg.XFrameOptions)
 		}
-		//@TODO - Add Secure() only after Gin TLS is identified
-		// if (c.Secure() || (c.Header(utils.HeaderXForwardedProto, "") == "https")) && cfg.HSTSMaxAge != 0 {
-		if (c.Header(utils.HeaderXForwardedProto, "") == "https") && cfg.HSTSMaxAge != 0 {
+		hstsMaxAge := cfg.HSTSMaxAge
+		if cfg.HSTSMaxAgeFunc != nil {
+			hstsMaxAge = cfg.HSTSMaxAgeFunc()
+		}
+		if isHTTPS(c) && cfg.HSTSDisable {
+			c.SetHeader(utils.HeaderStrictTransportSecurity, "max-age=0")
+		} else if isHTTPS(c) && hstsMaxAge != 0 {
 			subdomains := ""
 			if !cfg.HSTSExcludeSubdomains {
 				subdomains = "; includeSubdomains"
@@ -87,7 +108,7 @@ func Secure(config ...ConfigSecure) http.HandlerFunc {
 			if cfg.HSTSPreloadEnabled {
 				subdomains = fmt.Sprintf("%s; preload", subdomains)
 			}
-			c.SetHeader(utils.HeaderStrictTransportSecurity, fmt.Sprintf("max-age=%d%s", cfg.HSTSMaxAge, subdomains))
+			c.SetHeader(utils.HeaderStrictTransportSecurity, fmt.Sprintf("max-age=%d%s", hstsMaxAge, subdomains))
 		}
 		if cfg.ContentSecurityPolicy != "" {
 			if cfg.CSPReportOnly {
@@ -106,3 +127,53 @@ func Secure(config ...ConfigSecure) http.HandlerFunc {
 		return c.Next()
 	}
 }
+
+// isHTTPS reports whether the client connected over TLS, directly or
+// through a proxy announcing it with X-Forwarded-Proto
+func isHTTPS(c http.Context) bool {
+	if r := c.Origin(); r != nil && r.TLS != nil {
+		return true
+	}
+	return c.Header(utils.HeaderXForwardedProto, "") == "https"
+}
+
+// HSTSStage is a step of an HSTS rollout
+type HSTSStage struct {
+	// After is the time since the start of the rollout the stage begins
+	After time.Duration
+	// MaxAge in seconds sent during the stage
+	MaxAge int
+}
+
+// DefaultHSTSStages raises max-age from 5 minutes to a week, a month and
+// finally two years, each stage lasting long enough to notice breakage
+var DefaultHSTSStages = []HSTSStage{
+	{After: 0, MaxAge: 300},
+	{After: 7 * 24 * time.Hour, MaxAge: 7 * 24 * 3600},
+	{After: 14 * 24 * time.Hour, MaxAge: 30 * 24 * 3600},
+	{After: 44 * 24 * time.Hour, MaxAge: 2 * 365 * 24 * 3600},
+}
+
+// HSTSRamp returns a HSTSMaxAgeFunc sending the max-age of the latest
+// stage begun since start, stages must be sorted by After
+func HSTSRamp(start time.Time, stages ...HSTSStage) func() int {
+	if len(stages) == 0 {
+		stages = DefaultHSTSStages
+	}
+	return func() int {
+		return hstsRampAt(time.Since(start), stages)
+	}
+}
+
+// hstsRampAt returns the max-age of the stage running at elapsed, 0 before
+// the first one
+func hstsRampAt(elapsed time.Duration, stages []HSTSStage) int {
+	maxAge := 0
+	for _, stage := range stages {
+		if elapsed < stage.After {
+			break
+		}
+		maxAge = stage.MaxAge
+	}
+	return maxAge
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// SecureOption sets a field of ConfigSecure, see SecureWith
+type SecureOption func(cfg *ConfigSecure)
+
+// SecureWith creates a Secure middleware from options applied to
+// ConfigSecureDefault in order, a later option overrides an earlier one
+//
+//	middleware.SecureWith(
+//		middleware.SecureHSTS(365*24*time.Hour, true),
+//		middleware.SecureFrameOptions("DENY"),
+//	)
+func SecureWith(opts ...SecureOption) http.HandlerFunc {
+	cfg := ConfigSecureDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Secure(cfg)
+}
+
+// SecureOptions bundles options into one, e.g. to share a preset between
+// services
+func SecureOptions(opts ...SecureOption) SecureOption {
+	return func(cfg *ConfigSecure) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// SecureFilter sets ConfigSecure.Filter
+func SecureFilter(filter func(http.Context) bool) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.Filter = filter }
+}
+
+// SecureXSSProtection sets ConfigSecure.XSSProtection
+func SecureXSSProtection(value string) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.XSSProtection = value }
+}
+
+// SecureContentTypeNosniff sets ConfigSecure.ContentTypeNosniff
+func SecureContentTypeNosniff(value string) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.ContentTypeNosniff = value }
+}
+
+// SecureNosniffSkip sets ConfigSecure.NosniffSkip
+func SecureNosniffSkip(skip func(http.Context) bool) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.NosniffSkip = skip }
+}
+
+// SecureFrameOptions sets ConfigSecure.XFrameOptions
+func SecureFrameOptions(value string) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.XFrameOptions = value }
+}
+
+// SecureHSTS sets ConfigSecure.HSTSMaxAge in whole seconds and
+// HSTSPreloadEnabled. Unlike the field, a max-age of 0 is sent, so clients
+// forget a previous policy.
+func SecureHSTS(maxAge time.Duration, preload bool) SecureOption {
+	return func(cfg *ConfigSecure) {
+		cfg.HSTSMaxAge = int(maxAge / time.Second)
+		cfg.HSTSDisable = cfg.HSTSMaxAge <= 0
+		cfg.HSTSPreloadEnabled = preload
+	}
+}
+
+// SecureHSTSRamp sets ConfigSecure.HSTSMaxAgeFunc to HSTSRamp(start, stages...)
+func SecureHSTSRamp(start time.Time, stages ...HSTSStage) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.HSTSMaxAgeFunc = HSTSRamp(start, stages...) }
+}
+
+// SecureHSTSExcludeSubdomains sets ConfigSecure.HSTSExcludeSubdomains
+func SecureHSTSExcludeSubdomains() SecureOption {
+	return func(cfg *ConfigSecure) { cfg.HSTSExcludeSubdomains = true }
+}
+
+// SecureCSP sets ConfigSecure.ContentSecurityPolicy and CSPReportOnly
+func SecureCSP(policy string, reportOnly bool) SecureOption {
+	return func(cfg *ConfigSecure) {
+		cfg.ContentSecurityPolicy = policy
+		cfg.CSPReportOnly = reportOnly
+	}
+}
+
+// SecureReferrerPolicy sets ConfigSecure.ReferrerPolicy
+func SecureReferrerPolicy(policy string) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.ReferrerPolicy = policy }
+}
+
+// SecurePermissionPolicy sets ConfigSecure.PermissionPolicy
+func SecurePermissionPolicy(policy string) SecureOption {
+	return func(cfg *ConfigSecure) { cfg.PermissionPolicy = policy }
+}
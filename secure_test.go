@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestSecureNosniffSkip(t *testing.T) {
+	skipDownloads := Secure(ConfigSecure{NosniffSkip: func(c http.Context) bool {
+		return strings.HasPrefix(c.Path(), "/downloads/")
+	}})
+	for _, tt := range []struct {
+		handler http.HandlerFunc
+		path    string
+		want    string
+	}{
+		{Secure(), "/downloads/file", "nosniff"},
+		{skipDownloads, "/api", "nosniff"},
+		{skipDownloads, "/downloads/file", ""},
+	} {
+		c := run(t, httptest.NewRequest("GET", tt.path, nil), tt.handler, ok)
+		if got := c.Recorder.Header().Get(utils.HeaderXContentTypeOptions); got != tt.want {
+			t.Errorf("%s: X-Content-Type-Options = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHSTSRamp(t *testing.T) {
+	day := 24 * time.Hour
+	for _, tt := range []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 300},
+		{6 * day, 300},
+		{7 * day, 7 * 24 * 3600},
+		{20 * day, 30 * 24 * 3600},
+		{400 * day, 2 * 365 * 24 * 3600},
+	} {
+		if got := HSTSRamp(time.Now().Add(-tt.elapsed))(); got != tt.want {
+			t.Errorf("after %v: max-age = %d, want %d", tt.elapsed, got, tt.want)
+		}
+	}
+
+	// Before the first stage no header is sent
+	stages := []HSTSStage{{After: time.Hour, MaxAge: 60}, {After: 2 * time.Hour, MaxAge: 3600}}
+	for elapsed, want := range map[time.Duration]int{0: 0, time.Hour: 60, 3 * time.Hour: 3600} {
+		if got := HSTSRamp(time.Now().Add(-elapsed), stages...)(); got != want {
+			t.Errorf("custom stages after %v: max-age = %d, want %d", elapsed, got, want)
+		}
+	}
+}
+
+func TestSecureHSTS(t *testing.T) {
+	https := func() *stdHttp.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderXForwardedProto, "https")
+		return req
+	}
+	for _, tt := range []struct {
+		name string
+		cfg  ConfigSecure
+		req  *stdHttp.Request
+		want string
+	}{
+		{"max-age", ConfigSecure{HSTSMaxAge: 3600}, https(), "max-age=3600; includeSubdomains"},
+		{"preload", ConfigSecure{HSTSMaxAge: 3600, HSTSPreloadEnabled: true, HSTSExcludeSubdomains: true}, https(), "max-age=3600; preload"},
+		{"ramp", ConfigSecure{HSTSMaxAge: 3600, HSTSMaxAgeFunc: HSTSRamp(time.Now())}, https(), "max-age=300; includeSubdomains"},
+		{"ramp not begun", ConfigSecure{HSTSMaxAge: 3600, HSTSMaxAgeFunc: func() int { return 0 }}, https(), ""},
+		// Clearing overrides any max-age
+		{"disable", ConfigSecure{HSTSMaxAge: 3600, HSTSDisable: true}, https(), "max-age=0"},
+		{"disable without max-age", ConfigSecure{HSTSDisable: true}, https(), "max-age=0"},
+		{"plain http", ConfigSecure{HSTSMaxAge: 3600, HSTSDisable: true}, httptest.NewRequest("GET", "/", nil), ""},
+	} {
+		c := run(t, tt.req, Secure(tt.cfg), ok)
+		if got := c.Recorder.Header().Get(utils.HeaderStrictTransportSecurity); got != tt.want {
+			t.Errorf("%s: Strict-Transport-Security = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
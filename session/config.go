@@ -0,0 +1,127 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// Config defines the config for middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Expiration is the time a session lives without being used, or in
+	// total when Absolute is set
+	//
+	// Optional. Default: 30 * time.Minute
+	Expiration time.Duration
+
+	// Absolute makes the expiration count from the creation of the session
+	// instead of sliding with every request
+	//
+	// Optional. Default: false
+	Absolute bool
+
+	// KeyLookup is a string in the form of "<source>:<name>" that is used
+	// to read the session id from the request.
+	// Possible values:
+	// - "cookie:<name>"
+	// - "header:<name>"
+	//
+	// Optional. Default: "cookie:session_id"
+	KeyLookup string
+
+	// CookieDomain of the session cookie
+	//
+	// Optional. Default: ""
+	CookieDomain string
+
+	// CookiePath of the session cookie
+	//
+	// Optional. Default: "/"
+	CookiePath string
+
+	// CookieSecure indicates if the session cookie is only sent over HTTPS
+	//
+	// Optional. Default: false
+	CookieSecure bool
+
+	// CookieHTTPOnly indicates if the session cookie is hidden from scripts
+	//
+	// Optional. Default: false
+	CookieHTTPOnly bool
+
+	// CookieSameSite of the session cookie
+	//
+	// Optional. Default: "Lax"
+	CookieSameSite string
+
+	// KeyGenerator generates the session ids
+	//
+	// Optional. Default: 32 crypto random bytes, base64url encoded
+	KeyGenerator func() string
+
+	// ContextKey is the key to store the *Session in the context
+	//
+	// Optional. Default: "session"
+	ContextKey string
+
+	// Storage is used to store the session data
+	//
+	// Default: an in memory store for this process only
+	Storage storage.Storage
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Expiration:     30 * time.Minute,
+	KeyLookup:      "cookie:session_id",
+	CookiePath:     "/",
+	CookieSameSite: "Lax",
+	KeyGenerator:   generateID,
+	ContextKey:     "session",
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+	if int(cfg.Expiration.Seconds()) <= 0 {
+		cfg.Expiration = ConfigDefault.Expiration
+	}
+	if cfg.KeyLookup == "" {
+		cfg.KeyLookup = ConfigDefault.KeyLookup
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = ConfigDefault.CookiePath
+	}
+	if cfg.CookieSameSite == "" {
+		cfg.CookieSameSite = ConfigDefault.CookieSameSite
+	}
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigDefault.KeyGenerator
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigDefault.ContextKey
+	}
+	return cfg
+}
+
+func generateID() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
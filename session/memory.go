@@ -0,0 +1,53 @@
+package session
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/middleware/limiter/memory"
+)
+
+// MemoryStorage is an in memory implementation of the storage contract,
+// sessions are lost when the process exits
+type MemoryStorage struct {
+	store *memory.Storage
+}
+
+// NewMemoryStorage creates a new in memory storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{store: memory.New()}
+}
+
+// Get value by key
+func (s *MemoryStorage) Get(key string) ([]byte, error) {
+	raw, _ := s.store.Get(key).([]byte)
+	return raw, nil
+}
+
+// Set key with value
+func (s *MemoryStorage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) <= 0 || len(val) <= 0 {
+		return nil
+	}
+	s.store.Set(key, val, exp)
+	return nil
+}
+
+// Delete key by key
+func (s *MemoryStorage) Delete(key string) error {
+	if len(key) <= 0 {
+		return nil
+	}
+	s.store.Delete(key)
+	return nil
+}
+
+// Reset all keys
+func (s *MemoryStorage) Reset() error {
+	s.store.Reset()
+	return nil
+}
+
+// Close the storage
+func (s *MemoryStorage) Close() error {
+	return nil
+}
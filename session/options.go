@@ -0,0 +1,97 @@
+package session
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// Option sets a field of Config, see NewWith
+type Option func(cfg *Config)
+
+// NewWith creates a session middleware from options applied to
+// ConfigDefault in order, a later option overrides an earlier one
+//
+//	session.NewWith(
+//		session.WithExpiration(time.Hour),
+//		session.WithSecureCookie(),
+//	)
+func NewWith(opts ...Option) http.HandlerFunc {
+	cfg := ConfigDefault
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return New(cfg)
+}
+
+// Options bundles options into one, e.g. to share a preset between
+// services
+func Options(opts ...Option) Option {
+	return func(cfg *Config) {
+		for _, opt := range opts {
+			opt(cfg)
+		}
+	}
+}
+
+// WithNext sets Config.Next
+func WithNext(next func(c http.Context) bool) Option {
+	return func(cfg *Config) { cfg.Next = next }
+}
+
+// WithExpiration sets Config.Expiration
+func WithExpiration(d time.Duration) Option {
+	return func(cfg *Config) { cfg.Expiration = d }
+}
+
+// WithAbsoluteExpiration sets Config.Expiration and Config.Absolute
+func WithAbsoluteExpiration(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Expiration = d
+		cfg.Absolute = true
+	}
+}
+
+// WithKeyLookup sets Config.KeyLookup
+func WithKeyLookup(lookup string) Option {
+	return func(cfg *Config) { cfg.KeyLookup = lookup }
+}
+
+// WithCookieDomain sets Config.CookieDomain
+func WithCookieDomain(domain string) Option {
+	return func(cfg *Config) { cfg.CookieDomain = domain }
+}
+
+// WithCookiePath sets Config.CookiePath
+func WithCookiePath(path string) Option {
+	return func(cfg *Config) { cfg.CookiePath = path }
+}
+
+// WithSecureCookie sets Config.CookieSecure and Config.CookieHTTPOnly
+func WithSecureCookie() Option {
+	return func(cfg *Config) {
+		cfg.CookieSecure = true
+		cfg.CookieHTTPOnly = true
+	}
+}
+
+// WithCookieSameSite sets Config.CookieSameSite
+func WithCookieSameSite(sameSite string) Option {
+	return func(cfg *Config) { cfg.CookieSameSite = sameSite }
+}
+
+// WithKeyGenerator sets Config.KeyGenerator
+func WithKeyGenerator(keyGenerator func() string) Option {
+	return func(cfg *Config) { cfg.KeyGenerator = keyGenerator }
+}
+
+// WithContextKey sets Config.ContextKey
+func WithContextKey(key string) Option {
+	return func(cfg *Config) { cfg.ContextKey = key }
+}
+
+// WithStorage sets Config.Storage
+func WithStorage(store storage.Storage) Option {
+	return func(cfg *Config) { cfg.Storage = store }
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+func TestOptions(t *testing.T) {
+	store := NewMemoryStorage()
+	cfg := ConfigDefault
+	Options(
+		WithAbsoluteExpiration(time.Hour),
+		WithKeyLookup("header:X-Session"),
+		WithCookieDomain("example.com"),
+		WithCookiePath("/app"),
+		WithSecureCookie(),
+		WithCookieSameSite("Strict"),
+		WithContextKey("sess"),
+		WithStorage(store),
+		WithKeyGenerator(func() string { return "id" }),
+		WithNext(func(http.Context) bool { return false }),
+	)(&cfg)
+	if cfg.Expiration != time.Hour || !cfg.Absolute || cfg.KeyLookup != "header:X-Session" ||
+		cfg.CookieDomain != "example.com" || cfg.CookiePath != "/app" || !cfg.CookieSecure ||
+		!cfg.CookieHTTPOnly || cfg.CookieSameSite != "Strict" || cfg.ContextKey != "sess" ||
+		cfg.Storage != store || cfg.KeyGenerator() != "id" || cfg.Next == nil {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	cfg = ConfigDefault
+	WithExpiration(time.Minute)(&cfg)
+	if cfg.Expiration != time.Minute || cfg.Absolute {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestNewWith(t *testing.T) {
+	c := middlewaretest.NewMockContext(httptest.NewRequest("GET", "/", nil),
+		NewWith(WithSecureCookie()),
+		func(c http.Context) error { return c.String("ok") })
+	_ = c.Run()
+	cookie := c.Recorder.Header().Get("Set-Cookie")
+	// Unspecified options keep the defaults
+	for _, want := range []string{"session_id=", "Path=/", "HttpOnly", "Secure", "SameSite=Lax"} {
+		if !strings.Contains(cookie, want) {
+			t.Errorf("Set-Cookie = %q, missing %q", cookie, want)
+		}
+	}
+}
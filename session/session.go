@@ -0,0 +1,314 @@
+package session
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/contracts/storage"
+)
+
+// Session holds the data of one client. Values round trip through JSON,
+// so numbers come back as float64 after the first request.
+type Session struct {
+	mu        sync.RWMutex
+	ctx       http.Context
+	cfg       *Config
+	id        string
+	oldIDs    []string
+	created   time.Time
+	data      map[string]any
+	fresh     bool
+	dirty     bool
+	destroyed bool
+}
+
+// record is the serialized form of a session
+type record struct {
+	// Created is in unix nanoseconds, seconds would cut up to a second off
+	// an absolute expiration
+	Created int64          `json:"c"`
+	Data    map[string]any `json:"d"`
+}
+
+// ID returns the session id
+func (s *Session) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+// Fresh reports whether the session was created by this request
+func (s *Session) Fresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fresh
+}
+
+// Get returns the value stored under key
+func (s *Session) Get(key string) any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data[key]
+}
+
+// Keys returns the keys stored in the session
+func (s *Session) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Set stores a value under key
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	s.dirty = true
+}
+
+// Delete removes the value stored under key
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	s.dirty = true
+}
+
+// Destroy removes the session and its data, the client's id is cleared
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string]any)
+	s.destroyed = true
+	s.expireID()
+}
+
+// Regenerate moves the data to a new session id, call it on privilege
+// changes such as a login to prevent session fixation
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.fresh {
+		s.oldIDs = append(s.oldIDs, s.id)
+	}
+	s.id = s.cfg.KeyGenerator()
+	s.destroyed = false
+	s.dirty = true
+	s.sendID(s.expiresAt())
+}
+
+// expiresAt returns when the session expires if saved now
+func (s *Session) expiresAt() time.Time {
+	if s.cfg.Absolute {
+		return s.created.Add(s.cfg.Expiration)
+	}
+	return time.Now().Add(s.cfg.Expiration)
+}
+
+// sendID hands the session id to the client
+func (s *Session) sendID(expires time.Time) {
+	source, name := lookup(s.cfg.KeyLookup)
+	if source == "header" {
+		s.ctx.SetHeader(name, s.id)
+		return
+	}
+	s.ctx.Cookie(&http.Cookie{
+		Name:     name,
+		Value:    s.id,
+		Domain:   s.cfg.CookieDomain,
+		Path:     s.cfg.CookiePath,
+		Expires:  expires,
+		Secure:   s.cfg.CookieSecure,
+		HTTPOnly: s.cfg.CookieHTTPOnly,
+		SameSite: s.cfg.CookieSameSite,
+	})
+}
+
+// expireID tells the client to forget the session id
+func (s *Session) expireID() {
+	source, name := lookup(s.cfg.KeyLookup)
+	if source == "header" {
+		s.ctx.SetHeader(name, "")
+		return
+	}
+	s.ctx.Cookie(&http.Cookie{
+		Name:     name,
+		Value:    "",
+		Domain:   s.cfg.CookieDomain,
+		Path:     s.cfg.CookiePath,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		Secure:   s.cfg.CookieSecure,
+		HTTPOnly: s.cfg.CookieHTTPOnly,
+		SameSite: s.cfg.CookieSameSite,
+	})
+}
+
+// save writes the session to the storage
+func (s *Session) save(store storage.Storage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.oldIDs {
+		_ = store.Delete(id)
+	}
+	s.oldIDs = nil
+	if s.destroyed {
+		return store.Delete(s.id)
+	}
+	// Sliding sessions are touched on every request
+	if !s.dirty && (s.cfg.Absolute || s.fresh) {
+		return nil
+	}
+	ttl := time.Until(s.expiresAt())
+	if ttl <= 0 {
+		return store.Delete(s.id)
+	}
+	raw, err := json.Marshal(record{Created: s.created.UnixNano(), Data: s.data})
+	if err != nil {
+		return err
+	}
+	return store.Set(s.id, raw, ttl)
+}
+
+// New creates a new middleware handler
+func New(config ...Config) http.HandlerFunc {
+	// Set default config
+	cfg := configDefault(config...)
+
+	store := cfg.Storage
+	if store == nil {
+		store = NewMemoryStorage()
+	}
+	source, name := lookup(cfg.KeyLookup)
+	if source != "cookie" && source != "header" {
+		panic("session: KeyLookup must be cookie:<name> or header:<name>")
+	}
+	locks := &locker{locks: make(map[string]*idLock)}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		var id string
+		if source == "header" {
+			id = c.Header(name, "")
+		} else {
+			id = c.Cookies(name)
+		}
+
+		sess := &Session{ctx: c, cfg: &cfg, id: id, data: make(map[string]any)}
+
+		// Requests of the same session are serialized so they don't
+		// overwrite each other's changes
+		if id != "" {
+			locks.lock(id)
+			defer locks.unlock(id)
+		}
+		if !sess.load(store) {
+			sess.id = cfg.KeyGenerator()
+			sess.created = time.Now()
+			sess.fresh = true
+			sess.data = make(map[string]any)
+		}
+
+		// The id has to be sent before the handler writes the response
+		if sess.fresh || !cfg.Absolute {
+			sess.sendID(sess.expiresAt())
+		}
+
+		c.WithValue(cfg.ContextKey, sess)
+		// FromContext finds it whatever the ContextKey
+		c.WithValue(sessionKey, sess)
+		err := c.Next()
+		if sErr := sess.save(store); err == nil {
+			err = sErr
+		}
+		return err
+	}
+}
+
+// load reads the session from the storage, false when it doesn't exist
+func (s *Session) load(store storage.Storage) bool {
+	if s.id == "" {
+		return false
+	}
+	raw, err := store.Get(s.id)
+	if err != nil || raw == nil {
+		return false
+	}
+	var rec record
+	if err = json.Unmarshal(raw, &rec); err != nil {
+		return false
+	}
+	s.created = time.Unix(0, rec.Created)
+	if s.cfg.Absolute && time.Now().After(s.expiresAt()) {
+		_ = store.Delete(s.id)
+		return false
+	}
+	if rec.Data != nil {
+		s.data = rec.Data
+	}
+	return true
+}
+
+// sessionKey is the context key FromContext reads, the session is stored
+// under it in addition to ContextKey
+const sessionKey = "session.session"
+
+// FromContext returns the session stored by New, whatever its ContextKey
+func FromContext(c http.Context) *Session {
+	sess, _ := c.Value(sessionKey).(*Session)
+	return sess
+}
+
+func lookup(keyLookup string) (source, name string) {
+	parts := strings.SplitN(keyLookup, ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// locker hands out one mutex per session id and forgets it once unused
+type locker struct {
+	mu    sync.Mutex
+	locks map[string]*idLock
+}
+
+type idLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (l *locker) lock(id string) {
+	l.mu.Lock()
+	lk, ok := l.locks[id]
+	if !ok {
+		lk = &idLock{}
+		l.locks[id] = lk
+	}
+	lk.refs++
+	l.mu.Unlock()
+	lk.Lock()
+}
+
+func (l *locker) unlock(id string) {
+	l.mu.Lock()
+	lk := l.locks[id]
+	lk.refs--
+	if lk.refs == 0 {
+		delete(l.locks, id)
+	}
+	l.mu.Unlock()
+	lk.Unlock()
+}
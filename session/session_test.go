@@ -0,0 +1,175 @@
+package session
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/middlewaretest"
+)
+
+// request runs the session middleware and fn for a request carrying the
+// session id, it returns the session id sent back, "" when it was cleared
+func request(t *testing.T, handler http.HandlerFunc, id string, fn func(s *Session)) (string, *middlewaretest.MockContext) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	if id != "" {
+		req.AddCookie(&stdHttp.Cookie{Name: "session_id", Value: id})
+	}
+	c := middlewaretest.NewMockContext(req, handler, func(c http.Context) error {
+		if fn != nil {
+			fn(FromContext(c))
+		}
+		return c.String("ok")
+	})
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	sent := id
+	for _, cookie := range c.Recorder.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			sent = cookie.Value
+		}
+	}
+	return sent, c
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	handler := New(Config{Storage: NewMemoryStorage()})
+
+	var fresh bool
+	id, _ := request(t, handler, "", func(s *Session) {
+		fresh = s.Fresh()
+		s.Set("user", "john")
+		s.Set("n", 1)
+	})
+	if id == "" || !fresh {
+		t.Fatalf("id = %q, fresh = %v", id, fresh)
+	}
+	if len(id) != 43 {
+		t.Errorf("id %q isn't 32 base64url encoded bytes", id)
+	}
+
+	var user, n any
+	request(t, handler, id, func(s *Session) {
+		fresh = s.Fresh()
+		user, n = s.Get("user"), s.Get("n")
+		s.Delete("n")
+	})
+	if fresh || user != "john" || n != float64(1) {
+		t.Errorf("reloaded fresh = %v, user = %v, n = %v", fresh, user, n)
+	}
+	request(t, handler, id, func(s *Session) { n = s.Get("n") })
+	if n != nil {
+		t.Errorf("deleted value = %v", n)
+	}
+
+	// Regenerate moves the data to a new id and drops the old one
+	newID, _ := request(t, handler, id, func(s *Session) { s.Regenerate() })
+	if newID == id || newID == "" {
+		t.Fatalf("regenerated id = %q", newID)
+	}
+	request(t, handler, newID, func(s *Session) { user = s.Get("user") })
+	if user != "john" {
+		t.Errorf("user after Regenerate = %v", user)
+	}
+	request(t, handler, id, func(s *Session) { fresh, user = s.Fresh(), s.Get("user") })
+	if !fresh || user != nil {
+		t.Errorf("old id still loads: fresh = %v, user = %v", fresh, user)
+	}
+
+	// Destroy clears the cookie and the data
+	cleared, _ := request(t, handler, newID, func(s *Session) { s.Destroy() })
+	if cleared != "" {
+		t.Errorf("cookie after Destroy = %q", cleared)
+	}
+	request(t, handler, newID, func(s *Session) { fresh = s.Fresh() })
+	if !fresh {
+		t.Error("destroyed session still loads")
+	}
+}
+
+func TestSessionAbsoluteExpiration(t *testing.T) {
+	handler := New(Config{Expiration: 2 * time.Second, Absolute: true})
+	id, _ := request(t, handler, "", func(s *Session) { s.Set("a", "b") })
+
+	var value any
+	if _, c := request(t, handler, id, func(s *Session) { value = s.Get("a") }); value != "b" {
+		t.Fatalf("value = %v", value)
+	} else if len(c.Recorder.Result().Cookies()) != 0 {
+		t.Error("absolute session resent its cookie")
+	}
+
+	time.Sleep(2100 * time.Millisecond)
+	var fresh bool
+	request(t, handler, id, func(s *Session) { fresh = s.Fresh() })
+	if !fresh {
+		t.Error("session outlived its absolute expiration")
+	}
+}
+
+func TestSessionHeaderLookup(t *testing.T) {
+	handler := New(Config{KeyLookup: "header:X-Session"})
+	c := middlewaretest.NewMockContext(httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		FromContext(c).Set("a", "b")
+		return c.String("ok")
+	})
+	_ = c.Run()
+	id := c.Recorder.Header().Get("X-Session")
+	if id == "" {
+		t.Fatal("no session id header")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Session", id)
+	var value any
+	c = middlewaretest.NewMockContext(req, handler, func(c http.Context) error {
+		value = FromContext(c).Get("a")
+		return nil
+	})
+	_ = c.Run()
+	if value != "b" {
+		t.Errorf("value = %v", value)
+	}
+}
+
+func TestFromContextCustomKey(t *testing.T) {
+	handler := New(Config{ContextKey: "sess"})
+	var fromKey, fromContext *Session
+	c := middlewaretest.NewMockContext(httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		fromKey, _ = c.Value("sess").(*Session)
+		fromContext = FromContext(c)
+		return nil
+	})
+	_ = c.Run()
+	if fromKey == nil || fromContext != fromKey {
+		t.Errorf("FromContext = %p, ContextKey holds %p", fromContext, fromKey)
+	}
+}
+
+func TestSessionConcurrentRequests(t *testing.T) {
+	handler := New(Config{})
+	id, _ := request(t, handler, "", func(s *Session) { s.Set("n", 0) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request(t, handler, id, func(s *Session) {
+				n, _ := s.Get("n").(float64)
+				s.Set("n", n+1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	var n any
+	request(t, handler, id, func(s *Session) { n = s.Get("n") })
+	if n != float64(20) {
+		t.Errorf("n = %v, concurrent requests lost updates", n)
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// Skip wraps h so it is left out and the stack continues when pred returns
+// true, for handlers that don't offer a Next option of their own
+func Skip(h http.HandlerFunc, pred func(c http.Context) bool) http.HandlerFunc {
+	return func(c http.Context) error {
+		if pred(c) {
+			return c.Next()
+		}
+		return h(c)
+	}
+}
+
+// Unless wraps h so it only runs when pred returns true
+func Unless(h http.HandlerFunc, pred func(c http.Context) bool) http.HandlerFunc {
+	return Skip(h, func(c http.Context) bool {
+		return !pred(c)
+	})
+}
+
+// OnPath returns a predicate matching requests whose path starts with one
+// of the prefixes
+func OnPath(prefixes ...string) func(c http.Context) bool {
+	return func(c http.Context) bool {
+		path := c.Origin().URL.Path
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OnMethod returns a predicate matching requests using one of the methods
+func OnMethod(methods ...string) func(c http.Context) bool {
+	// Don't change the caller's slice
+	upper := make([]string, len(methods))
+	for i, method := range methods {
+		upper[i] = strings.ToUpper(method)
+	}
+	return func(c http.Context) bool {
+		return containsMethod(upper, c.Method())
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+func TestSkipAndUnless(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	block := func(c http.Context) error { return errBlocked }
+	// block runs on /admin except for GET requests
+	handler := Skip(Unless(block, OnPath("/admin")), OnMethod("get", "head"))
+
+	for _, tt := range []struct {
+		method, path string
+		blocked      bool
+	}{
+		{"POST", "/admin/users", true},
+		{"DELETE", "/admin", true},
+		{"GET", "/admin/users", false},
+		{"HEAD", "/admin/users", false},
+		{"POST", "/public", false},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, tt.path, nil), handler, ok)
+		if blocked := c.Body() != "ok"; blocked != tt.blocked {
+			t.Errorf("%s %s: blocked = %v", tt.method, tt.path, blocked)
+		}
+		// The error of the wrapped handler is returned as is
+		if tt.blocked && c.Errors()[0] != errBlocked {
+			t.Errorf("%s %s: err = %v", tt.method, tt.path, c.Errors()[0])
+		}
+	}
+}
+
+func TestSkipComposes(t *testing.T) {
+	var ran bool
+	h := func(c http.Context) error {
+		ran = true
+		return c.Next()
+	}
+	handler := Skip(Skip(h, OnPath("/a")), OnPath("/b"))
+	for path, want := range map[string]bool{"/a": false, "/b": false, "/c": true} {
+		ran = false
+		c := run(t, httptest.NewRequest("GET", path, nil), handler, ok)
+		if ran != want || c.Body() != "ok" {
+			t.Errorf("%s: ran = %v, body = %q", path, ran, c.Body())
+		}
+	}
+}
+
+func TestOnMethodKeepsArguments(t *testing.T) {
+	methods := []string{"get"}
+	OnMethod(methods...)
+	if methods[0] != "get" {
+		t.Errorf("OnMethod changed its argument to %q", methods[0])
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// SlashMode is how StripSlashes brings a path into its canonical form
+type SlashMode int
+
+const (
+	// SlashRedirect redirects the client to the canonical path
+	SlashRedirect SlashMode = iota
+	// SlashRewrite rewrites the path before the router sees it
+	SlashRewrite
+)
+
+// ConfigSlashes defines the config for middleware.
+type ConfigSlashes struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Mode is how the canonical path is reached
+	//
+	// Optional. Default: SlashRedirect
+	Mode SlashMode
+
+	// AddSlash makes paths ending in a slash canonical instead of paths
+	// without one
+	//
+	// Optional. Default: false
+	AddSlash bool
+
+	// RedirectUnsafe also redirects methods other than GET and HEAD, with
+	// a 308 so the client repeats the method and body. Otherwise those
+	// requests are left alone in redirect mode.
+	//
+	// Optional. Default: false
+	RedirectUnsafe bool
+}
+
+// ConfigSlashesDefault is the default config
+var ConfigSlashesDefault = ConfigSlashes{
+	Next: nil,
+	Mode: SlashRedirect,
+}
+
+// Helper function to set default values
+func configSlashesDefault(config ...ConfigSlashes) ConfigSlashes {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigSlashesDefault
+	}
+
+	// Override default config
+	return config[0]
+}
+
+// StripSlashes creates a new middleware handler
+func StripSlashes(config ...ConfigSlashes) http.HandlerFunc {
+	// Set default config
+	cfg := configSlashesDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		r := c.Origin()
+		path := r.URL.Path
+
+		// The root is canonical either way
+		if path == "/" || path == "" {
+			return c.Next()
+		}
+		var canonical string
+		if cfg.AddSlash {
+			if strings.HasSuffix(path, "/") {
+				return c.Next()
+			}
+			canonical = path + "/"
+		} else {
+			if !strings.HasSuffix(path, "/") {
+				return c.Next()
+			}
+			canonical = strings.TrimRight(path, "/")
+			if canonical == "" {
+				canonical = "/"
+			}
+		}
+
+		if cfg.Mode == SlashRewrite {
+			r.URL.Path = canonical
+			r.URL.RawPath = ""
+			r.RequestURI = r.URL.RequestURI()
+			return c.Next()
+		}
+
+		status := utils.StatusMovedPermanently
+		if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+			if !cfg.RedirectUnsafe {
+				return c.Next()
+			}
+			status = utils.StatusPermanentRedirect
+		}
+
+		// A leading // would make the location protocol relative
+		location := (&url.URL{Path: "/" + strings.TrimLeft(canonical, "/")}).EscapedPath()
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		c.SetHeader(utils.HeaderLocation, location)
+		c.AbortWithStatus(status)
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// pathHandler reports the request URI the rest of the stack sees
+func pathHandler(c http.Context) error {
+	return c.String(c.Origin().RequestURI)
+}
+
+func TestStripSlashesRedirect(t *testing.T) {
+	handler := StripSlashes()
+	for _, tt := range []struct {
+		method, target string
+		status         int
+		location       string
+	}{
+		{"GET", "/users/", utils.StatusMovedPermanently, "/users"},
+		{"HEAD", "/users/?page=2&sort=name", utils.StatusMovedPermanently, "/users?page=2&sort=name"},
+		{"GET", "/users///", utils.StatusMovedPermanently, "/users"},
+		{"GET", "/a%20b/", utils.StatusMovedPermanently, "/a%20b"},
+		// Collapsing to // would send the client to another host
+		{"GET", "//evil.com/", utils.StatusMovedPermanently, "/evil.com"},
+		{"GET", "/users", utils.StatusOK, ""},
+		{"GET", "/", utils.StatusOK, ""},
+		// Requests with a body aren't redirected without RedirectUnsafe
+		{"POST", "/users/", utils.StatusOK, ""},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, tt.target, nil), handler, pathHandler)
+		if c.Recorder.Code != tt.status || c.Recorder.Header().Get(utils.HeaderLocation) != tt.location {
+			t.Errorf("%s %s: status = %d, Location = %q", tt.method, tt.target, c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderLocation))
+		}
+		if tt.status == utils.StatusOK && c.Body() != tt.target {
+			t.Errorf("%s %s: handler saw %q", tt.method, tt.target, c.Body())
+		}
+	}
+}
+
+func TestStripSlashesRedirectUnsafe(t *testing.T) {
+	handler := StripSlashes(ConfigSlashes{RedirectUnsafe: true})
+	for method, want := range map[string]int{
+		"GET":    utils.StatusMovedPermanently,
+		"POST":   utils.StatusPermanentRedirect,
+		"DELETE": utils.StatusPermanentRedirect,
+	} {
+		c := run(t, httptest.NewRequest(method, "/users/?x=1", nil), handler, pathHandler)
+		if c.Recorder.Code != want || c.Recorder.Header().Get(utils.HeaderLocation) != "/users?x=1" {
+			t.Errorf("%s: status = %d, Location = %q", method, c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderLocation))
+		}
+	}
+}
+
+func TestStripSlashesRewrite(t *testing.T) {
+	handler := StripSlashes(ConfigSlashes{Mode: SlashRewrite})
+	for target, want := range map[string]string{
+		"/users/?page=2": "/users?page=2",
+		"/users//":       "/users",
+		"/users":         "/users",
+		"/":              "/",
+	} {
+		for _, method := range []string{"GET", "POST"} {
+			c := run(t, httptest.NewRequest(method, target, nil), handler, pathHandler)
+			if c.Recorder.Code != utils.StatusOK || c.Body() != want {
+				t.Errorf("%s %s: status = %d, handler saw %q", method, target, c.Recorder.Code, c.Body())
+			}
+		}
+	}
+}
+
+func TestStripSlashesAddSlash(t *testing.T) {
+	c := run(t, httptest.NewRequest("GET", "/users?page=2", nil), StripSlashes(ConfigSlashes{AddSlash: true}), pathHandler)
+	if c.Recorder.Code != utils.StatusMovedPermanently || c.Recorder.Header().Get(utils.HeaderLocation) != "/users/?page=2" {
+		t.Errorf("redirect: status = %d, Location = %q", c.Recorder.Code, c.Recorder.Header().Get(utils.HeaderLocation))
+	}
+	c = run(t, httptest.NewRequest("GET", "/users/", nil), StripSlashes(ConfigSlashes{AddSlash: true}), pathHandler)
+	if c.Body() != "/users/" {
+		t.Errorf("canonical path: handler saw %q", c.Body())
+	}
+
+	c = run(t, httptest.NewRequest("GET", "/users", nil), StripSlashes(ConfigSlashes{AddSlash: true, Mode: SlashRewrite}), pathHandler)
+	if c.Body() != "/users/" {
+		t.Errorf("rewrite: handler saw %q", c.Body())
+	}
+	c = run(t, httptest.NewRequest("GET", "/", nil), StripSlashes(ConfigSlashes{AddSlash: true}), pathHandler)
+	if c.Body() != "/" {
+		t.Errorf("root: handler saw %q", c.Body())
+	}
+}
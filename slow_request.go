@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/phuslu/log"
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// ConfigSlow defines the config for middleware.
+type ConfigSlow struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Threshold is the latency budget of a request
+	//
+	// Optional. Default: 1 * time.Second
+	Threshold time.Duration
+
+	// OnSlow is called after a request that took longer than Threshold
+	//
+	// Optional. Default: logs a warning
+	OnSlow func(c http.Context, elapsed time.Duration)
+
+	// WarnAfter calls OnWarn while the request is still running once it
+	// takes this long, 0 disables the warning
+	//
+	// Optional. Default: 0
+	WarnAfter time.Duration
+
+	// OnWarn is called from another goroutine with the stacks of all
+	// goroutines, to find out where a stuck request hangs. It gets a copy
+	// of the request details rather than the context, which belongs to the
+	// goroutine serving the request.
+	//
+	// Optional. Default: logs a warning with the stacks
+	OnWarn func(w SlowWarning)
+}
+
+// SlowWarning describes a request still running after WarnAfter
+type SlowWarning struct {
+	Method  string
+	Path    string
+	Elapsed time.Duration
+	// Stack holds the stacks of all goroutines
+	Stack []byte
+}
+
+// ConfigSlowDefault is the default config
+var ConfigSlowDefault = ConfigSlow{
+	Next:      nil,
+	Threshold: 1 * time.Second,
+	OnSlow: func(c http.Context, elapsed time.Duration) {
+		log.Warn().
+			Str("method", c.Method()).
+			Str("path", c.Path()).
+			Str("latency", elapsed.String()).
+			Msg("slow request")
+	},
+	OnWarn: func(w SlowWarning) {
+		log.Warn().
+			Str("method", w.Method).
+			Str("path", w.Path).
+			Str("latency", w.Elapsed.String()).
+			Bytes("stack", w.Stack).
+			Msg("request still running")
+	},
+}
+
+// Helper function to set default values
+func configSlowDefault(config ...ConfigSlow) ConfigSlow {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigSlowDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = ConfigSlowDefault.Threshold
+	}
+	if cfg.OnSlow == nil {
+		cfg.OnSlow = ConfigSlowDefault.OnSlow
+	}
+	if cfg.OnWarn == nil {
+		cfg.OnWarn = ConfigSlowDefault.OnWarn
+	}
+	return cfg
+}
+
+// SlowRequest creates a new middleware handler
+func SlowRequest(config ConfigSlow) http.HandlerFunc {
+	// Set default config
+	cfg := configSlowDefault(config)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		if cfg.WarnAfter > 0 {
+			// The timer goroutine must not touch c
+			method, path := c.Method(), c.Path()
+			timer := time.AfterFunc(cfg.WarnAfter, func() {
+				buf := make([]byte, defaultStackTraceBufLen)
+				cfg.OnWarn(SlowWarning{
+					Method:  method,
+					Path:    path,
+					Elapsed: time.Since(start),
+					Stack:   buf[:runtime.Stack(buf, true)],
+				})
+			})
+			// Fast requests don't leave a pending timer behind
+			defer timer.Stop()
+		}
+
+		err := c.Next()
+		if elapsed := time.Since(start); elapsed > cfg.Threshold {
+			cfg.OnSlow(c, elapsed)
+		}
+		return err
+	}
+}
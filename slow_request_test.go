@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+func sleepHandler(d time.Duration) http.HandlerFunc {
+	return func(c http.Context) error {
+		time.Sleep(d)
+		return c.String("ok")
+	}
+}
+
+func TestSlowRequest(t *testing.T) {
+	var slow []time.Duration
+	warnings := make(chan SlowWarning, 1)
+	handler := SlowRequest(ConfigSlow{
+		Threshold: 20 * time.Millisecond,
+		OnSlow:    func(c http.Context, elapsed time.Duration) { slow = append(slow, elapsed) },
+		WarnAfter: 50 * time.Millisecond,
+		OnWarn:    func(w SlowWarning) { warnings <- w },
+	})
+
+	run(t, httptest.NewRequest("GET", "/fast", nil), handler, ok)
+	if len(slow) != 0 {
+		t.Errorf("fast request reported as slow")
+	}
+
+	run(t, httptest.NewRequest("GET", "/slow", nil), handler, sleepHandler(30*time.Millisecond))
+	if len(slow) != 1 || slow[0] < 20*time.Millisecond {
+		t.Errorf("slow = %v", slow)
+	}
+
+	// Neither request ran long enough for the warning, and the timers of
+	// both were stopped
+	time.Sleep(60 * time.Millisecond)
+	select {
+	case w := <-warnings:
+		t.Fatalf("unexpected warning %+v", w)
+	default:
+	}
+
+	run(t, httptest.NewRequest("POST", "/stuck", nil), handler, sleepHandler(100*time.Millisecond))
+	select {
+	case w := <-warnings:
+		if w.Method != "POST" || w.Path != "/stuck" || w.Elapsed < 50*time.Millisecond {
+			t.Errorf("warning = %+v", w)
+		}
+		if !strings.Contains(string(w.Stack), "goroutine") {
+			t.Errorf("stack = %q", w.Stack)
+		}
+	default:
+		t.Fatal("no warning for the stuck request")
+	}
+}
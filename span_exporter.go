@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdHttp "net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// JSONSpanExporter writes every span as a line of JSON, e.g. to stdout
+type JSONSpanExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSpanExporter creates an exporter writing JSON lines to w
+func NewJSONSpanExporter(w io.Writer) *JSONSpanExporter {
+	return &JSONSpanExporter{w: w}
+}
+
+// Export writes the spans, spans that fail to encode are skipped
+func (e *JSONSpanExporter) Export(spans []Span) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, span := range spans {
+		_ = enc.Encode(span)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.w.Write(buf.Bytes())
+}
+
+// ConfigOTLP defines the config of the OTLP exporter
+type ConfigOTLP struct {
+	// Endpoint of the collector's OTLP/HTTP traces receiver
+	//
+	// Optional. Default: "http://localhost:4318/v1/traces"
+	Endpoint string
+
+	// ServiceName is sent as the service.name resource attribute
+	//
+	// Optional. Default: "unknown_service"
+	ServiceName string
+
+	// Headers are added to every export request, e.g. for authentication
+	//
+	// Optional. Default: nil
+	Headers map[string]string
+
+	// Client sends the export requests
+	//
+	// Optional. Default: a client with a 10 second timeout
+	Client *stdHttp.Client
+
+	// OnError is called when an export fails, the spans are dropped
+	//
+	// Optional. Default: nil
+	OnError func(err error)
+}
+
+// ConfigOTLPDefault is the default config
+var ConfigOTLPDefault = ConfigOTLP{
+	Endpoint:    "http://localhost:4318/v1/traces",
+	ServiceName: "unknown_service",
+	Client:      &stdHttp.Client{Timeout: 10 * time.Second},
+}
+
+// Helper function to set default values
+func configOTLPDefault(config ...ConfigOTLP) ConfigOTLP {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigOTLPDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = ConfigOTLPDefault.Endpoint
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = ConfigOTLPDefault.ServiceName
+	}
+	if cfg.Client == nil {
+		cfg.Client = ConfigOTLPDefault.Client
+	}
+	return cfg
+}
+
+// OTLPSpanExporter posts spans to an OpenTelemetry collector using the
+// OTLP/HTTP JSON encoding
+type OTLPSpanExporter struct {
+	cfg ConfigOTLP
+}
+
+// NewOTLPSpanExporter creates an OTLP/HTTP JSON exporter
+func NewOTLPSpanExporter(config ...ConfigOTLP) *OTLPSpanExporter {
+	return &OTLPSpanExporter{cfg: configOTLPDefault(config...)}
+}
+
+// Export posts the spans in a single request
+func (e *OTLPSpanExporter) Export(spans []Span) {
+	if err := e.export(spans); err != nil && e.cfg.OnError != nil {
+		e.cfg.OnError(err)
+	}
+}
+
+func (e *OTLPSpanExporter) export(spans []Span) error {
+	body, err := json.Marshal(otlpRequest(e.cfg.ServiceName, spans))
+	if err != nil {
+		return err
+	}
+	req, err := stdHttp.NewRequest(utils.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(utils.HeaderContentType, "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= utils.StatusBadRequest {
+		return fmt.Errorf("otlp: collector responded with %d", res.StatusCode)
+	}
+	return nil
+}
+
+// otlpKindServer is the SPAN_KIND_SERVER of the OTLP protocol
+const otlpKindServer = 2
+
+// OTLP status codes
+const (
+	otlpStatusUnset = 0
+	otlpStatusError = 2
+)
+
+// otlpRequest builds an ExportTraceServiceRequest in the OTLP JSON mapping,
+// with ids in hex and 64 bit integers as strings
+func otlpRequest(service string, spans []Span) map[string]any {
+	otlpSpans := make([]map[string]any, len(spans))
+	for i, span := range spans {
+		s := map[string]any{
+			"traceId":           span.TraceID,
+			"spanId":            span.SpanID,
+			"name":              span.Name,
+			"kind":              otlpKindServer,
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+			"status":            map[string]any{"code": otlpStatusUnset},
+		}
+		if span.ParentID != "" {
+			s["parentSpanId"] = span.ParentID
+		}
+		// Server spans only fail for 5xx, 4xx are the client's mistake
+		if span.Status >= utils.StatusInternalServerError || span.Error != "" {
+			s["status"] = map[string]any{"code": otlpStatusError, "message": span.Error}
+		}
+		otlpSpans[i] = s
+	}
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{"service.name": service}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/sujit-baniya/middleware"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+// otlpAttributes converts attributes to OTLP key values
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]map[string]any, 0, len(attributes))
+	for _, k := range keys {
+		var value map[string]any
+		switch v := attributes[k].(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		kvs = append(kvs, map[string]any{"key": k, "value": value})
+	}
+	return kvs
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJSONSpanExporter(t *testing.T) {
+	var buf bytes.Buffer
+	NewJSONSpanExporter(&buf).Export([]Span{{Name: "a", TraceID: "t"}, {Name: "b"}})
+	dec := json.NewDecoder(&buf)
+	var names []string
+	for dec.More() {
+		var span Span
+		if err := dec.Decode(&span); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, span.Name)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestOTLPSpanExporter(t *testing.T) {
+	var body map[string]any
+	var auth string
+	server := httptest.NewServer(stdHttp.HandlerFunc(func(w stdHttp.ResponseWriter, r *stdHttp.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	var exportErr error
+	exporter := NewOTLPSpanExporter(ConfigOTLP{
+		Endpoint:    server.URL,
+		ServiceName: "shop",
+		Headers:     map[string]string{"Authorization": "Bearer x"},
+		OnError:     func(err error) { exportErr = err },
+	})
+	start := time.Unix(1700000000, 0)
+	exporter.Export([]Span{{
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		ParentID:   "b7ad6b7169203331",
+		Name:       "GET /",
+		Start:      start,
+		End:        start.Add(time.Second),
+		Status:     500,
+		Error:      "boom",
+		Attributes: map[string]any{"http.response.status_code": 500},
+	}})
+	if exportErr != nil {
+		t.Fatal(exportErr)
+	}
+	if auth != "Bearer x" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	rs := body["resourceSpans"].([]any)[0].(map[string]any)
+	span := rs["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	if span["parentSpanId"] != "b7ad6b7169203331" || span["startTimeUnixNano"] != "1700000000000000000" {
+		t.Errorf("span = %v", span)
+	}
+	if status := span["status"].(map[string]any); status["code"] != float64(otlpStatusError) || status["message"] != "boom" {
+		t.Errorf("status = %v", status)
+	}
+}
+
+func TestOTLPSpanExporterError(t *testing.T) {
+	server := httptest.NewServer(stdHttp.HandlerFunc(func(w stdHttp.ResponseWriter, r *stdHttp.Request) {
+		w.WriteHeader(stdHttp.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	var exportErr error
+	NewOTLPSpanExporter(ConfigOTLP{Endpoint: server.URL, OnError: func(err error) { exportErr = err }}).Export([]Span{{}})
+	if exportErr == nil {
+		t.Error("OnError not called for a 503")
+	}
+}
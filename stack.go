@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/middleware/limiter"
+)
+
+// Stack composes middlewares into a single handler, run in the order they
+// were added
+type Stack struct {
+	handlers []http.HandlerFunc
+}
+
+// DefaultOption configures a member of the Default stack
+type DefaultOption func(*defaultStack)
+
+// defaultStack holds the configs of the Default members, nil for the
+// disabled ones
+type defaultStack struct {
+	recover   *ConfigRecover
+	requestID *ConfigRequestID
+	log       *ConfigLog
+	secure    *ConfigSecure
+	cors      *ConfigCors
+	limiter   *limiter.Config
+}
+
+// Default returns a Stack with the recommended middlewares in this order:
+// Recover first so it catches panics of everything after it, RequestID so
+// the log lines carry the id, Log, Secure, then Cors and the limiter when
+// enabled by WithCors and WithLimiter, so preflights are answered before
+// they count against the limit. Members are only created when enabled.
+//
+// A Stack is returned rather than the []http.HandlerFunc of the members so
+// it can be extended with Use and mounted as one handler; Handlers returns
+// the members as a slice, in the order above, for routers taking them one
+// by one.
+func Default(opts ...DefaultOption) *Stack {
+	recoverCfg, requestIDCfg, secureCfg := ConfigRecoverDefault, ConfigRequestIDDefault, ConfigSecureDefault
+	d := &defaultStack{
+		recover:   &recoverCfg,
+		requestID: &requestIDCfg,
+		log:       &ConfigLog{},
+		secure:    &secureCfg,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	s := new(Stack)
+	if d.recover != nil {
+		s.Use(Recover(*d.recover))
+	}
+	if d.requestID != nil {
+		s.Use(RequestID(*d.requestID))
+	}
+	if d.log != nil {
+		s.Use(Log(*d.log))
+	}
+	if d.secure != nil {
+		s.Use(Secure(*d.secure))
+	}
+	if d.cors != nil {
+		s.Use(Cors(*d.cors))
+	}
+	if d.limiter != nil {
+		s.Use(limiter.New(*d.limiter))
+	}
+	return s
+}
+
+// WithRecover configures the Recover member of Default
+func WithRecover(config ConfigRecover) DefaultOption {
+	return func(d *defaultStack) { d.recover = &config }
+}
+
+// WithoutRecover leaves Recover out of Default
+func WithoutRecover() DefaultOption {
+	return func(d *defaultStack) { d.recover = nil }
+}
+
+// WithRequestID configures the RequestID member of Default
+func WithRequestID(config ConfigRequestID) DefaultOption {
+	return func(d *defaultStack) { d.requestID = &config }
+}
+
+// WithoutRequestID leaves RequestID out of Default
+func WithoutRequestID() DefaultOption {
+	return func(d *defaultStack) { d.requestID = nil }
+}
+
+// WithLogger configures the Log member of Default
+func WithLogger(config ConfigLog) DefaultOption {
+	return func(d *defaultStack) { d.log = &config }
+}
+
+// WithoutLogger leaves Log out of Default
+func WithoutLogger() DefaultOption {
+	return func(d *defaultStack) { d.log = nil }
+}
+
+// WithSecure configures the Secure member of Default
+func WithSecure(config ConfigSecure) DefaultOption {
+	return func(d *defaultStack) { d.secure = &config }
+}
+
+// WithoutSecure leaves Secure out of Default
+func WithoutSecure() DefaultOption {
+	return func(d *defaultStack) { d.secure = nil }
+}
+
+// WithCors adds Cors to Default
+func WithCors(config ConfigCors) DefaultOption {
+	return func(d *defaultStack) { d.cors = &config }
+}
+
+// WithLimiter adds the limiter to Default
+func WithLimiter(config limiter.Config) DefaultOption {
+	return func(d *defaultStack) { d.limiter = &config }
+}
+
+// Use appends middlewares to the stack
+func (s *Stack) Use(h ...http.HandlerFunc) *Stack {
+	s.handlers = append(s.handlers, h...)
+	return s
+}
+
+// Handlers returns the middlewares in order, e.g. to register them on a
+// router one by one
+func (s *Stack) Handlers() []http.HandlerFunc {
+	return append([]http.HandlerFunc(nil), s.handlers...)
+}
+
+// Handler returns the composed handler, later calls to Use don't change it
+func (s *Stack) Handler() http.HandlerFunc {
+	handlers := append([]http.HandlerFunc(nil), s.handlers...)
+	return func(c http.Context) error {
+		if len(handlers) == 0 {
+			return c.Next()
+		}
+		return handlers[0](&stackContext{engineContext: c, handlers: handlers})
+	}
+}
+
+// engineContext names the embedded context, a field called Context would
+// collide with the Context method
+type engineContext = http.Context
+
+// stackContext runs the next handler of the stack on Next, and continues
+// with the engine's chain after the last one
+type stackContext struct {
+	engineContext
+	handlers []http.HandlerFunc
+	index    int
+}
+
+// Next runs the next middleware of the stack
+func (s *stackContext) Next() error {
+	s.index++
+	if s.index < len(s.handlers) {
+		return s.handlers[s.index](s)
+	}
+	return s.engineContext.Next()
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/phuslu/log"
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/limiter"
+)
+
+// trace records its name and continues the stack
+func trace(calls *[]string, name string) http.HandlerFunc {
+	return func(c http.Context) error {
+		*calls = append(*calls, name)
+		return c.Next()
+	}
+}
+
+func TestStackOrder(t *testing.T) {
+	var calls []string
+	stack := new(Stack).Use(trace(&calls, "a"), trace(&calls, "b")).Use(trace(&calls, "c"))
+	handler := stack.Handler()
+	// Later additions don't change a built handler
+	stack.Use(trace(&calls, "d"))
+
+	// The handler is built once and reused
+	for i := 0; i < 2; i++ {
+		calls = nil
+		c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+			calls = append(calls, "final")
+			return c.String("ok")
+		})
+		if got := strings.Join(calls, ","); got != "a,b,c,final" || c.Body() != "ok" {
+			t.Errorf("run %d: calls = %s, body = %q", i, got, c.Body())
+		}
+	}
+	if len(stack.Handlers()) != 4 {
+		t.Errorf("Handlers = %d", len(stack.Handlers()))
+	}
+}
+
+func TestStackEarlyTermination(t *testing.T) {
+	var calls []string
+	stop := func(c http.Context) error {
+		calls = append(calls, "stop")
+		return c.String("stopped")
+	}
+	handler := new(Stack).Use(trace(&calls, "a"), stop, trace(&calls, "b")).Handler()
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if got := strings.Join(calls, ","); got != "a,stop" || c.Body() != "stopped" {
+		t.Errorf("calls = %s, body = %q", got, c.Body())
+	}
+}
+
+func TestStackErrorBubbling(t *testing.T) {
+	errInner := errors.New("inner")
+	var seen error
+	outer := func(c http.Context) error {
+		seen = c.Next()
+		return seen
+	}
+	handler := new(Stack).Use(outer, func(c http.Context) error { return errInner }).Handler()
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if seen != errInner || c.Errors()[0] != errInner {
+		t.Errorf("seen = %v, returned = %v", seen, c.Errors()[0])
+	}
+}
+
+func TestStackEmpty(t *testing.T) {
+	if c := run(t, httptest.NewRequest("GET", "/", nil), new(Stack).Handler(), ok); c.Body() != "ok" {
+		t.Errorf("body = %q", c.Body())
+	}
+}
+
+func TestDefault(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Default(WithLogger(ConfigLog{LogWriter: &log.IOWriter{Writer: &buf}})).Handler()
+	c := run(t, httptest.NewRequest("GET", "/", nil), handler, func(c http.Context) error {
+		panic("boom")
+	})
+
+	// Recover runs first and catches the panic of the final handler, after
+	// the members behind it set their headers
+	h := c.Recorder.Header()
+	if c.Recorder.Code != utils.StatusInternalServerError {
+		t.Errorf("status = %d", c.Recorder.Code)
+	}
+	if h.Get(utils.HeaderXRequestID) == "" || h.Get(utils.HeaderXContentTypeOptions) != "nosniff" {
+		t.Errorf("headers = %v", h)
+	}
+
+	// Log runs behind RequestID
+	c = run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+	if id := c.Recorder.Header().Get(utils.HeaderXRequestID); id == "" || !strings.Contains(buf.String(), id) {
+		t.Errorf("log line %q lacks the request id %q", buf.String(), id)
+	}
+	if got := len(Default().Handlers()); got != 4 {
+		t.Errorf("Default has %d members", got)
+	}
+}
+
+func TestDefaultOptions(t *testing.T) {
+	var calls []string
+	// seen records the members reached, in order, without skipping them
+	seen := func(name string) func(c http.Context) bool {
+		return func(c http.Context) bool {
+			calls = append(calls, name)
+			return false
+		}
+	}
+	var buf bytes.Buffer
+	members := []DefaultOption{
+		WithRecover(ConfigRecover{Next: seen("recover")}),
+		WithRequestID(ConfigRequestID{Next: seen("request id")}),
+		WithLogger(ConfigLog{LogWriter: &log.IOWriter{Writer: &buf}}),
+		WithSecure(ConfigSecure{Filter: seen("secure")}),
+		WithCors(ConfigCors{Next: seen("cors")}),
+		WithLimiter(limiter.Config{Next: seen("limiter")}),
+	}
+	for _, tt := range []struct {
+		name    string
+		without []DefaultOption
+		want    string
+		logged  bool
+		members int
+	}{
+		{"all", nil, "recover,request id,secure,cors,limiter", true, 6},
+		{"without recover", []DefaultOption{WithoutRecover()}, "request id,secure,cors,limiter", true, 5},
+		{"without request id", []DefaultOption{WithoutRequestID()}, "recover,secure,cors,limiter", true, 5},
+		{"without logger", []DefaultOption{WithoutLogger()}, "recover,request id,secure,cors,limiter", false, 5},
+		{"without secure", []DefaultOption{WithoutSecure()}, "recover,request id,cors,limiter", true, 5},
+	} {
+		calls, buf = nil, bytes.Buffer{}
+		stack := Default(append(append([]DefaultOption(nil), members...), tt.without...)...)
+		run(t, httptest.NewRequest("GET", "/", nil), stack.Handler(), ok)
+		if got := strings.Join(calls, ","); got != tt.want {
+			t.Errorf("%s: order = %s, want %s", tt.name, got, tt.want)
+		}
+		if logged := buf.Len() > 0; logged != tt.logged {
+			t.Errorf("%s: logged = %v", tt.name, logged)
+		}
+		if got := len(stack.Handlers()); got != tt.members {
+			t.Errorf("%s: %d members, want %d", tt.name, got, tt.members)
+		}
+	}
+
+	// Handlers lists the members in the same order, one per entry
+	var order []string
+	for _, h := range Default(members...).Handlers() {
+		calls, buf = nil, bytes.Buffer{}
+		run(t, httptest.NewRequest("GET", "/", nil), h, ok)
+		switch {
+		case len(calls) == 1 && buf.Len() == 0:
+			order = append(order, calls[0])
+		case len(calls) == 0 && buf.Len() > 0:
+			order = append(order, "log")
+		default:
+			t.Fatalf("handler ran calls = %v, logged = %v", calls, buf.Len() > 0)
+		}
+	}
+	if got := strings.Join(order, ","); got != "recover,request id,log,secure,cors,limiter" {
+		t.Errorf("Handlers order = %s", got)
+	}
+
+	// Cors and the limiter are only added on request
+	calls = nil
+	run(t, httptest.NewRequest("GET", "/", nil), Default(members[0], WithoutLogger()).Handler(), ok)
+	if strings.Join(calls, ",") != "recover" || len(Default(WithoutLogger()).Handlers()) != 3 {
+		t.Errorf("calls = %v", calls)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/sujit-baniya/middleware/limiter/memory"
+)
+
+// memoryStorage adapts the limiter's in-memory store to the storage contract
+// so middlewares can fall back to process-local state
+type memoryStorage struct {
+	store *memory.Storage
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{store: memory.New()}
+}
+
+// Get value by key
+func (s *memoryStorage) Get(key string) ([]byte, error) {
+	raw, _ := s.store.Get(key).([]byte)
+	return raw, nil
+}
+
+// Set key with value
+func (s *memoryStorage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) <= 0 || len(val) <= 0 {
+		return nil
+	}
+	s.store.Set(key, val, exp)
+	return nil
+}
+
+// Delete key by key
+func (s *memoryStorage) Delete(key string) error {
+	if len(key) <= 0 {
+		return nil
+	}
+	s.store.Delete(key)
+	return nil
+}
+
+// Reset all keys
+func (s *memoryStorage) Reset() error {
+	s.store.Reset()
+	return nil
+}
+
+// Close the storage
+func (s *memoryStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var (
+	// ErrTenantMissing is passed to the ErrorHandler when no source names
+	// a tenant
+	ErrTenantMissing = errors.New("tenant: missing")
+	// ErrTenantForbidden can be returned by Lookup for tenants that exist
+	// but may not be served, e.g. suspended ones
+	ErrTenantForbidden = errors.New("tenant: forbidden")
+)
+
+// TenantSource is a place the tenant key is read from
+type TenantSource int
+
+const (
+	// TenantSubdomain reads the label in front of BaseDomain
+	TenantSubdomain TenantSource = iota
+	// TenantHeader reads the Header
+	TenantHeader
+	// TenantPath reads the path segment following PathPrefix
+	TenantPath
+)
+
+// maxTenantKeyLength bounds the keys passed to Lookup
+const maxTenantKeyLength = 128
+
+// ConfigTenant defines the config for middleware.
+type ConfigTenant struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Sources are tried in order until one names a tenant, sources that
+	// aren't configured are skipped
+	//
+	// Optional. Default: TenantSubdomain, TenantHeader, TenantPath
+	Sources []TenantSource
+
+	// BaseDomain the tenant subdomains live under, e.g. "app.example.com"
+	// for acme.app.example.com
+	//
+	// Optional. Default: "", the subdomain isn't used
+	BaseDomain string
+
+	// ReservedSubdomains never name a tenant
+	//
+	// Optional. Default: "www", "api"
+	ReservedSubdomains []string
+
+	// Header carrying the tenant key
+	//
+	// Optional. Default: "X-Tenant-ID"
+	Header string
+
+	// PathPrefix followed by the tenant key, e.g. "/t/" for /t/acme/orders.
+	// It matches whole segments, "/t" doesn't match /tenants/acme. The path
+	// isn't rewritten, routes must include the key
+	//
+	// Optional. Default: "", the path isn't used
+	PathPrefix string
+
+	// Lookup loads and validates the tenant of a key, return
+	// ErrTenantForbidden for tenants that may not be served
+	//
+	// Optional. Default: nil, the key is the tenant
+	Lookup func(c http.Context, tenantKey string) (any, error)
+
+	// CacheTTL is how long successful lookups are kept, -1 disables the
+	// cache
+	//
+	// Optional. Default: 1 * time.Minute
+	CacheTTL time.Duration
+
+	// ContextKey stores the tenant returned by Lookup
+	//
+	// Optional. Default: "tenant"
+	ContextKey string
+
+	// KeyContextKey stores the tenant key
+	//
+	// Optional. Default: "tenant_key"
+	KeyContextKey string
+
+	// ErrorHandler is called for requests without a tenant or whose lookup
+	// failed
+	//
+	// Optional. Default: responds with 403 Forbidden for ErrTenantForbidden
+	// and 404 Not Found otherwise
+	ErrorHandler func(c http.Context, err error) error
+}
+
+// ConfigTenantDefault is the default config
+var ConfigTenantDefault = ConfigTenant{
+	Next:               nil,
+	Sources:            []TenantSource{TenantSubdomain, TenantHeader, TenantPath},
+	ReservedSubdomains: []string{"www", "api"},
+	Header:             "X-Tenant-ID",
+	CacheTTL:           1 * time.Minute,
+	ContextKey:         "tenant",
+	KeyContextKey:      "tenant_key",
+	ErrorHandler: func(c http.Context, err error) error {
+		if errors.Is(err, ErrTenantForbidden) {
+			c.AbortWithStatus(utils.StatusForbidden)
+			return fmt.Errorf("%w: %v", ErrTenantDenied, err)
+		}
+		c.AbortWithStatus(utils.StatusNotFound)
+		return fmt.Errorf("%w: %v", ErrTenantNotFound, err)
+	},
+}
+
+// Helper function to set default values
+func configTenantDefault(config ...ConfigTenant) ConfigTenant {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigTenantDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Sources == nil {
+		cfg.Sources = ConfigTenantDefault.Sources
+	}
+	if cfg.ReservedSubdomains == nil {
+		cfg.ReservedSubdomains = ConfigTenantDefault.ReservedSubdomains
+	}
+	if cfg.Header == "" {
+		cfg.Header = ConfigTenantDefault.Header
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = ConfigTenantDefault.CacheTTL
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigTenantDefault.ContextKey
+	}
+	if cfg.KeyContextKey == "" {
+		cfg.KeyContextKey = ConfigTenantDefault.KeyContextKey
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigTenantDefault.ErrorHandler
+	}
+	return cfg
+}
+
+// tenantEntry is a cached lookup
+type tenantEntry struct {
+	tenant  any
+	expires time.Time
+}
+
+// Tenant creates a new middleware handler resolving the tenant of every
+// request and storing it for the handlers, see TenantKey and TenantAs
+func Tenant(config ConfigTenant) http.HandlerFunc {
+	// Set default config
+	cfg := configTenantDefault(config)
+
+	baseDomain := "." + strings.ToLower(strings.Trim(cfg.BaseDomain, "."))
+	pathPrefix := strings.TrimSuffix(cfg.PathPrefix, "/") + "/"
+	reserved := make(map[string]struct{}, len(cfg.ReservedSubdomains))
+	for _, sub := range cfg.ReservedSubdomains {
+		reserved[strings.ToLower(sub)] = struct{}{}
+	}
+
+	var (
+		mu    sync.Mutex
+		cache = make(map[string]tenantEntry)
+	)
+	lookup := func(c http.Context, key string) (any, error) {
+		if cfg.Lookup == nil {
+			return key, nil
+		}
+		if cfg.CacheTTL > 0 {
+			mu.Lock()
+			entry, ok := cache[key]
+			mu.Unlock()
+			if ok && time.Now().Before(entry.expires) {
+				return entry.tenant, nil
+			}
+		}
+		tenant, err := cfg.Lookup(c, key)
+		// Failures aren't cached, so new tenants work right away
+		if err == nil && cfg.CacheTTL > 0 {
+			mu.Lock()
+			cache[key] = tenantEntry{tenant: tenant, expires: time.Now().Add(cfg.CacheTTL)}
+			mu.Unlock()
+		}
+		return tenant, err
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		key := ""
+		for _, source := range cfg.Sources {
+			switch source {
+			case TenantSubdomain:
+				if cfg.BaseDomain == "" {
+					continue
+				}
+				host := c.Origin().Host
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+				// Only a single label directly under the base domain counts
+				sub := strings.TrimSuffix(strings.ToLower(host), baseDomain)
+				if _, ok := reserved[sub]; !ok && len(sub) < len(host) && !strings.Contains(sub, ".") {
+					key = sub
+				}
+			case TenantHeader:
+				key = strings.TrimSpace(c.Header(cfg.Header, ""))
+			case TenantPath:
+				if cfg.PathPrefix == "" {
+					continue
+				}
+				if path := c.Origin().URL.Path; strings.HasPrefix(path, pathPrefix) {
+					key, _, _ = strings.Cut(path[len(pathPrefix):], "/")
+				}
+			}
+			if key != "" {
+				break
+			}
+		}
+		if key == "" || len(key) > maxTenantKeyLength {
+			return cfg.ErrorHandler(c, ErrTenantMissing)
+		}
+
+		tenant, err := lookup(c, key)
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+		c.WithValue(cfg.KeyContextKey, key)
+		c.WithValue(cfg.ContextKey, tenant)
+		// TenantKey and TenantAs find them whatever the context keys
+		c.WithValue(tenantKeyKey, key)
+		c.WithValue(tenantValueKey, tenant)
+		return c.Next()
+	}
+}
+
+// Context keys TenantKey and TenantAs read, the tenant is stored under them
+// in addition to ContextKey and KeyContextKey
+const (
+	tenantKeyKey   = "middleware.tenant_key"
+	tenantValueKey = "middleware.tenant"
+)
+
+// TenantKey returns the tenant key stored by Tenant, whatever its
+// KeyContextKey
+func TenantKey(c http.Context) string {
+	key, _ := c.Value(tenantKeyKey).(string)
+	return key
+}
+
+// TenantAs returns the tenant stored by Tenant, typed as Lookup returned
+// it, whatever its ContextKey
+func TenantAs[T any](c http.Context) (T, bool) {
+	tenant, ok := c.Value(tenantValueKey).(T)
+	return tenant, ok
+}
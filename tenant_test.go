@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+type testTenant struct{ name string }
+
+// tenantOf runs the Tenant middleware and returns the key and tenant the
+// handler sees
+func tenantOf(t *testing.T, handler http.HandlerFunc, host, path, header string) (string, any, int) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Host = host
+	if header != "" {
+		req.Header.Set("X-Tenant-ID", header)
+	}
+	var key string
+	var tenant any
+	c := run(t, req, handler, func(c http.Context) error {
+		key = TenantKey(c)
+		tenant, _ = TenantAs[any](c)
+		return c.String("ok")
+	})
+	return key, tenant, c.Recorder.Code
+}
+
+func TestTenantSources(t *testing.T) {
+	handler := Tenant(ConfigTenant{BaseDomain: "app.example.com", PathPrefix: "/t/"})
+	for _, tt := range []struct {
+		name, host, path, header string
+		want                     string
+	}{
+		{"subdomain", "acme.app.example.com", "/", "", "acme"},
+		{"subdomain with port", "Acme.app.example.com:8080", "/", "", "acme"},
+		{"header", "app.example.com", "/", "globex", "globex"},
+		{"path", "app.example.com", "/t/initech/orders", "", "initech"},
+		{"subdomain first", "acme.app.example.com", "/t/initech", "globex", "acme"},
+		{"reserved subdomain", "www.app.example.com", "/", "globex", "globex"},
+		{"nested subdomain", "a.acme.app.example.com", "/t/initech", "", "initech"},
+		{"other domain", "acme.other.com", "/t/initech", "", "initech"},
+	} {
+		key, tenant, status := tenantOf(t, handler, tt.host, tt.path, tt.header)
+		if key != tt.want || tenant != tt.want || status != utils.StatusOK {
+			t.Errorf("%s: key = %q, tenant = %v, status = %d", tt.name, key, tenant, status)
+		}
+	}
+
+	if _, _, status := tenantOf(t, handler, "www.app.example.com", "/", ""); status != utils.StatusNotFound {
+		t.Errorf("missing tenant status = %d", status)
+	}
+
+	// The prefix matches whole segments with or without a trailing slash
+	handler = Tenant(ConfigTenant{Sources: []TenantSource{TenantPath}, PathPrefix: "/t"})
+	for path, want := range map[string]string{"/t/initech/orders": "initech", "/t/initech": "initech", "/tenants/initech": "", "/t": ""} {
+		key, _, status := tenantOf(t, handler, "app.example.com", path, "")
+		if key != want || (want == "") != (status == utils.StatusNotFound) {
+			t.Errorf("%s: key = %q, status = %d", path, key, status)
+		}
+	}
+}
+
+func TestTenantLookup(t *testing.T) {
+	errUnknown := errors.New("unknown")
+	lookups := 0
+	handler := Tenant(ConfigTenant{
+		Sources: []TenantSource{TenantHeader},
+		Lookup: func(c http.Context, key string) (any, error) {
+			lookups++
+			switch key {
+			case "acme":
+				return &testTenant{name: "Acme"}, nil
+			case "suspended":
+				return nil, ErrTenantForbidden
+			}
+			return nil, errUnknown
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		_, tenant, status := tenantOf(t, handler, "", "/", "acme")
+		if tt, ok := tenant.(*testTenant); !ok || tt.name != "Acme" || status != utils.StatusOK {
+			t.Errorf("tenant = %v, status = %d", tenant, status)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("lookups = %d, the second request should hit the cache", lookups)
+	}
+
+	if _, _, status := tenantOf(t, handler, "", "/", "suspended"); status != utils.StatusForbidden {
+		t.Errorf("forbidden status = %d", status)
+	}
+	if _, _, status := tenantOf(t, handler, "", "/", "nobody"); status != utils.StatusNotFound {
+		t.Errorf("unknown status = %d", status)
+	}
+	// Failures aren't cached
+	lookups = 0
+	tenantOf(t, handler, "", "/", "nobody")
+	if lookups != 1 {
+		t.Errorf("failed lookup cached")
+	}
+}
+
+func TestTenantCacheDisabled(t *testing.T) {
+	lookups := 0
+	handler := Tenant(ConfigTenant{
+		Sources:  []TenantSource{TenantHeader},
+		CacheTTL: -1,
+		Lookup: func(c http.Context, key string) (any, error) {
+			lookups++
+			return key, nil
+		},
+	})
+	tenantOf(t, handler, "", "/", "acme")
+	tenantOf(t, handler, "", "/", "acme")
+	if lookups != 2 {
+		t.Errorf("lookups = %d with the cache disabled", lookups)
+	}
+}
+
+func TestTenantCustomContextKeys(t *testing.T) {
+	handler := Tenant(ConfigTenant{
+		Sources:       []TenantSource{TenantHeader},
+		ContextKey:    "org",
+		KeyContextKey: "org_key",
+		Lookup: func(c http.Context, key string) (any, error) {
+			return &testTenant{name: key}, nil
+		},
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	run(t, req, handler, func(c http.Context) error {
+		if c.Value("org_key") != "acme" || TenantKey(c) != "acme" {
+			t.Errorf("key = %v, TenantKey = %q", c.Value("org_key"), TenantKey(c))
+		}
+		tenant, ok := TenantAs[*testTenant](c)
+		if !ok || tenant != c.Value("org") {
+			t.Errorf("TenantAs = %v, ContextKey holds %v", tenant, c.Value("org"))
+		}
+		return nil
+	})
+}
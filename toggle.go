@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// Toggle wraps a middleware so it can be switched on and off at runtime,
+// while enabled is false requests skip m and continue the stack
+func Toggle(enabled *atomic.Bool, m http.HandlerFunc) http.HandlerFunc {
+	return func(c http.Context) error {
+		if !enabled.Load() {
+			return c.Next()
+		}
+		return m(c)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestToggle(t *testing.T) {
+	var enabled atomic.Bool
+	enabled.Store(true)
+	deny := func(c http.Context) error {
+		c.AbortWithStatus(utils.StatusForbidden)
+		return nil
+	}
+	handler := Toggle(&enabled, deny)
+
+	for _, on := range []bool{true, false, true} {
+		enabled.Store(on)
+		c := run(t, httptest.NewRequest("GET", "/", nil), handler, ok)
+		if passed := c.Body() == "ok"; passed == on {
+			t.Errorf("enabled %v: status = %d", on, c.Recorder.Code)
+		}
+		if on && c.Recorder.Code != utils.StatusForbidden {
+			t.Errorf("enabled: status = %d", c.Recorder.Code)
+		}
+	}
+}
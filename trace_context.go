@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	stdHttp "net/http"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+const (
+	// HeaderTraceparent carries the trace id, parent id and flags of the
+	// W3C Trace Context specification
+	HeaderTraceparent = "traceparent"
+	// HeaderTracestate carries vendor specific trace data
+	HeaderTracestate = "tracestate"
+)
+
+// TraceInfo is the trace context of a request
+type TraceInfo struct {
+	// TraceID is the 32 hex digit id shared by every span of the trace
+	TraceID string
+	// ParentID is the 16 hex digit span id of the caller, empty when the
+	// trace starts here
+	ParentID string
+	// SpanID is the 16 hex digit id of this request's span
+	SpanID string
+	// Flags are the trace flags, bit 0 is the sampled flag
+	Flags byte
+	// State is the tracestate header of the caller
+	State string
+}
+
+// Sampled reports whether the caller records the trace
+func (t TraceInfo) Sampled() bool {
+	return t.Flags&0x01 == 0x01
+}
+
+// Traceparent formats the trace context for outgoing requests, with this
+// request's span as the parent
+func (t TraceInfo) Traceparent() string {
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + hex.EncodeToString([]byte{t.Flags})
+}
+
+// ConfigTrace defines the config for middleware.
+type ConfigTrace struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// ContextKey the TraceInfo is stored under
+	//
+	// Optional. Default: "trace"
+	ContextKey string
+
+	// Sampled sets the sampled flag of traces started here
+	//
+	// Optional. Default: false
+	Sampled bool
+
+	// OnTrace is called with the trace context of every request, e.g. to
+	// export it
+	//
+	// Optional. Default: nil
+	OnTrace func(c http.Context, trace TraceInfo)
+}
+
+// ConfigTraceDefault is the default config
+var ConfigTraceDefault = ConfigTrace{
+	Next:       nil,
+	ContextKey: "trace",
+}
+
+// Helper function to set default values
+func configTraceDefault(config ...ConfigTrace) ConfigTrace {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigTraceDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigTraceDefault.ContextKey
+	}
+	return cfg
+}
+
+// TraceContext creates a new middleware handler
+func TraceContext(config ...ConfigTrace) http.HandlerFunc {
+	// Set default config
+	cfg := configTraceDefault(config...)
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		trace := startTrace(c, cfg.ContextKey, cfg.Sampled)
+		if cfg.OnTrace != nil {
+			cfg.OnTrace(c, trace)
+		}
+		return c.Next()
+	}
+}
+
+// startTrace continues the trace of the traceparent header, or starts a new
+// one, with a new span for this request. The TraceInfo is stored under key
+// and sent back in the response headers.
+func startTrace(c http.Context, key string, sampled bool) TraceInfo {
+	trace, ok := parseTraceparent(c.Header(HeaderTraceparent, ""))
+	if ok {
+		trace.State = strings.TrimSpace(c.Header(HeaderTracestate, ""))
+	} else {
+		// Start a new trace, the tracestate belongs to the invalid parent
+		trace = TraceInfo{TraceID: randomTraceID(16)}
+		if sampled {
+			trace.Flags = 0x01
+		}
+	}
+	trace.SpanID = randomTraceID(8)
+
+	c.SetHeader(HeaderTraceparent, trace.Traceparent())
+	if trace.State != "" {
+		c.SetHeader(HeaderTracestate, trace.State)
+	}
+	c.WithValue(key, trace)
+	// TraceFromContext finds it whatever the ContextKey
+	c.WithValue(traceInfoKey, trace)
+	return trace
+}
+
+// InjectTrace sets the traceparent and tracestate headers of an outgoing
+// request, so the service called continues the trace of this request. It
+// returns false when no trace was started.
+func InjectTrace(c http.Context, header stdHttp.Header) bool {
+	trace, ok := TraceFromContext(c)
+	if !ok {
+		return false
+	}
+	header.Set(HeaderTraceparent, trace.Traceparent())
+	if trace.State != "" {
+		header.Set(HeaderTracestate, trace.State)
+	}
+	return true
+}
+
+// traceInfoKey is the context key TraceFromContext reads, the TraceInfo is
+// stored under it in addition to ContextKey
+const traceInfoKey = "middleware.trace_info"
+
+// TraceFromContext returns the TraceInfo stored by TraceContext or Tracing,
+// whatever their ContextKey
+func TraceFromContext(c http.Context) (TraceInfo, bool) {
+	trace, ok := c.Value(traceInfoKey).(TraceInfo)
+	return trace, ok
+}
+
+// parseTraceparent parses a traceparent header following version 00 of the
+// specification, later versions are parsed as far as 00 defines them
+func parseTraceparent(header string) (TraceInfo, bool) {
+	header = strings.TrimSpace(header)
+	if len(header) < 55 {
+		return TraceInfo{}, false
+	}
+	version := header[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return TraceInfo{}, false
+	}
+	if version == "00" && len(header) != 55 {
+		return TraceInfo{}, false
+	}
+	if len(header) > 55 && header[55] != '-' {
+		return TraceInfo{}, false
+	}
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return TraceInfo{}, false
+	}
+	traceID, parentID, flags := header[3:35], header[36:52], header[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(flags) {
+		return TraceInfo{}, false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return TraceInfo{}, false
+	}
+	b, _ := hex.DecodeString(flags)
+	return TraceInfo{TraceID: traceID, ParentID: parentID, Flags: b[0]}, true
+}
+
+// isLowerHex reports whether s only consists of lowercase hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// randomTraceID returns n random bytes in hex, never all zeros
+func randomTraceID(n int) string {
+	b := make([]byte, n)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		for _, v := range b {
+			if v != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	stdHttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	trace, ok := parseTraceparent(validTraceparent)
+	if !ok || trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.ParentID != "00f067aa0ba902b7" || !trace.Sampled() {
+		t.Errorf("valid header = %+v, %v", trace, ok)
+	}
+	// Later versions may append fields
+	if _, ok = parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); !ok {
+		t.Error("future version with extra fields rejected")
+	}
+
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", // version 00 has no extra fields
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",       // forbidden version
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",       // all zero trace id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",       // all zero parent id
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",       // uppercase
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",        // short trace id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b-01",        // short parent id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0",        // short flags
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",       // wrong separator
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g",       // not hex
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00.extra", // extra field without dash
+	} {
+		if trace, ok := parseTraceparent(header); ok {
+			t.Errorf("%q parsed as %+v", header, trace)
+		}
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	var exported []TraceInfo
+	handler := TraceContext(ConfigTrace{OnTrace: func(c http.Context, trace TraceInfo) {
+		exported = append(exported, trace)
+	}})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceparent, validTraceparent)
+	req.Header.Set(HeaderTracestate, "vendor=value")
+	var seen TraceInfo
+	c := run(t, req, handler, func(c http.Context) error {
+		seen, _ = TraceFromContext(c)
+		return c.String("ok")
+	})
+	if seen.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || seen.ParentID != "00f067aa0ba902b7" ||
+		seen.SpanID == "" || seen.SpanID == seen.ParentID || seen.State != "vendor=value" {
+		t.Errorf("trace = %+v", seen)
+	}
+	h := c.Recorder.Header()
+	if h.Get(HeaderTraceparent) != seen.Traceparent() || h.Get(HeaderTracestate) != "vendor=value" {
+		t.Errorf("headers = %v", h)
+	}
+	if len(exported) != 1 || exported[0] != seen {
+		t.Errorf("exported = %+v", exported)
+	}
+
+	// An invalid header starts a new trace and drops the tracestate
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceparent, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	req.Header.Set(HeaderTracestate, "vendor=value")
+	c = run(t, req, handler, func(c http.Context) error {
+		seen, _ = TraceFromContext(c)
+		return nil
+	})
+	if len(seen.TraceID) != 32 || seen.ParentID != "" || len(seen.SpanID) != 16 || seen.State != "" || seen.Sampled() {
+		t.Errorf("new trace = %+v", seen)
+	}
+	if c.Recorder.Header().Get(HeaderTracestate) != "" {
+		t.Error("tracestate of the invalid parent sent back")
+	}
+}
+
+func TestTraceFromContextCustomKey(t *testing.T) {
+	var fromKey, fromContext TraceInfo
+	run(t, httptest.NewRequest("GET", "/", nil), TraceContext(ConfigTrace{ContextKey: "otel", Sampled: true}), func(c http.Context) error {
+		fromKey, _ = c.Value("otel").(TraceInfo)
+		fromContext, _ = TraceFromContext(c)
+		return nil
+	})
+	if fromKey.TraceID == "" || fromContext != fromKey || !fromContext.Sampled() {
+		t.Errorf("TraceFromContext = %+v, ContextKey holds %+v", fromContext, fromKey)
+	}
+}
+
+func TestInjectTraceContext(t *testing.T) {
+	header := stdHttp.Header{}
+	run(t, httptest.NewRequest("GET", "/", nil), func(c http.Context) error {
+		if InjectTrace(c, header) {
+			t.Error("InjectTrace without a trace")
+		}
+		return c.Next()
+	}, TraceContext(), func(c http.Context) error {
+		if !InjectTrace(c, header) {
+			t.Error("InjectTrace failed")
+		}
+		return nil
+	})
+	if _, ok := parseTraceparent(header.Get(HeaderTraceparent)); !ok {
+		t.Errorf("injected traceparent = %q", header.Get(HeaderTraceparent))
+	}
+}
@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	stdHttp "net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// Span is a finished request
+type Span struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Status     int            `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// SpanExporter sends finished spans to a tracing backend
+type SpanExporter interface {
+	Export(spans []Span)
+}
+
+// ConfigTracing defines the config for middleware.
+type ConfigTracing struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// Queue the finished spans are handed to, create it with NewSpanQueue
+	// and shut it down on exit to flush the spans still queued
+	//
+	// Required unless Exporter is set
+	Queue *SpanQueue
+
+	// Exporter is used with a queue of its own when Queue is nil, spans
+	// still queued at exit are lost
+	//
+	// Optional. Default: nil
+	Exporter SpanExporter
+
+	// SpanName returns the name of the span, it's called after the handler
+	// so the route is resolved
+	//
+	// Optional. Default: the method and the route set with SetSpanRoute,
+	// or the path
+	SpanName func(c http.Context) string
+
+	// ContextKey of the TraceInfo stored by TraceContext, the trace is
+	// started here when it is missing
+	//
+	// Optional. Default: "trace"
+	ContextKey string
+}
+
+// ConfigTracingDefault is the default config
+var ConfigTracingDefault = ConfigTracing{
+	Next: nil,
+	SpanName: func(c http.Context) string {
+		if route := spanRoute(c); route != "" {
+			return c.Method() + " " + route
+		}
+		return c.Method() + " " + c.Origin().URL.Path
+	},
+	ContextKey: "trace",
+}
+
+// Helper function to set default values
+func configTracingDefault(config ...ConfigTracing) ConfigTracing {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigTracingDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.SpanName == nil {
+		cfg.SpanName = ConfigTracingDefault.SpanName
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigTracingDefault.ContextKey
+	}
+	return cfg
+}
+
+// Tracing creates a new middleware handler recording a span per request.
+// The span's error is the one of a panic, recovered by a Recover mounted
+// after Tracing or passed on, the cancellation of the request's context or
+// the text of a 5xx status.
+func Tracing(config ConfigTracing) http.HandlerFunc {
+	// Set default config
+	cfg := configTracingDefault(config)
+
+	if cfg.Queue == nil {
+		if cfg.Exporter == nil {
+			panic("tracing: Queue or Exporter is required")
+		}
+		cfg.Queue = NewSpanQueue(cfg.Exporter)
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		trace, ok := c.Value(cfg.ContextKey).(TraceInfo)
+		if !ok {
+			trace = startTrace(c, cfg.ContextKey, false)
+		}
+		span := Span{
+			TraceID:  trace.TraceID,
+			SpanID:   trace.SpanID,
+			ParentID: trace.ParentID,
+			Start:    time.Now(),
+			Attributes: map[string]any{
+				"http.request.method": c.Method(),
+				"url.path":            c.Origin().URL.Path,
+				"client.address":      peerAddr(c),
+			},
+		}
+
+		info := trackRequest(c)
+		rec, captured := captureResponse(c)
+		defer func() {
+			r := recover()
+			span.End = time.Now()
+			span.Name = cfg.SpanName(c)
+			switch {
+			case r != nil:
+				span.Status = utils.StatusInternalServerError
+				span.Error = fmt.Sprintf("panic: %v", r)
+			case captured:
+				span.Status = rec.Status()
+			default:
+				span.Status = c.StatusCode()
+			}
+			// The engine drops the errors of the handlers, Recover reports
+			// the panics it turned into responses
+			if span.Error == "" {
+				switch hErr := info.loadError(); {
+				case hErr != nil:
+					span.Error = hErr.Error()
+				case c.Origin().Context().Err() != nil:
+					span.Error = c.Origin().Context().Err().Error()
+				case span.Status >= utils.StatusInternalServerError:
+					span.Error = stdHttp.StatusText(span.Status)
+				}
+			}
+			span.Attributes["http.response.status_code"] = span.Status
+			if route := spanRoute(c); route != "" {
+				span.Attributes["http.route"] = route
+			}
+			cfg.Queue.Enqueue(span)
+			if r != nil {
+				panic(r)
+			}
+		}()
+
+		if captured {
+			return rec.next(c)
+		}
+		return c.Next()
+	}
+}
+
+// SetSpanRoute records the route pattern matched for the request, like
+// "/users/{id}", so spans of one route share a name instead of carrying
+// every id in it
+func SetSpanRoute(c http.Context, route string) {
+	if info := requestInfoOf(c); info != nil {
+		info.mu.Lock()
+		info.route = route
+		info.mu.Unlock()
+	}
+}
+
+// spanRoute returns the route set with SetSpanRoute after the Tracing
+// middleware of c, "" when none was
+func spanRoute(c http.Context) string {
+	info := requestInfoOf(c)
+	if info == nil {
+		return ""
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	return info.route
+}
+
+// SpanQueue buffers finished spans and exports them in batches from a
+// background goroutine. Spans are dropped when the queue is full, so a slow
+// backend never blocks requests.
+type SpanQueue struct {
+	exporter  SpanExporter
+	spans     chan Span
+	batchSize int
+	interval  time.Duration
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+	dropped   atomic.Uint64
+}
+
+// ConfigSpanQueue defines the config of a SpanQueue
+type ConfigSpanQueue struct {
+	// Size is the number of spans held before new ones are dropped
+	//
+	// Optional. Default: 2048
+	Size int
+
+	// BatchSize is the largest number of spans exported at once
+	//
+	// Optional. Default: 256
+	BatchSize int
+
+	// Interval after which a partial batch is exported
+	//
+	// Optional. Default: 5 * time.Second
+	Interval time.Duration
+}
+
+// ConfigSpanQueueDefault is the default config
+var ConfigSpanQueueDefault = ConfigSpanQueue{
+	Size:      2048,
+	BatchSize: 256,
+	Interval:  5 * time.Second,
+}
+
+// Helper function to set default values
+func configSpanQueueDefault(config ...ConfigSpanQueue) ConfigSpanQueue {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigSpanQueueDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Size <= 0 {
+		cfg.Size = ConfigSpanQueueDefault.Size
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = ConfigSpanQueueDefault.BatchSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = ConfigSpanQueueDefault.Interval
+	}
+	return cfg
+}
+
+// NewSpanQueue starts a queue exporting to exporter
+func NewSpanQueue(exporter SpanExporter, config ...ConfigSpanQueue) *SpanQueue {
+	// Set default config
+	cfg := configSpanQueueDefault(config...)
+
+	q := &SpanQueue{
+		exporter:  exporter,
+		spans:     make(chan Span, cfg.Size),
+		batchSize: cfg.BatchSize,
+		interval:  cfg.Interval,
+		done:      make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue adds a span without blocking, it returns false when the span was
+// dropped because the queue is full or shut down
+func (q *SpanQueue) Enqueue(span Span) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if !q.closed {
+		select {
+		case q.spans <- span:
+			return true
+		default:
+		}
+	}
+	q.dropped.Add(1)
+	return false
+}
+
+// Dropped returns the number of spans dropped so far
+func (q *SpanQueue) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Shutdown exports the queued spans and stops the queue, it returns the
+// context's error when ctx is done first
+func (q *SpanQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.spans)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run exports full batches right away and partial ones every interval
+func (q *SpanQueue) run() {
+	defer close(q.done)
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	batch := make([]Span, 0, q.batchSize)
+	export := func() {
+		if len(batch) > 0 {
+			q.exporter.Export(batch)
+			batch = make([]Span, 0, q.batchSize)
+		}
+	}
+	for {
+		select {
+		case span, ok := <-q.spans:
+			if !ok {
+				export()
+				return
+			}
+			if batch = append(batch, span); len(batch) >= q.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		}
+	}
+}
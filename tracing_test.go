@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"context"
+	stdHttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// spanRecorder is a SpanExporter keeping the spans
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func (r *spanRecorder) Export(spans []Span) {
+	r.mu.Lock()
+	r.spans = append(r.spans, spans...)
+	r.mu.Unlock()
+}
+
+// traceRequests runs each request through Tracing and returns the spans
+func traceRequests(t *testing.T, final http.HandlerFunc, reqs ...*stdHttp.Request) []Span {
+	t.Helper()
+	exporter := &spanRecorder{}
+	queue := NewSpanQueue(exporter)
+	handler := Tracing(ConfigTracing{Queue: queue})
+	for _, req := range reqs {
+		run(t, req, handler, final)
+	}
+	if err := queue.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return exporter.spans
+}
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	var inner TraceInfo
+	spans := traceRequests(t, func(c http.Context) error {
+		inner, _ = TraceFromContext(c)
+		return c.String("ok")
+	}, req)
+
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d", len(spans))
+	}
+	span := spans[0]
+	if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("span = %+v", span)
+	}
+	if inner.TraceID != span.TraceID || inner.SpanID != span.SpanID {
+		t.Errorf("handler trace = %+v, span = %+v", inner, span)
+	}
+}
+
+func TestTracingStartsNewTrace(t *testing.T) {
+	spans := traceRequests(t, ok, httptest.NewRequest("GET", "/", nil))
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d", len(spans))
+	}
+	if span := spans[0]; len(span.TraceID) != 32 || len(span.SpanID) != 16 || span.ParentID != "" {
+		t.Errorf("span = %+v", span)
+	}
+}
+
+func TestTracingRouteFromHandler(t *testing.T) {
+	spans := traceRequests(t, func(c http.Context) error {
+		SetSpanRoute(c, "/users/{id}")
+		return c.String("ok")
+	}, httptest.NewRequest("GET", "/users/42", nil))
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d", len(spans))
+	}
+	if spans[0].Name != "GET /users/{id}" || spans[0].Attributes["http.route"] != "/users/{id}" {
+		t.Errorf("span = %+v", spans[0])
+	}
+
+	spans = traceRequests(t, ok, httptest.NewRequest("GET", "/users/42", nil))
+	if spans[0].Name != "GET /users/42" {
+		t.Errorf("name without route = %q", spans[0].Name)
+	}
+}
+
+func TestInjectTrace(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(HeaderTracestate, "vendor=1")
+	out := stdHttp.Header{}
+	var injected bool
+	run(t, req, TraceContext(), func(c http.Context) error {
+		injected = InjectTrace(c, out)
+		return nil
+	})
+	if !injected {
+		t.Fatal("InjectTrace returned false")
+	}
+	parent := out.Get(HeaderTraceparent)
+	if len(parent) != 55 || parent[3:35] != "4bf92f3577b34da6a3ce929d0e0e4736" || parent[36:52] == "00f067aa0ba902b7" {
+		t.Errorf("traceparent = %q", parent)
+	}
+	if out.Get(HeaderTracestate) != "vendor=1" {
+		t.Errorf("tracestate = %q", out.Get(HeaderTracestate))
+	}
+}
+
+func TestTracingSpanFields(t *testing.T) {
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.RemoteAddr = "192.0.2.1:1000"
+	// The client address is the connection's, not a forged one
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	spans := traceRequests(t, func(c http.Context) error {
+		return c.Status(stdHttp.StatusCreated).String("created")
+	}, req)
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "POST /orders" || span.Status != stdHttp.StatusCreated || span.Error != "" {
+		t.Errorf("span = %+v", span)
+	}
+	if span.End.Before(span.Start) {
+		t.Errorf("span ends before it starts: %+v", span)
+	}
+	for key, want := range map[string]any{
+		"http.request.method":       "POST",
+		"url.path":                  "/orders",
+		"client.address":            "192.0.2.1",
+		"http.response.status_code": stdHttp.StatusCreated,
+	} {
+		if got := span.Attributes[key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestTracingErrorFromRecover(t *testing.T) {
+	exporter := &spanRecorder{}
+	queue := NewSpanQueue(exporter)
+	run(t, httptest.NewRequest("GET", "/", nil),
+		Tracing(ConfigTracing{Queue: queue}),
+		Recover(),
+		func(c http.Context) error { panic("database is gone") },
+	)
+	_ = queue.Shutdown(context.Background())
+	if len(exporter.spans) != 1 {
+		t.Fatalf("spans = %d", len(exporter.spans))
+	}
+	if span := exporter.spans[0]; span.Status != stdHttp.StatusInternalServerError || span.Error != "database is gone" {
+		t.Errorf("span = %+v", span)
+	}
+}
+
+func TestTracingErrorFromPanic(t *testing.T) {
+	exporter := &spanRecorder{}
+	queue := NewSpanQueue(exporter)
+	func() {
+		defer func() { _ = recover() }()
+		run(t, httptest.NewRequest("GET", "/", nil),
+			Tracing(ConfigTracing{Queue: queue}),
+			func(c http.Context) error { panic("boom") },
+		)
+	}()
+	_ = queue.Shutdown(context.Background())
+	if len(exporter.spans) != 1 || exporter.spans[0].Error != "panic: boom" {
+		t.Errorf("spans = %+v", exporter.spans)
+	}
+}
+
+func TestTracingErrorFromStatus(t *testing.T) {
+	spans := traceRequests(t, func(c http.Context) error {
+		c.AbortWithStatus(stdHttp.StatusBadGateway)
+		return nil
+	}, httptest.NewRequest("GET", "/", nil))
+	if len(spans) != 1 || spans[0].Status != stdHttp.StatusBadGateway || spans[0].Error != "Bad Gateway" {
+		t.Errorf("spans = %+v", spans)
+	}
+
+	spans = traceRequests(t, func(c http.Context) error {
+		c.AbortWithStatus(stdHttp.StatusNotFound)
+		return nil
+	}, httptest.NewRequest("GET", "/", nil))
+	if spans[0].Error != "" {
+		t.Errorf("4xx error = %q, want none", spans[0].Error)
+	}
+}
+
+func TestTracingErrorFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	spans := traceRequests(t, ok, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if len(spans) != 1 || spans[0].Error != context.Canceled.Error() {
+		t.Errorf("spans = %+v", spans)
+	}
+}
+
+// blockingExporter blocks every export until release is closed
+type blockingExporter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *blockingExporter) Export([]Span) {
+	select {
+	case e.started <- struct{}{}:
+	default:
+	}
+	<-e.release
+}
+
+func TestSpanQueueDropsWhenFull(t *testing.T) {
+	exporter := &blockingExporter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	queue := NewSpanQueue(exporter, ConfigSpanQueue{Size: 1, BatchSize: 1})
+	defer func() {
+		close(exporter.release)
+		_ = queue.Shutdown(context.Background())
+	}()
+
+	if !queue.Enqueue(Span{Name: "exporting"}) {
+		t.Fatal("first span dropped")
+	}
+	<-exporter.started
+	if !queue.Enqueue(Span{Name: "queued"}) {
+		t.Fatal("second span dropped")
+	}
+
+	done := make(chan bool)
+	go func() { done <- queue.Enqueue(Span{Name: "dropped"}) }()
+	select {
+	case queued := <-done:
+		if queued {
+			t.Error("span queued beyond Size")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Enqueue blocked on a full queue")
+	}
+	if queue.Dropped() != 1 {
+		t.Errorf("Dropped() = %d", queue.Dropped())
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// UAAction is what UserAgentFilter does with a blocked request
+type UAAction int
+
+const (
+	// UAActionReject responds 403 Forbidden
+	UAActionReject UAAction = iota
+	// UAActionTooManyRequests responds 429 Too Many Requests
+	UAActionTooManyRequests
+	// UAActionFlag only stores true under ContextKey and continues
+	UAActionFlag
+)
+
+// ConfigUAFilter defines the config for middleware.
+type ConfigUAFilter struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// BlockPatterns are substrings of blocked User-Agents, compared case
+	// insensitively
+	//
+	// Optional. Default: nil
+	BlockPatterns []string
+
+	// BlockRegexps are regular expressions of blocked User-Agents, compiled
+	// case insensitively
+	//
+	// Optional. Default: nil
+	BlockRegexps []string
+
+	// AllowEmpty lets requests without a User-Agent through, otherwise
+	// they are blocked
+	//
+	// Optional. Default: false
+	AllowEmpty bool
+
+	// Action taken for blocked requests
+	//
+	// Optional. Default: UAActionReject
+	Action UAAction
+
+	// ContextKey is the key the UAActionFlag stores true under
+	//
+	// Optional. Default: "ua_blocked"
+	ContextKey string
+
+	// OnBlocked is called for every blocked request with the pattern that
+	// matched, "" for a missing User-Agent
+	//
+	// Optional. Default: nil
+	OnBlocked func(c http.Context, pattern string)
+}
+
+// ConfigUAFilterDefault is the default config
+var ConfigUAFilterDefault = ConfigUAFilter{
+	Next:       nil,
+	Action:     UAActionReject,
+	ContextKey: "ua_blocked",
+}
+
+// Helper function to set default values
+func configUAFilterDefault(config ...ConfigUAFilter) ConfigUAFilter {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigUAFilterDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigUAFilterDefault.ContextKey
+	}
+	return cfg
+}
+
+// UserAgentFilter creates a new middleware handler
+func UserAgentFilter(config ConfigUAFilter) http.HandlerFunc {
+	// Set default config
+	cfg := configUAFilterDefault(config)
+
+	// Compile the patterns once
+	patterns := make([]string, len(cfg.BlockPatterns))
+	for i, p := range cfg.BlockPatterns {
+		patterns[i] = strings.ToLower(p)
+	}
+	regexps := make([]*regexp.Regexp, len(cfg.BlockRegexps))
+	for i, expr := range cfg.BlockRegexps {
+		regexps[i] = regexp.MustCompile("(?i)" + expr)
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		ua := c.Header(utils.HeaderUserAgent, "")
+		blocked, pattern := false, ""
+		if ua == "" {
+			blocked = !cfg.AllowEmpty
+		} else {
+			lower := strings.ToLower(ua)
+			for i, p := range patterns {
+				if strings.Contains(lower, p) {
+					blocked, pattern = true, cfg.BlockPatterns[i]
+					break
+				}
+			}
+			if !blocked {
+				for i, re := range regexps {
+					if re.MatchString(ua) {
+						blocked, pattern = true, cfg.BlockRegexps[i]
+						break
+					}
+				}
+			}
+		}
+		if !blocked {
+			return c.Next()
+		}
+
+		if cfg.OnBlocked != nil {
+			cfg.OnBlocked(c, pattern)
+		}
+		switch cfg.Action {
+		case UAActionFlag:
+			c.WithValue(cfg.ContextKey, true)
+			return c.Next()
+		case UAActionTooManyRequests:
+			c.AbortWithStatus(utils.StatusTooManyRequests)
+			return fmt.Errorf("%w: %q", ErrUserAgentThrottled, pattern)
+		}
+		c.AbortWithStatus(utils.StatusForbidden)
+		return fmt.Errorf("%w: %q", ErrUserAgentDenied, pattern)
+	}
+}
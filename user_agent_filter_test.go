@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func uaRequest(t *testing.T, handler http.HandlerFunc, ua string) (int, error) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	if ua != "" {
+		req.Header.Set(utils.HeaderUserAgent, ua)
+	}
+	c := run(t, req, handler, ok)
+	return c.Recorder.Code, c.Errors()[0]
+}
+
+func TestUserAgentFilter(t *testing.T) {
+	var matched []string
+	handler := UserAgentFilter(ConfigUAFilter{
+		BlockPatterns: []string{"BadBot"},
+		BlockRegexps:  []string{`^curl/\d+`, `scrap(er|y)`},
+		AllowEmpty:    true,
+		OnBlocked:     func(c http.Context, pattern string) { matched = append(matched, pattern) },
+	})
+	for _, tt := range []struct {
+		ua      string
+		pattern string
+	}{
+		{"Mozilla/5.0 (compatible; badbot/2.1)", "BadBot"},
+		{"CURL/8.4.0", `^curl/\d+`},
+		{"Scrapy/2.11 (+https://scrapy.org)", `scrap(er|y)`},
+		{"Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0", ""},
+		{"libcurl-agent/1.0", ""},
+		{"", ""},
+	} {
+		matched = nil
+		status, err := uaRequest(t, handler, tt.ua)
+		if tt.pattern == "" {
+			if status != utils.StatusOK || matched != nil {
+				t.Errorf("%q: status = %d, matched %q", tt.ua, status, matched)
+			}
+			continue
+		}
+		if status != utils.StatusForbidden || !errors.Is(err, ErrUserAgentDenied) {
+			t.Errorf("%q: status = %d, err = %v", tt.ua, status, err)
+		}
+		if len(matched) != 1 || matched[0] != tt.pattern {
+			t.Errorf("%q: OnBlocked got %q, want %q", tt.ua, matched, tt.pattern)
+		}
+	}
+}
+
+func TestUserAgentFilterEmpty(t *testing.T) {
+	var matched []string
+	handler := UserAgentFilter(ConfigUAFilter{
+		OnBlocked: func(c http.Context, pattern string) { matched = append(matched, pattern) },
+	})
+	status, err := uaRequest(t, handler, "")
+	if status != utils.StatusForbidden || !errors.Is(err, ErrUserAgentDenied) {
+		t.Errorf("status = %d, err = %v", status, err)
+	}
+	if len(matched) != 1 || matched[0] != "" {
+		t.Errorf("OnBlocked got %q", matched)
+	}
+}
+
+func TestUserAgentFilterActions(t *testing.T) {
+	status, err := uaRequest(t, UserAgentFilter(ConfigUAFilter{
+		BlockPatterns: []string{"badbot"},
+		Action:        UAActionTooManyRequests,
+	}), "BadBot/1.0")
+	if status != utils.StatusTooManyRequests || !errors.Is(err, ErrUserAgentThrottled) {
+		t.Errorf("throttle: status = %d, err = %v", status, err)
+	}
+
+	// The flag only marks the request for the handlers downstream
+	handler := UserAgentFilter(ConfigUAFilter{
+		BlockPatterns: []string{"badbot"},
+		Action:        UAActionFlag,
+		ContextKey:    "bot",
+	})
+	flagged := func(c http.Context) error {
+		if c.Value("bot") == true {
+			return c.String("flagged")
+		}
+		return c.String("ok")
+	}
+	for ua, want := range map[string]string{"BadBot/1.0": "flagged", "Mozilla/5.0": "ok"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(utils.HeaderUserAgent, ua)
+		if c := run(t, req, handler, flagged); c.Recorder.Code != utils.StatusOK || c.Body() != want {
+			t.Errorf("%q: status = %d, body = %q", ua, c.Recorder.Code, c.Body())
+		}
+	}
+}
+
+func TestUserAgentFilterInvalidRegexp(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("UserAgentFilter didn't panic on an invalid regexp")
+		}
+	}()
+	UserAgentFilter(ConfigUAFilter{BlockRegexps: []string{"(unclosed"}})
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/sujit-baniya/framework/contracts/http"
+)
+
+// UserKeyGenerator returns a limiter KeyGenerator counting requests per
+// authenticated user, read from the context keys BasicAuth and JWT store
+// the identity under, and per IP for anonymous requests. Mount the limiter
+// after the authentication middleware.
+//
+//	limiter.New(limiter.Config{KeyGenerator: middleware.UserKeyGenerator()})
+func UserKeyGenerator(keys ...string) func(c http.Context) string {
+	if len(keys) == 0 {
+		keys = []string{ConfigBasicAuthDefault.ContextUsername, ConfigJWTDefault.ContextClaims}
+	}
+	return func(c http.Context) string {
+		// The prefixes keep a user named like an IP out of its bucket
+		if user := auditPrincipal(c, keys); user != "" {
+			return "user:" + user
+		}
+		return "ip:" + c.Ip()
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+	"github.com/sujit-baniya/middleware/limiter"
+)
+
+func TestUserKeyGenerator(t *testing.T) {
+	keyHandler := func(keys ...string) http.HandlerFunc {
+		generate := UserKeyGenerator(keys...)
+		return func(c http.Context) error {
+			return c.String(generate(c))
+		}
+	}
+	withValue := func(key string, value any) http.HandlerFunc {
+		return func(c http.Context) error {
+			c.WithValue(key, value)
+			return c.Next()
+		}
+	}
+	for _, tt := range []struct {
+		name     string
+		handlers []http.HandlerFunc
+		want     string
+	}{
+		{"basic auth", []http.HandlerFunc{withValue("username", "alice"), keyHandler()}, "user:alice"},
+		{"jwt subject", []http.HandlerFunc{withValue("claims", JWTClaims{"sub": "42"}), keyHandler()}, "user:42"},
+		{"anonymous", []http.HandlerFunc{keyHandler()}, "ip:192.0.2.1"},
+		{"claims without subject", []http.HandlerFunc{withValue("claims", JWTClaims{"name": "x"}), keyHandler()}, "ip:192.0.2.1"},
+		{"custom key", []http.HandlerFunc{withValue("account", "acme"), withValue("username", "alice"), keyHandler("account")}, "user:acme"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", "192.0.2.1")
+		if c := run(t, req, tt.handlers...); c.Body() != tt.want {
+			t.Errorf("%s: key = %q, want %q", tt.name, c.Body(), tt.want)
+		}
+	}
+}
+
+func TestUserKeyGeneratorLimiter(t *testing.T) {
+	auth := BasicAuth(ConfigBasicAuth{Users: map[string]string{"alice": "a", "bob": "b"}})
+	perUser := limiter.New(limiter.Config{Max: 1, KeyGenerator: UserKeyGenerator()})
+	send := func(user, ip string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Real-IP", ip)
+		handlers := []http.HandlerFunc{perUser, ok}
+		if user != "" {
+			req.SetBasicAuth(user, user[:1])
+			handlers = append([]http.HandlerFunc{auth}, handlers...)
+		}
+		return run(t, req, handlers...).Recorder.Code
+	}
+
+	// The same user shares a bucket from any address
+	if code := send("alice", "192.0.2.1"); code != utils.StatusOK {
+		t.Errorf("alice: status = %d", code)
+	}
+	if code := send("alice", "192.0.2.2"); code != utils.StatusTooManyRequests {
+		t.Errorf("alice again: status = %d", code)
+	}
+	if code := send("bob", "192.0.2.1"); code != utils.StatusOK {
+		t.Errorf("bob: status = %d", code)
+	}
+
+	// Anonymous requests are counted per address
+	if code := send("", "192.0.2.1"); code != utils.StatusOK {
+		t.Errorf("anonymous: status = %d", code)
+	}
+	if code := send("", "192.0.2.1"); code != utils.StatusTooManyRequests {
+		t.Errorf("anonymous again: status = %d", code)
+	}
+	if code := send("", "192.0.2.3"); code != utils.StatusOK {
+		t.Errorf("anonymous elsewhere: status = %d", code)
+	}
+}
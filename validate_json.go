@@ -0,0 +1,458 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// ConfigValidateJSON defines the config for middleware.
+type ConfigValidateJSON struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// ContextKey is the key to store the decoded body in the context
+	//
+	// Optional. Default: "json"
+	ContextKey string
+
+	// MaxBodySize is the largest body read in bytes, a negative value
+	// disables the check
+	//
+	// Optional. Default: 1 MB
+	MaxBodySize int
+
+	// ErrorHandler is called with 400 for malformed JSON, with 413 when the
+	// body is larger than MaxBodySize and with 422 and the field errors
+	// when the body doesn't match the schema
+	//
+	// Optional. Default: responds with the status and a JSON error list
+	ErrorHandler func(c http.Context, status int, errs []SchemaError) error
+}
+
+// ConfigValidateJSONDefault is the default config
+var ConfigValidateJSONDefault = ConfigValidateJSON{
+	Next:        nil,
+	ContextKey:  "json",
+	MaxBodySize: defaultMaxBufferSize,
+	ErrorHandler: func(c http.Context, status int, errs []SchemaError) error {
+		return c.Status(status).Json(map[string]any{"errors": errs})
+	},
+}
+
+// Helper function to set default values
+func configValidateJSONDefault(config ...ConfigValidateJSON) ConfigValidateJSON {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigValidateJSONDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigValidateJSONDefault.ContextKey
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = ConfigValidateJSONDefault.MaxBodySize
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigValidateJSONDefault.ErrorHandler
+	}
+	return cfg
+}
+
+// SchemaError describes a value that doesn't match the schema
+type SchemaError struct {
+	// Field is the JSON pointer of the offending value
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidateJSON creates a new middleware handler validating request bodies
+// against a JSON Schema. The supported keywords are type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum and
+// exclusiveMaximum, annotations like title and description are ignored. It
+// panics when the schema can't be compiled or uses any other keyword.
+func ValidateJSON(schema []byte, config ...ConfigValidateJSON) http.HandlerFunc {
+	// Set default config
+	cfg := configValidateJSONDefault(config...)
+
+	// Compile the schema once
+	var raw map[string]any
+	if err := json.Unmarshal(schema, &raw); err != nil {
+		panic(fmt.Errorf("validate json: %w", err))
+	}
+	compiled, err := compileSchema(raw)
+	if err != nil {
+		panic(fmt.Errorf("validate json: %w", err))
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		req := c.Origin()
+		if req.Body == nil {
+			return cfg.ErrorHandler(c, utils.StatusBadRequest, []SchemaError{{Field: "", Message: "body is required"}})
+		}
+		tooLarge := []SchemaError{{Field: "", Message: fmt.Sprintf("body is larger than %d bytes", cfg.MaxBodySize)}}
+		if cfg.MaxBodySize >= 0 && req.ContentLength > int64(cfg.MaxBodySize) {
+			return cfg.ErrorHandler(c, utils.StatusRequestEntityTooLarge, tooLarge)
+		}
+		var r io.Reader = req.Body
+		if cfg.MaxBodySize >= 0 {
+			// Read one byte more to tell a body of exactly MaxBodySize from
+			// a larger one
+			r = io.LimitReader(r, int64(cfg.MaxBodySize)+1)
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return cfg.ErrorHandler(c, utils.StatusBadRequest, []SchemaError{{Field: "", Message: err.Error()}})
+		}
+		if cfg.MaxBodySize >= 0 && len(body) > cfg.MaxBodySize {
+			return cfg.ErrorHandler(c, utils.StatusRequestEntityTooLarge, tooLarge)
+		}
+		// Leave the body for the handler
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var value any
+		if err = json.Unmarshal(body, &value); err != nil {
+			return cfg.ErrorHandler(c, utils.StatusBadRequest, []SchemaError{{Field: "", Message: err.Error()}})
+		}
+		if errs := compiled.validate("", value, nil); len(errs) > 0 {
+			return cfg.ErrorHandler(c, utils.StatusUnprocessableEntity, errs)
+		}
+
+		c.WithValue(cfg.ContextKey, value)
+		return c.Next()
+	}
+}
+
+// jsonSchema is a compiled schema node
+type jsonSchema struct {
+	types                []string
+	enum                 []any
+	constant             any
+	hasConst             bool
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *bool
+	items                *jsonSchema
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+}
+
+// schemaKeywords are the keywords compileSchema understands; annotations
+// are accepted and ignored
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true,
+	"required": true, "additionalProperties": true, "items": true,
+	"minItems": true, "maxItems": true, "minLength": true, "maxLength": true,
+	"pattern": true, "minimum": true, "maximum": true,
+	"exclusiveMinimum": true, "exclusiveMaximum": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+}
+
+func compileSchema(raw map[string]any) (*jsonSchema, error) {
+	// Refuse what would otherwise be skipped, a schema with allOf or $ref
+	// would accept any body
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		if !schemaKeywords[k] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return nil, fmt.Errorf("unsupported keyword %q", keys[0])
+	}
+	s := &jsonSchema{}
+	switch t := raw["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []any:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("type must be a string or an array of strings")
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("type must be a string or an array of strings")
+	}
+	// A supported keyword of an unexpected form, such as a schema for
+	// additionalProperties or a draft-4 boolean exclusiveMinimum, is refused
+	// rather than ignored
+	if v, ok := raw["enum"]; ok {
+		enum, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("enum must be an array")
+		}
+		s.enum = enum
+	}
+	if constant, ok := raw["const"]; ok {
+		s.constant, s.hasConst = constant, true
+	}
+	if v, ok := raw["properties"]; ok {
+		props, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("properties must be an object")
+		}
+		s.properties = make(map[string]*jsonSchema, len(props))
+		for name, prop := range props {
+			child, ok := prop.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("property %q must be a schema", name)
+			}
+			compiled, err := compileSchema(child)
+			if err != nil {
+				return nil, fmt.Errorf("property %q: %w", name, err)
+			}
+			s.properties[name] = compiled
+		}
+	}
+	if v, ok := raw["required"]; ok {
+		required, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("required must be an array of strings")
+		}
+		for _, v := range required {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("required must be an array of strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if v, ok := raw["additionalProperties"]; ok {
+		additional, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("additionalProperties must be a boolean")
+		}
+		s.additionalProperties = &additional
+	}
+	if v, ok := raw["items"]; ok {
+		items, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("items must be a schema")
+		}
+		compiled, err := compileSchema(items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		s.items = compiled
+	}
+	if v, ok := raw["pattern"]; ok {
+		pattern, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern must be a string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		s.pattern = re
+	}
+	var err error
+	if s.minItems, err = schemaInt(raw, "minItems"); err != nil {
+		return nil, err
+	}
+	if s.maxItems, err = schemaInt(raw, "maxItems"); err != nil {
+		return nil, err
+	}
+	if s.minLength, err = schemaInt(raw, "minLength"); err != nil {
+		return nil, err
+	}
+	if s.maxLength, err = schemaInt(raw, "maxLength"); err != nil {
+		return nil, err
+	}
+	if s.minimum, err = schemaFloat(raw, "minimum"); err != nil {
+		return nil, err
+	}
+	if s.maximum, err = schemaFloat(raw, "maximum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMinimum, err = schemaFloat(raw, "exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMaximum, err = schemaFloat(raw, "exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func schemaInt(raw map[string]any, key string) (*int, error) {
+	v, ok := raw[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	i := int(f)
+	return &i, nil
+}
+
+func schemaFloat(raw map[string]any, key string) (*float64, error) {
+	v, ok := raw[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", key)
+	}
+	return &f, nil
+}
+
+// validate appends an error for every violation found in value
+func (s *jsonSchema) validate(path string, value any, errs []SchemaError) []SchemaError {
+	fail := func(format string, args ...any) {
+		errs = append(errs, SchemaError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 {
+		matched := false
+		for _, t := range s.types {
+			if jsonTypeMatches(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must be of type %s", joinTypes(s.types))
+			return errs
+		}
+	}
+	if len(s.enum) > 0 {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of the allowed values")
+		}
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constant, value) {
+		fail("must be equal to the constant value")
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, SchemaError{Field: path + "/" + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		// Report errors in a stable order
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.properties[name]; ok {
+				errs = prop.validate(path+"/"+name, v[name], errs)
+			} else if s.additionalProperties != nil && !*s.additionalProperties {
+				errs = append(errs, SchemaError{Field: path + "/" + name, Message: "is not allowed"})
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				errs = s.items.validate(path+"/"+strconv.Itoa(i), item, errs)
+			}
+		}
+	case string:
+		length := len([]rune(v))
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match pattern %s", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail("must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail("must be < %v", *s.exclusiveMaximum)
+		}
+	}
+	return errs
+}
+
+func jsonTypeMatches(t string, value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	var b bytes.Buffer
+	for i, t := range types {
+		if i > 0 {
+			b.WriteString(" or ")
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
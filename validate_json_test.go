@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+var userSchema = []byte(`{
+	"type": "object",
+	"required": ["name", "email"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 20},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+	}
+}`)
+
+func TestValidateJSONValid(t *testing.T) {
+	body := `{"name":"Jane","email":"jane@example.com","age":30,"role":"admin","tags":["a"]}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	c := run(t, req, ValidateJSON(userSchema), func(c http.Context) error {
+		// The parsed body is in the context and the body is still readable
+		parsed, _ := c.Value("json").(map[string]any)
+		if parsed["name"] != "Jane" || parsed["age"] != float64(30) {
+			t.Errorf("parsed = %v", parsed)
+		}
+		raw, _ := io.ReadAll(c.Origin().Body)
+		return c.String(string(raw))
+	})
+	if c.Body() != body {
+		t.Errorf("handler read %q", c.Body())
+	}
+}
+
+func TestValidateJSONInvalid(t *testing.T) {
+	body := `{"name":"","email":"nope","age":1.5,"role":"root","tags":["a",2,"c"],"extra":true}`
+	c := run(t, httptest.NewRequest("POST", "/", strings.NewReader(body)), ValidateJSON(userSchema), ok)
+	if c.Recorder.Code != utils.StatusUnprocessableEntity {
+		t.Fatalf("status = %d", c.Recorder.Code)
+	}
+	var res struct{ Errors []SchemaError }
+	if err := json.Unmarshal(c.Recorder.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	want := []SchemaError{
+		{Field: "/age", Message: "must be of type integer"},
+		{Field: "/email", Message: "must match pattern ^[^@]+@[^@]+$"},
+		{Field: "/extra", Message: "is not allowed"},
+		{Field: "/name", Message: "must be at least 1 characters"},
+		{Field: "/role", Message: "must be one of the allowed values"},
+		{Field: "/tags", Message: "must have at most 2 items"},
+		{Field: "/tags/1", Message: "must be of type string"},
+	}
+	if !reflect.DeepEqual(res.Errors, want) {
+		t.Errorf("errors = %+v", res.Errors)
+	}
+
+	c = run(t, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"Jane"}`)), ValidateJSON(userSchema), ok)
+	if !strings.Contains(c.Body(), `{"field":"/email","message":"is required"}`) {
+		t.Errorf("missing field: %s", c.Body())
+	}
+}
+
+func TestValidateJSONMalformed(t *testing.T) {
+	c := run(t, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":`)), ValidateJSON(userSchema), ok)
+	if c.Recorder.Code != utils.StatusBadRequest || c.Body() == "ok" {
+		t.Errorf("status = %d, body = %q", c.Recorder.Code, c.Body())
+	}
+}
+
+func TestValidateJSONInvalidSchema(t *testing.T) {
+	for _, schema := range []string{`{`, `{"type": 1}`, `{"properties": {"a": {"pattern": "("}}}`,
+		`{"$ref": "#/definitions/user"}`, `{"allOf": [{"type": "string"}]}`, `{"items": {"format": "email"}}`,
+		// Supported keywords in a form that isn't
+		`{"additionalProperties": {"type": "string"}}`, `{"items": [{"type": "string"}]}`,
+		`{"minimum": 0, "exclusiveMinimum": true}`, `{"exclusiveMaximum": false}`, `{"required": "name"}`,
+		`{"properties": []}`, `{"enum": "a"}`, `{"pattern": 1}`, `{"minLength": "1"}`, `{"maxItems": 1.5}`} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s compiled", schema)
+				}
+			}()
+			ValidateJSON([]byte(schema))
+		}()
+	}
+}
+
+func TestValidateJSONMaxBodySize(t *testing.T) {
+	body := `{"name":"Jane","email":"jane@example.com"}`
+	handler := ValidateJSON(userSchema, ConfigValidateJSON{MaxBodySize: len(body) - 1})
+	for _, length := range []int64{int64(len(body)), -1} {
+		// An unknown length is caught while reading
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.ContentLength = length
+		c := run(t, req, handler, ok)
+		if c.Recorder.Code != utils.StatusRequestEntityTooLarge || c.Body() == "ok" {
+			t.Errorf("length %d: status = %d, body = %q", length, c.Recorder.Code, c.Body())
+		}
+	}
+
+	for _, max := range []int{len(body), -1} {
+		c := run(t, httptest.NewRequest("POST", "/", strings.NewReader(body)), ValidateJSON(userSchema, ConfigValidateJSON{MaxBodySize: max}), ok)
+		if c.Body() != "ok" {
+			t.Errorf("max %d: status = %d, body = %q", max, c.Recorder.Code, c.Body())
+		}
+	}
+}
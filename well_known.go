@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/sujit-baniya/framework/contracts/http"
+	"github.com/sujit-baniya/framework/utils"
+)
+
+// wellKnownPrefix is the path of the well-known URIs of RFC 8615
+const wellKnownPrefix = "/.well-known/"
+
+// ConfigWellKnown defines the config for middleware.
+type ConfigWellKnown struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c http.Context) bool
+
+	// ACMETokens maps the tokens of pending HTTP-01 challenges to their key
+	// authorization, served under /.well-known/acme-challenge/<token>
+	//
+	// Optional. Default: nil
+	ACMETokens map[string]string
+
+	// ACMEChallenge looks up tokens missing from ACMETokens, e.g. from a
+	// store the certificate client writes to while it runs
+	//
+	// Optional. Default: nil
+	ACMEChallenge func(token string) (string, bool)
+
+	// SecurityTxt is served as /.well-known/security.txt
+	//
+	// Optional. Default: ""
+	SecurityTxt string
+
+	// Files maps other names below /.well-known/ to their content
+	//
+	// Optional. Default: nil
+	Files map[string]string
+}
+
+// ConfigWellKnownDefault is the default config
+var ConfigWellKnownDefault = ConfigWellKnown{
+	Next: nil,
+}
+
+// Helper function to set default values
+func configWellKnownDefault(config ...ConfigWellKnown) ConfigWellKnown {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigWellKnownDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+	return cfg
+}
+
+// WellKnown creates a new middleware handler answering well-known URIs
+// itself. Mount it before authentication and rate limits so certificate
+// authorities and researchers always reach them. Paths that aren't
+// configured continue the stack.
+func WellKnown(config ConfigWellKnown) http.HandlerFunc {
+	// Set default config
+	cfg := configWellKnownDefault(config)
+
+	files := make(map[string]string, len(cfg.Files)+1)
+	for name, content := range cfg.Files {
+		files[strings.Trim(name, "/")] = content
+	}
+	if cfg.SecurityTxt != "" {
+		files["security.txt"] = cfg.SecurityTxt
+	}
+
+	// Return new handler
+	return func(c http.Context) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if c.Method() != utils.MethodGet && c.Method() != utils.MethodHead {
+			return c.Next()
+		}
+		name := strings.TrimPrefix(c.Origin().URL.Path, wellKnownPrefix)
+		if len(name) == len(c.Origin().URL.Path) {
+			return c.Next()
+		}
+
+		content, ok := files[name]
+		if token := strings.TrimPrefix(name, "acme-challenge/"); !ok && len(token) < len(name) {
+			content, ok = cfg.ACMETokens[token]
+			if !ok && cfg.ACMEChallenge != nil && token != "" {
+				content, ok = cfg.ACMEChallenge(token)
+			}
+		}
+		if !ok {
+			return c.Next()
+		}
+
+		c.SetHeader(utils.HeaderContentType, "text/plain; charset=utf-8")
+		c.Status(utils.StatusOK)
+		if c.Method() == utils.MethodHead {
+			return nil
+		}
+		return c.String("%s", content)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sujit-baniya/framework/utils"
+)
+
+func TestWellKnown(t *testing.T) {
+	handler := WellKnown(ConfigWellKnown{
+		ACMETokens:  map[string]string{"tok1": "tok1.thumbprint"},
+		SecurityTxt: "Contact: mailto:security@example.com\n",
+		Files:       map[string]string{"/change-password": "/account/password"},
+		ACMEChallenge: func(token string) (string, bool) {
+			if token == "tok2" {
+				return "tok2.thumbprint", true
+			}
+			return "", false
+		},
+	})
+	for _, tt := range []struct {
+		method, target string
+		want           string
+	}{
+		{"GET", "/.well-known/acme-challenge/tok1", "tok1.thumbprint"},
+		{"GET", "/.well-known/acme-challenge/tok2", "tok2.thumbprint"},
+		{"GET", "/.well-known/security.txt", "Contact: mailto:security@example.com\n"},
+		{"GET", "/.well-known/change-password", "/account/password"},
+		{"HEAD", "/.well-known/acme-challenge/tok1", ""},
+	} {
+		c := run(t, httptest.NewRequest(tt.method, tt.target, nil), handler, ok)
+		if c.Recorder.Code != utils.StatusOK || c.Body() != tt.want || c.Recorder.Header().Get(utils.HeaderContentType) != "text/plain; charset=utf-8" {
+			t.Errorf("%s %s: status = %d, body = %q, Content-Type = %q", tt.method, tt.target, c.Recorder.Code, c.Body(), c.Recorder.Header().Get(utils.HeaderContentType))
+		}
+		if c.CalledInOrder("Next") {
+			t.Errorf("%s %s: continued the stack", tt.method, tt.target)
+		}
+	}
+
+	// Everything else reaches the rest of the stack
+	for _, tt := range []struct {
+		method, target string
+	}{
+		{"GET", "/.well-known/acme-challenge/unknown"},
+		{"GET", "/.well-known/acme-challenge/"},
+		{"GET", "/.well-known/openid-configuration"},
+		{"GET", "/security.txt"},
+		{"POST", "/.well-known/acme-challenge/tok1"},
+	} {
+		if c := run(t, httptest.NewRequest(tt.method, tt.target, nil), handler, ok); c.Body() != "ok" {
+			t.Errorf("%s %s: body = %q", tt.method, tt.target, c.Body())
+		}
+	}
+}